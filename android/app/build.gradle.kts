@@ -55,6 +55,7 @@ tasks.register("generateKotlinModels") {
     inputs.file(schemaFile)
     inputs.file(File(rootDir, "scripts/generate-kotlin-models.go"))
     inputs.file(File(rootDir, "scripts/go.mod"))
+    inputs.dir(File(rootDir, "util/schema"))
     outputs.file(outputFile)
 
     doLast {
@@ -75,6 +76,7 @@ tasks.register("generateKotlinEvents") {
     inputs.file(schemaFile)
     inputs.file(File(rootDir, "scripts/generate-kotlin-models.go"))
     inputs.file(File(rootDir, "scripts/go.mod"))
+    inputs.dir(File(rootDir, "util/schema"))
     outputs.file(outputFile)
 
     doLast {
@@ -95,6 +97,7 @@ tasks.register("generateKotlinRoutes") {
     inputs.file(schemaFile)
     inputs.file(File(rootDir, "scripts/generate-kotlin-models.go"))
     inputs.file(File(rootDir, "scripts/go.mod"))
+    inputs.dir(File(rootDir, "util/schema"))
     outputs.file(outputFile)
 
     doLast {
@@ -8,6 +8,7 @@ import android.widget.TextView
 import androidx.recyclerview.widget.RecyclerView
 import com.tomyedwab.yellowstone.R
 import com.tomyedwab.yellowstone.generated.TaskList
+import com.tomyedwab.yellowstone.generated.TaskListCategory
 import java.util.Collections
 
 class TaskListAdapter : RecyclerView.Adapter<TaskListAdapter.TaskListViewHolder> {
@@ -53,7 +54,7 @@ class TaskListAdapter : RecyclerView.Adapter<TaskListAdapter.TaskListViewHolder>
             if (metadata != null) {
                 val (totalTasks, completedTasks) = metadata
                 // For templates, only show task count, not completion status
-                if (taskList.category == "template") {
+                if (taskList.category == TaskListCategory.TEMPLATE) {
                     tvItemCount.text = "$totalTasks tasks"
                 } else {
                     tvItemCount.text = "$totalTasks tasks, $completedTasks completed"
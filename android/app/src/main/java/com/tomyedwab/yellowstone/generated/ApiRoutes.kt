@@ -6,7 +6,7 @@ import com.tomyedwab.yellowstone.services.connection.DataViewResult
 import com.tomyedwab.yellowstone.services.connection.DataViewService
 import com.tomyedwab.yellowstone.provider.connection.HubConnectionState
 
-// Auto-generated from backend/tasks/schema/api.yml
+// Auto-generated from the tasks schema's api.yml
 // Do not edit this file directly
 
 class ApiRoutes(
@@ -15,16 +15,19 @@ class ApiRoutes(
 ) {
 
     /**
-     * Get all tasks for a specific task list
+     * Get all tasks for a specific task list, evaluating the filter for smart lists
      */
-    fun getTaskList(listId: Int): LiveData<DataViewResult<TaskResponse>> {
+    fun getTaskList(listId: Int, sort: TaskSortMode? = null, hideCompleted: Boolean? = null, includeSnoozed: Boolean? = null): LiveData<DataViewResult<TaskResponse>> {
         return dataViewService.createDataView(
             connectionState = connectionState,
             componentName = "tasks",
             apiPath = "api/task/list",
-            apiParams = mapOf(
-                "listId" to listId.toString()
-            ),
+            apiParams = listOfNotNull(
+                "listId" to listId.toString(),
+                sort?.let { "sort" to it.value },
+                hideCompleted?.let { "hideCompleted" to it.toString() },
+                includeSnoozed?.let { "includeSnoozed" to it.toString() }
+            ).toMap(),
             typeToken = object : TypeToken<TaskResponse>() {}
         )
     }
@@ -56,6 +59,46 @@ class ApiRoutes(
             typeToken = object : TypeToken<TaskHistoryResponse>() {}
         )
     }
+    /**
+     * Get the reminders for a specific task
+     */
+    fun getTaskReminders(taskId: Int): LiveData<DataViewResult<TaskReminderResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/task/reminders",
+            apiParams = mapOf(
+                "taskId" to taskId.toString()
+            ),
+            typeToken = object : TypeToken<TaskReminderResponse>() {}
+        )
+    }
+    /**
+     * Get a task's notes rendered to HTML
+     */
+    fun getTaskNotes(id: Int): LiveData<DataViewResult<TaskNotesResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/task/notes",
+            apiParams = mapOf(
+                "id" to id.toString()
+            ),
+            typeToken = object : TypeToken<TaskNotesResponse>() {}
+        )
+    }
+    /**
+     * Get recently deleted tasks. Deleted tasks are kept for 30 days, and then permanently deleted
+     */
+    fun getTaskTrash(): LiveData<DataViewResult<DeletedTaskResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/task/trash",
+            apiParams = emptyMap(),
+            typeToken = object : TypeToken<DeletedTaskResponse>() {}
+        )
+    }
     /**
      * Get a specific task list by ID
      */
@@ -106,6 +149,32 @@ class ApiRoutes(
             typeToken = object : TypeToken<TaskListResponse>() {}
         )
     }
+    /**
+     * Get all task lists in the 'smart' category
+     */
+    fun getTasklistSmart(): LiveData<DataViewResult<TaskListResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/tasklist/smart",
+            apiParams = emptyMap(),
+            typeToken = object : TypeToken<TaskListResponse>() {}
+        )
+    }
+    /**
+     * Get the filter that defines the contents of a smart list
+     */
+    fun getTasklistFilter(listId: Int): LiveData<DataViewResult<TaskListFilter>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/tasklist/filter",
+            apiParams = mapOf(
+                "listId" to listId.toString()
+            ),
+            typeToken = object : TypeToken<TaskListFilter>() {}
+        )
+    }
     /**
      * Get all archived task lists
      */
@@ -119,14 +188,28 @@ class ApiRoutes(
         )
     }
     /**
-     * Get metadata (total and completed task counts) for all task lists
+     * Get all task lists in the trash
      */
-    fun getTasklistMetadata(): LiveData<DataViewResult<TaskListMetadataResponse>> {
+    fun getTasklistTrash(): LiveData<DataViewResult<TrashedTaskListResponse>> {
         return dataViewService.createDataView(
             connectionState = connectionState,
             componentName = "tasks",
-            apiPath = "api/tasklist/metadata",
+            apiPath = "api/tasklist/trash",
             apiParams = emptyMap(),
+            typeToken = object : TypeToken<TrashedTaskListResponse>() {}
+        )
+    }
+    /**
+     * Get metadata (total and completed task counts) for all task lists, including smart lists
+     */
+    fun getTasklistMetadata(includeSnoozed: Boolean? = null): LiveData<DataViewResult<TaskListMetadataResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/tasklist/metadata",
+            apiParams = listOfNotNull(
+                includeSnoozed?.let { "includeSnoozed" to it.toString() }
+            ).toMap(),
             typeToken = object : TypeToken<TaskListMetadataResponse>() {}
         )
     }
@@ -158,4 +241,68 @@ class ApiRoutes(
             typeToken = object : TypeToken<TaskLabelsResponse>() {}
         )
     }
+    /**
+     * Render Markdown to HTML the same way task notes are rendered, e.g. to preview an edit
+     */
+    fun getMarkdownRender(text: String): LiveData<DataViewResult<RenderedMarkdownResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/markdown/render",
+            apiParams = mapOf(
+                "text" to text.toString()
+            ),
+            typeToken = object : TypeToken<RenderedMarkdownResponse>() {}
+        )
+    }
+    /**
+     * Full-text search across task titles, comments and task list titles
+     */
+    fun getSearch(q: String): LiveData<DataViewResult<SearchResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/search",
+            apiParams = mapOf(
+                "q" to q.toString()
+            ),
+            typeToken = object : TypeToken<SearchResponse>() {}
+        )
+    }
+    /**
+     * Get all calendar feeds, including their tokens
+     */
+    fun getCalendarfeedList(): LiveData<DataViewResult<CalendarFeedResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/calendarfeed/list",
+            apiParams = emptyMap(),
+            typeToken = object : TypeToken<CalendarFeedResponse>() {}
+        )
+    }
+    /**
+     * Export every list and task as a versioned JSON document, for backups and for moving to another server
+     */
+    fun getExport(): LiveData<DataViewResult<ExportDocument>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/export",
+            apiParams = emptyMap(),
+            typeToken = object : TypeToken<ExportDocument>() {}
+        )
+    }
+    /**
+     * Get the most recent events that can be undone with an Undo:Apply event
+     */
+    fun getUndo(): LiveData<DataViewResult<UndoResponse>> {
+        return dataViewService.createDataView(
+            connectionState = connectionState,
+            componentName = "tasks",
+            apiPath = "api/undo",
+            apiParams = emptyMap(),
+            typeToken = object : TypeToken<UndoResponse>() {}
+        )
+    }
 }
\ No newline at end of file
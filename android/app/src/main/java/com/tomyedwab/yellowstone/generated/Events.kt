@@ -7,13 +7,58 @@ import java.text.SimpleDateFormat
 import java.util.*
 import java.util.UUID
 
-// Auto-generated from backend/tasks/schema/events.yml
+// Auto-generated from the tasks schema's events.yml
 // Do not edit this file directly
 
 class Events(
     private val connectionStateProvider: ConnectionStateProvider
 ) {
 
+    /**
+     * Event to create an iCalendar feed of the tasks with a due date
+     */
+    fun calendarFeedAdd(includeEvents: Boolean, labelId: Int?, listId: Int?, title: String) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "CalendarFeed:Add",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "IncludeEvents" to includeEvents,
+                "LabelId" to labelId,
+                "ListId" to listId,
+                "Title" to title
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to delete a calendar feed, so its URL stops working
+     */
+    fun calendarFeedDelete(feedId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "CalendarFeed:Delete",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "FeedId" to feedId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to replace a calendar feed's token, so the old feed URL stops working
+     */
+    fun calendarFeedResetToken(feedId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "CalendarFeed:ResetToken",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "FeedId" to feedId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to add a new task
      */
@@ -46,7 +91,23 @@ class Events(
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
     /**
-     * Event to delete a task
+     * Event to add a reminder to a task, either at a fixed time or relative to the due date
+     */
+    fun taskAddReminder(minutesBeforeDue: Int?, remindAt: String?, taskId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:AddReminder",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "MinutesBeforeDue" to minutesBeforeDue,
+                "RemindAt" to remindAt,
+                "TaskId" to taskId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to move a task to the trash, making its subtasks top-level tasks
      */
     fun taskDelete(taskId: Int) {
         val event = PendingEvent(
@@ -59,6 +120,93 @@ class Events(
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to permanently delete the tasks that were moved to the trash before a time, published by the server once they are past the retention window
+     */
+    fun taskPurgeTrash(purgeBefore: String) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:PurgeTrash",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "PurgeBefore" to purgeBefore
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to remove a reminder from a task
+     */
+    fun taskRemoveReminder(reminderId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:RemoveReminder",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "ReminderId" to reminderId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to restore a deleted task to the lists and position it was in, along with the subtasks it had
+     */
+    fun taskRestore(taskId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:Restore",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "TaskId" to taskId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to make a task a subtask of another task, or a top-level task again
+     */
+    fun taskSetParent(afterTaskId: Int?, parentTaskId: Int?, taskId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:SetParent",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "AfterTaskId" to afterTaskId,
+                "ParentTaskId" to parentTaskId,
+                "TaskId" to taskId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to hide a task from its lists until a given time
+     */
+    fun taskSnooze(taskId: Int, until: String) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:Snooze",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "TaskId" to taskId,
+                "Until" to until
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to show a snoozed task in its lists again right away
+     */
+    fun taskUnsnooze(taskId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:Unsnooze",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "TaskId" to taskId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to update a task's completion status
      */
@@ -89,6 +237,73 @@ class Events(
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to store a to-do that a CalDAV client put, creating the task or changing all of its fields at once
+     */
+    fun taskUpdateFromCalDAV(completedAt: String?, dueDate: String?, notes: String, priority: Int?, resourceName: String, resourceUid: String, taskId: Int?, taskListId: Int, title: String) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:UpdateFromCalDAV",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "CompletedAt" to completedAt,
+                "DueDate" to dueDate,
+                "Notes" to notes,
+                "Priority" to priority,
+                "ResourceName" to resourceName,
+                "ResourceUid" to resourceUid,
+                "TaskId" to taskId,
+                "TaskListId" to taskListId,
+                "Title" to title
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to update a task's notes
+     */
+    fun taskUpdateNotes(notes: String, taskId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:UpdateNotes",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "Notes" to notes,
+                "TaskId" to taskId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to update a task's priority
+     */
+    fun taskUpdatePriority(priority: Int?, taskId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:UpdatePriority",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "Priority" to priority,
+                "TaskId" to taskId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to update a task's recurrence rule
+     */
+    fun taskUpdateRecurrence(recurrence: String?, taskId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Task:UpdateRecurrence",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "Recurrence" to recurrence,
+                "TaskId" to taskId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to update a task's title
      */
@@ -107,7 +322,7 @@ class Events(
     /**
      * Event to add a new task list
      */
-    fun taskListAdd(archived: Boolean, category: String, title: String) {
+    fun taskListAdd(archived: Boolean, category: TaskListCategory, title: String) {
         val event = PendingEvent(
             clientId = UUID.randomUUID().toString(),
             type = "TaskList:Add",
@@ -150,6 +365,21 @@ class Events(
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to move a task list to the trash, removing all of its tasks from it
+     */
+    fun taskListDelete(deleteOrphanedTasks: Boolean, listId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "TaskList:Delete",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "DeleteOrphanedTasks" to deleteOrphanedTasks,
+                "ListId" to listId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to duplicate tasks to another list
      */
@@ -165,6 +395,23 @@ class Events(
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to create a new to-do list from a template list, duplicating all of its tasks in order
+     */
+    fun taskListInstantiate(placeholders: List<TemplatePlaceholder>, startDate: String?, templateListId: Int, title: String) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "TaskList:Instantiate",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "Placeholders" to placeholders,
+                "StartDate" to startDate,
+                "TemplateListId" to templateListId,
+                "Title" to title
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to move tasks from one list to another
      */
@@ -181,6 +428,35 @@ class Events(
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to permanently delete the task lists that were moved to the trash before a time, published by the server once they are past the retention window
+     */
+    fun taskListPurgeTrash(purgeBefore: String) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "TaskList:PurgeTrash",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "PurgeBefore" to purgeBefore
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
+    /**
+     * Event to remove tasks from a list, without removing their subtasks
+     */
+    fun taskListRemoveTasks(listId: Int, taskIds: List<Int>) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "TaskList:RemoveTasks",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "ListId" to listId,
+                "TaskIds" to taskIds
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to reorder a task list
      */
@@ -199,19 +475,34 @@ class Events(
     /**
      * Event to reorder tasks within a list
      */
-    fun taskListReorderTasks(afterTaskId: Int?, oldTaskId: Int, taskListId: Int) {
+    fun taskListReorderTasks(afterTaskId: Int?, beforeTaskId: Int?, oldTaskId: Int, taskListId: Int) {
         val event = PendingEvent(
             clientId = UUID.randomUUID().toString(),
             type = "TaskList:ReorderTasks",
             timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
             data = mapOf(
                 "AfterTaskId" to afterTaskId,
+                "BeforeTaskId" to beforeTaskId,
                 "OldTaskId" to oldTaskId,
                 "TaskListId" to taskListId
             )
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to restore a task list from the trash along with its tasks
+     */
+    fun taskListRestore(listId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "TaskList:Restore",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "ListId" to listId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to update a task list's archived status
      */
@@ -227,6 +518,24 @@ class Events(
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to update the filter that defines the contents of a smart list
+     */
+    fun taskListUpdateFilter(dueWithinDays: Int?, includeCompleted: Boolean, labelIds: List<Int>, listId: Int, listIds: List<Int>) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "TaskList:UpdateFilter",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "DueWithinDays" to dueWithinDays,
+                "IncludeCompleted" to includeCompleted,
+                "LabelIds" to labelIds,
+                "ListId" to listId,
+                "ListIds" to listIds
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
     /**
      * Event to update a task list's title
      */
@@ -242,4 +551,18 @@ class Events(
         )
         connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
     }
+    /**
+     * Event to undo an earlier event by applying its compensating events
+     */
+    fun undoApply(eventId: Int) {
+        val event = PendingEvent(
+            clientId = UUID.randomUUID().toString(),
+            type = "Undo:Apply",
+            timestamp = SimpleDateFormat("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").apply { timeZone = TimeZone.getTimeZone("UTC") }.format(Date()),
+            data = mapOf(
+                "EventId" to eventId
+            )
+        )
+        connectionStateProvider.dispatch(ConnectionAction.PublishEvent(event))
+    }
 }
\ No newline at end of file
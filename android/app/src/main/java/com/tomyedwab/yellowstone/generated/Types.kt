@@ -2,9 +2,142 @@ package com.tomyedwab.yellowstone.generated
 
 import com.google.gson.annotations.SerializedName
 
-// Auto-generated from backend/tasks/schema/types.yml
+// Auto-generated from the tasks schema's types.yml
 // Do not edit this file directly
 
+/**
+ * An iCalendar feed of the tasks with a due date, which calendar apps can subscribe to
+ */
+data class CalendarFeed(
+    @SerializedName("Id") val id: Int,
+    @SerializedName("IncludeEvents") val includeEvents: Boolean,
+    @SerializedName("LabelId") val labelId: Int?,
+    @SerializedName("ListId") val listId: Int?,
+    @SerializedName("Title") val title: String,
+    @SerializedName("Token") val token: String
+)
+/**
+ * Response containing all calendar feeds
+ */
+data class CalendarFeedResponse(
+    @SerializedName("Feeds") val feeds: List<CalendarFeed>
+)
+/**
+ * A task in the trash
+ */
+data class DeletedTask(
+    @SerializedName("DeletedAt") val deletedAt: String,
+    @SerializedName("Lists") val lists: List<TaskList>,
+    @SerializedName("PurgeAfter") val purgeAfter: String,
+    @SerializedName("Task") val task: Task
+)
+/**
+ * Response containing recently deleted tasks
+ */
+data class DeletedTaskResponse(
+    @SerializedName("Tasks") val tasks: List<DeletedTask>
+)
+/**
+ * A portable backup of every list and task, produced by /api/export and read back by /api/import
+ */
+data class ExportDocument(
+    @SerializedName("ExportedAt") val exportedAt: String,
+    @SerializedName("Lists") val lists: List<ExportedList>,
+    @SerializedName("Tasks") val tasks: List<ExportedTask>,
+    @SerializedName("Version") val version: Int
+)
+/**
+ * A history entry in an export document. Only comments are imported; the rest of the history is recreated by the import itself
+ */
+data class ExportedHistory(
+    @SerializedName("CreatedAt") val createdAt: String,
+    @SerializedName("SystemComment") val systemComment: String,
+    @SerializedName("UpdateType") val updateType: TaskUpdateType,
+    @SerializedName("UserComment") val userComment: String?
+)
+/**
+ * A list in an export document
+ */
+data class ExportedList(
+    @SerializedName("Archived") val archived: Boolean,
+    @SerializedName("Category") val category: TaskListCategory,
+    @SerializedName("Filter") val filter: TaskListFilter?,
+    @SerializedName("Id") val id: Int,
+    @SerializedName("Position") val position: Int,
+    @SerializedName("Title") val title: String
+)
+/**
+ * A task's place in a list
+ */
+data class ExportedMembership(
+    @SerializedName("ListId") val listId: Int,
+    @SerializedName("Position") val position: Int
+)
+/**
+ * A reminder in an export document
+ */
+data class ExportedReminder(
+    @SerializedName("MinutesBeforeDue") val minutesBeforeDue: Int?,
+    @SerializedName("RemindAt") val remindAt: String?
+)
+/**
+ * A task in an export document
+ */
+data class ExportedTask(
+    @SerializedName("CompletedAt") val completedAt: String?,
+    @SerializedName("DueDate") val dueDate: String?,
+    @SerializedName("History") val history: List<ExportedHistory>,
+    @SerializedName("Id") val id: Int,
+    @SerializedName("Memberships") val memberships: List<ExportedMembership>,
+    @SerializedName("Notes") val notes: String,
+    @SerializedName("ParentTaskId") val parentTaskId: Int?,
+    @SerializedName("Priority") val priority: Int?,
+    @SerializedName("Recurrence") val recurrence: String?,
+    @SerializedName("Reminders") val reminders: List<ExportedReminder>,
+    @SerializedName("StartAt") val startAt: String?,
+    @SerializedName("SubtaskPosition") val subtaskPosition: Int?,
+    @SerializedName("Title") val title: String
+)
+/**
+ * Result of importing an export document
+ */
+data class ImportResponse(
+    @SerializedName("CommentCount") val commentCount: Int,
+    @SerializedName("ListCount") val listCount: Int,
+    @SerializedName("TaskCount") val taskCount: Int
+)
+/**
+ * Result of rebuilding the projection tables from the event log
+ */
+data class ProjectionRebuildResponse(
+    @SerializedName("DurationMs") val durationMs: Int,
+    @SerializedName("EventCount") val eventCount: Int,
+    @SerializedName("IgnoredCount") val ignoredCount: Int
+)
+/**
+ * Markdown rendered to HTML
+ */
+data class RenderedMarkdownResponse(
+    @SerializedName("Html") val html: String
+)
+/**
+ * Response containing full-text search results ordered by relevance
+ */
+data class SearchResponse(
+    @SerializedName("Results") val results: List<SearchResult>
+)
+/**
+ * A single full-text search match, either a task or a task list
+ */
+data class SearchResult(
+    @SerializedName("Kind") val kind: SearchResultKind,
+    @SerializedName("List") val list: TaskList?,
+    @SerializedName("Lists") val lists: List<TaskList>,
+    @SerializedName("MatchedField") val matchedField: String,
+    @SerializedName("Score") val score: Double,
+    @SerializedName("Snippet") val snippet: String,
+    @SerializedName("Task") val task: Task?
+)
 /**
  * Represents a single task in the system
  */
@@ -12,6 +145,14 @@ data class Task(
     @SerializedName("CompletedAt") val completedAt: String?,
     @SerializedName("DueDate") val dueDate: String?,
     @SerializedName("Id") val id: Int,
+    @SerializedName("Notes") val notes: String,
+    @SerializedName("ParentTaskId") val parentTaskId: Int?,
+    @SerializedName("Priority") val priority: Int?,
+    @SerializedName("Recurrence") val recurrence: String?,
+    @SerializedName("StartAt") val startAt: String?,
+    @SerializedName("SubtaskCount") val subtaskCount: Int,
+    @SerializedName("Subtasks") val subtasks: List<Task>,
+    @SerializedName("SubtasksCompleted") val subtasksCompleted: Int,
     @SerializedName("Title") val title: String
 )
 /**
@@ -22,7 +163,7 @@ data class TaskHistory(
     @SerializedName("Id") val id: Int,
     @SerializedName("SystemComment") val systemComment: String,
     @SerializedName("TaskId") val taskId: Int,
-    @SerializedName("UpdateType") val updateType: String,
+    @SerializedName("UpdateType") val updateType: TaskUpdateType,
     @SerializedName("UserComment") val userComment: String?
 )
 /**
@@ -51,10 +192,20 @@ data class TaskLabelsResponse(
  */
 data class TaskList(
     @SerializedName("Archived") val archived: Boolean,
-    @SerializedName("Category") val category: String,
+    @SerializedName("Category") val category: TaskListCategory,
     @SerializedName("Id") val id: Int,
     @SerializedName("Title") val title: String
 )
+/**
+ * The filter that defines the contents of a smart list
+ */
+data class TaskListFilter(
+    @SerializedName("DueWithinDays") val dueWithinDays: Int?,
+    @SerializedName("IncludeCompleted") val includeCompleted: Boolean,
+    @SerializedName("LabelIds") val labelIds: List<Int>,
+    @SerializedName("ListId") val listId: Int,
+    @SerializedName("ListIds") val listIds: List<Int>
+)
 /**
  * Metadata information for a task list
  */
@@ -75,6 +226,14 @@ data class TaskListMetadataResponse(
 data class TaskListResponse(
     @SerializedName("TaskLists") val taskLists: List<TaskList>
 )
+/**
+ * A task's notes, along with the HTML they render to
+ */
+data class TaskNotesResponse(
+    @SerializedName("Html") val html: String,
+    @SerializedName("Markdown") val markdown: String,
+    @SerializedName("TaskId") val taskId: Int
+)
 /**
  * Recent comment information for a task
  */
@@ -90,9 +249,115 @@ data class TaskRecentComment(
 data class TaskRecentCommentResponse(
     @SerializedName("Comments") val comments: List<TaskRecentComment>
 )
+/**
+ * A reminder for a task, at a fixed time or a number of minutes before its due date
+ */
+data class TaskReminder(
+    @SerializedName("FireAt") val fireAt: String?,
+    @SerializedName("Fired") val fired: Boolean,
+    @SerializedName("Id") val id: Int,
+    @SerializedName("MinutesBeforeDue") val minutesBeforeDue: Int?,
+    @SerializedName("RemindAt") val remindAt: String?,
+    @SerializedName("TaskId") val taskId: Int
+)
+/**
+ * Response containing the reminders for a task
+ */
+data class TaskReminderResponse(
+    @SerializedName("Reminders") val reminders: List<TaskReminder>
+)
 /**
  * Response containing a list of tasks
  */
 data class TaskResponse(
     @SerializedName("Tasks") val tasks: List<Task>
 )
+/**
+ * A value to substitute for a {{Name}} placeholder when instantiating a template
+ */
+data class TemplatePlaceholder(
+    @SerializedName("Name") val name: String,
+    @SerializedName("Value") val value: String
+)
+/**
+ * A task list in the trash
+ */
+data class TrashedTaskList(
+    @SerializedName("DeleteOrphanedTasks") val deleteOrphanedTasks: Boolean,
+    @SerializedName("DeletedAt") val deletedAt: String,
+    @SerializedName("List") val list: TaskList,
+    @SerializedName("PurgeAfter") val purgeAfter: String,
+    @SerializedName("TaskCount") val taskCount: Int
+)
+/**
+ * Response containing the task lists in the trash
+ */
+data class TrashedTaskListResponse(
+    @SerializedName("TaskLists") val taskLists: List<TrashedTaskList>
+)
+/**
+ * An event that can be undone
+ */
+data class UndoEntry(
+    @SerializedName("CreatedAt") val createdAt: String,
+    @SerializedName("EventId") val eventId: Int,
+    @SerializedName("EventType") val eventType: String,
+    @SerializedName("Events") val events: List<UndoEvent>
+)
+/**
+ * A compensating event that reverts part of an earlier event
+ */
+data class UndoEvent(
+    @SerializedName("Payload") val payload: String,
+    @SerializedName("Type") val type: String
+)
+/**
+ * Response containing the events that can be undone
+ */
+data class UndoResponse(
+    @SerializedName("Entries") val entries: List<UndoEntry>
+)
+/**
+ * What a search result is
+ */
+enum class SearchResultKind(val value: String) {
+    @SerializedName("task") TASK("task"),
+    @SerializedName("list") LIST("list")
+}
+/**
+ * Category of a task list
+ */
+enum class TaskListCategory(val value: String) {
+    @SerializedName("toDoList") TO_DO_LIST("toDoList"),
+    @SerializedName("template") TEMPLATE("template"),
+    @SerializedName("label") LABEL("label"),
+    @SerializedName("smart") SMART("smart")
+}
+/**
+ * Order of the top-level tasks of a task list: manual, due (earliest first), priority (P1 first), created (newest first) or completed (incomplete tasks first, then most recently completed)
+ */
+enum class TaskSortMode(val value: String) {
+    @SerializedName("manual") MANUAL("manual"),
+    @SerializedName("due") DUE("due"),
+    @SerializedName("priority") PRIORITY("priority"),
+    @SerializedName("created") CREATED("created"),
+    @SerializedName("completed") COMPLETED("completed")
+}
+/**
+ * Type of change recorded in a task's history
+ */
+enum class TaskUpdateType(val value: String) {
+    @SerializedName("update_title") UPDATE_TITLE("update_title"),
+    @SerializedName("update_notes") UPDATE_NOTES("update_notes"),
+    @SerializedName("update_priority") UPDATE_PRIORITY("update_priority"),
+    @SerializedName("update_completed") UPDATE_COMPLETED("update_completed"),
+    @SerializedName("update_due_date") UPDATE_DUE_DATE("update_due_date"),
+    @SerializedName("snooze") SNOOZE("snooze"),
+    @SerializedName("update_reminders") UPDATE_REMINDERS("update_reminders"),
+    @SerializedName("update_recurrence") UPDATE_RECURRENCE("update_recurrence"),
+    @SerializedName("next_occurrence") NEXT_OCCURRENCE("next_occurrence"),
+    @SerializedName("update_parent") UPDATE_PARENT("update_parent"),
+    @SerializedName("delete") DELETE("delete"),
+    @SerializedName("restore") RESTORE("restore"),
+    @SerializedName("add_comment") ADD_COMMENT("add_comment")
+}
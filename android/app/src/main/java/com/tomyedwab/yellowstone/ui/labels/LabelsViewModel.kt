@@ -6,6 +6,7 @@ import androidx.lifecycle.map
 import com.tomyedwab.yellowstone.generated.ApiRoutes
 import com.tomyedwab.yellowstone.generated.Events
 import com.tomyedwab.yellowstone.generated.TaskList
+import com.tomyedwab.yellowstone.generated.TaskListCategory
 import com.tomyedwab.yellowstone.provider.connection.ConnectionStateProvider
 import com.tomyedwab.yellowstone.provider.connection.HubConnectionState
 import com.tomyedwab.yellowstone.services.connection.DataViewService
@@ -26,7 +27,7 @@ class LabelsViewModel(
     val labels: LiveData<List<TaskList>> =
             taskListDataView.map { result ->
                 result.data?.taskLists?.filter {
-                    it.category == "label" && !it.archived
+                    it.category == TaskListCategory.LABEL && !it.archived
                 } ?: emptyList()
             }
 
@@ -41,7 +42,7 @@ class LabelsViewModel(
     val error: LiveData<String?> = taskListDataView.map { result -> result.error }
 
     fun addLabel(title: String) {
-        events.taskListAdd(false, "label", title)
+        events.taskListAdd(false, TaskListCategory.LABEL, title)
     }
 
     fun reorderLabel(listId: Int, afterListId: Int?) {
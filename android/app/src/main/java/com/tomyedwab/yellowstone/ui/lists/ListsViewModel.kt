@@ -6,6 +6,7 @@ import androidx.lifecycle.map
 import com.tomyedwab.yellowstone.generated.ApiRoutes
 import com.tomyedwab.yellowstone.generated.Events
 import com.tomyedwab.yellowstone.generated.TaskList
+import com.tomyedwab.yellowstone.generated.TaskListCategory
 import com.tomyedwab.yellowstone.provider.connection.ConnectionStateProvider
 import com.tomyedwab.yellowstone.provider.connection.HubConnectionState
 import com.tomyedwab.yellowstone.services.connection.DataViewService
@@ -36,7 +37,7 @@ class ListsViewModel(
     val error: LiveData<String?> = taskListDataView.map { result -> result.error }
 
     fun addTaskList(title: String) {
-        events.taskListAdd(false, "toDoList", title)
+        events.taskListAdd(false, TaskListCategory.TO_DO_LIST, title)
     }
 
     fun archiveTaskList(listId: Int) {
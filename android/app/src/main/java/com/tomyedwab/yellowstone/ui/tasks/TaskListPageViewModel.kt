@@ -87,10 +87,11 @@ class TaskListPageViewModel(
 
         val task = currentTasks.removeAt(fromPosition)
         val afterTask = if (toPosition > 0) currentTasks[toPosition - 1] else null
+        val beforeTask = if (toPosition < currentTasks.size) currentTasks[toPosition] else null
 
         viewModelScope.launch {
             try {
-                events.taskListReorderTasks(afterTask?.id, task.id, listId)
+                events.taskListReorderTasks(afterTask?.id, beforeTask?.id, task.id, listId)
             } catch (e: Exception) {
                 // Error handling would be managed by the data views
             }
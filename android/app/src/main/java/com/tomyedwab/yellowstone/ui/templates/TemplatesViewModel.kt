@@ -6,6 +6,7 @@ import androidx.lifecycle.map
 import com.tomyedwab.yellowstone.generated.ApiRoutes
 import com.tomyedwab.yellowstone.generated.Events
 import com.tomyedwab.yellowstone.generated.TaskList
+import com.tomyedwab.yellowstone.generated.TaskListCategory
 import com.tomyedwab.yellowstone.generated.TaskListMetadataResponse
 import com.tomyedwab.yellowstone.provider.connection.ConnectionStateProvider
 import com.tomyedwab.yellowstone.provider.connection.HubConnectionState
@@ -42,7 +43,7 @@ class TemplatesViewModel(
     }
 
     fun addTemplate(title: String) {
-        events.taskListAdd(false, "template", title)
+        events.taskListAdd(false, TaskListCategory.TEMPLATE, title)
     }
 
     fun reorderTemplate(listId: Int, afterListId: Int?) {
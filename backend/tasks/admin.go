@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tomyedwab/yesterday/applib/database"
+	"github.com/tomyedwab/yesterday/applib/httputils"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// Admin routes change state without going through an event, so they are
+// registered by hand instead of being generated from api.yml.
+func initAdminHandlers(db *database.Database) {
+	http.HandleFunc("/api/admin/rebuild", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp, err := state.RebuildProjections(db.GetDB())
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tomyedwab/yesterday/applib/database"
+	"tomyedwab.com/yellowstone-server/tasks/caldav"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/ical"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// CalDAV clients can't sign in to the server like the apps do, so the CalDAV
+// server has its own credentials:
+//
+//	TASKS_CALDAV_USERNAME  user name for HTTP basic authentication
+//	TASKS_CALDAV_PASSWORD  password; CalDAV is turned off unless both are set
+//
+// Each change a CalDAV client makes to a to-do is published as one
+// Task:UpdateFromCalDAV event, which is applied with the same handlers as the
+// apps' events, so it shows up in the history and can be undone.
+func initCalDAVHandlers(db *database.Database) {
+	username := os.Getenv("TASKS_CALDAV_USERNAME")
+	password := os.Getenv("TASKS_CALDAV_PASSWORD")
+	if username == "" || password == "" {
+		return
+	}
+	handler := &caldav.Handler{
+		Backend:  &caldavBackend{db: db},
+		Prefix:   "/caldav/",
+		Username: username,
+		Password: password,
+	}
+	http.Handle("/caldav/", handler)
+	http.Handle("/caldav", handler)
+	// Clients look here for the CalDAV server (RFC 6764)
+	http.HandleFunc("/.well-known/caldav", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/caldav/", http.StatusMovedPermanently)
+	})
+}
+
+// caldavBackend maps to-do lists to calendars and tasks to VTODOs.
+type caldavBackend struct {
+	db *database.Database
+}
+
+func (b *caldavBackend) Calendars() ([]caldav.Calendar, error) {
+	lists, err := state.GetCalDAVCalendars(b.db.GetDB())
+	if err != nil {
+		return nil, err
+	}
+	calendars := make([]caldav.Calendar, 0, len(lists))
+	for _, list := range lists {
+		calendars = append(calendars, caldav.Calendar{Id: list.Id, Name: list.Title})
+	}
+	return calendars, nil
+}
+
+func (b *caldavBackend) Calendar(id int) (caldav.Calendar, bool, error) {
+	list, found, err := state.GetCalDAVCalendar(b.db.GetDB(), id)
+	return caldav.Calendar{Id: list.Id, Name: list.Title}, found, err
+}
+
+func caldavObject(object state.CalDAVObject) caldav.Object {
+	return caldav.Object{Name: object.Name, ETag: object.ETag, Data: object.Data}
+}
+
+func (b *caldavBackend) Objects(calendarId int) ([]caldav.Object, error) {
+	objects, err := state.GetCalDAVObjects(b.db.GetDB(), calendarId)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]caldav.Object, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, caldavObject(object))
+	}
+	return result, nil
+}
+
+func (b *caldavBackend) Object(calendarId int, name string) (caldav.Object, bool, error) {
+	object, found, err := state.GetCalDAVObject(b.db.GetDB(), calendarId, name)
+	return caldavObject(object), found, err
+}
+
+// todoFields are the task fields that CalDAV clients can change.
+type todoFields struct {
+	Title       string
+	Notes       string
+	DueDate     *time.Time
+	Priority    *int
+	CompletedAt *time.Time
+}
+
+func parseToDo(todo ical.Component) (todoFields, error) {
+	var fields todoFields
+	if summary := todo.Get("SUMMARY"); summary != nil {
+		fields.Title = summary.TextValue()
+	}
+	if description := todo.Get("DESCRIPTION"); description != nil {
+		fields.Notes = description.TextValue()
+	}
+	if due := todo.Get("DUE"); due != nil {
+		dueDate, err := due.TimeValue()
+		if err != nil {
+			return fields, fmt.Errorf("%w: invalid DUE: %v", caldav.ErrInvalidObject, err)
+		}
+		dueDate = dueDate.UTC()
+		fields.DueDate = &dueDate
+	}
+	if priority := todo.Get("PRIORITY"); priority != nil {
+		value, err := strconv.Atoi(priority.Value)
+		if err != nil || value < 0 || value > 9 {
+			return fields, fmt.Errorf("%w: invalid PRIORITY %q", caldav.ErrInvalidObject, priority.Value)
+		}
+		// 0 is no priority; 1-9 are split into P1 to P4, the inverse of the
+		// mapping used for the calendar feeds
+		if value > 0 {
+			p := (value + 1) / 2
+			if p > 4 {
+				p = 4
+			}
+			fields.Priority = &p
+		}
+	}
+	status := todo.Get("STATUS")
+	if completed := todo.Get("COMPLETED"); completed != nil {
+		completedAt, err := completed.TimeValue()
+		if err != nil {
+			return fields, fmt.Errorf("%w: invalid COMPLETED: %v", caldav.ErrInvalidObject, err)
+		}
+		completedAt = completedAt.UTC()
+		fields.CompletedAt = &completedAt
+	} else if status != nil && status.Value == "COMPLETED" {
+		now := time.Now().UTC()
+		fields.CompletedAt = &now
+	}
+	if status != nil && status.Value != "COMPLETED" {
+		fields.CompletedAt = nil
+	}
+	return fields, nil
+}
+
+// PutObject checks the precondition and publishes the whole change as a single
+// event while holding creationMu, which other CalDAV requests take too, so
+// that two clients can't both change the version they last saw.
+func (b *caldavBackend) PutObject(calendarId int, name string, todo ical.Component, precondition caldav.Precondition) (bool, error) {
+	fields, err := parseToDo(todo)
+	if err != nil {
+		return false, err
+	}
+	creationMu.Lock()
+	defer creationMu.Unlock()
+
+	object, found, err := state.GetCalDAVObject(b.db.GetDB(), calendarId, name)
+	if err != nil {
+		return false, err
+	}
+	if !precondition(caldavObject(object), found) {
+		return false, caldav.ErrPreconditionFailed
+	}
+	event := generated.TaskUpdateFromCalDAVEvent{
+		TaskListId:   calendarId,
+		ResourceName: name,
+		Title:        fields.Title,
+		Notes:        fields.Notes,
+		DueDate:      fields.DueDate,
+		Priority:     fields.Priority,
+		CompletedAt:  fields.CompletedAt,
+	}
+	if property := todo.Get("UID"); property != nil {
+		event.ResourceUid = property.TextValue()
+	}
+	if found {
+		event.TaskId = &object.Task.Id
+	}
+	_, err = publishEvent(b.db, "Task:UpdateFromCalDAV", event)
+	return !found, err
+}
+
+// DeleteObject moves the task to the trash, from where it can be restored.
+func (b *caldavBackend) DeleteObject(calendarId int, name string, precondition caldav.Precondition) error {
+	creationMu.Lock()
+	defer creationMu.Unlock()
+
+	object, found, err := state.GetCalDAVObject(b.db.GetDB(), calendarId, name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return caldav.ErrNotFound
+	}
+	if !precondition(caldavObject(object), found) {
+		return caldav.ErrPreconditionFailed
+	}
+	_, err = publishEvent(b.db, "Task:Delete", generated.TaskDeleteEvent{TaskId: object.Task.Id})
+	return err
+}
@@ -0,0 +1,401 @@
+// Package caldav serves calendars of VTODOs over CalDAV (RFC 4791), so that
+// task apps that speak CalDAV can sync with the server.
+//
+// Only what those apps need is supported: discovery through PROPFIND, the
+// calendar-query and calendar-multiget reports, and GET, PUT and DELETE of
+// single to-dos, with ETags for conditional requests. Calendars can't be
+// created or deleted, and changes are found by comparing ETags (or the
+// calendar's CTag) rather than with sync-collection.
+//
+// The URL space under the handler's prefix is:
+//
+//	/                the principal, i.e. the user
+//	/lists/          the calendar home, which holds the calendars
+//	/lists/<id>/     a calendar
+//	/lists/<id>/<n>  a to-do resource named n
+package caldav
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tomyedwab.com/yellowstone-server/tasks/ical"
+)
+
+// Calendar is a collection of to-dos.
+type Calendar struct {
+	Id   int
+	Name string
+}
+
+// Object is a to-do resource in a calendar.
+type Object struct {
+	Name string
+	// A quoted strong ETag, which changes whenever the data does
+	ETag string
+	// A VCALENDAR containing the VTODO
+	Data []byte
+}
+
+// ErrInvalidObject is returned by a backend for a to-do it can't store, such
+// as one with a malformed property.
+var ErrInvalidObject = errors.New("invalid calendar object")
+
+// ErrPreconditionFailed is returned by a backend when a change's Precondition
+// doesn't hold.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrNotFound is returned by a backend for a resource that doesn't exist.
+var ErrNotFound = errors.New("resource not found")
+
+// A Precondition reports whether a change can be made to a resource, given the
+// resource as it is or false if it doesn't exist. Backends check it while
+// nothing else can change the resource, so that a client doesn't overwrite a
+// change it hasn't seen.
+type Precondition func(object Object, found bool) bool
+
+// Backend stores the calendars. The handler takes care of the protocol and
+// works out the preconditions; the backend only deals in calendars and to-dos.
+type Backend interface {
+	Calendars() ([]Calendar, error)
+	// Calendar returns false if there is no calendar with the ID.
+	Calendar(id int) (Calendar, bool, error)
+	Objects(calendarId int) ([]Object, error)
+	// Object returns false if the calendar has no resource with the name.
+	Object(calendarId int, name string) (Object, bool, error)
+	// PutObject creates or replaces a resource with the given VTODO, and
+	// returns true if it created it.
+	PutObject(calendarId int, name string, todo ical.Component, precondition Precondition) (bool, error)
+	DeleteObject(calendarId int, name string, precondition Precondition) error
+}
+
+// Handler serves the CalDAV protocol for a backend, for a single user who signs
+// in with HTTP basic authentication.
+type Handler struct {
+	Backend Backend
+	// The path the handler is mounted at, ending in a slash
+	Prefix   string
+	Username string
+	Password string
+}
+
+// Where a request path points to
+type target struct {
+	kind       targetKind
+	calendarId int
+	name       string
+}
+
+type targetKind int
+
+const (
+	targetPrincipal targetKind = iota
+	targetHome
+	targetCalendar
+	targetObject
+)
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Tasks", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	t, ok := h.parsePath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("DAV", "1, 3, calendar-access")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+	case "PROPFIND":
+		err = h.propfind(w, r, t)
+	case "REPORT":
+		err = h.report(w, r, t)
+	case http.MethodGet, http.MethodHead:
+		err = h.get(w, r, t)
+	case http.MethodPut:
+		err = h.put(w, r, t)
+	case http.MethodDelete:
+		err = h.delete(w, r, t)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+	if err != nil {
+		log.Printf("CalDAV %s %s failed: %v", r.Method, r.URL.Path, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	// Compare hashes, so that the comparison takes as long whatever the
+	// lengths are
+	usernameHash := sha256.Sum256([]byte(username))
+	passwordHash := sha256.Sum256([]byte(password))
+	expectedUsernameHash := sha256.Sum256([]byte(h.Username))
+	expectedPasswordHash := sha256.Sum256([]byte(h.Password))
+	usernameMatch := subtle.ConstantTimeCompare(usernameHash[:], expectedUsernameHash[:])
+	passwordMatch := subtle.ConstantTimeCompare(passwordHash[:], expectedPasswordHash[:])
+	return usernameMatch&passwordMatch == 1
+}
+
+func (h *Handler) parsePath(path string) (target, bool) {
+	rest, ok := strings.CutPrefix(path, h.Prefix)
+	if !ok {
+		// The prefix without its trailing slash is the principal too
+		if path+"/" == h.Prefix {
+			return target{kind: targetPrincipal}, true
+		}
+		return target{}, false
+	}
+	if rest == "" {
+		return target{kind: targetPrincipal}, true
+	}
+	rest, ok = strings.CutPrefix(rest, "lists")
+	if !ok {
+		return target{}, false
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	if rest == "" {
+		return target{kind: targetHome}, true
+	}
+	id, name, _ := strings.Cut(rest, "/")
+	calendarId, err := strconv.Atoi(id)
+	if err != nil {
+		return target{}, false
+	}
+	if name == "" {
+		return target{kind: targetCalendar, calendarId: calendarId}, true
+	}
+	if strings.Contains(name, "/") {
+		return target{}, false
+	}
+	return target{kind: targetObject, calendarId: calendarId, name: name}, true
+}
+
+func (h *Handler) homeHref() string {
+	return h.Prefix + "lists/"
+}
+
+func (h *Handler) calendarHref(calendarId int) string {
+	return fmt.Sprintf("%slists/%d/", h.Prefix, calendarId)
+}
+
+func (h *Handler) objectHref(calendarId int, name string) string {
+	return h.calendarHref(calendarId) + pathEscape(name)
+}
+
+// pathEscape escapes a resource name for use in a path. url.PathEscape
+// escapes too little for some clients (e.g. @), so everything but unreserved
+// characters is escaped.
+func pathEscape(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// calendar returns the calendar a request is for, or false if it doesn't
+// exist.
+func (h *Handler) calendar(t target) (Calendar, bool, error) {
+	if t.kind != targetCalendar && t.kind != targetObject {
+		return Calendar{}, false, nil
+	}
+	return h.Backend.Calendar(t.calendarId)
+}
+
+// ctag changes whenever a resource in the calendar is added, changed or
+// removed, so clients can tell whether there is anything to sync.
+func ctag(objects []Object) string {
+	lines := make([]string, 0, len(objects))
+	for _, object := range objects {
+		lines = append(lines, object.Name+" "+object.ETag)
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, t target) error {
+	if t.kind != targetObject {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil
+	}
+	object, found, err := h.object(t)
+	if err != nil {
+		return err
+	}
+	if !found {
+		http.NotFound(w, r)
+		return nil
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", object.ETag)
+	if r.Header.Get("If-None-Match") == object.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(object.Data)))
+	if r.Method == http.MethodGet {
+		w.Write(object.Data)
+	}
+	return nil
+}
+
+// object returns the resource a request is for, or false if it or its
+// calendar doesn't exist.
+func (h *Handler) object(t target) (Object, bool, error) {
+	_, found, err := h.calendar(t)
+	if err != nil || !found {
+		return Object{}, false, err
+	}
+	return h.Backend.Object(t.calendarId, t.name)
+}
+
+// precondition checks If-Match and If-None-Match, which clients send to avoid
+// overwriting changes they haven't seen.
+func precondition(r *http.Request) Precondition {
+	return func(object Object, found bool) bool {
+		return !preconditionFailed(r, object, found)
+	}
+}
+
+func preconditionFailed(r *http.Request, object Object, found bool) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !found || (ifMatch != "*" && !etagListContains(ifMatch, object.ETag)) {
+			return true
+		}
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if found && (ifNoneMatch == "*" || etagListContains(ifNoneMatch, object.ETag)) {
+			return true
+		}
+	}
+	return false
+}
+
+func etagListContains(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, t target) error {
+	if t.kind != targetObject {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil
+	}
+	if _, found, err := h.calendar(t); err != nil || !found {
+		if !found && err == nil {
+			http.Error(w, "Calendar not found", http.StatusConflict)
+		}
+		return err
+	}
+	calendar, err := ical.Parse(io.LimitReader(r.Body, maxBodySize))
+	if err != nil || calendar.Name != "VCALENDAR" {
+		writeError(w, http.StatusBadRequest, caldavNamespace, "valid-calendar-data")
+		return nil
+	}
+	todo, ok := masterToDo(calendar)
+	if !ok {
+		writeError(w, http.StatusForbidden, caldavNamespace, "supported-calendar-component")
+		return nil
+	}
+	created, err := h.Backend.PutObject(t.calendarId, t.name, todo, precondition(r))
+	if errors.Is(err, ErrInvalidObject) {
+		writeError(w, http.StatusForbidden, caldavNamespace, "valid-calendar-object-resource")
+		return nil
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// The stored to-do isn't byte for byte what the client sent, so there is
+	// no ETag: the client has to fetch it again to get one
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return nil
+}
+
+// masterToDo returns the VTODO in a calendar. Recurring to-dos may come with
+// overridden instances, which have a RECURRENCE-ID; those are ignored.
+func masterToDo(calendar ical.Component) (ical.Component, bool) {
+	var todo *ical.Component
+	for i, component := range calendar.Components {
+		switch component.Name {
+		case "VTODO":
+			if component.Get("RECURRENCE-ID") == nil {
+				if todo != nil {
+					return ical.Component{}, false
+				}
+				todo = &calendar.Components[i]
+			}
+		case "VTIMEZONE":
+		default:
+			return ical.Component{}, false
+		}
+	}
+	if todo == nil {
+		return ical.Component{}, false
+	}
+	return *todo, true
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, t target) error {
+	if t.kind != targetObject {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil
+	}
+	if _, found, err := h.calendar(t); err != nil || !found {
+		if !found && err == nil {
+			http.NotFound(w, r)
+		}
+		return err
+	}
+	err := h.Backend.DeleteObject(t.calendarId, t.name, precondition(r))
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return nil
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
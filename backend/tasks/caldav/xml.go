@@ -0,0 +1,430 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	davNamespace            = "DAV:"
+	caldavNamespace         = "urn:ietf:params:xml:ns:caldav"
+	calendarServerNamespace = "http://calendarserver.org/ns/"
+)
+
+// Request bodies are small XML documents or single to-dos
+const maxBodySize = 1 << 20
+
+// prop is a property and its value as XML, which is already escaped.
+type prop struct {
+	name  xml.Name
+	value string
+}
+
+// response is a resource in a multistatus response: the properties it has and
+// the requested ones it doesn't have.
+type response struct {
+	href    string
+	found   []prop
+	missing []xml.Name
+	// Set instead of properties for a resource that doesn't exist
+	notFound bool
+}
+
+// propRequest is the property selection in a PROPFIND or REPORT body.
+type propRequest struct {
+	Prop *struct {
+		Names []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"DAV: prop"`
+	PropName *struct{} `xml:"DAV: propname"`
+}
+
+func (p propRequest) names() []xml.Name {
+	if p.Prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(p.Prop.Names))
+	for _, name := range p.Prop.Names {
+		names = append(names, name.XMLName)
+	}
+	return names
+}
+
+type propfindRequest struct {
+	XMLName xml.Name `xml:"DAV: propfind"`
+	propRequest
+}
+
+type compFilter struct {
+	Name         string       `xml:"name,attr"`
+	IsNotDefined *struct{}    `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	CompFilters  []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type calendarQueryRequest struct {
+	propRequest
+	Filter *compFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+type calendarMultigetRequest struct {
+	propRequest
+	Hrefs []string `xml:"DAV: href"`
+}
+
+var errNoBody = errors.New("empty request body")
+
+// decodeBody decodes the request body into the value returned by newValue for
+// the name of its root element.
+func decodeBody(r *http.Request, newValue func(name xml.Name) interface{}) (interface{}, error) {
+	decoder := xml.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, errNoBody
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value := newValue(start.Name)
+			if value == nil {
+				return nil, nil
+			}
+			return value, decoder.DecodeElement(value, &start)
+		}
+	}
+}
+
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, t target) error {
+	var request propfindRequest
+	_, err := decodeBody(r, func(name xml.Name) interface{} { return &request })
+	if err != nil && err != errNoBody {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil
+	}
+	// Clients that want the whole tree ask for depth infinity, which is
+	// answered as depth 1
+	depth := r.Header.Get("Depth")
+
+	var responses []response
+	switch t.kind {
+	case targetPrincipal:
+		responses = append(responses, h.principalResponse())
+		if depth != "0" {
+			responses = append(responses, h.homeResponse())
+		}
+	case targetHome:
+		responses = append(responses, h.homeResponse())
+		if depth != "0" {
+			calendars, err := h.Backend.Calendars()
+			if err != nil {
+				return err
+			}
+			for _, calendar := range calendars {
+				objects, err := h.Backend.Objects(calendar.Id)
+				if err != nil {
+					return err
+				}
+				responses = append(responses, h.calendarResponse(calendar, objects))
+			}
+		}
+	case targetCalendar:
+		calendar, found, err := h.calendar(t)
+		if err != nil {
+			return err
+		}
+		if !found {
+			http.NotFound(w, r)
+			return nil
+		}
+		objects, err := h.Backend.Objects(calendar.Id)
+		if err != nil {
+			return err
+		}
+		responses = append(responses, h.calendarResponse(calendar, objects))
+		if depth != "0" {
+			for _, object := range objects {
+				responses = append(responses, h.objectResponse(calendar.Id, object))
+			}
+		}
+	case targetObject:
+		object, found, err := h.object(t)
+		if err != nil {
+			return err
+		}
+		if !found {
+			http.NotFound(w, r)
+			return nil
+		}
+		responses = append(responses, h.objectResponse(t.calendarId, object))
+	}
+	writeMultistatus(w, selectProps(responses, request.propRequest))
+	return nil
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, t target) error {
+	if t.kind != targetCalendar {
+		writeError(w, http.StatusForbidden, davNamespace, "supported-report")
+		return nil
+	}
+	body, err := decodeBody(r, func(name xml.Name) interface{} {
+		switch name {
+		case xml.Name{Space: caldavNamespace, Local: "calendar-query"}:
+			return &calendarQueryRequest{}
+		case xml.Name{Space: caldavNamespace, Local: "calendar-multiget"}:
+			return &calendarMultigetRequest{}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil
+	}
+	if body == nil {
+		writeError(w, http.StatusForbidden, davNamespace, "supported-report")
+		return nil
+	}
+	calendar, found, err := h.calendar(t)
+	if err != nil {
+		return err
+	}
+	if !found {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	var responses []response
+	var props propRequest
+	switch request := body.(type) {
+	case *calendarQueryRequest:
+		props = request.propRequest
+		if request.Filter == nil || request.Filter.matchesToDos() {
+			objects, err := h.Backend.Objects(calendar.Id)
+			if err != nil {
+				return err
+			}
+			for _, object := range objects {
+				responses = append(responses, h.objectResponse(calendar.Id, object))
+			}
+		}
+	case *calendarMultigetRequest:
+		props = request.propRequest
+		for _, href := range request.Hrefs {
+			resp, err := h.multigetResponse(calendar.Id, href)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, resp)
+		}
+	}
+	writeMultistatus(w, selectProps(responses, props))
+	return nil
+}
+
+// matchesToDos returns whether a calendar-query filter matches VTODOs. There
+// are only VTODOs, so the filter either matches all of them or none. Property
+// and time range filters are not applied; the query returns a superset of the
+// matches, which clients filter again anyway.
+func (f *compFilter) matchesToDos() bool {
+	if f.Name != "VCALENDAR" {
+		return f.IsNotDefined != nil
+	}
+	for _, filter := range f.CompFilters {
+		if (filter.Name == "VTODO") == (filter.IsNotDefined != nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) multigetResponse(calendarId int, href string) (response, error) {
+	resp := response{href: href, notFound: true}
+	u, err := url.Parse(href)
+	if err != nil {
+		return resp, nil
+	}
+	t, ok := h.parsePath(u.Path)
+	if !ok || t.kind != targetObject || t.calendarId != calendarId {
+		return resp, nil
+	}
+	object, found, err := h.Backend.Object(calendarId, t.name)
+	if err != nil || !found {
+		return resp, err
+	}
+	return h.objectResponse(calendarId, object), nil
+}
+
+func davName(local string) xml.Name {
+	return xml.Name{Space: davNamespace, Local: local}
+}
+
+func caldavName(local string) xml.Name {
+	return xml.Name{Space: caldavNamespace, Local: local}
+}
+
+func hrefValue(href string) string {
+	return "<href>" + escape(href) + "</href>"
+}
+
+func (h *Handler) principalResponse() response {
+	return response{
+		href: h.Prefix,
+		found: []prop{
+			{davName("resourcetype"), "<collection/><principal/>"},
+			{davName("displayname"), escape(h.Username)},
+			{davName("current-user-principal"), hrefValue(h.Prefix)},
+			{davName("principal-URL"), hrefValue(h.Prefix)},
+			{caldavName("calendar-home-set"), `<href xmlns="DAV:">` + escape(h.homeHref()) + "</href>"},
+		},
+	}
+}
+
+func (h *Handler) homeResponse() response {
+	return response{
+		href: h.homeHref(),
+		found: []prop{
+			{davName("resourcetype"), "<collection/>"},
+			{davName("displayname"), "Lists"},
+			{davName("current-user-principal"), hrefValue(h.Prefix)},
+		},
+	}
+}
+
+func (h *Handler) calendarResponse(calendar Calendar, objects []Object) response {
+	return response{
+		href: h.calendarHref(calendar.Id),
+		found: []prop{
+			{davName("resourcetype"), `<collection/><calendar xmlns="urn:ietf:params:xml:ns:caldav"/>`},
+			{davName("displayname"), escape(calendar.Name)},
+			{davName("current-user-principal"), hrefValue(h.Prefix)},
+			{davName("current-user-privilege-set"), "<privilege><read/></privilege><privilege><write/></privilege><privilege><write-content/></privilege><privilege><bind/></privilege><privilege><unbind/></privilege>"},
+			{davName("supported-report-set"), `<supported-report><report><calendar-query xmlns="urn:ietf:params:xml:ns:caldav"/></report></supported-report><supported-report><report><calendar-multiget xmlns="urn:ietf:params:xml:ns:caldav"/></report></supported-report>`},
+			{caldavName("supported-calendar-component-set"), `<comp name="VTODO"/>`},
+			{xml.Name{Space: calendarServerNamespace, Local: "getctag"}, escape(ctag(objects))},
+		},
+	}
+}
+
+func (h *Handler) objectResponse(calendarId int, object Object) response {
+	return response{
+		href: h.objectHref(calendarId, object.Name),
+		found: []prop{
+			{davName("resourcetype"), ""},
+			{davName("getetag"), escape(object.ETag)},
+			{davName("getcontenttype"), "text/calendar; charset=utf-8; component=vtodo"},
+			{davName("getcontentlength"), strconv.Itoa(len(object.Data))},
+			{caldavName("calendar-data"), escape(string(object.Data))},
+		},
+	}
+}
+
+// selectProps picks the requested properties of each resource. Without a
+// list of properties, all of them are returned, except calendar data, which
+// clients have to ask for.
+func selectProps(responses []response, request propRequest) []response {
+	names := request.names()
+	for i, resp := range responses {
+		if resp.notFound {
+			continue
+		}
+		switch {
+		case request.PropName != nil:
+			for j := range resp.found {
+				resp.found[j].value = ""
+			}
+		case request.Prop == nil:
+			found := resp.found[:0]
+			for _, p := range resp.found {
+				if p.name != caldavName("calendar-data") {
+					found = append(found, p)
+				}
+			}
+			resp.found = found
+		default:
+			var found []prop
+			for _, name := range names {
+				if p, ok := findProp(resp.found, name); ok {
+					found = append(found, p)
+				} else {
+					resp.missing = append(resp.missing, name)
+				}
+			}
+			resp.found = found
+		}
+		responses[i] = resp
+	}
+	return responses
+}
+
+func findProp(props []prop, name xml.Name) (prop, bool) {
+	for _, p := range props {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return prop{}, false
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Every element sets its own default namespace, so that properties in any
+// namespace can be written without declaring prefixes
+func writeElement(b *bytes.Buffer, name xml.Name, value string) {
+	fmt.Fprintf(b, `<%s xmlns="%s">%s</%s>`, name.Local, escape(name.Space), value, name.Local)
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []response) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<multistatus xmlns="DAV:">`)
+	for _, resp := range responses {
+		b.WriteString("<response>")
+		b.WriteString(hrefValue(resp.href))
+		if resp.notFound {
+			b.WriteString("<status>HTTP/1.1 404 Not Found</status>")
+		}
+		if len(resp.found) > 0 {
+			b.WriteString("<propstat><prop>")
+			for _, p := range resp.found {
+				writeElement(&b, p.name, p.value)
+			}
+			b.WriteString("</prop><status>HTTP/1.1 200 OK</status></propstat>")
+		}
+		if len(resp.missing) > 0 {
+			b.WriteString("<propstat><prop>")
+			for _, name := range resp.missing {
+				writeElement(&b, name, "")
+			}
+			b.WriteString("</prop><status>HTTP/1.1 404 Not Found</status></propstat>")
+		}
+		b.WriteString("</response>")
+	}
+	b.WriteString("</multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	w.Write(b.Bytes())
+}
+
+// writeError responds with a failed precondition or postcondition, which
+// tells the client why the request was refused.
+func writeError(w http.ResponseWriter, status int, space, condition string) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<error xmlns="DAV:">`)
+	writeElement(&b, xml.Name{Space: space, Local: condition}, "")
+	b.WriteString("</error>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
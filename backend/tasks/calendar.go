@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tomyedwab/yesterday/applib/database"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// Calendar feeds are served as text/calendar rather than JSON, and calendar
+// apps authenticate with the feed's token, so the route is registered by hand
+// instead of being generated from api.yml.
+func initCalendarHandlers(db *database.Database) {
+	http.HandleFunc("/api/ical", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		feedId, err := strconv.Atoi(r.URL.Query().Get("feedId"))
+		if err != nil {
+			http.Error(w, "Invalid feedId", http.StatusBadRequest)
+			return
+		}
+		calendar, err := state.RenderCalendarFeed(db.GetDB(), feedId, r.URL.Query().Get("token"), time.Now())
+		if errors.Is(err, state.ErrCalendarFeedNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Rendering calendar feed %d failed: %v", feedId, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(calendar)
+	})
+}
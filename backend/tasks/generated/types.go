@@ -3,32 +3,302 @@
 package generated
 
 import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/tomyedwab/yesterday/applib/database"
 	"github.com/tomyedwab/yesterday/applib/httputils"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Generated Enums from types.yml and events.yml
+
+// What a search result is
+type SearchResultKind string
+
+const (
+	SearchResultKindTask SearchResultKind = "task"
+	SearchResultKindList SearchResultKind = "list"
+)
+
+// Valid reports whether the value is one of the values of SearchResultKind.
+func (v SearchResultKind) Valid() bool {
+	switch v {
+	case SearchResultKindTask, SearchResultKindList:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON rejects values that are not values of SearchResultKind.
+func (v *SearchResultKind) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if !SearchResultKind(value).Valid() {
+		return fmt.Errorf("invalid SearchResultKind %q", value)
+	}
+	*v = SearchResultKind(value)
+	return nil
+}
+
+// Category of a task list
+type TaskListCategory string
+
+const (
+	TaskListCategoryToDoList TaskListCategory = "toDoList"
+	TaskListCategoryTemplate TaskListCategory = "template"
+	TaskListCategoryLabel    TaskListCategory = "label"
+	TaskListCategorySmart    TaskListCategory = "smart"
+)
+
+// Valid reports whether the value is one of the values of TaskListCategory.
+func (v TaskListCategory) Valid() bool {
+	switch v {
+	case TaskListCategoryToDoList, TaskListCategoryTemplate, TaskListCategoryLabel, TaskListCategorySmart:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON rejects values that are not values of TaskListCategory.
+func (v *TaskListCategory) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if !TaskListCategory(value).Valid() {
+		return fmt.Errorf("invalid TaskListCategory %q", value)
+	}
+	*v = TaskListCategory(value)
+	return nil
+}
+
+// Order of the top-level tasks of a task list: manual, due (earliest first), priority (P1 first), created (newest first) or completed (incomplete tasks first, then most recently completed)
+type TaskSortMode string
+
+const (
+	TaskSortModeManual    TaskSortMode = "manual"
+	TaskSortModeDue       TaskSortMode = "due"
+	TaskSortModePriority  TaskSortMode = "priority"
+	TaskSortModeCreated   TaskSortMode = "created"
+	TaskSortModeCompleted TaskSortMode = "completed"
+)
+
+// Valid reports whether the value is one of the values of TaskSortMode.
+func (v TaskSortMode) Valid() bool {
+	switch v {
+	case TaskSortModeManual, TaskSortModeDue, TaskSortModePriority, TaskSortModeCreated, TaskSortModeCompleted:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON rejects values that are not values of TaskSortMode.
+func (v *TaskSortMode) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if !TaskSortMode(value).Valid() {
+		return fmt.Errorf("invalid TaskSortMode %q", value)
+	}
+	*v = TaskSortMode(value)
+	return nil
+}
+
+// Type of change recorded in a task's history
+type TaskUpdateType string
+
+const (
+	TaskUpdateTypeUpdateTitle      TaskUpdateType = "update_title"
+	TaskUpdateTypeUpdateNotes      TaskUpdateType = "update_notes"
+	TaskUpdateTypeUpdatePriority   TaskUpdateType = "update_priority"
+	TaskUpdateTypeUpdateCompleted  TaskUpdateType = "update_completed"
+	TaskUpdateTypeUpdateDueDate    TaskUpdateType = "update_due_date"
+	TaskUpdateTypeSnooze           TaskUpdateType = "snooze"
+	TaskUpdateTypeUpdateReminders  TaskUpdateType = "update_reminders"
+	TaskUpdateTypeUpdateRecurrence TaskUpdateType = "update_recurrence"
+	TaskUpdateTypeNextOccurrence   TaskUpdateType = "next_occurrence"
+	TaskUpdateTypeUpdateParent     TaskUpdateType = "update_parent"
+	TaskUpdateTypeDelete           TaskUpdateType = "delete"
+	TaskUpdateTypeRestore          TaskUpdateType = "restore"
+	TaskUpdateTypeAddComment       TaskUpdateType = "add_comment"
+)
+
+// Valid reports whether the value is one of the values of TaskUpdateType.
+func (v TaskUpdateType) Valid() bool {
+	switch v {
+	case TaskUpdateTypeUpdateTitle, TaskUpdateTypeUpdateNotes, TaskUpdateTypeUpdatePriority, TaskUpdateTypeUpdateCompleted, TaskUpdateTypeUpdateDueDate, TaskUpdateTypeSnooze, TaskUpdateTypeUpdateReminders, TaskUpdateTypeUpdateRecurrence, TaskUpdateTypeNextOccurrence, TaskUpdateTypeUpdateParent, TaskUpdateTypeDelete, TaskUpdateTypeRestore, TaskUpdateTypeAddComment:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON rejects values that are not values of TaskUpdateType.
+func (v *TaskUpdateType) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if !TaskUpdateType(value).Valid() {
+		return fmt.Errorf("invalid TaskUpdateType %q", value)
+	}
+	*v = TaskUpdateType(value)
+	return nil
+}
+
 // Generated Types from types.yml
 
+// An iCalendar feed of the tasks with a due date, which calendar apps can subscribe to
+type CalendarFeed struct {
+	Id            int    `json:"Id"`            // Unique identifier for the feed
+	IncludeEvents bool   `json:"IncludeEvents"` // Whether each task is also included as an event, for calendar apps that don't show to-dos
+	LabelId       *int   `json:"LabelId"`       // Only include tasks with this label, null for tasks with any label or none
+	ListId        *int   `json:"ListId"`        // Only include tasks in this list, null for tasks in any list
+	Title         string `json:"Title"`         // Name of the calendar shown in calendar apps
+	Token         string `json:"Token"`         // Secret token that grants access to the feed at /api/ical?feedId=<Id>&token=<Token>
+}
+
+// Response containing all calendar feeds
+type CalendarFeedResponse struct {
+	Feeds []CalendarFeed `json:"Feeds"` // Array of calendar feeds
+}
+
+// A task in the trash
+type DeletedTask struct {
+	DeletedAt  time.Time  `json:"DeletedAt"`  // When the task was deleted
+	Lists      []TaskList `json:"Lists"`      // Lists the task will be restored to
+	PurgeAfter time.Time  `json:"PurgeAfter"` // When the task will be permanently deleted and can no longer be restored
+	Task       Task       `json:"Task"`       // The deleted task
+}
+
+// Response containing recently deleted tasks
+type DeletedTaskResponse struct {
+	Tasks []DeletedTask `json:"Tasks"` // Array of deleted tasks, most recently deleted first
+}
+
+// A portable backup of every list and task, produced by /api/export and read back by /api/import
+type ExportDocument struct {
+	ExportedAt time.Time      `json:"ExportedAt"` // When the document was exported
+	Lists      []ExportedList `json:"Lists"`      // Every list that is not in the trash, in display order
+	Tasks      []ExportedTask `json:"Tasks"`      // Every task that is in at least one of the lists
+	Version    int            `json:"Version"`    // Version of the document format, currently 1
+}
+
+// A history entry in an export document. Only comments are imported; the rest of the history is recreated by the import itself
+type ExportedHistory struct {
+	CreatedAt     time.Time      `json:"CreatedAt"`     // When the history entry was created
+	SystemComment string         `json:"SystemComment"` // System-generated comment describing the change
+	UpdateType    TaskUpdateType `json:"UpdateType"`    // Type of update, add_comment for comments
+	UserComment   *string        `json:"UserComment"`   // The user's comment, for comments
+}
+
+// A list in an export document
+type ExportedList struct {
+	Archived bool             `json:"Archived"` // Whether the list is archived
+	Category TaskListCategory `json:"Category"` // Category of the list
+	Filter   *TaskListFilter  `json:"Filter"`   // The filter of a smart list, null for other lists
+	Id       int              `json:"Id"`       // ID of the list on the exporting server, which tasks refer to; lists get new IDs when imported
+	Position int              `json:"Position"` // Position of the list in display order, from 0
+	Title    string           `json:"Title"`    // Title/name of the list
+}
+
+// A task's place in a list
+type ExportedMembership struct {
+	ListId   int `json:"ListId"`   // ID of the list in the export document
+	Position int `json:"Position"` // Position of the task in the list, from 0
+}
+
+// A reminder in an export document
+type ExportedReminder struct {
+	MinutesBeforeDue *int       `json:"MinutesBeforeDue"` // Minutes before the due date to send the reminder, null for reminders at a fixed time
+	RemindAt         *time.Time `json:"RemindAt"`         // Fixed time of the reminder, null for reminders relative to the due date
+}
+
+// A task in an export document
+type ExportedTask struct {
+	CompletedAt     *time.Time           `json:"CompletedAt"`     // Timestamp when the task was completed, null if not completed
+	DueDate         *time.Time           `json:"DueDate"`         // Due date, null if the task has none
+	History         []ExportedHistory    `json:"History"`         // The task's history and comments, oldest first
+	Id              int                  `json:"Id"`              // ID of the task on the exporting server, which other tasks refer to; tasks get new IDs when imported
+	Memberships     []ExportedMembership `json:"Memberships"`     // The lists the task is in
+	Notes           string               `json:"Notes"`           // Notes in Markdown, empty if the task has none
+	ParentTaskId    *int                 `json:"ParentTaskId"`    // ID of the parent task, null for top-level tasks
+	Priority        *int                 `json:"Priority"`        // Priority from 1 (P1, highest) to 4 (P4), null if the task has no priority
+	Recurrence      *string              `json:"Recurrence"`      // Normalized recurrence rule, null if the task does not recur
+	Reminders       []ExportedReminder   `json:"Reminders"`       // The task's reminders
+	StartAt         *time.Time           `json:"StartAt"`         // Time the task is snoozed until, null if it has never been snoozed
+	SubtaskPosition *int                 `json:"SubtaskPosition"` // Position of the task among its parent's subtasks, from 0; null for top-level tasks
+	Title           string               `json:"Title"`           // Title/name of the task
+}
+
+// Result of importing an export document
+type ImportResponse struct {
+	CommentCount int `json:"CommentCount"` // Number of comments added
+	ListCount    int `json:"ListCount"`    // Number of lists created
+	TaskCount    int `json:"TaskCount"`    // Number of tasks created
+}
+
+// Result of rebuilding the projection tables from the event log
+type ProjectionRebuildResponse struct {
+	DurationMs   int `json:"DurationMs"`   // How long the rebuild took, in milliseconds
+	EventCount   int `json:"EventCount"`   // Number of events replayed
+	IgnoredCount int `json:"IgnoredCount"` // Number of events that no handler applies to
+}
+
+// Markdown rendered to HTML
+type RenderedMarkdownResponse struct {
+	Html string `json:"Html"` // The rendered HTML, with any raw HTML in the Markdown escaped
+}
+
+// Response containing full-text search results ordered by relevance
+type SearchResponse struct {
+	Results []SearchResult `json:"Results"` // Array of search results
+}
+
+// A single full-text search match, either a task or a task list
+type SearchResult struct {
+	Kind         SearchResultKind `json:"Kind"`         // What matched: task (title or comment) or list (list title)
+	List         *TaskList        `json:"List"`         // The matching task list, null for task matches
+	Lists        []TaskList       `json:"Lists"`        // Task lists the matching task belongs to, empty for list matches
+	MatchedField string           `json:"MatchedField"` // Which field matched (title, comment, list_title)
+	Score        float64          `json:"Score"`        // Relevance from 0 to 1, higher is better. Each of the task title, comment and list title indexes is scored separately, relative to its own best match, so that scores are comparable across them
+	Snippet      string           `json:"Snippet"`      // Excerpt of the matching text, with each match between U+E000 and U+E001 from the private use area. These characters are removed from text when it is indexed, so they only ever mark matches
+	Task         *Task            `json:"Task"`         // The matching task, null for list matches
+}
+
 // Represents a single task in the system
 type Task struct {
-	CompletedAt *time.Time `json:"CompletedAt"` // Timestamp when the task was completed, null if not completed
-	DueDate     *time.Time `json:"DueDate"`     // Optional due date for the task
-	Id          int        `json:"Id"`          // Unique identifier for the task
-	Title       string     `json:"Title"`       // Title/name of the task
+	CompletedAt       *time.Time `json:"CompletedAt"`       // Timestamp when the task was completed, null if not completed
+	DueDate           *time.Time `json:"DueDate"`           // Optional due date for the task
+	Id                int        `json:"Id"`                // Unique identifier for the task
+	Notes             string     `json:"Notes"`             // Long-form notes in Markdown, empty if the task has none
+	ParentTaskId      *int       `json:"ParentTaskId"`      // ID of the parent task, null for top-level tasks
+	Priority          *int       `json:"Priority"`          // Priority from 1 (P1, highest) to 4 (P4), null if the task has no priority
+	Recurrence        *string    `json:"Recurrence"`        // Normalized recurrence rule (e.g. FREQ=WEEKLY;BYDAY=MO), null if the task does not recur
+	StartAt           *time.Time `json:"StartAt"`           // The task is snoozed, and left out of lists, until this time; null if it has never been snoozed
+	SubtaskCount      int        `json:"SubtaskCount"`      // Number of direct subtasks
+	Subtasks          []Task     `json:"Subtasks"`          // Ordered subtasks of this task
+	SubtasksCompleted int        `json:"SubtasksCompleted"` // Number of direct subtasks that are completed
+	Title             string     `json:"Title"`             // Title/name of the task
 }
 
 // Represents a single history entry for a task
 type TaskHistory struct {
-	CreatedAt     time.Time `json:"CreatedAt"`     // When this history entry was created
-	Id            int       `json:"Id"`            // Unique identifier for the history entry
-	SystemComment string    `json:"SystemComment"` // System-generated comment describing the change
-	TaskId        int       `json:"TaskId"`        // ID of the task this history entry belongs to
-	UpdateType    string    `json:"UpdateType"`    // Type of update (update_title, update_completed, update_due_date, delete, add_comment)
-	UserComment   *string   `json:"UserComment"`   // Optional user-provided comment
+	CreatedAt     time.Time      `json:"CreatedAt"`     // When this history entry was created
+	Id            int            `json:"Id"`            // Unique identifier for the history entry
+	SystemComment string         `json:"SystemComment"` // System-generated comment describing the change
+	TaskId        int            `json:"TaskId"`        // ID of the task this history entry belongs to
+	UpdateType    TaskUpdateType `json:"UpdateType"`    // Type of update
+	UserComment   *string        `json:"UserComment"`   // Optional user-provided comment
 }
 
 // Response containing task history and title
@@ -51,10 +321,19 @@ type TaskLabelsResponse struct {
 
 // Represents a task list in the system
 type TaskList struct {
-	Archived bool   `json:"Archived"` // Whether the task list is archived
-	Category string `json:"Category"` // Category of the list (toDoList, template, label, etc.)
-	Id       int    `json:"Id"`       // Unique identifier for the task list
-	Title    string `json:"Title"`    // Title/name of the task list
+	Archived bool             `json:"Archived"` // Whether the task list is archived
+	Category TaskListCategory `json:"Category"` // Category of the list
+	Id       int              `json:"Id"`       // Unique identifier for the task list
+	Title    string           `json:"Title"`    // Title/name of the task list
+}
+
+// The filter that defines the contents of a smart list
+type TaskListFilter struct {
+	DueWithinDays    *int  `json:"DueWithinDays"`    // Only include tasks due within this many days from now (including overdue tasks), null for no due date condition
+	IncludeCompleted bool  `json:"IncludeCompleted"` // Whether completed tasks are included
+	LabelIds         []int `json:"LabelIds"`         // Only include tasks that have any of these labels, empty for no label condition
+	ListId           int   `json:"ListId"`           // ID of the smart list
+	ListIds          []int `json:"ListIds"`          // Only include tasks that are in any of these lists, empty for no list condition
 }
 
 // Metadata information for a task list
@@ -74,6 +353,13 @@ type TaskListResponse struct {
 	TaskLists []TaskList `json:"TaskLists"` // Array of task lists
 }
 
+// A task's notes, along with the HTML they render to
+type TaskNotesResponse struct {
+	Html     string `json:"Html"`     // The notes rendered to HTML, with any raw HTML in the Markdown escaped
+	Markdown string `json:"Markdown"` // The notes as entered, in Markdown
+	TaskId   int    `json:"TaskId"`   // ID of the task
+}
+
 // Recent comment information for a task
 type TaskRecentComment struct {
 	CreatedAt   *time.Time `json:"CreatedAt"`   // When the comment was created, null if no comment
@@ -87,72 +373,281 @@ type TaskRecentCommentResponse struct {
 	Comments []TaskRecentComment `json:"Comments"` // Array of recent comment entries
 }
 
+// A reminder for a task, at a fixed time or a number of minutes before its due date
+type TaskReminder struct {
+	FireAt           *time.Time `json:"FireAt"`           // When the reminder is sent, null if it is relative to the due date and the task has none
+	Fired            bool       `json:"Fired"`            // Whether the reminder has been sent for its current time
+	Id               int        `json:"Id"`               // Unique identifier for the reminder
+	MinutesBeforeDue *int       `json:"MinutesBeforeDue"` // Minutes before the due date to send the reminder, null for reminders at a fixed time
+	RemindAt         *time.Time `json:"RemindAt"`         // Fixed time of the reminder, null for reminders relative to the due date
+	TaskId           int        `json:"TaskId"`           // ID of the task the reminder is for
+}
+
+// Response containing the reminders for a task
+type TaskReminderResponse struct {
+	Reminders []TaskReminder `json:"Reminders"` // Array of reminders, in the order they are sent
+}
+
 // Response containing a list of tasks
 type TaskResponse struct {
-	Tasks []Task `json:"Tasks"` // Array of tasks
+	Tasks []Task `json:"Tasks"` // Array of top-level tasks, with subtasks nested under their parents
+}
+
+// A value to substitute for a {{Name}} placeholder when instantiating a template
+type TemplatePlaceholder struct {
+	Name  string `json:"Name"`  // Name of the placeholder, without the surrounding braces
+	Value string `json:"Value"` // Text to substitute for the placeholder
+}
+
+// A task list in the trash
+type TrashedTaskList struct {
+	DeleteOrphanedTasks bool      `json:"DeleteOrphanedTasks"` // Whether tasks that are in no other list will be deleted along with the list
+	DeletedAt           time.Time `json:"DeletedAt"`           // When the task list was deleted
+	List                TaskList  `json:"List"`                // The deleted task list
+	PurgeAfter          time.Time `json:"PurgeAfter"`          // When the task list will be permanently deleted and can no longer be restored
+	TaskCount           int       `json:"TaskCount"`           // Number of tasks that were in the list when it was deleted
+}
+
+// Response containing the task lists in the trash
+type TrashedTaskListResponse struct {
+	TaskLists []TrashedTaskList `json:"TaskLists"` // Array of deleted task lists, most recently deleted first
+}
+
+// An event that can be undone
+type UndoEntry struct {
+	CreatedAt time.Time   `json:"CreatedAt"` // When the event was applied
+	EventId   int         `json:"EventId"`   // ID of the event in the event log, which identifies the undo entry
+	EventType string      `json:"EventType"` // Type of the event that would be undone
+	Events    []UndoEvent `json:"Events"`    // Compensating events, computed from the state before the event, in the order they are applied
+}
+
+// A compensating event that reverts part of an earlier event
+type UndoEvent struct {
+	Payload string `json:"Payload"` // JSON-encoded event payload
+	Type    string `json:"Type"`    // Event type, e.g. Task:UpdateTitle
+}
+
+// Response containing the events that can be undone
+type UndoResponse struct {
+	Entries []UndoEntry `json:"Entries"` // Array of undo entries, most recent first
 }
 
 // Generated Event Types from events.yml
 
+// Event to create an iCalendar feed of the tasks with a due date
+type CalendarFeedAddEvent struct {
+	IncludeEvents bool   `json:"IncludeEvents"` // Whether each task is also included as an event, for calendar apps that don't show to-dos
+	LabelId       *int   `json:"LabelId"`       // Only include tasks with this label, null for no label condition
+	ListId        *int   `json:"ListId"`        // Only include tasks in this list (a to-do list or label), null for tasks in any list
+	Title         string `json:"Title"`         // Name of the calendar shown in calendar apps
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to delete a calendar feed, so its URL stops working
+type CalendarFeedDeleteEvent struct {
+	FeedId int `json:"FeedId"` // ID of the feed to delete
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to replace a calendar feed's token, so the old feed URL stops working
+type CalendarFeedResetTokenEvent struct {
+	FeedId int `json:"FeedId"` // ID of the feed to reset the token of
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
 // Event to add a new task
 type TaskAddEvent struct {
 	DueDate    *time.Time `json:"DueDate"`    // Optional due date for the task
 	TaskListId int        `json:"TaskListId"` // ID of the task list to add the task to
 	Title      string     `json:"Title"`      // Title of the new task
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to add a user comment to a task
 type TaskAddCommentEvent struct {
 	TaskId      int    `json:"TaskId"`      // ID of the task to add comment to
 	UserComment string `json:"UserComment"` // The user comment to add
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to add a reminder to a task, either at a fixed time or relative to the due date
+type TaskAddReminderEvent struct {
+	MinutesBeforeDue *int       `json:"MinutesBeforeDue"` // Minutes before the due date to send the reminder, which follows the due date when it changes
+	RemindAt         *time.Time `json:"RemindAt"`         // Fixed time of the reminder; exactly one of RemindAt and MinutesBeforeDue must be set
+	TaskId           int        `json:"TaskId"`           // ID of the task to add a reminder to
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
-// Event to delete a task
+// Event to move a task to the trash, making its subtasks top-level tasks
 type TaskDeleteEvent struct {
 	TaskId int `json:"TaskId"` // ID of the task to delete
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to permanently delete the tasks that were moved to the trash before a time, published by the server once they are past the retention window
+type TaskPurgeTrashEvent struct {
+	PurgeBefore time.Time `json:"PurgeBefore"` // Tasks deleted before this time are purged; it can be no later than the retention window before the event
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to remove a reminder from a task
+type TaskRemoveReminderEvent struct {
+	ReminderId int `json:"ReminderId"` // ID of the reminder to remove
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to restore a deleted task to the lists and position it was in, along with the subtasks it had
+type TaskRestoreEvent struct {
+	TaskId int `json:"TaskId"` // ID of the deleted task to restore
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to make a task a subtask of another task, or a top-level task again
+type TaskSetParentEvent struct {
+	AfterTaskId  *int `json:"AfterTaskId"`  // ID of the sibling subtask to place this task after, null to move to front
+	ParentTaskId *int `json:"ParentTaskId"` // ID of the new parent task, which can't be in the trash or be this task or one of its subtasks; null to make this a top-level task
+	TaskId       int  `json:"TaskId"`       // ID of the task to update
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to hide a task from its lists until a given time
+type TaskSnoozeEvent struct {
+	TaskId int       `json:"TaskId"` // ID of the task to snooze
+	Until  time.Time `json:"Until"`  // When the task shows up in its lists again
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to show a snoozed task in its lists again right away
+type TaskUnsnoozeEvent struct {
+	TaskId int `json:"TaskId"` // ID of the task to unsnooze
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to update a task's completion status
 type TaskUpdateCompletedEvent struct {
 	CompletedAt *time.Time `json:"CompletedAt"` // Completion timestamp, null to mark as not completed
 	TaskId      int        `json:"TaskId"`      // ID of the task to update
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to update a task's due date
 type TaskUpdateDueDateEvent struct {
 	DueDate *time.Time `json:"DueDate"` // New due date, null to remove due date
 	TaskId  int        `json:"TaskId"`  // ID of the task to update
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to store a to-do that a CalDAV client put, creating the task or changing all of its fields at once
+type TaskUpdateFromCalDAVEvent struct {
+	CompletedAt  *time.Time `json:"CompletedAt"`  // Completion timestamp, null if the to-do is not completed
+	DueDate      *time.Time `json:"DueDate"`      // Due date of the task, null for none
+	Notes        string     `json:"Notes"`        // Notes of the task
+	Priority     *int       `json:"Priority"`     // Priority from 1 (P1, highest) to 4 (P4), null for none
+	ResourceName string     `json:"ResourceName"` // Name of the CalDAV resource, which the client finds the to-do under
+	ResourceUid  string     `json:"ResourceUid"`  // UID of the to-do
+	TaskId       *int       `json:"TaskId"`       // ID of the task to update, null to create a new task
+	TaskListId   int        `json:"TaskListId"`   // ID of the task list the to-do was put in, which a new task is added to
+	Title        string     `json:"Title"`        // Title of the task
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to update a task's notes
+type TaskUpdateNotesEvent struct {
+	Notes  string `json:"Notes"`  // New notes for the task, in Markdown; empty to clear them
+	TaskId int    `json:"TaskId"` // ID of the task to update
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to update a task's priority
+type TaskUpdatePriorityEvent struct {
+	Priority *int `json:"Priority"` // New priority from 1 (P1, highest) to 4 (P4), null to clear it
+	TaskId   int  `json:"TaskId"`   // ID of the task to update
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to update a task's recurrence rule
+type TaskUpdateRecurrenceEvent struct {
+	Recurrence *string `json:"Recurrence"` // Recurrence rule (daily, weekly, monthly, yearly or an RRULE such as FREQ=WEEKLY;BYDAY=MO), null to stop recurring
+	TaskId     int     `json:"TaskId"`     // ID of the task to update
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to update a task's title
 type TaskUpdateTitleEvent struct {
 	TaskId int    `json:"TaskId"` // ID of the task to update
 	Title  string `json:"Title"`  // New title for the task
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to add a new task list
 type TaskListAddEvent struct {
-	Archived bool   `json:"Archived"` // Whether the task list should be archived
-	Category string `json:"Category"` // Category of the task list (toDoList, template, label, etc.)
-	Title    string `json:"Title"`    // Title of the new task list
+	Archived bool             `json:"Archived"` // Whether the task list should be archived
+	Category TaskListCategory `json:"Category"` // Category of the task list
+	Title    string           `json:"Title"`    // Title of the new task list
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to add a task to a task list
 type TaskListAddTaskEvent struct {
 	ListId int `json:"ListId"` // ID of the list to add the task to
 	TaskId int `json:"TaskId"` // ID of the task to add
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to copy tasks to another list
 type TaskListCopyTasksEvent struct {
 	NewListId int   `json:"NewListId"` // ID of the destination list
 	TaskIds   []int `json:"TaskIds"`   // Array of task IDs to copy
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to move a task list to the trash, removing all of its tasks from it
+type TaskListDeleteEvent struct {
+	DeleteOrphanedTasks bool `json:"DeleteOrphanedTasks"` // Whether tasks that are in no other list are deleted when the list is purged from the trash
+	ListId              int  `json:"ListId"`              // ID of the task list to delete
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to duplicate tasks to another list
 type TaskListDuplicateTasksEvent struct {
 	NewListId int   `json:"NewListId"` // ID of the destination list
 	TaskIds   []int `json:"TaskIds"`   // Array of task IDs to duplicate
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to create a new to-do list from a template list, duplicating all of its tasks in order
+type TaskListInstantiateEvent struct {
+	Placeholders   []TemplatePlaceholder `json:"Placeholders"`   // Values to substitute for {{Name}} placeholders in the list and task titles and notes
+	StartDate      *time.Time            `json:"StartDate"`      // If set, due dates are shifted so that the earliest due date in the template falls on this date
+	TemplateListId int                   `json:"TemplateListId"` // ID of the template list to instantiate
+	Title          string                `json:"Title"`          // Title of the new to-do list, which may also contain placeholders
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to move tasks from one list to another
@@ -160,88 +655,438 @@ type TaskListMoveTasksEvent struct {
 	NewListId int   `json:"NewListId"` // ID of the destination list
 	OldListId int   `json:"OldListId"` // ID of the source list
 	TaskIds   []int `json:"TaskIds"`   // Array of task IDs to move
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to permanently delete the task lists that were moved to the trash before a time, published by the server once they are past the retention window
+type TaskListPurgeTrashEvent struct {
+	PurgeBefore time.Time `json:"PurgeBefore"` // Lists deleted before this time are purged; it can be no later than the retention window before the event
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to remove tasks from a list, without removing their subtasks
+type TaskListRemoveTasksEvent struct {
+	ListId  int   `json:"ListId"`  // ID of the list to remove the tasks from
+	TaskIds []int `json:"TaskIds"` // Array of task IDs to remove
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to reorder a task list
 type TaskListReorderEvent struct {
 	AfterListId *int `json:"AfterListId"` // ID of the list to place this list after, null to move to front
 	ListId      int  `json:"ListId"`      // ID of the task list to reorder
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to reorder tasks within a list
 type TaskListReorderTasksEvent struct {
-	AfterTaskId *int `json:"AfterTaskId"` // ID of the task to place this task after, null to move to front
-	OldTaskId   int  `json:"OldTaskId"`   // ID of the task to reorder
-	TaskListId  int  `json:"TaskListId"`  // ID of the task list containing the tasks
+	AfterTaskId  *int `json:"AfterTaskId"`  // ID of the task to place this task after; takes precedence over BeforeTaskId
+	BeforeTaskId *int `json:"BeforeTaskId"` // ID of the task to place this task before, if AfterTaskId is null; both null moves it to the front
+	OldTaskId    int  `json:"OldTaskId"`    // ID of the task to reorder
+	TaskListId   int  `json:"TaskListId"`   // ID of the task list containing the tasks
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to restore a task list from the trash along with its tasks
+type TaskListRestoreEvent struct {
+	ListId int `json:"ListId"` // ID of the task list to restore
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to update a task list's archived status
 type TaskListUpdateArchivedEvent struct {
 	Archived bool `json:"Archived"` // New archived status for the task list
 	ListId   int  `json:"ListId"`   // ID of the task list to update
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to update the filter that defines the contents of a smart list
+type TaskListUpdateFilterEvent struct {
+	DueWithinDays    *int  `json:"DueWithinDays"`    // Only include tasks due within this many days from now (including overdue tasks), null for no due date condition
+	IncludeCompleted bool  `json:"IncludeCompleted"` // Whether completed tasks are included
+	LabelIds         []int `json:"LabelIds"`         // Only include tasks that have any of these labels, empty for no label condition
+	ListId           int   `json:"ListId"`           // ID of the smart list to update
+	ListIds          []int `json:"ListIds"`          // Only include tasks that are in any of these lists, empty for no list condition
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Event to update a task list's title
 type TaskListUpdateTitleEvent struct {
 	ListId int    `json:"ListId"` // ID of the task list to update
 	Title  string `json:"Title"`  // New title for the task list
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
+}
+
+// Event to undo an earlier event by applying its compensating events
+type UndoApplyEvent struct {
+	EventId int `json:"EventId"` // ID of the event to undo, from /api/undo
+
+	Timestamp time.Time `json:"timestamp"` // When the event was published, from the stored event
 }
 
 // Generated Resolver Interface from api.yml
 
 type Resolver interface {
-	GetApiTaskList(db *sqlx.DB, listId int) (TaskResponse, error)
+	GetApiTaskList(db *sqlx.DB, hideCompleted *bool, includeSnoozed *bool, listId int, sort *TaskSortMode) (TaskResponse, error)
 	GetApiTaskGet(db *sqlx.DB, id int) (Task, error)
 	GetApiTaskHistory(db *sqlx.DB, id int) (TaskHistoryResponse, error)
+	GetApiTaskReminders(db *sqlx.DB, taskId int) (TaskReminderResponse, error)
+	GetApiTaskNotes(db *sqlx.DB, id int) (TaskNotesResponse, error)
+	GetApiTaskTrash(db *sqlx.DB) (DeletedTaskResponse, error)
 	GetApiTasklistGet(db *sqlx.DB, id int) (TaskList, error)
 	GetApiTasklistAll(db *sqlx.DB) (TaskListResponse, error)
 	GetApiTasklistTodo(db *sqlx.DB) (TaskListResponse, error)
 	GetApiTasklistTemplate(db *sqlx.DB) (TaskListResponse, error)
+	GetApiTasklistSmart(db *sqlx.DB) (TaskListResponse, error)
+	GetApiTasklistFilter(db *sqlx.DB, listId int) (TaskListFilter, error)
 	GetApiTasklistArchived(db *sqlx.DB) (TaskListResponse, error)
-	GetApiTasklistMetadata(db *sqlx.DB) (TaskListMetadataResponse, error)
+	GetApiTasklistTrash(db *sqlx.DB) (TrashedTaskListResponse, error)
+	GetApiTasklistMetadata(db *sqlx.DB, includeSnoozed *bool) (TaskListMetadataResponse, error)
 	GetApiTasklistRecent_comments(db *sqlx.DB, listId int) (TaskRecentCommentResponse, error)
 	GetApiTasklistLabels(db *sqlx.DB, listId int) (TaskLabelsResponse, error)
+	GetApiMarkdownRender(db *sqlx.DB, text string) (RenderedMarkdownResponse, error)
+	GetApiSearch(db *sqlx.DB, q string) (SearchResponse, error)
+	GetApiCalendarfeedList(db *sqlx.DB) (CalendarFeedResponse, error)
+	GetApiExport(db *sqlx.DB) (ExportDocument, error)
+	GetApiUndo(db *sqlx.DB) (UndoResponse, error)
 }
 
 // Generated EventHandler Interface from events.yml
 
 type EventHandler interface {
+	HandleCalendarFeedAddEvent(tx *sqlx.Tx, event *CalendarFeedAddEvent) (bool, error)
+	HandleCalendarFeedDeleteEvent(tx *sqlx.Tx, event *CalendarFeedDeleteEvent) (bool, error)
+	HandleCalendarFeedResetTokenEvent(tx *sqlx.Tx, event *CalendarFeedResetTokenEvent) (bool, error)
 	HandleTaskAddEvent(tx *sqlx.Tx, event *TaskAddEvent) (bool, error)
 	HandleTaskAddCommentEvent(tx *sqlx.Tx, event *TaskAddCommentEvent) (bool, error)
+	HandleTaskAddReminderEvent(tx *sqlx.Tx, event *TaskAddReminderEvent) (bool, error)
 	HandleTaskDeleteEvent(tx *sqlx.Tx, event *TaskDeleteEvent) (bool, error)
+	HandleTaskPurgeTrashEvent(tx *sqlx.Tx, event *TaskPurgeTrashEvent) (bool, error)
+	HandleTaskRemoveReminderEvent(tx *sqlx.Tx, event *TaskRemoveReminderEvent) (bool, error)
+	HandleTaskRestoreEvent(tx *sqlx.Tx, event *TaskRestoreEvent) (bool, error)
+	HandleTaskSetParentEvent(tx *sqlx.Tx, event *TaskSetParentEvent) (bool, error)
+	HandleTaskSnoozeEvent(tx *sqlx.Tx, event *TaskSnoozeEvent) (bool, error)
+	HandleTaskUnsnoozeEvent(tx *sqlx.Tx, event *TaskUnsnoozeEvent) (bool, error)
 	HandleTaskUpdateCompletedEvent(tx *sqlx.Tx, event *TaskUpdateCompletedEvent) (bool, error)
 	HandleTaskUpdateDueDateEvent(tx *sqlx.Tx, event *TaskUpdateDueDateEvent) (bool, error)
+	HandleTaskUpdateFromCalDAVEvent(tx *sqlx.Tx, event *TaskUpdateFromCalDAVEvent) (bool, error)
+	HandleTaskUpdateNotesEvent(tx *sqlx.Tx, event *TaskUpdateNotesEvent) (bool, error)
+	HandleTaskUpdatePriorityEvent(tx *sqlx.Tx, event *TaskUpdatePriorityEvent) (bool, error)
+	HandleTaskUpdateRecurrenceEvent(tx *sqlx.Tx, event *TaskUpdateRecurrenceEvent) (bool, error)
 	HandleTaskUpdateTitleEvent(tx *sqlx.Tx, event *TaskUpdateTitleEvent) (bool, error)
 	HandleTaskListAddEvent(tx *sqlx.Tx, event *TaskListAddEvent) (bool, error)
 	HandleTaskListAddTaskEvent(tx *sqlx.Tx, event *TaskListAddTaskEvent) (bool, error)
 	HandleTaskListCopyTasksEvent(tx *sqlx.Tx, event *TaskListCopyTasksEvent) (bool, error)
+	HandleTaskListDeleteEvent(tx *sqlx.Tx, event *TaskListDeleteEvent) (bool, error)
 	HandleTaskListDuplicateTasksEvent(tx *sqlx.Tx, event *TaskListDuplicateTasksEvent) (bool, error)
+	HandleTaskListInstantiateEvent(tx *sqlx.Tx, event *TaskListInstantiateEvent) (bool, error)
 	HandleTaskListMoveTasksEvent(tx *sqlx.Tx, event *TaskListMoveTasksEvent) (bool, error)
+	HandleTaskListPurgeTrashEvent(tx *sqlx.Tx, event *TaskListPurgeTrashEvent) (bool, error)
+	HandleTaskListRemoveTasksEvent(tx *sqlx.Tx, event *TaskListRemoveTasksEvent) (bool, error)
 	HandleTaskListReorderEvent(tx *sqlx.Tx, event *TaskListReorderEvent) (bool, error)
 	HandleTaskListReorderTasksEvent(tx *sqlx.Tx, event *TaskListReorderTasksEvent) (bool, error)
+	HandleTaskListRestoreEvent(tx *sqlx.Tx, event *TaskListRestoreEvent) (bool, error)
 	HandleTaskListUpdateArchivedEvent(tx *sqlx.Tx, event *TaskListUpdateArchivedEvent) (bool, error)
+	HandleTaskListUpdateFilterEvent(tx *sqlx.Tx, event *TaskListUpdateFilterEvent) (bool, error)
 	HandleTaskListUpdateTitleEvent(tx *sqlx.Tx, event *TaskListUpdateTitleEvent) (bool, error)
+	HandleUndoApplyEvent(tx *sqlx.Tx, event *UndoApplyEvent) (bool, error)
+}
+
+// Generated event dispatch from events.yml, for applying stored events
+// outside of the database's own event handling. Unknown event types are ignored.
+
+func HandleEvent(tx *sqlx.Tx, eventHandler EventHandler, eventType string, eventData []byte) (bool, error) {
+	switch eventType {
+	case "CalendarFeed:Add":
+		var event CalendarFeedAddEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleCalendarFeedAddEvent(tx, &event)
+	case "CalendarFeed:Delete":
+		var event CalendarFeedDeleteEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleCalendarFeedDeleteEvent(tx, &event)
+	case "CalendarFeed:ResetToken":
+		var event CalendarFeedResetTokenEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleCalendarFeedResetTokenEvent(tx, &event)
+	case "Task:Add":
+		var event TaskAddEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskAddEvent(tx, &event)
+	case "Task:AddComment":
+		var event TaskAddCommentEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskAddCommentEvent(tx, &event)
+	case "Task:AddReminder":
+		var event TaskAddReminderEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskAddReminderEvent(tx, &event)
+	case "Task:Delete":
+		var event TaskDeleteEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskDeleteEvent(tx, &event)
+	case "Task:PurgeTrash":
+		var event TaskPurgeTrashEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskPurgeTrashEvent(tx, &event)
+	case "Task:RemoveReminder":
+		var event TaskRemoveReminderEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskRemoveReminderEvent(tx, &event)
+	case "Task:Restore":
+		var event TaskRestoreEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskRestoreEvent(tx, &event)
+	case "Task:SetParent":
+		var event TaskSetParentEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskSetParentEvent(tx, &event)
+	case "Task:Snooze":
+		var event TaskSnoozeEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskSnoozeEvent(tx, &event)
+	case "Task:Unsnooze":
+		var event TaskUnsnoozeEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUnsnoozeEvent(tx, &event)
+	case "Task:UpdateCompleted":
+		var event TaskUpdateCompletedEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUpdateCompletedEvent(tx, &event)
+	case "Task:UpdateDueDate":
+		var event TaskUpdateDueDateEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUpdateDueDateEvent(tx, &event)
+	case "Task:UpdateFromCalDAV":
+		var event TaskUpdateFromCalDAVEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUpdateFromCalDAVEvent(tx, &event)
+	case "Task:UpdateNotes":
+		var event TaskUpdateNotesEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUpdateNotesEvent(tx, &event)
+	case "Task:UpdatePriority":
+		var event TaskUpdatePriorityEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUpdatePriorityEvent(tx, &event)
+	case "Task:UpdateRecurrence":
+		var event TaskUpdateRecurrenceEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUpdateRecurrenceEvent(tx, &event)
+	case "Task:UpdateTitle":
+		var event TaskUpdateTitleEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskUpdateTitleEvent(tx, &event)
+	case "TaskList:Add":
+		var event TaskListAddEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListAddEvent(tx, &event)
+	case "TaskList:AddTask":
+		var event TaskListAddTaskEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListAddTaskEvent(tx, &event)
+	case "TaskList:CopyTasks":
+		var event TaskListCopyTasksEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListCopyTasksEvent(tx, &event)
+	case "TaskList:Delete":
+		var event TaskListDeleteEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListDeleteEvent(tx, &event)
+	case "TaskList:DuplicateTasks":
+		var event TaskListDuplicateTasksEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListDuplicateTasksEvent(tx, &event)
+	case "TaskList:Instantiate":
+		var event TaskListInstantiateEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListInstantiateEvent(tx, &event)
+	case "TaskList:MoveTasks":
+		var event TaskListMoveTasksEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListMoveTasksEvent(tx, &event)
+	case "TaskList:PurgeTrash":
+		var event TaskListPurgeTrashEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListPurgeTrashEvent(tx, &event)
+	case "TaskList:RemoveTasks":
+		var event TaskListRemoveTasksEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListRemoveTasksEvent(tx, &event)
+	case "TaskList:Reorder":
+		var event TaskListReorderEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListReorderEvent(tx, &event)
+	case "TaskList:ReorderTasks":
+		var event TaskListReorderTasksEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListReorderTasksEvent(tx, &event)
+	case "TaskList:Restore":
+		var event TaskListRestoreEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListRestoreEvent(tx, &event)
+	case "TaskList:UpdateArchived":
+		var event TaskListUpdateArchivedEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListUpdateArchivedEvent(tx, &event)
+	case "TaskList:UpdateFilter":
+		var event TaskListUpdateFilterEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListUpdateFilterEvent(tx, &event)
+	case "TaskList:UpdateTitle":
+		var event TaskListUpdateTitleEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleTaskListUpdateTitleEvent(tx, &event)
+	case "Undo:Apply":
+		var event UndoApplyEvent
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.HandleUndoApplyEvent(tx, &event)
+	}
+	return false, nil
 }
 
 // Generated initialization function
 
 func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHandler) error {
 	// Register event handlers
+	database.AddEventHandler(db, "CalendarFeed:Add", func(tx *sqlx.Tx, event *CalendarFeedAddEvent) (bool, error) {
+		return eventHandler.HandleCalendarFeedAddEvent(tx, event)
+	})
+	database.AddEventHandler(db, "CalendarFeed:Delete", func(tx *sqlx.Tx, event *CalendarFeedDeleteEvent) (bool, error) {
+		return eventHandler.HandleCalendarFeedDeleteEvent(tx, event)
+	})
+	database.AddEventHandler(db, "CalendarFeed:ResetToken", func(tx *sqlx.Tx, event *CalendarFeedResetTokenEvent) (bool, error) {
+		return eventHandler.HandleCalendarFeedResetTokenEvent(tx, event)
+	})
 	database.AddEventHandler(db, "Task:Add", func(tx *sqlx.Tx, event *TaskAddEvent) (bool, error) {
 		return eventHandler.HandleTaskAddEvent(tx, event)
 	})
 	database.AddEventHandler(db, "Task:AddComment", func(tx *sqlx.Tx, event *TaskAddCommentEvent) (bool, error) {
 		return eventHandler.HandleTaskAddCommentEvent(tx, event)
 	})
+	database.AddEventHandler(db, "Task:AddReminder", func(tx *sqlx.Tx, event *TaskAddReminderEvent) (bool, error) {
+		return eventHandler.HandleTaskAddReminderEvent(tx, event)
+	})
 	database.AddEventHandler(db, "Task:Delete", func(tx *sqlx.Tx, event *TaskDeleteEvent) (bool, error) {
 		return eventHandler.HandleTaskDeleteEvent(tx, event)
 	})
+	database.AddEventHandler(db, "Task:PurgeTrash", func(tx *sqlx.Tx, event *TaskPurgeTrashEvent) (bool, error) {
+		return eventHandler.HandleTaskPurgeTrashEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:RemoveReminder", func(tx *sqlx.Tx, event *TaskRemoveReminderEvent) (bool, error) {
+		return eventHandler.HandleTaskRemoveReminderEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:Restore", func(tx *sqlx.Tx, event *TaskRestoreEvent) (bool, error) {
+		return eventHandler.HandleTaskRestoreEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:SetParent", func(tx *sqlx.Tx, event *TaskSetParentEvent) (bool, error) {
+		return eventHandler.HandleTaskSetParentEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:Snooze", func(tx *sqlx.Tx, event *TaskSnoozeEvent) (bool, error) {
+		return eventHandler.HandleTaskSnoozeEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:Unsnooze", func(tx *sqlx.Tx, event *TaskUnsnoozeEvent) (bool, error) {
+		return eventHandler.HandleTaskUnsnoozeEvent(tx, event)
+	})
 	database.AddEventHandler(db, "Task:UpdateCompleted", func(tx *sqlx.Tx, event *TaskUpdateCompletedEvent) (bool, error) {
 		return eventHandler.HandleTaskUpdateCompletedEvent(tx, event)
 	})
 	database.AddEventHandler(db, "Task:UpdateDueDate", func(tx *sqlx.Tx, event *TaskUpdateDueDateEvent) (bool, error) {
 		return eventHandler.HandleTaskUpdateDueDateEvent(tx, event)
 	})
+	database.AddEventHandler(db, "Task:UpdateFromCalDAV", func(tx *sqlx.Tx, event *TaskUpdateFromCalDAVEvent) (bool, error) {
+		return eventHandler.HandleTaskUpdateFromCalDAVEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:UpdateNotes", func(tx *sqlx.Tx, event *TaskUpdateNotesEvent) (bool, error) {
+		return eventHandler.HandleTaskUpdateNotesEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:UpdatePriority", func(tx *sqlx.Tx, event *TaskUpdatePriorityEvent) (bool, error) {
+		return eventHandler.HandleTaskUpdatePriorityEvent(tx, event)
+	})
+	database.AddEventHandler(db, "Task:UpdateRecurrence", func(tx *sqlx.Tx, event *TaskUpdateRecurrenceEvent) (bool, error) {
+		return eventHandler.HandleTaskUpdateRecurrenceEvent(tx, event)
+	})
 	database.AddEventHandler(db, "Task:UpdateTitle", func(tx *sqlx.Tx, event *TaskUpdateTitleEvent) (bool, error) {
 		return eventHandler.HandleTaskUpdateTitleEvent(tx, event)
 	})
@@ -254,24 +1099,45 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 	database.AddEventHandler(db, "TaskList:CopyTasks", func(tx *sqlx.Tx, event *TaskListCopyTasksEvent) (bool, error) {
 		return eventHandler.HandleTaskListCopyTasksEvent(tx, event)
 	})
+	database.AddEventHandler(db, "TaskList:Delete", func(tx *sqlx.Tx, event *TaskListDeleteEvent) (bool, error) {
+		return eventHandler.HandleTaskListDeleteEvent(tx, event)
+	})
 	database.AddEventHandler(db, "TaskList:DuplicateTasks", func(tx *sqlx.Tx, event *TaskListDuplicateTasksEvent) (bool, error) {
 		return eventHandler.HandleTaskListDuplicateTasksEvent(tx, event)
 	})
+	database.AddEventHandler(db, "TaskList:Instantiate", func(tx *sqlx.Tx, event *TaskListInstantiateEvent) (bool, error) {
+		return eventHandler.HandleTaskListInstantiateEvent(tx, event)
+	})
 	database.AddEventHandler(db, "TaskList:MoveTasks", func(tx *sqlx.Tx, event *TaskListMoveTasksEvent) (bool, error) {
 		return eventHandler.HandleTaskListMoveTasksEvent(tx, event)
 	})
+	database.AddEventHandler(db, "TaskList:PurgeTrash", func(tx *sqlx.Tx, event *TaskListPurgeTrashEvent) (bool, error) {
+		return eventHandler.HandleTaskListPurgeTrashEvent(tx, event)
+	})
+	database.AddEventHandler(db, "TaskList:RemoveTasks", func(tx *sqlx.Tx, event *TaskListRemoveTasksEvent) (bool, error) {
+		return eventHandler.HandleTaskListRemoveTasksEvent(tx, event)
+	})
 	database.AddEventHandler(db, "TaskList:Reorder", func(tx *sqlx.Tx, event *TaskListReorderEvent) (bool, error) {
 		return eventHandler.HandleTaskListReorderEvent(tx, event)
 	})
 	database.AddEventHandler(db, "TaskList:ReorderTasks", func(tx *sqlx.Tx, event *TaskListReorderTasksEvent) (bool, error) {
 		return eventHandler.HandleTaskListReorderTasksEvent(tx, event)
 	})
+	database.AddEventHandler(db, "TaskList:Restore", func(tx *sqlx.Tx, event *TaskListRestoreEvent) (bool, error) {
+		return eventHandler.HandleTaskListRestoreEvent(tx, event)
+	})
 	database.AddEventHandler(db, "TaskList:UpdateArchived", func(tx *sqlx.Tx, event *TaskListUpdateArchivedEvent) (bool, error) {
 		return eventHandler.HandleTaskListUpdateArchivedEvent(tx, event)
 	})
+	database.AddEventHandler(db, "TaskList:UpdateFilter", func(tx *sqlx.Tx, event *TaskListUpdateFilterEvent) (bool, error) {
+		return eventHandler.HandleTaskListUpdateFilterEvent(tx, event)
+	})
 	database.AddEventHandler(db, "TaskList:UpdateTitle", func(tx *sqlx.Tx, event *TaskListUpdateTitleEvent) (bool, error) {
 		return eventHandler.HandleTaskListUpdateTitleEvent(tx, event)
 	})
+	database.AddEventHandler(db, "Undo:Apply", func(tx *sqlx.Tx, event *UndoApplyEvent) (bool, error) {
+		return eventHandler.HandleUndoApplyEvent(tx, event)
+	})
 
 	// Register HTTP routes
 	http.HandleFunc("/api/task/list", func(w http.ResponseWriter, r *http.Request) {
@@ -285,8 +1151,41 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 			http.Error(w, "Invalid listId parameter", http.StatusBadRequest)
 			return
 		}
+		sortStr := r.URL.Query().Get("sort")
+		// Optional parameters are nil when they are missing
+		var sort *TaskSortMode
+		if sortStr != "" {
+			value := TaskSortMode(sortStr)
+			if !value.Valid() {
+				http.Error(w, "Invalid sort parameter", http.StatusBadRequest)
+				return
+			}
+			sort = &value
+		}
+		hideCompletedStr := r.URL.Query().Get("hideCompleted")
+		// Optional parameters are nil when they are missing
+		var hideCompleted *bool
+		if hideCompletedStr != "" {
+			value, err := strconv.ParseBool(hideCompletedStr)
+			if err != nil {
+				http.Error(w, "Invalid hideCompleted parameter", http.StatusBadRequest)
+				return
+			}
+			hideCompleted = &value
+		}
+		includeSnoozedStr := r.URL.Query().Get("includeSnoozed")
+		// Optional parameters are nil when they are missing
+		var includeSnoozed *bool
+		if includeSnoozedStr != "" {
+			value, err := strconv.ParseBool(includeSnoozedStr)
+			if err != nil {
+				http.Error(w, "Invalid includeSnoozed parameter", http.StatusBadRequest)
+				return
+			}
+			includeSnoozed = &value
+		}
 
-		resp, err := resolver.GetApiTaskList(db.GetDB(), listId)
+		resp, err := resolver.GetApiTaskList(db.GetDB(), hideCompleted, includeSnoozed, listId, sort)
 		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
 	})
 	http.HandleFunc("/api/task/get", func(w http.ResponseWriter, r *http.Request) {
@@ -319,6 +1218,41 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 		resp, err := resolver.GetApiTaskHistory(db.GetDB(), id)
 		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
 	})
+	http.HandleFunc("/api/task/reminders", func(w http.ResponseWriter, r *http.Request) {
+		taskIdStr := r.URL.Query().Get("taskId")
+		if taskIdStr == "" {
+			http.Error(w, "Missing taskId parameter", http.StatusBadRequest)
+			return
+		}
+		taskId, err := strconv.Atoi(taskIdStr)
+		if err != nil {
+			http.Error(w, "Invalid taskId parameter", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := resolver.GetApiTaskReminders(db.GetDB(), taskId)
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+	http.HandleFunc("/api/task/notes", func(w http.ResponseWriter, r *http.Request) {
+		idStr := r.URL.Query().Get("id")
+		if idStr == "" {
+			http.Error(w, "Missing id parameter", http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid id parameter", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := resolver.GetApiTaskNotes(db.GetDB(), id)
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+	http.HandleFunc("/api/task/trash", func(w http.ResponseWriter, r *http.Request) {
+
+		resp, err := resolver.GetApiTaskTrash(db.GetDB())
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
 	http.HandleFunc("/api/tasklist/get", func(w http.ResponseWriter, r *http.Request) {
 		idStr := r.URL.Query().Get("id")
 		if idStr == "" {
@@ -349,14 +1283,50 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 		resp, err := resolver.GetApiTasklistTemplate(db.GetDB())
 		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
 	})
+	http.HandleFunc("/api/tasklist/smart", func(w http.ResponseWriter, r *http.Request) {
+
+		resp, err := resolver.GetApiTasklistSmart(db.GetDB())
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+	http.HandleFunc("/api/tasklist/filter", func(w http.ResponseWriter, r *http.Request) {
+		listIdStr := r.URL.Query().Get("listId")
+		if listIdStr == "" {
+			http.Error(w, "Missing listId parameter", http.StatusBadRequest)
+			return
+		}
+		listId, err := strconv.Atoi(listIdStr)
+		if err != nil {
+			http.Error(w, "Invalid listId parameter", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := resolver.GetApiTasklistFilter(db.GetDB(), listId)
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
 	http.HandleFunc("/api/tasklist/archived", func(w http.ResponseWriter, r *http.Request) {
 
 		resp, err := resolver.GetApiTasklistArchived(db.GetDB())
 		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
 	})
+	http.HandleFunc("/api/tasklist/trash", func(w http.ResponseWriter, r *http.Request) {
+
+		resp, err := resolver.GetApiTasklistTrash(db.GetDB())
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
 	http.HandleFunc("/api/tasklist/metadata", func(w http.ResponseWriter, r *http.Request) {
+		includeSnoozedStr := r.URL.Query().Get("includeSnoozed")
+		// Optional parameters are nil when they are missing
+		var includeSnoozed *bool
+		if includeSnoozedStr != "" {
+			value, err := strconv.ParseBool(includeSnoozedStr)
+			if err != nil {
+				http.Error(w, "Invalid includeSnoozed parameter", http.StatusBadRequest)
+				return
+			}
+			includeSnoozed = &value
+		}
 
-		resp, err := resolver.GetApiTasklistMetadata(db.GetDB())
+		resp, err := resolver.GetApiTasklistMetadata(db.GetDB(), includeSnoozed)
 		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
 	})
 	http.HandleFunc("/api/tasklist/recent_comments", func(w http.ResponseWriter, r *http.Request) {
@@ -389,6 +1359,109 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 		resp, err := resolver.GetApiTasklistLabels(db.GetDB(), listId)
 		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
 	})
+	http.HandleFunc("/api/markdown/render", func(w http.ResponseWriter, r *http.Request) {
+		textStr := r.URL.Query().Get("text")
+		if textStr == "" {
+			http.Error(w, "Missing text parameter", http.StatusBadRequest)
+			return
+		}
+		text := textStr
+
+		resp, err := resolver.GetApiMarkdownRender(db.GetDB(), text)
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+	http.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		qStr := r.URL.Query().Get("q")
+		if qStr == "" {
+			http.Error(w, "Missing q parameter", http.StatusBadRequest)
+			return
+		}
+		q := qStr
+
+		resp, err := resolver.GetApiSearch(db.GetDB(), q)
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+	http.HandleFunc("/api/calendarfeed/list", func(w http.ResponseWriter, r *http.Request) {
+
+		resp, err := resolver.GetApiCalendarfeedList(db.GetDB())
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+	http.HandleFunc("/api/export", func(w http.ResponseWriter, r *http.Request) {
+
+		resp, err := resolver.GetApiExport(db.GetDB())
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+	http.HandleFunc("/api/undo", func(w http.ResponseWriter, r *http.Request) {
+
+		resp, err := resolver.GetApiUndo(db.GetDB())
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+
+	// Serve the OpenAPI document describing the routes and events above
+	http.HandleFunc("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument)
+	})
 
 	return nil
 }
+
+// Generated OpenAPI document from api.yml, types.yml and events.yml
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// Generated request body handling
+
+// Request bodies are limited to this size
+const maxRequestBodySize = 1 << 20
+
+// decodeRequestBody decodes a JSON request body after checking it with a
+// validation function. Properties that the body type doesn't have are
+// rejected, so that misspelled properties don't go unnoticed.
+func decodeRequestBody(w http.ResponseWriter, r *http.Request, value interface{}, validate func(json.RawMessage) error) error {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		return err
+	}
+	if err = validate(data); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+// parseIntegerArray parses the values of an array parameter, each of which can
+// be a comma-separated list.
+func parseIntegerArray(values []string) ([]int, error) {
+	result := make([]int, 0, len(values))
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item == "" {
+				continue
+			}
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+func decodeObject(data json.RawMessage, typeName string) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("expected %s object", typeName)
+	}
+	return fields, nil
+}
+
+func isMissing(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+// Generated validation of the request body types from api.yml. Properties
+// are required unless they are nullable.
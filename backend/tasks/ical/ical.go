@@ -0,0 +1,105 @@
+// Package ical reads and writes iCalendar (RFC 5545) data, for calendar feeds
+// and CalDAV clients.
+//
+// Only what those need is supported: components made of properties, with
+// text, date and date-time values. Lines are folded and text is escaped as the
+// RFC requires, so any task title can be written as-is.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Lines longer than this many octets are folded
+const maxLineLength = 75
+
+// Component is a calendar component such as VTODO or VEVENT. Components nest,
+// e.g. VCALENDAR contains the others.
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []Component
+}
+
+// Property is a content line. Value must already be in the format of the
+// property's value type; see Text, DateTime and Date.
+type Property struct {
+	Name string
+	// Parameters, e.g. "VALUE=DATE"
+	Params []string
+	Value  string
+}
+
+// Add appends a property to the component.
+func (c *Component) Add(name string, value string, params ...string) {
+	c.Properties = append(c.Properties, Property{Name: name, Params: params, Value: value})
+}
+
+// Text escapes a TEXT value.
+func Text(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	return textEscaper.Replace(value)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// DateTime formats a DATE-TIME value in UTC.
+func DateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// Date formats a DATE value, for properties with the VALUE=DATE parameter.
+func Date(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// Encode writes a component, and everything in it, with CRLF line endings.
+func Encode(w io.Writer, c Component) error {
+	b := bufio.NewWriter(w)
+	encodeComponent(b, c)
+	return b.Flush()
+}
+
+func encodeComponent(b *bufio.Writer, c Component) {
+	writeLine(b, "BEGIN:"+c.Name)
+	for _, property := range c.Properties {
+		line := property.Name
+		for _, param := range property.Params {
+			line += ";" + param
+		}
+		writeLine(b, line+":"+property.Value)
+	}
+	for _, child := range c.Components {
+		encodeComponent(b, child)
+	}
+	writeLine(b, "END:"+c.Name)
+}
+
+// writeLine folds a content line into lines of at most 75 octets, without
+// splitting a UTF-8 sequence. Continuation lines start with a space.
+func writeLine(b *bufio.Writer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(line[n]) {
+			n--
+		}
+		b.WriteString(line[:n])
+		b.WriteString("\r\n ")
+		line = line[n:]
+		// The leading space counts towards the length of the next line
+		limit = maxLineLength - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
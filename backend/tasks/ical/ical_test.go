@@ -0,0 +1,74 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestText(t *testing.T) {
+	for _, test := range []struct{ value, want string }{
+		{"plain", "plain"},
+		{`a,b;c\d`, `a\,b\;c\\d`},
+		{"one\r\ntwo\nthree\rfour", `one\ntwo\nthree\nfour`},
+	} {
+		if got := Text(test.value); got != test.want {
+			t.Errorf("Text(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestDates(t *testing.T) {
+	tz := time.FixedZone("UTC-5", -5*60*60)
+	at := time.Date(2026, 1, 5, 22, 30, 0, 0, tz)
+	if got := DateTime(at); got != "20260106T033000Z" {
+		t.Errorf("DateTime(%v) = %q", at, got)
+	}
+	if got := Date(at); got != "20260105" {
+		t.Errorf("Date(%v) = %q", at, got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	todo := Component{Name: "VTODO"}
+	todo.Add("SUMMARY", Text(strings.Repeat("é", 60)))
+	todo.Add("DTSTART", "20260105", "VALUE=DATE")
+	calendar := Component{Name: "VCALENDAR", Components: []Component{todo}}
+	calendar.Add("VERSION", "2.0")
+
+	var b bytes.Buffer
+	if err := Encode(&b, calendar); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	want := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "BEGIN:VTODO"}
+	if strings.Join(lines[:3], "\n") != strings.Join(want, "\n") {
+		t.Errorf("calendar starts with %q, want %q", lines[:3], want)
+	}
+
+	// Long lines are folded at 75 octets without splitting a character, and
+	// unfold to the original
+	var summary string
+	i := 3
+	for ; i < len(lines) && (i == 3 || strings.HasPrefix(lines[i], " ")); i++ {
+		if len(lines[i]) > 75 {
+			t.Errorf("line %q is %d octets long", lines[i], len(lines[i]))
+		}
+		if !utf8.ValidString(lines[i]) {
+			t.Errorf("line %q splits a character", lines[i])
+		}
+		summary += strings.TrimPrefix(lines[i], " ")
+	}
+	if i == 4 {
+		t.Errorf("summary line %q was not folded", lines[3])
+	}
+	if want := "SUMMARY:" + strings.Repeat("é", 60); summary != want {
+		t.Errorf("unfolded summary %q, want %q", summary, want)
+	}
+	want = []string{"DTSTART;VALUE=DATE:20260105", "END:VTODO", "END:VCALENDAR"}
+	if strings.Join(lines[i:], "\n") != strings.Join(want, "\n") {
+		t.Errorf("calendar ends with %q, want %q", lines[i:], want)
+	}
+}
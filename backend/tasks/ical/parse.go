@@ -0,0 +1,169 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Parse reads a component, such as a VCALENDAR, from iCalendar data.
+// Properties and components it doesn't know are kept, so that they can be
+// looked at or ignored by the caller.
+func Parse(r io.Reader) (Component, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return Component{}, err
+	}
+	var stack []Component
+	var root *Component
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		property, err := parseLine(line)
+		if err != nil {
+			return Component{}, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		switch property.Name {
+		case "BEGIN":
+			stack = append(stack, Component{Name: strings.ToUpper(property.Value)})
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(property.Value) {
+				return Component{}, fmt.Errorf("line %d: unexpected END:%s", n+1, property.Value)
+			}
+			done := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				if root != nil {
+					return Component{}, fmt.Errorf("line %d: more than one top-level component", n+1)
+				}
+				root = &done
+			} else {
+				parent := &stack[len(stack)-1]
+				parent.Components = append(parent.Components, done)
+			}
+		default:
+			if len(stack) == 0 {
+				return Component{}, fmt.Errorf("line %d: property %s outside of a component", n+1, property.Name)
+			}
+			current := &stack[len(stack)-1]
+			current.Properties = append(current.Properties, property)
+		}
+	}
+	if len(stack) > 0 {
+		return Component{}, fmt.Errorf("missing END:%s", stack[len(stack)-1].Name)
+	}
+	if root == nil {
+		return Component{}, fmt.Errorf("no component found")
+	}
+	return *root, nil
+}
+
+// unfoldLines splits the data into content lines, joining folded lines.
+// Clients don't all use CRLF, so a bare LF ends a line too.
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into its name, parameters and value.
+// Parameter values may be quoted, and quoted values may contain ; and :.
+func parseLine(line string) (Property, error) {
+	var property Property
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return property, fmt.Errorf("invalid content line %q", line)
+	}
+	property.Name = strings.ToUpper(line[:i])
+	for line[i] == ';' {
+		start := i + 1
+		quoted := false
+		for i = start; i < len(line); i++ {
+			c := line[i]
+			if c == '"' {
+				quoted = !quoted
+			} else if !quoted && (c == ';' || c == ':') {
+				break
+			}
+		}
+		if i == len(line) {
+			return property, fmt.Errorf("invalid content line %q", line)
+		}
+		property.Params = append(property.Params, line[start:i])
+	}
+	property.Value = line[i+1:]
+	return property, nil
+}
+
+// Get returns the first property with the given name, or nil.
+func (c *Component) Get(name string) *Property {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Param returns the value of a parameter, without quotes, or "" if the
+// property doesn't have it.
+func (p *Property) Param(name string) string {
+	for _, param := range p.Params {
+		key, value, ok := strings.Cut(param, "=")
+		if ok && strings.EqualFold(key, name) {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// TextValue unescapes a TEXT value.
+func (p *Property) TextValue() string {
+	var b strings.Builder
+	for i := 0; i < len(p.Value); i++ {
+		c := p.Value[i]
+		if c == '\\' && i+1 < len(p.Value) {
+			i++
+			switch p.Value[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(p.Value[i])
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// TimeValue parses a DATE or DATE-TIME value. Dates are midnight UTC, and
+// date-times without a time zone ("floating" times) are taken to be UTC.
+func (p *Property) TimeValue() (time.Time, error) {
+	value := p.Value
+	if strings.EqualFold(p.Param("VALUE"), "DATE") || len(value) == len("20060102") {
+		return time.Parse("20060102", value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	location := time.UTC
+	if tzid := p.Param("TZID"); tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	return time.ParseInLocation("20060102T150405", value, location)
+}
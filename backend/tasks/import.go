@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/tomyedwab/yesterday/applib/database"
+	"github.com/tomyedwab/yesterday/applib/httputils"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/importer"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// Export documents can be large, but not this large
+const maxImportSize = 64 << 20
+
+// Importing takes a request body, which generated routes don't support, so the
+// route is registered by hand instead of being generated from api.yml.
+//
+// The body is an export document, or with ?format= an export from another
+// task manager in one of the formats the importer package reads.
+func initImportHandlers(db *database.Database) {
+	http.HandleFunc("/api/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var parser importer.Parser
+		if format := r.URL.Query().Get("format"); format != "" {
+			var ok bool
+			if parser, ok = importer.Get(format); !ok {
+				http.Error(w, fmt.Sprintf("Unknown format %q, expected one of: %s", format, strings.Join(importer.Formats(), ", ")), http.StatusBadRequest)
+				return
+			}
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		var doc generated.ExportDocument
+		if parser != nil {
+			doc, err = parser.Parse(body)
+		} else {
+			err = json.Unmarshal(body, &doc)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid export document: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err = validateExportDocument(doc); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid export document: %v", err), http.StatusBadRequest)
+			return
+		}
+		resp, err := importExportDocument(db, doc)
+		if err != nil {
+			log.Printf("Import failed after %d lists and %d tasks: %v", resp.ListCount, resp.TaskCount, err)
+		}
+		httputils.HandleAPIResponse(w, r, resp, err, http.StatusInternalServerError)
+	})
+}
+
+// validateExportDocument checks the references within a document, so that an
+// import doesn't stop halfway through because of a broken document.
+func validateExportDocument(doc generated.ExportDocument) error {
+	if doc.Version < 1 || doc.Version > state.ExportVersion {
+		return fmt.Errorf("unsupported version %d", doc.Version)
+	}
+	lists := make(map[int]bool, len(doc.Lists))
+	for _, list := range doc.Lists {
+		if lists[list.Id] {
+			return fmt.Errorf("duplicate list ID %d", list.Id)
+		}
+		lists[list.Id] = true
+	}
+	for _, list := range doc.Lists {
+		if list.Filter == nil {
+			continue
+		}
+		for _, listId := range append(list.Filter.ListIds, list.Filter.LabelIds...) {
+			if !lists[listId] {
+				return fmt.Errorf("filter of list %d refers to unknown list %d", list.Id, listId)
+			}
+		}
+	}
+	tasks := make(map[int]bool, len(doc.Tasks))
+	for _, task := range doc.Tasks {
+		if tasks[task.Id] {
+			return fmt.Errorf("duplicate task ID %d", task.Id)
+		}
+		tasks[task.Id] = true
+	}
+	for _, task := range doc.Tasks {
+		if len(task.Memberships) == 0 {
+			return fmt.Errorf("task %d is not in any list", task.Id)
+		}
+		for _, membership := range task.Memberships {
+			if !lists[membership.ListId] {
+				return fmt.Errorf("task %d is in unknown list %d", task.Id, membership.ListId)
+			}
+		}
+		if task.ParentTaskId != nil && (!tasks[*task.ParentTaskId] || *task.ParentTaskId == task.Id) {
+			return fmt.Errorf("task %d has invalid parent %d", task.Id, *task.ParentTaskId)
+		}
+	}
+	return nil
+}
+
+// importExportDocument recreates the lists and tasks in a document by
+// publishing events, as if they had been entered by hand, and returns how
+// much it created. The import is not atomic: if an event fails, what was
+// created up to then stays (see api.yml for how to recover).
+func importExportDocument(db *database.Database, doc generated.ExportDocument) (generated.ImportResponse, error) {
+	var resp generated.ImportResponse
+	sqlDB := db.GetDB()
+
+	// Lists are appended, so they keep their order
+	lists := append([]generated.ExportedList(nil), doc.Lists...)
+	sort.SliceStable(lists, func(i, j int) bool { return lists[i].Position < lists[j].Position })
+	listIds := make(map[int]int, len(lists))
+	for _, list := range lists {
+		eventId, err := publishEvent(db, "TaskList:Add", generated.TaskListAddEvent{
+			Title:    list.Title,
+			Category: list.Category,
+			Archived: list.Archived,
+		})
+		if err != nil {
+			return resp, err
+		}
+		listIds[list.Id], err = state.GetTaskListAddedByEvent(sqlDB, eventId)
+		if err != nil {
+			return resp, err
+		}
+		resp.ListCount++
+	}
+	for _, list := range lists {
+		if list.Filter == nil {
+			continue
+		}
+		_, err := publishEvent(db, "TaskList:UpdateFilter", generated.TaskListUpdateFilterEvent{
+			ListId:           listIds[list.Id],
+			DueWithinDays:    list.Filter.DueWithinDays,
+			IncludeCompleted: list.Filter.IncludeCompleted,
+			LabelIds:         remapIds(list.Filter.LabelIds, listIds),
+			ListIds:          remapIds(list.Filter.ListIds, listIds),
+		})
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	// Tasks are appended to each list in the order they were in, and created
+	// in the first list they are found in
+	type member struct {
+		task     *generated.ExportedTask
+		position int
+	}
+	members := make(map[int][]member)
+	for i := range doc.Tasks {
+		for _, membership := range doc.Tasks[i].Memberships {
+			members[membership.ListId] = append(members[membership.ListId], member{&doc.Tasks[i], membership.Position})
+		}
+	}
+	taskIds := make(map[int]int, len(doc.Tasks))
+	for _, list := range lists {
+		listMembers := members[list.Id]
+		sort.SliceStable(listMembers, func(i, j int) bool { return listMembers[i].position < listMembers[j].position })
+		for _, m := range listMembers {
+			if taskId, ok := taskIds[m.task.Id]; ok {
+				_, err := publishEvent(db, "TaskList:AddTask", generated.TaskListAddTaskEvent{TaskId: taskId, ListId: listIds[list.Id]})
+				if err != nil {
+					return resp, err
+				}
+				continue
+			}
+			eventId, err := publishEvent(db, "Task:Add", generated.TaskAddEvent{
+				Title:      m.task.Title,
+				DueDate:    m.task.DueDate,
+				TaskListId: listIds[list.Id],
+			})
+			if err != nil {
+				return resp, err
+			}
+			taskIds[m.task.Id], err = state.GetTaskAddedByEvent(sqlDB, eventId)
+			if err != nil {
+				return resp, err
+			}
+			resp.TaskCount++
+		}
+	}
+
+	for _, task := range doc.Tasks {
+		if err := importTaskDetails(db, taskIds[task.Id], task); err != nil {
+			return resp, err
+		}
+	}
+
+	// Subtasks are placed after their preceding sibling, so they keep their
+	// order
+	subtasks := make([]generated.ExportedTask, 0)
+	for _, task := range doc.Tasks {
+		if task.ParentTaskId != nil {
+			subtasks = append(subtasks, task)
+		}
+	}
+	sort.SliceStable(subtasks, func(i, j int) bool {
+		if *subtasks[i].ParentTaskId != *subtasks[j].ParentTaskId {
+			return *subtasks[i].ParentTaskId < *subtasks[j].ParentTaskId
+		}
+		return position(subtasks[i].SubtaskPosition) < position(subtasks[j].SubtaskPosition)
+	})
+	for i, task := range subtasks {
+		var afterTaskId *int
+		if i > 0 && *subtasks[i-1].ParentTaskId == *task.ParentTaskId {
+			previous := taskIds[subtasks[i-1].Id]
+			afterTaskId = &previous
+		}
+		parentTaskId := taskIds[*task.ParentTaskId]
+		_, err := publishEvent(db, "Task:SetParent", generated.TaskSetParentEvent{
+			TaskId:       taskIds[task.Id],
+			ParentTaskId: &parentTaskId,
+			AfterTaskId:  afterTaskId,
+		})
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	// Comments can't be backdated, but they are added in the order they
+	// were made
+	type comment struct {
+		taskId int
+		entry  generated.ExportedHistory
+	}
+	var comments []comment
+	for _, task := range doc.Tasks {
+		for _, entry := range task.History {
+			if entry.UpdateType == generated.TaskUpdateTypeAddComment && entry.UserComment != nil {
+				comments = append(comments, comment{taskIds[task.Id], entry})
+			}
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].entry.CreatedAt.Before(comments[j].entry.CreatedAt) })
+	for _, c := range comments {
+		_, err := publishEvent(db, "Task:AddComment", generated.TaskAddCommentEvent{TaskId: c.taskId, UserComment: *c.entry.UserComment})
+		if err != nil {
+			return resp, err
+		}
+		resp.CommentCount++
+	}
+	return resp, nil
+}
+
+// importTaskDetails publishes the events that set the fields of a task that
+// Task:Add doesn't.
+func importTaskDetails(db *database.Database, taskId int, task generated.ExportedTask) error {
+	if task.Notes != "" {
+		_, err := publishEvent(db, "Task:UpdateNotes", generated.TaskUpdateNotesEvent{TaskId: taskId, Notes: task.Notes})
+		if err != nil {
+			return err
+		}
+	}
+	if task.Priority != nil {
+		_, err := publishEvent(db, "Task:UpdatePriority", generated.TaskUpdatePriorityEvent{TaskId: taskId, Priority: task.Priority})
+		if err != nil {
+			return err
+		}
+	}
+	// Completing a recurring task creates its next occurrence, so the task is
+	// completed before it recurs
+	if task.CompletedAt != nil {
+		_, err := publishEvent(db, "Task:UpdateCompleted", generated.TaskUpdateCompletedEvent{TaskId: taskId, CompletedAt: task.CompletedAt})
+		if err != nil {
+			return err
+		}
+	}
+	if task.Recurrence != nil {
+		_, err := publishEvent(db, "Task:UpdateRecurrence", generated.TaskUpdateRecurrenceEvent{TaskId: taskId, Recurrence: task.Recurrence})
+		if err != nil {
+			return err
+		}
+	}
+	if task.StartAt != nil {
+		_, err := publishEvent(db, "Task:Snooze", generated.TaskSnoozeEvent{TaskId: taskId, Until: *task.StartAt})
+		if err != nil {
+			return err
+		}
+	}
+	for _, reminder := range task.Reminders {
+		_, err := publishEvent(db, "Task:AddReminder", generated.TaskAddReminderEvent{
+			TaskId:           taskId,
+			RemindAt:         reminder.RemindAt,
+			MinutesBeforeDue: reminder.MinutesBeforeDue,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func remapIds(ids []int, newIds map[int]int) []int {
+	remapped := make([]int, 0, len(ids))
+	for _, id := range ids {
+		remapped = append(remapped, newIds[id])
+	}
+	return remapped
+}
+
+func position(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
@@ -0,0 +1,186 @@
+// Package importer reads tasks exported from other task managers.
+//
+// Each format has a Parser, which turns an export into the same document that
+// /api/export produces; /api/import then creates its lists and tasks like it
+// does for a backup. Projects become to-do lists, and tags and contexts become
+// labels. Parsers register themselves under a format name, so adding a format
+// doesn't touch the import route.
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// Parser converts an export from another task manager.
+type Parser interface {
+	Parse(data []byte) (generated.ExportDocument, error)
+}
+
+var parsers = map[string]Parser{}
+
+// Register makes a parser available under a format name.
+func Register(format string, parser Parser) {
+	if _, ok := parsers[format]; ok {
+		panic(fmt.Sprintf("importer: format %q registered twice", format))
+	}
+	parsers[format] = parser
+}
+
+// Get returns the parser for a format, or false if there is none.
+func Get(format string) (Parser, bool) {
+	parser, ok := parsers[format]
+	return parser, ok
+}
+
+// Formats returns the names of the registered formats, sorted.
+func Formats() []string {
+	formats := make([]string, 0, len(parsers))
+	for format := range parsers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// builder assembles an export document, numbering lists and tasks as they are
+// added and keeping track of their positions.
+type builder struct {
+	doc   generated.ExportDocument
+	lists map[string]int
+	// Next position in each list, and among each task's subtasks
+	listPositions    map[int]int
+	subtaskPositions map[int]int
+}
+
+func newBuilder() *builder {
+	return &builder{
+		doc: generated.ExportDocument{
+			Version:    state.ExportVersion,
+			ExportedAt: time.Now().UTC(),
+			Lists:      make([]generated.ExportedList, 0),
+			Tasks:      make([]generated.ExportedTask, 0),
+		},
+		lists:            map[string]int{},
+		listPositions:    map[int]int{},
+		subtaskPositions: map[int]int{},
+	}
+}
+
+// list returns the ID of the list with a title and category, adding it if it
+// doesn't exist yet.
+func (b *builder) list(title string, category generated.TaskListCategory) int {
+	key := string(category) + "\x00" + title
+	if id, ok := b.lists[key]; ok {
+		return id
+	}
+	id := len(b.doc.Lists) + 1
+	b.doc.Lists = append(b.doc.Lists, generated.ExportedList{
+		Id:       id,
+		Title:    title,
+		Category: category,
+		Position: len(b.doc.Lists),
+	})
+	b.lists[key] = id
+	return id
+}
+
+// labels returns the IDs of the label lists with the given titles.
+func (b *builder) labels(titles []string) []int {
+	ids := make([]int, 0, len(titles))
+	for _, title := range titles {
+		ids = append(ids, b.list(title, generated.TaskListCategoryLabel))
+	}
+	return ids
+}
+
+// addTask adds a task to the end of the given lists and returns its ID.
+func (b *builder) addTask(task generated.ExportedTask, listIds ...int) int {
+	task.Id = len(b.doc.Tasks) + 1
+	task.Memberships = make([]generated.ExportedMembership, 0, len(listIds))
+	task.Reminders = make([]generated.ExportedReminder, 0)
+	task.History = make([]generated.ExportedHistory, 0)
+	b.doc.Tasks = append(b.doc.Tasks, task)
+	for _, listId := range listIds {
+		b.addToList(task.Id, listId)
+	}
+	return task.Id
+}
+
+// addToList adds a task to the end of another list.
+func (b *builder) addToList(taskId, listId int) {
+	task := &b.doc.Tasks[taskId-1]
+	if hasMembership(task.Memberships, listId) {
+		return
+	}
+	task.Memberships = append(task.Memberships, generated.ExportedMembership{
+		ListId:   listId,
+		Position: b.listPositions[listId],
+	})
+	b.listPositions[listId]++
+}
+
+func hasMembership(memberships []generated.ExportedMembership, listId int) bool {
+	for _, membership := range memberships {
+		if membership.ListId == listId {
+			return true
+		}
+	}
+	return false
+}
+
+// addSubtask adds a task as the last subtask of another one, in the same
+// lists, and returns its ID.
+func (b *builder) addSubtask(task generated.ExportedTask, parentId int) int {
+	parent := b.doc.Tasks[parentId-1]
+	listIds := make([]int, 0, len(parent.Memberships))
+	for _, membership := range parent.Memberships {
+		listIds = append(listIds, membership.ListId)
+	}
+	position := b.subtaskPositions[parentId]
+	b.subtaskPositions[parentId]++
+	task.ParentTaskId = &parentId
+	task.SubtaskPosition = &position
+	return b.addTask(task, listIds...)
+}
+
+// addComment adds a comment to a task.
+func (b *builder) addComment(taskId int, text string, createdAt time.Time) {
+	task := &b.doc.Tasks[taskId-1]
+	task.History = append(task.History, generated.ExportedHistory{
+		UpdateType:    generated.TaskUpdateTypeAddComment,
+		SystemComment: "Comment added",
+		UserComment:   &text,
+		CreatedAt:     createdAt,
+	})
+}
+
+// parseTime reads the date and date-time formats that exports use. Dates are
+// midnight UTC, and date-times without a time zone are taken to be UTC.
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// simpleRecurrences maps recurrence phrases to the rules Task:UpdateRecurrence
+// accepts
+var simpleRecurrences = map[string]string{
+	"every day":   "daily",
+	"daily":       "daily",
+	"every week":  "weekly",
+	"weekly":      "weekly",
+	"every month": "monthly",
+	"monthly":     "monthly",
+	"every year":  "yearly",
+	"yearly":      "yearly",
+}
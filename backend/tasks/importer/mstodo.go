@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func init() {
+	Register("mstodo", msToDoParser{})
+}
+
+// msToDoParser reads Microsoft To Do lists in the JSON format of the Microsoft
+// Graph API: either an array of lists or a response with the lists in
+// "value", with each list's tasks in "tasks". Categories become labels, and
+// checklist items become subtasks. Times are taken to be UTC, which is the
+// time zone Graph returns them in unless asked otherwise.
+type msToDoParser struct{}
+
+type msToDoDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type msToDoTask struct {
+	Title             string          `json:"title"`
+	Status            string          `json:"status"`
+	Importance        string          `json:"importance"`
+	Categories        []string        `json:"categories"`
+	CompletedDateTime *msToDoDateTime `json:"completedDateTime"`
+	DueDateTime       *msToDoDateTime `json:"dueDateTime"`
+	Body              *struct {
+		Content     string `json:"content"`
+		ContentType string `json:"contentType"`
+	} `json:"body"`
+	Recurrence *struct {
+		Pattern struct {
+			Type     string `json:"type"`
+			Interval int    `json:"interval"`
+		} `json:"pattern"`
+	} `json:"recurrence"`
+	ChecklistItems []struct {
+		DisplayName     string `json:"displayName"`
+		IsChecked       bool   `json:"isChecked"`
+		CheckedDateTime string `json:"checkedDateTime"`
+	} `json:"checklistItems"`
+}
+
+type msToDoList struct {
+	DisplayName string       `json:"displayName"`
+	Tasks       []msToDoTask `json:"tasks"`
+}
+
+// Graph recurrence pattern types that map to the simple rules
+var msToDoRecurrences = map[string]string{
+	"daily":           "daily",
+	"weekly":          "weekly",
+	"absoluteMonthly": "monthly",
+	"relativeMonthly": "monthly",
+	"absoluteYearly":  "yearly",
+	"relativeYearly":  "yearly",
+}
+
+func (msToDoParser) Parse(data []byte) (generated.ExportDocument, error) {
+	b := newBuilder()
+	var lists []msToDoList
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &lists); err != nil {
+			return b.doc, err
+		}
+	} else {
+		var response struct {
+			Value []msToDoList `json:"value"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return b.doc, err
+		}
+		lists = response.Value
+	}
+
+	for _, list := range lists {
+		listId := b.list(list.DisplayName, generated.TaskListCategoryToDoList)
+		for _, item := range list.Tasks {
+			task, err := msToDoTaskFields(item)
+			if err != nil {
+				return b.doc, err
+			}
+			taskId := b.addTask(task, listId)
+			for _, labelId := range b.labels(item.Categories) {
+				b.addToList(taskId, labelId)
+			}
+			for _, checklistItem := range item.ChecklistItems {
+				subtask := generated.ExportedTask{Title: strings.TrimSpace(checklistItem.DisplayName)}
+				if checklistItem.IsChecked {
+					completedAt := time.Now().UTC()
+					if t, err := parseTime(checklistItem.CheckedDateTime); err == nil {
+						completedAt = t
+					}
+					subtask.CompletedAt = &completedAt
+				}
+				b.addSubtask(subtask, taskId)
+			}
+		}
+	}
+	return b.doc, nil
+}
+
+func msToDoTaskFields(item msToDoTask) (generated.ExportedTask, error) {
+	task := generated.ExportedTask{Title: strings.TrimSpace(item.Title)}
+	if item.Body != nil {
+		task.Notes = strings.TrimSpace(item.Body.Content)
+	}
+	switch item.Importance {
+	case "high":
+		p := 1
+		task.Priority = &p
+	case "low":
+		p := 4
+		task.Priority = &p
+	}
+	if item.DueDateTime != nil {
+		due, err := parseTime(item.DueDateTime.DateTime)
+		if err != nil {
+			return task, err
+		}
+		task.DueDate = &due
+	}
+	if item.Status == "completed" {
+		completedAt := time.Now().UTC()
+		if item.CompletedDateTime != nil {
+			if t, err := parseTime(item.CompletedDateTime.DateTime); err == nil {
+				completedAt = t
+			}
+		}
+		task.CompletedAt = &completedAt
+	}
+	if item.Recurrence != nil && item.Recurrence.Pattern.Interval <= 1 {
+		if rule, ok := msToDoRecurrences[item.Recurrence.Pattern.Type]; ok {
+			task.Recurrence = &rule
+		}
+	}
+	return task, nil
+}
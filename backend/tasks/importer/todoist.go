@@ -0,0 +1,322 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func init() {
+	Register("todoist-csv", todoistCSVParser{})
+	Register("todoist-json", todoistJSONParser{})
+}
+
+// Todoist's priorities run from p1 (highest) to p4, which means no priority.
+// CSV exports number them the same way; the JSON API numbers them the other
+// way round, with 4 for p1.
+func todoistPriority(p int) *int {
+	if p < 1 || p > 3 {
+		return nil
+	}
+	return &p
+}
+
+// Todoist puts labels in the task content as @label
+var todoistLabelPattern = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// todoistLabels takes the labels out of a task's content.
+func todoistLabels(content string) (string, []string) {
+	var labels []string
+	for _, match := range todoistLabelPattern.FindAllStringSubmatch(content, -1) {
+		labels = append(labels, match[2])
+	}
+	title := todoistLabelPattern.ReplaceAllString(content, "$1")
+	return strings.Join(strings.Fields(title), " "), labels
+}
+
+// todoistCSVParser reads Todoist's CSV exports: either a backup, which is a
+// zip file with one CSV file per project, or the CSV file of a single
+// project, which goes into a list named Todoist.
+type todoistCSVParser struct{}
+
+func (todoistCSVParser) Parse(data []byte) (generated.ExportDocument, error) {
+	b := newBuilder()
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		err := parseTodoistCSV(b, "Todoist", bytes.NewReader(data))
+		return b.doc, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return b.doc, err
+	}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(file.Name), ".csv") {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			return b.doc, err
+		}
+		err = parseTodoistCSV(b, todoistProjectName(file.Name), f)
+		f.Close()
+		if err != nil {
+			return b.doc, fmt.Errorf("%s: %w", file.Name, err)
+		}
+	}
+	return b.doc, nil
+}
+
+// Backup files are named after their project and its ID, e.g.
+// "Groceries [2203306141].csv"
+var todoistProjectIdPattern = regexp.MustCompile(`\s*\[\d+\]$`)
+
+func todoistProjectName(fileName string) string {
+	name := strings.TrimSuffix(path.Base(fileName), path.Ext(fileName))
+	return todoistProjectIdPattern.ReplaceAllString(name, "")
+}
+
+// parseTodoistCSV reads the rows of a project. Tasks are nested by their
+// INDENT column, and notes belong to the task before them.
+func parseTodoistCSV(b *builder, project string, r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("missing %s column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	listId := b.list(project, generated.TaskListCategoryToDoList)
+	// The task at each indent level, for finding the parent of a subtask
+	var parents []int
+	lastTaskId := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch field(record, "TYPE") {
+		case "task":
+			title, labels := todoistLabels(field(record, "CONTENT"))
+			task := generated.ExportedTask{
+				Title: title,
+				Notes: field(record, "DESCRIPTION"),
+			}
+			if priority, err := strconv.Atoi(field(record, "PRIORITY")); err == nil {
+				task.Priority = todoistPriority(priority)
+			}
+			if date := field(record, "DATE"); date != "" {
+				if rule, ok := simpleRecurrences[strings.ToLower(date)]; ok {
+					task.Recurrence = &rule
+				} else if due, err := parseTime(date); err == nil {
+					task.DueDate = &due
+				}
+			}
+			indent, err := strconv.Atoi(field(record, "INDENT"))
+			if err != nil || indent < 1 {
+				indent = 1
+			}
+			if indent > len(parents)+1 {
+				indent = len(parents) + 1
+			}
+			parents = parents[:indent-1]
+			if indent > 1 {
+				lastTaskId = b.addSubtask(task, parents[indent-2])
+			} else {
+				lastTaskId = b.addTask(task, listId)
+			}
+			for _, labelId := range b.labels(labels) {
+				b.addToList(lastTaskId, labelId)
+			}
+			parents = append(parents, lastTaskId)
+		case "note":
+			if lastTaskId != 0 {
+				b.addComment(lastTaskId, field(record, "CONTENT"), time.Now().UTC())
+			}
+		}
+	}
+}
+
+// todoistJSONParser reads the JSON that Todoist's sync API returns, with the
+// projects, items (tasks), notes (comments) and labels of an account. Only
+// the fields used here need to be present.
+type todoistJSONParser struct{}
+
+type todoistDue struct {
+	Date        string `json:"date"`
+	IsRecurring bool   `json:"is_recurring"`
+	String      string `json:"string"`
+}
+
+type todoistItem struct {
+	Id          string      `json:"id"`
+	ProjectId   string      `json:"project_id"`
+	ParentId    *string     `json:"parent_id"`
+	ChildOrder  int         `json:"child_order"`
+	Content     string      `json:"content"`
+	Description string      `json:"description"`
+	Priority    int         `json:"priority"`
+	Due         *todoistDue `json:"due"`
+	Labels      []string    `json:"labels"`
+	Checked     bool        `json:"checked"`
+	CompletedAt *string     `json:"completed_at"`
+	IsDeleted   bool        `json:"is_deleted"`
+}
+
+type todoistExport struct {
+	Projects []struct {
+		Id         string `json:"id"`
+		Name       string `json:"name"`
+		ChildOrder int    `json:"child_order"`
+		IsArchived bool   `json:"is_archived"`
+		IsDeleted  bool   `json:"is_deleted"`
+	} `json:"projects"`
+	Items []todoistItem `json:"items"`
+	Notes []struct {
+		ItemId    string `json:"item_id"`
+		Content   string `json:"content"`
+		PostedAt  string `json:"posted_at"`
+		IsDeleted bool   `json:"is_deleted"`
+	} `json:"notes"`
+}
+
+func (todoistJSONParser) Parse(data []byte) (generated.ExportDocument, error) {
+	b := newBuilder()
+	var export todoistExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return b.doc, err
+	}
+
+	projects := export.Projects[:0]
+	for _, project := range export.Projects {
+		if !project.IsDeleted {
+			projects = append(projects, project)
+		}
+	}
+	sort.SliceStable(projects, func(i, j int) bool { return projects[i].ChildOrder < projects[j].ChildOrder })
+	listIds := map[string]int{}
+	for _, project := range projects {
+		listIds[project.Id] = b.list(project.Name, generated.TaskListCategoryToDoList)
+		b.doc.Lists[listIds[project.Id]-1].Archived = project.IsArchived
+	}
+
+	// Parents are added before their children, each level in order. Tasks
+	// whose parent is missing from the export become top-level tasks.
+	exported := map[string]bool{}
+	for _, item := range export.Items {
+		if !item.IsDeleted {
+			exported[item.Id] = true
+		}
+	}
+	children := map[string][]todoistItem{}
+	for _, item := range export.Items {
+		if item.IsDeleted {
+			continue
+		}
+		parentId := ""
+		if item.ParentId != nil && exported[*item.ParentId] {
+			parentId = *item.ParentId
+		}
+		children[parentId] = append(children[parentId], item)
+	}
+	taskIds := map[string]int{}
+	var add func(parentId string) error
+	add = func(parentId string) error {
+		items := children[parentId]
+		sort.SliceStable(items, func(i, j int) bool { return items[i].ChildOrder < items[j].ChildOrder })
+		for _, item := range items {
+			task, err := todoistJSONTask(item)
+			if err != nil {
+				return err
+			}
+			if parentId == "" {
+				listId, ok := listIds[item.ProjectId]
+				if !ok {
+					listId = b.list("Todoist", generated.TaskListCategoryToDoList)
+				}
+				taskIds[item.Id] = b.addTask(task, listId)
+			} else {
+				taskIds[item.Id] = b.addSubtask(task, taskIds[parentId])
+			}
+			for _, labelId := range b.labels(item.Labels) {
+				b.addToList(taskIds[item.Id], labelId)
+			}
+			if err = add(item.Id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add(""); err != nil {
+		return b.doc, err
+	}
+
+	for _, note := range export.Notes {
+		taskId, ok := taskIds[note.ItemId]
+		if note.IsDeleted || !ok {
+			continue
+		}
+		postedAt, err := parseTime(note.PostedAt)
+		if err != nil {
+			postedAt = time.Now().UTC()
+		}
+		b.addComment(taskId, note.Content, postedAt)
+	}
+	return b.doc, nil
+}
+
+func todoistJSONTask(item todoistItem) (generated.ExportedTask, error) {
+	task := generated.ExportedTask{
+		Title:    strings.TrimSpace(item.Content),
+		Notes:    item.Description,
+		Priority: todoistPriority(5 - item.Priority),
+	}
+	if item.Due != nil && item.Due.Date != "" {
+		due, err := parseTime(item.Due.Date)
+		if err != nil {
+			return task, err
+		}
+		task.DueDate = &due
+		if rule, ok := simpleRecurrences[strings.ToLower(item.Due.String)]; ok && item.Due.IsRecurring {
+			task.Recurrence = &rule
+		}
+	}
+	if item.Checked {
+		completedAt := time.Now().UTC()
+		if item.CompletedAt != nil {
+			if t, err := parseTime(*item.CompletedAt); err == nil {
+				completedAt = t
+			}
+		}
+		task.CompletedAt = &completedAt
+	}
+	return task, nil
+}
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func init() {
+	Register("todotxt", todoTxtParser{})
+}
+
+// todoTxtParser reads the todo.txt format (https://github.com/todotxt/todo.txt),
+// one task per line. +project tags become to-do lists, with tasks that have no
+// project going into a list named Inbox, and @context tags become labels. The
+// due:, t: (threshold) and rec: extensions set the due date, snooze time and
+// recurrence.
+type todoTxtParser struct{}
+
+var (
+	todoTxtPriorityPattern = regexp.MustCompile(`^\(([A-Z])\)\s+`)
+	todoTxtDatePattern     = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})\s+`)
+	todoTxtTagPattern      = regexp.MustCompile(`^([+@])(\S+)$`)
+	todoTxtKeyValuePattern = regexp.MustCompile(`^([a-z]+):(\S+)$`)
+)
+
+// rec: values are an optional +, an optional count and a unit
+var todoTxtRecurrencePattern = regexp.MustCompile(`^\+?1?([dwmy])$`)
+
+var todoTxtRecurrences = map[string]string{
+	"d": "daily",
+	"w": "weekly",
+	"m": "monthly",
+	"y": "yearly",
+}
+
+func (todoTxtParser) Parse(data []byte) (generated.ExportDocument, error) {
+	b := newBuilder()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		task, projects, contexts, err := parseTodoTxtLine(line)
+		if err != nil {
+			return b.doc, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if len(projects) == 0 {
+			projects = []string{"Inbox"}
+		}
+		listIds := make([]int, 0, len(projects))
+		for _, project := range projects {
+			listIds = append(listIds, b.list(project, generated.TaskListCategoryToDoList))
+		}
+		taskId := b.addTask(task, listIds...)
+		for _, labelId := range b.labels(contexts) {
+			b.addToList(taskId, labelId)
+		}
+	}
+	return b.doc, scanner.Err()
+}
+
+func parseTodoTxtLine(line string) (generated.ExportedTask, []string, []string, error) {
+	var task generated.ExportedTask
+	// A completed task starts with "x", then its completion date and its
+	// creation date, both optional
+	if strings.HasPrefix(line, "x ") {
+		line = strings.TrimSpace(line[2:])
+		completedAt := time.Now().UTC()
+		if match := todoTxtDatePattern.FindStringSubmatch(line); match != nil {
+			completedAt, _ = parseTime(match[1])
+			line = line[len(match[0]):]
+		}
+		task.CompletedAt = &completedAt
+	}
+	// Priorities run from (A) down; anything below (C) is P4
+	if match := todoTxtPriorityPattern.FindStringSubmatch(line); match != nil {
+		p := int(match[1][0]-'A') + 1
+		if p > 4 {
+			p = 4
+		}
+		task.Priority = &p
+		line = line[len(match[0]):]
+	}
+	if match := todoTxtDatePattern.FindStringSubmatch(line); match != nil {
+		line = line[len(match[0]):]
+	}
+
+	var words, projects, contexts []string
+	for _, word := range strings.Fields(line) {
+		if match := todoTxtTagPattern.FindStringSubmatch(word); match != nil {
+			if match[1] == "+" {
+				projects = append(projects, match[2])
+			} else {
+				contexts = append(contexts, match[2])
+			}
+			continue
+		}
+		if match := todoTxtKeyValuePattern.FindStringSubmatch(word); match != nil {
+			switch match[1] {
+			case "due", "t":
+				date, err := parseTime(match[2])
+				if err != nil {
+					return task, nil, nil, err
+				}
+				if match[1] == "due" {
+					task.DueDate = &date
+				} else {
+					task.StartAt = &date
+				}
+				continue
+			case "rec":
+				recurrence := todoTxtRecurrencePattern.FindStringSubmatch(match[2])
+				if recurrence == nil {
+					return task, nil, nil, fmt.Errorf("unsupported recurrence %q", match[2])
+				}
+				rule := todoTxtRecurrences[recurrence[1]]
+				task.Recurrence = &rule
+				continue
+			}
+		}
+		words = append(words, word)
+	}
+	task.Title = strings.Join(words, " ")
+	if task.Title == "" {
+		return task, nil, nil, fmt.Errorf("task has no title")
+	}
+	return task, projects, contexts, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	_ "github.com/mattn/go-sqlite3"
 
@@ -23,23 +24,22 @@ func initApplication(application *applib.Application) error {
 
 	tx := db.GetDB().MustBegin()
 	defer tx.Rollback()
-	if err = state.InitTask(tx); err != nil {
+	if err = state.InitProjections(tx); err != nil {
 		return err
 	}
-	if err = state.InitTaskList(tx); err != nil {
-		return err
-	}
-	if err = state.InitTaskHistory(tx); err != nil {
-		return err
-	}
-	if err = state.InitTaskToList(tx); err != nil {
+	if err = tx.Commit(); err != nil {
 		return err
 	}
-	if err = tx.Commit(); err != nil {
+	if err = state.RunMigrations(db.GetDB()); err != nil {
 		return err
 	}
 
 	generated.InitHandlers(db, state.NewResolver(), state.NewEventHandler())
+	initAdminHandlers(db)
+	initCalendarHandlers(db)
+	initCalDAVHandlers(db)
+	initImportHandlers(db)
+	initTaskListExportHandlers(db)
 	return nil
 }
 
@@ -54,5 +54,25 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// `tasks rebuild-projections` rebuilds the state tables from the event log
+	// and exits instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-projections" {
+		_, err = state.RebuildProjections(application.GetDatabase().GetDB())
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	startTrashPurgeScheduler(application.GetDatabase())
+
+	notifier, err := newNotifierFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if notifier != nil {
+		startReminderScheduler(application.GetDatabase().GetDB(), notifier)
+	}
+
 	application.Serve()
 }
@@ -0,0 +1,317 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Link URLs with any other scheme (e.g. javascript:) are dropped
+var allowedSchemes = []string{"http://", "https://", "mailto:"}
+
+const linkAttributes = ` rel="nofollow noopener noreferrer"`
+
+// safeURL returns the URL to link to, or false if it can't be linked to.
+func safeURL(url string) (string, bool) {
+	url = strings.TrimSpace(url)
+	lower := strings.ToLower(url)
+	for _, scheme := range allowedSchemes {
+		if strings.HasPrefix(lower, scheme) && len(url) > len(scheme) {
+			return url, true
+		}
+	}
+	return "", false
+}
+
+func isPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n'
+}
+
+// renderInline renders the inline content of a block, escaping everything that
+// isn't Markdown syntax.
+func renderInline(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isPunctuation(text[i+1]):
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			if rendered, n := renderCodeSpan(text[i:]); n > 0 {
+				b.WriteString(rendered)
+				i += n
+				continue
+			}
+			// An unmatched run of backticks is literal text
+			n := runLength(text[i:], '`')
+			b.WriteString(text[i : i+n])
+			i += n
+			continue
+
+		case c == '[' || (c == '!' && i+1 < len(text) && text[i+1] == '['):
+			start := i
+			if c == '!' {
+				start++
+			}
+			if rendered, n := renderLink(text[start:]); n > 0 {
+				b.WriteString(rendered)
+				i = start + n
+				continue
+			}
+
+		case c == '<':
+			if rendered, n := renderAutolink(text[i:]); n > 0 {
+				b.WriteString(rendered)
+				i += n
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if rendered, n := renderEmphasis(text, i); n > 0 {
+				b.WriteString(rendered)
+				i += n
+				continue
+			}
+			// Runs are skipped as a whole, so that e.g. the first * of an
+			// unmatched ** doesn't close an emphasis later on
+			n := runLength(text[i:], c)
+			b.WriteString(text[i : i+n])
+			i += n
+			continue
+
+		case c == 'h' && (i == 0 || !isAlphanumeric(text[i-1])):
+			if rendered, n := renderBareURL(text[i:]); n > 0 {
+				b.WriteString(rendered)
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// unescapeBackslashes removes the backslashes that escape punctuation in link
+// destinations and titles.
+func unescapeBackslashes(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && isPunctuation(text[i+1]) {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+func runLength(text string, c byte) int {
+	n := 0
+	for n < len(text) && text[n] == c {
+		n++
+	}
+	return n
+}
+
+// renderCodeSpan renders a code span starting at text[0], and returns the
+// number of bytes consumed, or 0 if there is no matching closing run.
+func renderCodeSpan(text string) (string, int) {
+	n := runLength(text, '`')
+	for i := n; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		m := runLength(text[i:], '`')
+		if m == n {
+			code := text[n:i]
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			return "<code>" + html.EscapeString(code) + "</code>", i + m
+		}
+		i += m
+	}
+	return "", 0
+}
+
+// renderLink renders [text](url "title") starting at text[0]. Links to URLs
+// that aren't allowed are rendered as their text only.
+func renderLink(text string) (string, int) {
+	depth := 0
+	closing := -1
+	for i := 0; i < len(text) && closing < 0; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(text) || text[closing+1] != '(' {
+		return "", 0
+	}
+	label := text[1:closing]
+
+	depth = 0
+	end := -1
+	for i := closing + 1; i < len(text) && end < 0; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return "", 0
+	}
+	destination := strings.TrimSpace(text[closing+2 : end])
+	title := ""
+	if n := strings.IndexAny(destination, " \n"); n >= 0 {
+		title = strings.TrimSpace(destination[n:])
+		destination = destination[:n]
+		if len(title) >= 2 && (title[0] == '"' || title[0] == '\'') && title[len(title)-1] == title[0] {
+			title = title[1 : len(title)-1]
+		} else {
+			return "", 0
+		}
+	}
+	destination = strings.TrimSuffix(strings.TrimPrefix(destination, "<"), ">")
+	destination, title = unescapeBackslashes(destination), unescapeBackslashes(title)
+
+	content := renderInline(label)
+	url, ok := safeURL(destination)
+	if !ok {
+		return content, end + 1
+	}
+	titleAttribute := ""
+	if title != "" {
+		titleAttribute = fmt.Sprintf(` title="%s"`, html.EscapeString(title))
+	}
+	return fmt.Sprintf(`<a href="%s"%s%s>%s</a>`, html.EscapeString(url), titleAttribute, linkAttributes, content), end + 1
+}
+
+// renderAutolink renders <https://example.com> starting at text[0].
+func renderAutolink(text string) (string, int) {
+	end := strings.IndexByte(text, '>')
+	if end < 0 {
+		return "", 0
+	}
+	destination := text[1:end]
+	if strings.ContainsAny(destination, " <\n") {
+		return "", 0
+	}
+	url, ok := safeURL(destination)
+	if !ok {
+		return "", 0
+	}
+	label := strings.TrimPrefix(destination, "mailto:")
+	return fmt.Sprintf(`<a href="%s"%s>%s</a>`, html.EscapeString(url), linkAttributes, html.EscapeString(label)), end + 1
+}
+
+// renderBareURL links a URL written out in the text. Trailing punctuation is
+// assumed to belong to the sentence rather than the URL.
+func renderBareURL(text string) (string, int) {
+	if !strings.HasPrefix(text, "http://") && !strings.HasPrefix(text, "https://") {
+		return "", 0
+	}
+	end := strings.IndexAny(text, " \n<")
+	if end < 0 {
+		end = len(text)
+	}
+	for end > 0 && strings.IndexByte(".,:;!?'\")*_~", text[end-1]) >= 0 {
+		// Keep a closing parenthesis that has a matching opening one
+		if text[end-1] == ')' && strings.Count(text[:end], "(") >= strings.Count(text[:end], ")") {
+			break
+		}
+		end--
+	}
+	url, ok := safeURL(text[:end])
+	if !ok {
+		return "", 0
+	}
+	return fmt.Sprintf(`<a href="%s"%s>%s</a>`, html.EscapeString(url), linkAttributes, html.EscapeString(url)), end
+}
+
+// renderEmphasis renders *em*, **strong** (or with underscores) and
+// ~~strikethrough~~ starting at text[start].
+func renderEmphasis(text string, start int) (string, int) {
+	c := text[start]
+	run := runLength(text[start:], c)
+	width := 1
+	if run >= 2 {
+		width = 2
+	}
+	if c == '~' && width != 2 {
+		return "", 0
+	}
+	// Underscores inside words (snake_case) are not emphasis
+	if c == '_' && start > 0 && isAlphanumeric(text[start-1]) {
+		return "", 0
+	}
+	open := start + width
+	if open >= len(text) || isSpace(text[open]) {
+		return "", 0
+	}
+
+	delimiter := strings.Repeat(string(c), width)
+	for i := open + 1; i < len(text); i++ {
+		if text[i] == '`' {
+			// Skip over code spans, which can contain delimiters
+			if _, n := renderCodeSpan(text[i:]); n > 0 {
+				i += n - 1
+			}
+			continue
+		}
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(text[i:], delimiter) || isSpace(text[i-1]) {
+			continue
+		}
+		// A single delimiter must not be part of a longer run
+		closingRun := runLength(text[i:], c)
+		if width == 1 && closingRun != 1 {
+			i += closingRun - 1
+			continue
+		}
+		if c == '_' && i+width < len(text) && isAlphanumeric(text[i+width]) {
+			continue
+		}
+		inner := renderInline(text[open:i])
+		var tag string
+		switch {
+		case c == '~':
+			tag = "del"
+		case width == 2:
+			tag = "strong"
+		default:
+			tag = "em"
+		}
+		return fmt.Sprintf("<%s>%s</%s>", tag, inner, tag), i + width - start
+	}
+	return "", 0
+}
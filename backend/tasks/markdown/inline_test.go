@@ -0,0 +1,100 @@
+package markdown
+
+import "testing"
+
+func TestSafeURL(t *testing.T) {
+	for _, test := range []struct {
+		url  string
+		want string
+		ok   bool
+	}{
+		{"https://example.com/a?b=c", "https://example.com/a?b=c", true},
+		{"http://example.com", "http://example.com", true},
+		{"mailto:me@example.com", "mailto:me@example.com", true},
+		{"  https://example.com  ", "https://example.com", true},
+		// The scheme is matched in any case, and the URL is kept as written
+		{"HTTPS://Example.com/A", "HTTPS://Example.com/A", true},
+		{"MailTo:me@example.com", "MailTo:me@example.com", true},
+
+		{"javascript:alert(1)", "", false},
+		{"JaVaScRiPt:alert(1)", "", false},
+		{" javascript:alert(1)", "", false},
+		{"\x01javascript:alert(1)", "", false},
+		{"java&#115;cript:alert(1)", "", false},
+		{"vbscript:msgbox(1)", "", false},
+		{"data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==", "", false},
+		{"DATA:text/html,<script>alert(1)</script>", "", false},
+		{"file:///etc/passwd", "", false},
+		// Relative URLs would resolve against whichever page shows the note
+		{"/tasks/1", "", false},
+		{"//example.com", "", false},
+		{"example.com", "", false},
+		// A scheme alone is not a URL
+		{"https://", "", false},
+		{"mailto:", "", false},
+		{"", "", false},
+	} {
+		got, ok := safeURL(test.url)
+		if got != test.want || ok != test.ok {
+			t.Errorf("safeURL(%q) = %q, %v, want %q, %v", test.url, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestRenderInline(t *testing.T) {
+	const rel = ` rel="nofollow noopener noreferrer"`
+	for _, test := range []struct {
+		text string
+		want string
+	}{
+		{"*em* **strong** __strong__ ~~del~~", "<em>em</em> <strong>strong</strong> <strong>strong</strong> <del>del</del>"},
+		{"snake_case_name", "snake_case_name"},
+		{"**unclosed *em*", "**unclosed <em>em</em>"},
+		{`\*not em\*`, "*not em*"},
+		{"`a <b> & c`", "<code>a &lt;b&gt; &amp; c</code>"},
+		{"`` a ` b ``", "<code>a ` b</code>"},
+
+		// Links
+		{"[text](https://example.com)", `<a href="https://example.com"` + rel + `>text</a>`},
+		{"[**bold**](https://example.com)", `<a href="https://example.com"` + rel + `><strong>bold</strong></a>`},
+		{"[a [nested] link](https://example.com/a_(b))", `<a href="https://example.com/a_(b)"` + rel + `>a [nested] link</a>`},
+		{"[x](https://example.com/\\))", `<a href="https://example.com/)"` + rel + `>x</a>`},
+		{"<https://example.com/a?b=1&c=2>", `<a href="https://example.com/a?b=1&amp;c=2"` + rel + `>https://example.com/a?b=1&amp;c=2</a>`},
+		{"<mailto:me@example.com>", `<a href="mailto:me@example.com"` + rel + `>me@example.com</a>`},
+		{"see https://example.com/path_x?a=1.", `see <a href="https://example.com/path_x?a=1"` + rel + `>https://example.com/path_x?a=1</a>.`},
+		{"(https://example.com/a_(b))", `(<a href="https://example.com/a_(b)"` + rel + `>https://example.com/a_(b)</a>)`},
+
+		// Links to anything but http, https and mailto are their text only
+		{"[x](javascript:alert(1))", "x"},
+		{"[x](JaVaScRiPt:alert(1))", "x"},
+		{"[x]( data:text/html;base64,PHNjcmlwdD4= )", "x"},
+		{"[x](DATA:text/html,hi)", "x"},
+		{"[<b>x</b>](javascript:alert(1))", "&lt;b&gt;x&lt;/b&gt;"},
+		// Images are linked to rather than embedded
+		{"![img](javascript:alert(1))", "img"},
+		{"![img](https://example.com/a.png)", `<a href="https://example.com/a.png"` + rel + `>img</a>`},
+		{"[x](/relative)", "x"},
+		{"<javascript:alert(1)>", "&lt;javascript:alert(1)&gt;"},
+		{"<Data:text/html,hi>", "&lt;Data:text/html,hi&gt;"},
+
+		// Quotes can't break out of the attributes
+		{`[x](https://example.com "a\" onmouseover=\"alert(1)")`,
+			`<a href="https://example.com" title="a&#34; onmouseover=&#34;alert(1)"` + rel + `>x</a>`},
+		{`[x](https://example.com 'a" onmouseover="alert(1)')`,
+			`<a href="https://example.com" title="a&#34; onmouseover=&#34;alert(1)"` + rel + `>x</a>`},
+		{`[x](https://example.com "<script>&")`,
+			`<a href="https://example.com" title="&lt;script&gt;&amp;"` + rel + `>x</a>`},
+		{`[x](https://example.com/"onmouseover="alert(1))`,
+			`<a href="https://example.com/&#34;onmouseover=&#34;alert(1)"` + rel + `>x</a>`},
+		{`<https://example.com/?a="b">`,
+			`<a href="https://example.com/?a=&#34;b&#34;"` + rel + `>https://example.com/?a=&#34;b&#34;</a>`},
+		{`https://example.com/"><script>`,
+			`<a href="https://example.com/&#34;&gt;"` + rel + `>https://example.com/&#34;&gt;</a>&lt;script&gt;`},
+		// A title without quotes isn't a link
+		{"[x](https://example.com title)", "[x](<a href=\"https://example.com\"" + rel + ">https://example.com</a> title)"},
+	} {
+		if got := renderInline(test.text); got != test.want {
+			t.Errorf("renderInline(%q)\n got %s\nwant %s", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Package markdown renders the Markdown used in task notes to HTML.
+//
+// Rendering happens on the server so that the Android and web clients show
+// notes identically. Only a subset of CommonMark is supported: headings,
+// paragraphs, block quotes, fenced code, bullet, ordered and task lists,
+// horizontal rules, emphasis, strikethrough, code spans and links. Raw HTML is
+// always escaped, and links are only emitted for http, https and mailto URLs,
+// so the output is safe to insert into a page as-is.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Render converts Markdown to HTML.
+func Render(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	source = strings.ReplaceAll(source, "\t", "    ")
+	return renderBlocks(strings.Split(source, "\n"), false)
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	hrPattern       = regexp.MustCompile(`^ {0,3}(?:(?:-[ ]*){3,}|(?:\*[ ]*){3,}|(?:_[ ]*){3,})$`)
+	fencePattern    = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ ]*([^`\\s]*)")
+	quotePattern    = regexp.MustCompile(`^ {0,3}>[ ]?`)
+	listItemPattern = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])(?:([ ]+)(.*))?$`)
+	taskItemPattern = regexp.MustCompile(`^\[([ xX])\](?:[ ]+|$)`)
+)
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// startsBlock reports whether a line starts a block that interrupts a
+// paragraph.
+func startsBlock(line string) bool {
+	return headingPattern.MatchString(line) || hrPattern.MatchString(line) ||
+		fencePattern.MatchString(line) || quotePattern.MatchString(line) ||
+		listItemPattern.MatchString(line)
+}
+
+// renderBlocks renders a sequence of lines as block elements. In a tight list
+// item, paragraphs are rendered without <p> tags.
+func renderBlocks(lines []string, tight bool) string {
+	var out []string
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+
+		case fencePattern.MatchString(line):
+			var block string
+			block, i = renderFence(lines, i)
+			out = append(out, block)
+
+		case headingPattern.MatchString(line):
+			match := headingPattern.FindStringSubmatch(line)
+			level := len(match[1])
+			out = append(out, fmt.Sprintf("<h%d>%s</h%d>", level, renderInline(match[2]), level))
+			i++
+
+		case hrPattern.MatchString(line):
+			out = append(out, "<hr>")
+			i++
+
+		case quotePattern.MatchString(line):
+			var quoted []string
+			for i < len(lines) && quotePattern.MatchString(lines[i]) {
+				quoted = append(quoted, quotePattern.ReplaceAllString(lines[i], ""))
+				i++
+			}
+			out = append(out, "<blockquote>\n"+renderBlocks(quoted, false)+"\n</blockquote>")
+
+		case listItemPattern.MatchString(line):
+			var block string
+			block, i = renderList(lines, i)
+			out = append(out, block)
+
+		default:
+			var paragraph []string
+			for i < len(lines) && !isBlank(lines[i]) && (len(paragraph) == 0 || !startsBlock(lines[i])) {
+				paragraph = append(paragraph, lines[i])
+				i++
+			}
+			text := renderParagraph(paragraph)
+			if !tight {
+				text = "<p>" + text + "</p>"
+			}
+			out = append(out, text)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func renderFence(lines []string, start int) (string, int) {
+	match := fencePattern.FindStringSubmatch(lines[start])
+	indent, fence, info := len(match[1]), match[2], match[3]
+	var code []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if len(lines[i])-len(trimmed) <= 3 && strings.HasPrefix(trimmed, fence) &&
+			strings.Trim(trimmed, fence[:1]+" ") == "" {
+			i++
+			break
+		}
+		// Remove the fence's indentation from the content
+		line := lines[i]
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		code = append(code, line)
+	}
+	class := ""
+	if info != "" {
+		class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(info))
+	}
+	content := html.EscapeString(strings.Join(code, "\n"))
+	if len(code) > 0 {
+		content += "\n"
+	}
+	return fmt.Sprintf("<pre><code%s>%s</code></pre>", class, content), i
+}
+
+// renderParagraph joins the lines of a paragraph, turning a trailing backslash
+// or two trailing spaces into a hard line break.
+func renderParagraph(lines []string) string {
+	var b strings.Builder
+	for n, line := range lines {
+		line = strings.TrimLeft(line, " ")
+		last := n == len(lines)-1
+		switch {
+		case !last && strings.HasSuffix(line, "\\"):
+			b.WriteString(renderInline(strings.TrimSuffix(line, "\\")))
+			b.WriteString("<br>\n")
+		case !last && strings.HasSuffix(line, "  "):
+			b.WriteString(renderInline(strings.TrimRight(line, " ")))
+			b.WriteString("<br>\n")
+		default:
+			b.WriteString(renderInline(strings.TrimRight(line, " ")))
+			if !last {
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+type listItem struct {
+	lines []string
+	// Whether the item ended with a blank line
+	blankAfter bool
+}
+
+func isOrderedMarker(marker string) bool {
+	return marker[0] >= '0' && marker[0] <= '9'
+}
+
+// renderList renders the list starting at lines[start] and returns the index
+// of the first line after it. An item's content is every following line that
+// is indented at least as far as the item's text.
+func renderList(lines []string, start int) (string, int) {
+	first := listItemPattern.FindStringSubmatch(lines[start])
+	ordered := isOrderedMarker(first[2])
+	delimiter := first[2][len(first[2])-1]
+
+	sameList := func(match []string) bool {
+		return match != nil && isOrderedMarker(match[2]) == ordered && match[2][len(match[2])-1] == delimiter
+	}
+
+	var items []listItem
+	loose := false
+	i := start
+	for i < len(lines) {
+		match := listItemPattern.FindStringSubmatch(lines[i])
+		if !sameList(match) {
+			break
+		}
+		if len(items) > 0 && items[len(items)-1].blankAfter {
+			loose = true
+		}
+		// Text indented by more than four spaces after the marker is treated
+		// as if it were indented by one space
+		spacing := len(match[3])
+		if spacing > 4 || spacing == 0 {
+			spacing = 1
+		}
+		contentIndent := len(match[1]) + len(match[2]) + spacing
+		item := listItem{lines: []string{match[4]}}
+		i++
+
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				// A blank line continues the item only if the next line is
+				// indented as part of it
+				next := i + 1
+				for next < len(lines) && isBlank(lines[next]) {
+					next++
+				}
+				if next < len(lines) && indentation(lines[next]) >= contentIndent {
+					for ; i < next; i++ {
+						item.lines = append(item.lines, "")
+					}
+					loose = true
+					continue
+				}
+				item.blankAfter = true
+				i = next
+				break
+			}
+			if indentation(line) >= contentIndent {
+				item.lines = append(item.lines, line[contentIndent:])
+				i++
+				continue
+			}
+			// Lazy continuation of the item's paragraph
+			if !startsBlock(line) && !isBlank(item.lines[len(item.lines)-1]) {
+				item.lines = append(item.lines, strings.TrimLeft(line, " "))
+				i++
+				continue
+			}
+			break
+		}
+		items = append(items, item)
+	}
+
+	var b strings.Builder
+	switch {
+	case !ordered:
+		b.WriteString("<ul>\n")
+	default:
+		number, _ := strconv.Atoi(first[2][:len(first[2])-1])
+		if number == 1 {
+			b.WriteString("<ol>\n")
+		} else {
+			fmt.Fprintf(&b, "<ol start=\"%d\">\n", number)
+		}
+	}
+	for _, item := range items {
+		content := item.lines
+		checkbox := ""
+		if match := taskItemPattern.FindStringSubmatch(content[0]); match != nil {
+			checked := ""
+			if match[1] != " " {
+				checked = " checked"
+			}
+			checkbox = fmt.Sprintf(`<input type="checkbox" disabled%s> `, checked)
+			content = append([]string{content[0][len(match[0]):]}, content[1:]...)
+		}
+		if checkbox != "" {
+			b.WriteString(`<li class="task-list-item">`)
+		} else {
+			b.WriteString("<li>")
+		}
+		b.WriteString(checkbox)
+		body := renderBlocks(content, !loose)
+		if loose || strings.Contains(body, "\n") {
+			b.WriteString("\n" + body + "\n")
+		} else {
+			b.WriteString(body)
+		}
+		b.WriteString("</li>\n")
+	}
+	if ordered {
+		b.WriteString("</ol>")
+	} else {
+		b.WriteString("</ul>")
+	}
+	return b.String(), i
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
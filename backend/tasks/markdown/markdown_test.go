@@ -0,0 +1,48 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		want   string
+	}{
+		{"", ""},
+		{"# Title #\n\nSome *text*.", "<h1>Title</h1>\n<p>Some <em>text</em>.</p>"},
+		{"#hashtag", "<p>#hashtag</p>"},
+		{"Line one  \nline two\\\nline three\nline four", "<p>Line one<br>\nline two<br>\nline three\nline four</p>"},
+		{"- a\n- b\n  - nested\n- [x] done\n- [ ] todo", "<ul>\n<li>a</li>\n<li>\nb\n<ul>\n<li>nested</li>\n</ul>\n</li>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled checked> done</li>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled> todo</li>\n</ul>"},
+		{"3. three\n4. four", "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>"},
+		{"> quote\n\nafter", "<blockquote>\n<p>quote</p>\n</blockquote>\n<p>after</p>"},
+		{"***", "<hr>"},
+		{"```go\nfunc main() {\n}\n```", "<pre><code class=\"language-go\">func main() {\n}\n</code></pre>"},
+		{"a\r\nb", "<p>a\nb</p>"},
+	} {
+		if got := Render(test.source); got != test.want {
+			t.Errorf("Render(%q)\n got %q\nwant %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestRenderEscapesRawHTML(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		want   string
+	}{
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{`<b onclick="x">hi</b> & more`, "<p>&lt;b onclick=&#34;x&#34;&gt;hi&lt;/b&gt; &amp; more</p>"},
+		{"<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"<!-- comment -->", "<p>&lt;!-- comment --&gt;</p>"},
+		{"<div>\nblock\n</div>", "<p>&lt;div&gt;\nblock\n&lt;/div&gt;</p>"},
+		{"# <i>heading</i>", "<h1>&lt;i&gt;heading&lt;/i&gt;</h1>"},
+		{"- <b>item</b>", "<ul>\n<li>&lt;b&gt;item&lt;/b&gt;</li>\n</ul>"},
+		{"> <q>", "<blockquote>\n<p>&lt;q&gt;</p>\n</blockquote>"},
+		{"```\n<script>alert(1)</script>\n```", "<pre><code>&lt;script&gt;alert(1)&lt;/script&gt;\n</code></pre>"},
+		{"```\"><script>\nx\n```", "<pre><code class=\"language-&#34;&gt;&lt;script&gt;\">x\n</code></pre>"},
+		{"~~~\nunterminated <b>", "<pre><code>unterminated &lt;b&gt;\n</code></pre>"},
+	} {
+		if got := Render(test.source); got != test.want {
+			t.Errorf("Render(%q)\n got %q\nwant %q", test.source, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package notify delivers task reminders to the user.
+//
+// The reminder scheduler doesn't know how reminders are delivered; it hands
+// each one to a Notifier, which is picked when the server starts.
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reminder is a reminder that is due to be sent.
+type Reminder struct {
+	TaskId int
+	Title  string
+	// The task's due date, if it has one
+	DueDate *time.Time
+	// The time the reminder was set for
+	FireAt time.Time
+}
+
+// Notifier delivers reminders. Notify returns an error if a reminder could not
+// be delivered, in which case it is tried again later.
+type Notifier interface {
+	Notify(reminder Reminder) error
+}
+
+// Subject returns a one-line summary of the reminder.
+func (r Reminder) Subject() string {
+	return fmt.Sprintf("Reminder: %s", r.Title)
+}
+
+// Body returns the text of the reminder.
+func (r Reminder) Body() string {
+	if r.DueDate == nil {
+		return fmt.Sprintf("%s\n\nTask #%d\n", r.Title, r.TaskId)
+	}
+	return fmt.Sprintf("%s\n\nTask #%d is due %s\n", r.Title, r.TaskId, r.DueDate.Format(time.RFC1123))
+}
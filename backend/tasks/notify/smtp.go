@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier sends each reminder as an email. The connection is upgraded
+// with STARTTLS when the server supports it; credentials are only sent over
+// TLS, or to a server on localhost.
+type SMTPNotifier struct {
+	// Address of the SMTP server, as host:port
+	Addr string
+	From string
+	To   []string
+	// Username and Password are optional, for servers that need PLAIN auth
+	Username string
+	Password string
+}
+
+func (n *SMTPNotifier) Notify(reminder Reminder) error {
+	from, err := mail.ParseAddress(n.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", n.From, err)
+	}
+	to := make([]*mail.Address, 0, len(n.To))
+	for _, recipient := range n.To {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+		to = append(to, address)
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, err := net.SplitHostPort(n.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+	envelopeTo := make([]string, 0, len(to))
+	for _, address := range to {
+		envelopeTo = append(envelopeTo, address.Address)
+	}
+	return smtp.SendMail(n.Addr, auth, from.Address, envelopeTo, message(reminder, from, to, time.Now()))
+}
+
+// message builds the email for a reminder. Header values go through
+// mail.Address and MIME word encoding, so a task title can't add headers.
+func message(reminder Reminder, from *mail.Address, to []*mail.Address, now time.Time) []byte {
+	recipients := make([]string, 0, len(to))
+	for _, address := range to {
+		recipients = append(recipients, address.String())
+	}
+	subject := strings.Join(strings.Fields(reminder.Subject()), " ")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(reminder.Body(), "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what a fakeSMTPServer received from one client.
+type smtpSession struct {
+	Auth     string
+	MailFrom string
+	RcptTo   []string
+	Data     string
+}
+
+// fakeSMTPServer accepts a single connection on a local port and plays the
+// server side of an SMTP session, without STARTTLS. The session is sent on the
+// returned channel when the client quits.
+func fakeSMTPServer(t *testing.T) (string, <-chan smtpSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		r := bufio.NewReader(conn)
+		reply := func(lines ...string) {
+			for _, line := range lines {
+				conn.Write([]byte(line + "\r\n"))
+			}
+		}
+
+		var session smtpSession
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250-fake", "250 AUTH PLAIN")
+			case strings.HasPrefix(command, "AUTH PLAIN "):
+				session.Auth = line[len("AUTH PLAIN "):]
+				reply("235 Authenticated")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				session.MailFrom = line[len("MAIL FROM:"):]
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				session.RcptTo = append(session.RcptTo, line[len("RCPT TO:"):])
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					// Undo the client's dot-stuffing
+					data.WriteString(strings.TrimPrefix(line, "."))
+				}
+				session.Data = data.String()
+				reply("250 Queued")
+			case command == "QUIT":
+				reply("221 Bye")
+				sessions <- session
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return listener.Addr().String(), sessions
+}
+
+func receiveSession(t *testing.T, sessions <-chan smtpSession) smtpSession {
+	t.Helper()
+	select {
+	case session := <-sessions:
+		return session
+	case <-time.After(10 * time.Second):
+		t.Fatal("the SMTP session did not finish")
+		return smtpSession{}
+	}
+}
+
+func TestSMTPNotifierSendsReminder(t *testing.T) {
+	addr, sessions := fakeSMTPServer(t)
+	notifier := &SMTPNotifier{
+		Addr: addr,
+		From: "Tasks <tasks@example.com>",
+		To:   []string{"me@example.com", "Someone Else <else@example.com>"},
+	}
+	due := time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC)
+	err := notifier.Notify(Reminder{TaskId: 7, Title: "Pay the rent", DueDate: &due, FireAt: due.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := receiveSession(t, sessions)
+
+	if session.Auth != "" {
+		t.Errorf("authenticated without credentials: %q", session.Auth)
+	}
+	if session.MailFrom != "<tasks@example.com>" {
+		t.Errorf("MAIL FROM %s, want <tasks@example.com>", session.MailFrom)
+	}
+	if got := strings.Join(session.RcptTo, " "); got != "<me@example.com> <else@example.com>" {
+		t.Errorf("RCPT TO %s, want <me@example.com> <else@example.com>", got)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(session.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for header, want := range map[string]string{
+		"From":         `"Tasks" <tasks@example.com>`,
+		"To":           `<me@example.com>, "Someone Else" <else@example.com>`,
+		"Subject":      "Reminder: Pay the rent",
+		"Content-Type": "text/plain; charset=utf-8",
+	} {
+		if got := msg.Header.Get(header); got != want {
+			t.Errorf("%s: %q, want %q", header, got, want)
+		}
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+	body := session.Data[strings.Index(session.Data, "\r\n\r\n")+4:]
+	want := "Pay the rent\r\n\r\nTask #7 is due Fri, 02 Jan 2026 15:04:00 UTC\r\n"
+	if body != want {
+		t.Errorf("body %q, want %q", body, want)
+	}
+}
+
+func TestSMTPNotifierKeepsTitleOutOfHeaders(t *testing.T) {
+	addr, sessions := fakeSMTPServer(t)
+	notifier := &SMTPNotifier{Addr: addr, From: "tasks@example.com", To: []string{"me@example.com"}}
+	title := "Grüße\r\nBcc: someone@example.com\n.\nend"
+	if err := notifier.Notify(Reminder{TaskId: 1, Title: title}); err != nil {
+		t.Fatal(err)
+	}
+	session := receiveSession(t, sessions)
+
+	if len(session.RcptTo) != 1 {
+		t.Errorf("RCPT TO %v, want only the configured recipient", session.RcptTo)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(session.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("title added a Bcc header: %q", bcc)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Reminder: Grüße Bcc: someone@example.com . end"; subject != want {
+		t.Errorf("Subject %q, want %q", subject, want)
+	}
+	// The lone dot in the title doesn't end the message early
+	if !strings.HasSuffix(session.Data, "\r\n.\r\nend\r\n\r\nTask #1\r\n") {
+		t.Errorf("body was cut short: %q", session.Data)
+	}
+}
+
+func TestSMTPNotifierAuthenticates(t *testing.T) {
+	addr, sessions := fakeSMTPServer(t)
+	notifier := &SMTPNotifier{
+		Addr:     addr,
+		From:     "tasks@example.com",
+		To:       []string{"me@example.com"},
+		Username: "tasks",
+		Password: "secret",
+	}
+	if err := notifier.Notify(Reminder{TaskId: 1, Title: "Call back"}); err != nil {
+		t.Fatal(err)
+	}
+	session := receiveSession(t, sessions)
+
+	// Plain authentication without TLS is only allowed to localhost
+	credentials, err := base64.StdEncoding.DecodeString(session.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(credentials) != "\x00tasks\x00secret" {
+		t.Errorf("credentials %q", credentials)
+	}
+}
+
+func TestSMTPNotifierRejectsInvalidAddresses(t *testing.T) {
+	for _, notifier := range []*SMTPNotifier{
+		{Addr: "127.0.0.1:1", From: "not an address", To: []string{"me@example.com"}},
+		{Addr: "127.0.0.1:1", From: "tasks@example.com", To: []string{"me@example.com\r\nBcc: x@example.com"}},
+	} {
+		if err := notifier.Notify(Reminder{TaskId: 1, Title: "x"}); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("From %q To %q: got %v, want an invalid address error", notifier.From, notifier.To, err)
+		}
+	}
+}
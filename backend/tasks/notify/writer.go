@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriterNotifier writes each reminder as a line of text, e.g. to stdout.
+type WriterNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterNotifier(w io.Writer) *WriterNotifier {
+	return &WriterNotifier{w: w}
+}
+
+func (n *WriterNotifier) Notify(reminder Reminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := io.WriteString(n.w, formatLine(reminder))
+	return err
+}
+
+// FileNotifier appends each reminder as a line of text to a file. The file is
+// opened for every reminder, so it can be rotated while the server is running.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Notify(reminder Reminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, formatLine(reminder))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// formatLine formats a reminder as a single line; line breaks in the title
+// would otherwise start a new entry.
+func formatLine(reminder Reminder) string {
+	title := strings.Join(strings.Fields(reminder.Title), " ")
+	line := fmt.Sprintf("%s reminder task=%d title=%q", reminder.FireAt.Format(time.RFC3339), reminder.TaskId, title)
+	if reminder.DueDate != nil {
+		line += fmt.Sprintf(" due=%s", reminder.DueDate.Format(time.RFC3339))
+	}
+	return line + "\n"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/tomyedwab/yesterday/applib/database"
+)
+
+// The CalDAV server holds creationMu from checking a resource's ETag until the
+// change is published, so that two clients can't both change the version they
+// last saw.
+var creationMu sync.Mutex
+
+// publishEvent publishes an event on behalf of a client that can't publish
+// events itself, such as a CalDAV client. As with /api/publish, the event is
+// stored in the event log and applied to the projections in one transaction,
+// and an error from a handler rejects it. It returns the ID of the event in the
+// event log.
+func publishEvent(db *database.Database, eventType string, event interface{}) (int, error) {
+	// Stored events are flat objects with the event's fields alongside its
+	// type and timestamp
+	fields, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	var eventData map[string]interface{}
+	if err = json.Unmarshal(fields, &eventData); err != nil {
+		return 0, err
+	}
+	eventData["type"] = eventType
+	eventData["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	buf, err := json.Marshal(eventData)
+	if err != nil {
+		return 0, err
+	}
+	return db.CreateEvent(buf, newServerClientId())
+}
+
+// newServerClientId returns a client ID for an event published by the server.
+// Client IDs deduplicate retried events, so each event gets a new one.
+func newServerClientId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "server-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/notify"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// How often the scheduler checks for reminders that are due
+const reminderInterval = time.Minute
+
+// newNotifierFromEnv picks how reminders are delivered:
+//
+//	TASKS_REMINDER_NOTIFIER  stdout (default), file, smtp or none
+//	TASKS_REMINDER_FILE      file to append reminders to, for "file"
+//	TASKS_SMTP_ADDR          host:port of the SMTP server, for "smtp"
+//	TASKS_SMTP_FROM          sender address
+//	TASKS_SMTP_TO            comma-separated recipient addresses
+//	TASKS_SMTP_USERNAME      optional credentials
+//	TASKS_SMTP_PASSWORD
+//
+// It returns nil if reminders are turned off.
+func newNotifierFromEnv() (notify.Notifier, error) {
+	switch kind := os.Getenv("TASKS_REMINDER_NOTIFIER"); kind {
+	case "", "stdout":
+		return notify.NewWriterNotifier(os.Stdout), nil
+	case "file":
+		path := os.Getenv("TASKS_REMINDER_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("TASKS_REMINDER_FILE must be set for the file notifier")
+		}
+		return notify.NewFileNotifier(path), nil
+	case "smtp":
+		notifier := &notify.SMTPNotifier{
+			Addr:     os.Getenv("TASKS_SMTP_ADDR"),
+			From:     os.Getenv("TASKS_SMTP_FROM"),
+			Username: os.Getenv("TASKS_SMTP_USERNAME"),
+			Password: os.Getenv("TASKS_SMTP_PASSWORD"),
+		}
+		for _, recipient := range strings.Split(os.Getenv("TASKS_SMTP_TO"), ",") {
+			if recipient = strings.TrimSpace(recipient); recipient != "" {
+				notifier.To = append(notifier.To, recipient)
+			}
+		}
+		if notifier.Addr == "" || notifier.From == "" || len(notifier.To) == 0 {
+			return nil, fmt.Errorf("TASKS_SMTP_ADDR, TASKS_SMTP_FROM and TASKS_SMTP_TO must be set for the smtp notifier")
+		}
+		return notifier, nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown reminder notifier %q", kind)
+	}
+}
+
+// startReminderScheduler sends due reminders in the background until the
+// process exits.
+func startReminderScheduler(db *sqlx.DB, notifier notify.Notifier) {
+	go func() {
+		ticker := time.NewTicker(reminderInterval)
+		defer ticker.Stop()
+		for {
+			if err := sendDueReminders(db, notifier, time.Now()); err != nil {
+				log.Printf("Sending reminders failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// sendDueReminders sends every reminder whose time has come and that hasn't
+// been sent yet. A reminder that fails to send is tried again on the next run.
+func sendDueReminders(db *sqlx.DB, notifier notify.Notifier, now time.Time) error {
+	reminders, err := state.GetDueReminders(db, now)
+	if err != nil {
+		return err
+	}
+	for _, reminder := range reminders {
+		claimed, err := state.ClaimReminder(db, reminder)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		err = notifier.Notify(notify.Reminder{
+			TaskId:  reminder.TaskId,
+			Title:   reminder.Title,
+			DueDate: reminder.DueDate,
+			FireAt:  reminder.FireAt,
+		})
+		if err != nil {
+			log.Printf("Sending reminder %d for task %d failed: %v", reminder.ReminderId, reminder.TaskId, err)
+			if err = state.ReleaseReminder(db, reminder); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
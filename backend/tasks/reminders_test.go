@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/notify"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// recordingNotifier records the reminders it is given, and fails while err
+// is set.
+type recordingNotifier struct {
+	err  error
+	sent []notify.Reminder
+}
+
+func (n *recordingNotifier) Notify(reminder notify.Reminder) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, reminder)
+	return nil
+}
+
+func TestSendDueRemindersRetriesFailedReminders(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	tx := db.MustBegin()
+	// A minimal event log, in place of the one owned by the framework
+	tx.MustExec("CREATE TABLE event_v1 (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, event_data BLOB NOT NULL)")
+	if err = state.InitProjections(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = state.RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &state.StateEventHandler{}
+	remindAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	// Each event is logged before it is applied, as the framework does
+	logEvent := func() { tx.MustExec("INSERT INTO event_v1 (event_data) VALUES ('{}')") }
+	tx = db.MustBegin()
+	logEvent()
+	if _, err = h.HandleTaskListAddEvent(tx, &generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList}); err != nil {
+		t.Fatal(err)
+	}
+	logEvent()
+	if _, err = h.HandleTaskAddEvent(tx, &generated.TaskAddEvent{Title: "Call the bank", TaskListId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	logEvent()
+	if _, err = h.HandleTaskAddReminderEvent(tx, &generated.TaskAddReminderEvent{TaskId: 1, RemindAt: &remindAt}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := &recordingNotifier{err: errors.New("connection refused")}
+	if err = sendDueReminders(db, notifier, remindAt); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 0 {
+		t.Fatalf("sent %d reminders while the notifier was failing", len(notifier.sent))
+	}
+
+	// The failed reminder was released, so the next run sends it, once
+	notifier.err = nil
+	for i := 0; i < 2; i++ {
+		if err = sendDueReminders(db, notifier, remindAt.Add(time.Duration(i+1)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].TaskId != 1 || notifier.sent[0].Title != "Call the bank" {
+		t.Fatalf("sent %+v, want the reminder for task 1 once", notifier.sent)
+	}
+}
@@ -0,0 +1,299 @@
+package state
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/ical"
+)
+
+// CalDAV clients see every to-do list as a calendar of VTODO resources, one
+// per task. A task's resource is named task-<id>.ics, with the same UID as in
+// the calendar feeds, unless it was created by a CalDAV client: clients pick
+// the name and UID of the resources they create, and expect to find them
+// again. The Task:UpdateFromCalDAV event that creates such a task records them
+// in caldav_resource_v1.
+
+// Table schema
+const caldavResourceSchema = `
+CREATE TABLE IF NOT EXISTS caldav_resource_v1 (
+    task_id INTEGER PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    uid TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS caldav_resource_name_v1 ON caldav_resource_v1 (name);
+`
+
+func InitCalDAVResource(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing CalDAVResource v1\n")
+	_, err := tx.Exec(caldavResourceSchema)
+	return err
+}
+
+const insertCalDAVResourceV1Sql = `
+INSERT INTO caldav_resource_v1 (task_id, name, uid)
+VALUES ($1, $2, $3);
+`
+
+const getCalDAVCalendarsV1Sql = `
+SELECT id, title, category, archived FROM task_list_v1
+WHERE category = 'toDoList' AND archived = false AND id NOT IN (SELECT list_id FROM task_list_trash_v1)
+ORDER BY sort_key;
+`
+
+const getCalDAVCalendarV1Sql = `
+SELECT id, title, category, archived FROM task_list_v1
+WHERE id = $1 AND category = 'toDoList' AND archived = false AND id NOT IN (SELECT list_id FROM task_list_trash_v1);
+`
+
+// Tasks are stamped with the time of their latest change
+const getCalDAVTasksV1Sql = `
+SELECT t.id, t.title, t.notes, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat, t.priority,
+    COALESCE(c.name, '') AS resourcename, COALESCE(c.uid, '') AS resourceuid,
+    (SELECT MAX(h.created_at) FROM task_history_v1 h WHERE h.task_id = t.id) AS modifiedat
+FROM task_v1 t
+JOIN task_to_list_v1 ttl ON ttl.task_id = t.id
+LEFT JOIN caldav_resource_v1 c ON c.task_id = t.id
+WHERE ttl.list_id = $1 AND ($2 = 0 OR t.id = $2)
+ORDER BY ttl.sort_key;
+`
+
+const getCalDAVResourceTaskIdsV1Sql = `
+SELECT task_id FROM caldav_resource_v1 WHERE name = $1;
+`
+
+// CalDAVObject is a task as a CalDAV resource.
+type CalDAVObject struct {
+	Name string
+	Uid  string
+	Task generated.Task
+	ETag string
+	// The resource's iCalendar data
+	Data []byte
+}
+
+type caldavTaskRow struct {
+	generated.Task
+	ResourceName string `db:"resourcename"`
+	ResourceUid  string `db:"resourceuid"`
+	// MAX() loses the column type, so this is the timestamp as text
+	ModifiedAt *string `db:"modifiedat"`
+}
+
+func (row caldavTaskRow) object() (CalDAVObject, error) {
+	object := CalDAVObject{
+		Name: row.ResourceName,
+		Uid:  row.ResourceUid,
+		Task: row.Task,
+	}
+	if object.Name == "" {
+		object.Name = fmt.Sprintf("task-%d.ics", row.Id)
+		object.Uid = DefaultTaskUid(row.Id)
+	}
+	stamp := time.Unix(0, 0)
+	if row.ModifiedAt != nil {
+		// Older rows have CURRENT_TIMESTAMP's format, newer ones the driver's
+		for _, format := range sqlite3.SQLiteTimestampFormats {
+			if modifiedAt, err := time.ParseInLocation(format, *row.ModifiedAt, time.UTC); err == nil {
+				stamp = modifiedAt
+				break
+			}
+		}
+	}
+	calendar := ical.Component{Name: "VCALENDAR"}
+	calendar.Add("VERSION", "2.0")
+	calendar.Add("PRODID", "-//Yellowstone//Tasks//EN")
+	calendar.Components = append(calendar.Components, TaskToDo(row.Task, object.Uid, stamp))
+	var b bytes.Buffer
+	if err := ical.Encode(&b, calendar); err != nil {
+		return object, err
+	}
+	object.Data = b.Bytes()
+	// The data only changes when the task does, so its hash is the ETag
+	sum := sha256.Sum256(object.Data)
+	object.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	return object, nil
+}
+
+// GetCalDAVCalendars returns the to-do lists that CalDAV clients see as
+// calendars.
+func GetCalDAVCalendars(db *sqlx.DB) ([]generated.TaskList, error) {
+	lists := make([]generated.TaskList, 0)
+	err := db.Select(&lists, getCalDAVCalendarsV1Sql)
+	return lists, err
+}
+
+// GetCalDAVCalendar returns a to-do list by ID, or false if it isn't visible
+// to CalDAV clients.
+func GetCalDAVCalendar(db *sqlx.DB, listId int) (generated.TaskList, bool, error) {
+	var list generated.TaskList
+	err := db.Get(&list, getCalDAVCalendarV1Sql, listId)
+	if err == sql.ErrNoRows {
+		return list, false, nil
+	}
+	return list, err == nil, err
+}
+
+func getCalDAVObjects(db *sqlx.DB, listId, taskId int) ([]CalDAVObject, error) {
+	var rows []caldavTaskRow
+	err := db.Select(&rows, getCalDAVTasksV1Sql, listId, taskId)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]CalDAVObject, 0, len(rows))
+	for _, row := range rows {
+		object, err := row.object()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// GetCalDAVObjects returns the resources of every task in a list.
+func GetCalDAVObjects(db *sqlx.DB, listId int) ([]CalDAVObject, error) {
+	return getCalDAVObjects(db, listId, 0)
+}
+
+// GetCalDAVObject returns a task's resource by name, or false if there is no
+// such task in the list.
+func GetCalDAVObject(db *sqlx.DB, listId int, name string) (CalDAVObject, bool, error) {
+	// Names picked by clients are only unique within a list
+	var taskIds []int
+	err := db.Select(&taskIds, getCalDAVResourceTaskIdsV1Sql, name)
+	if err != nil {
+		return CalDAVObject{}, false, err
+	}
+	if taskId, ok := defaultResourceTaskId(name); ok {
+		taskIds = append(taskIds, taskId)
+	}
+	for _, taskId := range taskIds {
+		objects, err := getCalDAVObjects(db, listId, taskId)
+		if err != nil {
+			return CalDAVObject{}, false, err
+		}
+		if len(objects) > 0 && objects[0].Name == name {
+			return objects[0], true, nil
+		}
+	}
+	return CalDAVObject{}, false, nil
+}
+
+// defaultResourceTaskId returns the task ID in a name like task-<id>.ics.
+func defaultResourceTaskId(name string) (int, bool) {
+	id, ok := strings.CutPrefix(name, "task-")
+	if !ok {
+		return 0, false
+	}
+	id, ok = strings.CutSuffix(id, ".ics")
+	if !ok {
+		return 0, false
+	}
+	taskId, err := strconv.Atoi(id)
+	if err != nil || fmt.Sprintf("task-%d.ics", taskId) != name {
+		return 0, false
+	}
+	return taskId, true
+}
+
+func (h *StateEventHandler) HandleTaskUpdateFromCalDAVEvent(tx *sqlx.Tx, event *generated.TaskUpdateFromCalDAVEvent) (bool, error) {
+	fmt.Printf("CalDAVResource v1: UpdateFromCalDAVEvent %s in list %d\n", event.ResourceName, event.TaskListId)
+	// The change is made with the same handlers as the individual events, and
+	// undone all at once
+	var inverse []undoEvent
+	h.combinedUndo = &inverse
+	err := h.updateFromCalDAV(tx, event)
+	h.combinedUndo = nil
+	if err != nil {
+		return true, err
+	}
+	return true, h.recordUndo(tx, "Task:UpdateFromCalDAV", event.Timestamp, inverse...)
+}
+
+func (h *StateEventHandler) updateFromCalDAV(tx *sqlx.Tx, event *generated.TaskUpdateFromCalDAVEvent) error {
+	var taskId int
+	if event.TaskId != nil {
+		taskId = *event.TaskId
+	} else {
+		var err error
+		taskId, err = h.addTask(tx, &generated.TaskAddEvent{
+			TaskListId: event.TaskListId,
+			Title:      event.Title,
+			DueDate:    event.DueDate,
+			Timestamp:  event.Timestamp,
+		})
+		if err != nil {
+			return err
+		}
+		if event.ResourceName != fmt.Sprintf("task-%d.ics", taskId) || event.ResourceUid != DefaultTaskUid(taskId) {
+			_, err = tx.Exec(insertCalDAVResourceV1Sql, taskId, event.ResourceName, event.ResourceUid)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	task, err := getTaskForUndo(tx, taskId)
+	if err != nil {
+		return err
+	}
+	if event.Title != task.Title {
+		_, err = h.HandleTaskUpdateTitleEvent(tx, &generated.TaskUpdateTitleEvent{TaskId: taskId, Title: event.Title, Timestamp: event.Timestamp})
+		if err != nil {
+			return err
+		}
+	}
+	if event.Notes != task.Notes {
+		_, err = h.HandleTaskUpdateNotesEvent(tx, &generated.TaskUpdateNotesEvent{TaskId: taskId, Notes: event.Notes, Timestamp: event.Timestamp})
+		if err != nil {
+			return err
+		}
+	}
+	if !sameSecond(event.DueDate, task.DueDate) {
+		_, err = h.HandleTaskUpdateDueDateEvent(tx, &generated.TaskUpdateDueDateEvent{TaskId: taskId, DueDate: event.DueDate, Timestamp: event.Timestamp})
+		if err != nil {
+			return err
+		}
+	}
+	if !samePriority(event.Priority, task.Priority) {
+		_, err = h.HandleTaskUpdatePriorityEvent(tx, &generated.TaskUpdatePriorityEvent{TaskId: taskId, Priority: event.Priority, Timestamp: event.Timestamp})
+		if err != nil {
+			return err
+		}
+	}
+	// Only completing or reopening the task counts; the completion time
+	// clients send back is rounded to the second
+	if (event.CompletedAt == nil) != (task.CompletedAt == nil) {
+		_, err = h.HandleTaskUpdateCompletedEvent(tx, &generated.TaskUpdateCompletedEvent{TaskId: taskId, CompletedAt: event.CompletedAt, Timestamp: event.Timestamp})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Times in iCalendar data have no fractional seconds
+func sameSecond(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+func samePriority(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
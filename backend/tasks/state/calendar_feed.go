@@ -0,0 +1,302 @@
+package state
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/ical"
+)
+
+// Calendar feeds publish the tasks with a due date as iCalendar to-dos (and
+// optionally events), for calendar apps to subscribe to. Calendar apps can't
+// sign in, so each feed URL carries a secret token instead.
+//
+// Tokens are derived from the feed ID and a per-server key, rather than stored
+// in the event log, so that they never leave the server except through
+// /api/calendarfeed/list. The key lives in calendar_feed_key_v1, which is not a
+// projection and survives rebuilds; resetting a feed's token bumps its
+// generation, which changes the token.
+//
+// Feed changes are not undoable: undoing a token reset or a deletion would
+// bring back a URL that was revoked on purpose.
+
+// Table schema
+const calendarFeedSchema = `
+CREATE TABLE IF NOT EXISTS calendar_feed_v1 (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    title TEXT NOT NULL,
+    list_id INTEGER,
+    label_id INTEGER,
+    include_events BOOLEAN NOT NULL,
+    token_generation INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (list_id) REFERENCES task_list_v1(id),
+    FOREIGN KEY (label_id) REFERENCES task_list_v1(id)
+);
+
+CREATE TABLE IF NOT EXISTS calendar_feed_key_v1 (
+    id INTEGER PRIMARY KEY NOT NULL,
+    key TEXT NOT NULL
+);
+`
+
+const insertCalendarFeedKeyV1Sql = `
+INSERT INTO calendar_feed_key_v1 (id, key)
+VALUES (1, $1)
+ON CONFLICT (id) DO NOTHING;
+`
+
+func InitCalendarFeed(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing CalendarFeed v1\n")
+	_, err := tx.Exec(calendarFeedSchema)
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return err
+	}
+	_, err = tx.Exec(insertCalendarFeedKeyV1Sql, hex.EncodeToString(key))
+	return err
+}
+
+// Event handlers
+const insertCalendarFeedV1Sql = `
+INSERT INTO calendar_feed_v1 (title, list_id, label_id, include_events)
+VALUES (:title, :listid, :labelid, :includeevents);
+`
+
+const resetCalendarFeedTokenV1Sql = `
+UPDATE calendar_feed_v1
+SET token_generation = token_generation + 1
+WHERE id = $1;
+`
+
+const deleteCalendarFeedV1Sql = `
+DELETE FROM calendar_feed_v1
+WHERE id = $1;
+`
+
+func (h *StateEventHandler) HandleCalendarFeedAddEvent(tx *sqlx.Tx, event *generated.CalendarFeedAddEvent) (bool, error) {
+	fmt.Printf("CalendarFeed v1: AddEvent %s\n", event.Title)
+	if event.ListId != nil {
+		var category generated.TaskListCategory
+		err := tx.Get(&category, getTaskListCategoryV1Sql, *event.ListId)
+		if err != nil {
+			return true, err
+		}
+		// Smart lists have no members to filter by
+		if category == generated.TaskListCategorySmart {
+			return true, fmt.Errorf("calendar feeds can't be limited to smart list %d", *event.ListId)
+		}
+	}
+	if event.LabelId != nil {
+		var category generated.TaskListCategory
+		err := tx.Get(&category, getTaskListCategoryV1Sql, *event.LabelId)
+		if err != nil {
+			return true, err
+		}
+		if category != generated.TaskListCategoryLabel {
+			return true, fmt.Errorf("list %d is not a label", *event.LabelId)
+		}
+	}
+	_, err := tx.NamedExec(insertCalendarFeedV1Sql, *event)
+	return true, err
+}
+
+func (h *StateEventHandler) HandleCalendarFeedResetTokenEvent(tx *sqlx.Tx, event *generated.CalendarFeedResetTokenEvent) (bool, error) {
+	fmt.Printf("CalendarFeed v1: ResetTokenEvent %d\n", event.FeedId)
+	return true, execOnCalendarFeed(tx, resetCalendarFeedTokenV1Sql, event.FeedId)
+}
+
+func (h *StateEventHandler) HandleCalendarFeedDeleteEvent(tx *sqlx.Tx, event *generated.CalendarFeedDeleteEvent) (bool, error) {
+	fmt.Printf("CalendarFeed v1: DeleteEvent %d\n", event.FeedId)
+	return true, execOnCalendarFeed(tx, deleteCalendarFeedV1Sql, event.FeedId)
+}
+
+func execOnCalendarFeed(tx *sqlx.Tx, query string, feedId int) error {
+	result, err := tx.Exec(query, feedId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("calendar feed %d does not exist", feedId)
+	}
+	return nil
+}
+
+// State queries
+const getCalendarFeedKeyV1Sql = `
+SELECT key FROM calendar_feed_key_v1 WHERE id = 1;
+`
+
+const getCalendarFeedsV1Sql = `
+SELECT id, title, list_id AS listid, label_id AS labelid, include_events AS includeevents, token_generation
+FROM calendar_feed_v1
+ORDER BY id;
+`
+
+const getCalendarFeedV1Sql = `
+SELECT id, title, list_id AS listid, label_id AS labelid, include_events AS includeevents, token_generation
+FROM calendar_feed_v1
+WHERE id = $1;
+`
+
+// Tasks that are in the trash or only in deleted lists are left out
+const getCalendarFeedTasksV1Sql = `
+SELECT t.id, t.title, t.notes, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat, t.priority
+FROM task_v1 t
+WHERE t.due_date IS NOT NULL
+    AND t.id NOT IN (SELECT task_id FROM task_trash_v1)
+    AND EXISTS (SELECT 1 FROM task_to_list_v1 x WHERE x.task_id = t.id AND ($1 IS NULL OR x.list_id = $1))
+    AND ($2 IS NULL OR EXISTS (SELECT 1 FROM task_to_list_v1 x WHERE x.task_id = t.id AND x.list_id = $2))
+ORDER BY t.due_date, t.id;
+`
+
+type calendarFeedRow struct {
+	generated.CalendarFeed
+	TokenGeneration int `db:"token_generation"`
+}
+
+// ErrCalendarFeedNotFound is returned for a feed that doesn't exist or a token
+// that doesn't match, which are deliberately indistinguishable.
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+func calendarFeedToken(key string, feed calendarFeedRow) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "calendar-feed:%d:%d", feed.Id, feed.TokenGeneration)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (r *StateResolver) GetApiCalendarfeedList(db *sqlx.DB) (generated.CalendarFeedResponse, error) {
+	var key string
+	err := db.Get(&key, getCalendarFeedKeyV1Sql)
+	if err != nil {
+		return generated.CalendarFeedResponse{}, err
+	}
+	var rows []calendarFeedRow
+	err = db.Select(&rows, getCalendarFeedsV1Sql)
+	if err != nil {
+		return generated.CalendarFeedResponse{}, err
+	}
+	feeds := make([]generated.CalendarFeed, 0, len(rows))
+	for _, row := range rows {
+		feed := row.CalendarFeed
+		feed.Token = calendarFeedToken(key, row)
+		feeds = append(feeds, feed)
+	}
+	return generated.CalendarFeedResponse{Feeds: feeds}, nil
+}
+
+// RenderCalendarFeed returns a feed as an iCalendar document, after checking
+// its token.
+func RenderCalendarFeed(db *sqlx.DB, feedId int, token string, now time.Time) ([]byte, error) {
+	var key string
+	err := db.Get(&key, getCalendarFeedKeyV1Sql)
+	if err != nil {
+		return nil, err
+	}
+	var feed calendarFeedRow
+	err = db.Get(&feed, getCalendarFeedV1Sql, feedId)
+	if err == sql.ErrNoRows {
+		return nil, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(token), []byte(calendarFeedToken(key, feed))) {
+		return nil, ErrCalendarFeedNotFound
+	}
+
+	var tasks []generated.Task
+	err = db.Select(&tasks, getCalendarFeedTasksV1Sql, feed.ListId, feed.LabelId)
+	if err != nil {
+		return nil, err
+	}
+	calendar := ical.Component{Name: "VCALENDAR"}
+	calendar.Add("VERSION", "2.0")
+	calendar.Add("PRODID", "-//Yellowstone//Tasks//EN")
+	calendar.Add("CALSCALE", "GREGORIAN")
+	calendar.Add("X-WR-CALNAME", ical.Text(feed.Title))
+	for _, task := range tasks {
+		calendar.Components = append(calendar.Components, TaskToDo(task, DefaultTaskUid(task.Id), now))
+		if feed.IncludeEvents {
+			calendar.Components = append(calendar.Components, taskEvent(task, now))
+		}
+	}
+	var b bytes.Buffer
+	err = ical.Encode(&b, calendar)
+	return b.Bytes(), err
+}
+
+// iCalendar priorities run from 1 (highest) to 9
+var icalPriorities = map[int]string{1: "1", 2: "3", 3: "5", 4: "9"}
+
+// DefaultTaskUid returns the UID of a task's VTODO. It only depends on the
+// task ID, so calendar apps update the same to-do when the task changes.
+func DefaultTaskUid(taskId int) string {
+	return fmt.Sprintf("task-%d@yellowstone", taskId)
+}
+
+// TaskToDo maps a task to a VTODO.
+func TaskToDo(task generated.Task, uid string, stamp time.Time) ical.Component {
+	todo := ical.Component{Name: "VTODO"}
+	todo.Add("UID", ical.Text(uid))
+	todo.Add("DTSTAMP", ical.DateTime(stamp))
+	todo.Add("SUMMARY", ical.Text(task.Title))
+	if task.Notes != "" {
+		todo.Add("DESCRIPTION", ical.Text(task.Notes))
+	}
+	// DTSTART must not be after DUE
+	if task.StartAt != nil && (task.DueDate == nil || !task.StartAt.After(*task.DueDate)) {
+		todo.Add("DTSTART", ical.DateTime(*task.StartAt))
+	}
+	if task.DueDate != nil {
+		todo.Add("DUE", ical.DateTime(*task.DueDate))
+	}
+	if task.Priority != nil {
+		todo.Add("PRIORITY", icalPriorities[*task.Priority])
+	}
+	if task.CompletedAt != nil {
+		todo.Add("STATUS", "COMPLETED")
+		todo.Add("COMPLETED", ical.DateTime(*task.CompletedAt))
+		todo.Add("PERCENT-COMPLETE", "100")
+	} else {
+		todo.Add("STATUS", "NEEDS-ACTION")
+	}
+	return todo
+}
+
+// taskEvent maps a task to a VEVENT on its due date. A due date at exactly
+// midnight UTC is taken to be a date without a time, and becomes an all-day
+// event; otherwise the event is a point in time.
+func taskEvent(task generated.Task, now time.Time) ical.Component {
+	event := ical.Component{Name: "VEVENT"}
+	event.Add("UID", fmt.Sprintf("task-%d-due@yellowstone", task.Id))
+	event.Add("DTSTAMP", ical.DateTime(now))
+	event.Add("SUMMARY", ical.Text(task.Title))
+	if task.Notes != "" {
+		event.Add("DESCRIPTION", ical.Text(task.Notes))
+	}
+	due := task.DueDate.UTC()
+	if due.Equal(due.Truncate(24 * time.Hour)) {
+		event.Add("DTSTART", ical.Date(due), "VALUE=DATE")
+	} else {
+		event.Add("DTSTART", ical.DateTime(due))
+	}
+	event.Add("TRANSP", "TRANSPARENT")
+	return event
+}
@@ -0,0 +1,133 @@
+package state
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	r := NewResolver()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	dentistDue := time.Date(2026, 3, 2, 15, 30, 0, 0, time.UTC)
+	taxesDue := time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC)
+	taxesStart := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	priority := 1
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Home", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Work", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Urgent", Category: generated.TaskListCategoryLabel})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Dentist, 2nd floor", TaskListId: 1, DueDate: &dentistDue})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "File taxes", TaskListId: 1, DueDate: &taxesDue})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "No due date", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Quarterly report", TaskListId: 2, DueDate: &taxesDue})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Deleted", TaskListId: 1, DueDate: &taxesDue})
+	apply(t, db, h.HandleTaskUpdateNotesEvent, generated.TaskUpdateNotesEvent{TaskId: 2, Notes: "Forms; receipts\nand statements"})
+	apply(t, db, h.HandleTaskUpdatePriorityEvent, generated.TaskUpdatePriorityEvent{TaskId: 2, Priority: &priority})
+	apply(t, db, h.HandleTaskSnoozeEvent, generated.TaskSnoozeEvent{TaskId: 2, Until: taxesStart})
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 1, CompletedAt: &now})
+	apply(t, db, h.HandleTaskListCopyTasksEvent, generated.TaskListCopyTasksEvent{NewListId: 3, TaskIds: []int{2, 4}})
+	apply(t, db, h.HandleTaskDeleteEvent, generated.TaskDeleteEvent{TaskId: 5})
+
+	home, urgent := 1, 3
+	apply(t, db, h.HandleCalendarFeedAddEvent, generated.CalendarFeedAddEvent{Title: "Everything", IncludeEvents: true})
+	apply(t, db, h.HandleCalendarFeedAddEvent, generated.CalendarFeedAddEvent{Title: "Home", ListId: &home})
+	apply(t, db, h.HandleCalendarFeedAddEvent, generated.CalendarFeedAddEvent{Title: "Urgent at home", ListId: &home, LabelId: &urgent})
+
+	feeds, err := r.GetApiCalendarfeedList(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds.Feeds) != 3 {
+		t.Fatalf("feeds %+v, want 3", feeds.Feeds)
+	}
+	render := func(feed generated.CalendarFeed) string {
+		t.Helper()
+		calendar, err := RenderCalendarFeed(db, feed.Id, feed.Token, now)
+		if err != nil {
+			t.Fatalf("rendering feed %d: %v", feed.Id, err)
+		}
+		return string(calendar)
+	}
+
+	everything := render(feeds.Feeds[0])
+	for _, line := range []string{
+		"X-WR-CALNAME:Everything",
+		// Completed to-do
+		"UID:task-1@yellowstone",
+		`SUMMARY:Dentist\, 2nd floor`,
+		"DUE:20260302T153000Z",
+		"STATUS:COMPLETED",
+		"COMPLETED:20260301T120000Z",
+		// Open to-do with notes, a start time and a priority
+		"UID:task-2@yellowstone",
+		`DESCRIPTION:Forms\; receipts\nand statements`,
+		"DTSTART:20260401T090000Z",
+		"DUE:20260415T000000Z",
+		"PRIORITY:1",
+		"STATUS:NEEDS-ACTION",
+		// A due date at midnight is an all-day event, any other time is not
+		"UID:task-2-due@yellowstone",
+		"DTSTART;VALUE=DATE:20260415",
+		"UID:task-1-due@yellowstone",
+		"DTSTART:20260302T153000Z",
+		"UID:task-4@yellowstone",
+	} {
+		if !strings.Contains(everything, line+"\r\n") {
+			t.Errorf("feed with everything has no line %q:\n%s", line, everything)
+		}
+	}
+	for _, uid := range []string{"task-3@", "task-5@"} {
+		if strings.Contains(everything, uid) {
+			t.Errorf("feed with everything includes %s, which has no due date or is deleted", uid)
+		}
+	}
+
+	// Feeds can be limited to a list, and to tasks with a label
+	for _, test := range []struct {
+		feed generated.CalendarFeed
+		uids []string
+	}{
+		{feeds.Feeds[1], []string{"task-1@", "task-2@"}},
+		{feeds.Feeds[2], []string{"task-2@"}},
+	} {
+		calendar := render(test.feed)
+		if got := strings.Count(calendar, "UID:"); got != len(test.uids) {
+			t.Errorf("feed %q has %d components, want %v", test.feed.Title, got, test.uids)
+		}
+		for _, uid := range test.uids {
+			if !strings.Contains(calendar, "UID:"+uid) {
+				t.Errorf("feed %q does not include %s", test.feed.Title, uid)
+			}
+		}
+		if strings.Contains(calendar, "BEGIN:VEVENT") {
+			t.Errorf("feed %q includes events", test.feed.Title)
+		}
+	}
+
+	// A feed can only be read with its current token
+	feed := feeds.Feeds[1]
+	if _, err = RenderCalendarFeed(db, feed.Id, feeds.Feeds[0].Token, now); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("rendering a feed with another feed's token: %v", err)
+	}
+	apply(t, db, h.HandleCalendarFeedResetTokenEvent, generated.CalendarFeedResetTokenEvent{FeedId: feed.Id})
+	if _, err = RenderCalendarFeed(db, feed.Id, feed.Token, now); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("rendering a feed with its old token: %v", err)
+	}
+	feeds, err = r.GetApiCalendarfeedList(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds.Feeds[1].Token == feed.Token {
+		t.Error("resetting the token did not change it")
+	}
+	render(feeds.Feeds[1])
+	apply(t, db, h.HandleCalendarFeedDeleteEvent, generated.CalendarFeedDeleteEvent{FeedId: feed.Id})
+	if _, err = RenderCalendarFeed(db, feed.Id, feeds.Feeds[1].Token, now); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("rendering a deleted feed: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// An export document holds what is needed to recreate every list and task
+// through events: /api/import publishes the events that create them, so the
+// document doesn't depend on the database schema, and the imported data gets
+// an event log of its own. Lists and tasks refer to each other by their IDs on
+// the exporting server, which are remapped when they are imported.
+//
+// Lists and tasks in the trash are not exported.
+
+// ExportVersion is the version of the export document format. It changes when
+// a document written by this version can't be read by older ones.
+const ExportVersion = 1
+
+// State queries
+const getExportListsV1Sql = `
+SELECT id, title, category, archived
+FROM task_list_v1
+WHERE id NOT IN (SELECT list_id FROM task_list_trash_v1)
+ORDER BY sort_key;
+`
+
+// Subtask positions are numbered from 0 among the exported siblings
+const getExportTasksV1Sql = `
+SELECT t.id, t.title, t.notes, t.priority, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat,
+    r.rule AS recurrence, tp.parent_id AS parenttaskid,
+    CASE WHEN tp.parent_id IS NULL THEN NULL
+        ELSE ROW_NUMBER() OVER (PARTITION BY tp.parent_id ORDER BY tp.position) - 1 END AS subtaskposition
+FROM task_v1 t
+LEFT JOIN task_recurrence_v1 r ON r.task_id = t.id
+LEFT JOIN task_parent_v1 tp ON tp.task_id = t.id
+WHERE EXISTS (SELECT 1 FROM task_to_list_v1 x WHERE x.task_id = t.id AND x.list_id NOT IN (SELECT list_id FROM task_list_trash_v1))
+ORDER BY t.id;
+`
+
+const getExportMembershipsV1Sql = `
+SELECT task_id, list_id AS listid, ROW_NUMBER() OVER (PARTITION BY list_id ORDER BY sort_key) - 1 AS position
+FROM task_to_list_v1
+WHERE list_id NOT IN (SELECT list_id FROM task_list_trash_v1)
+ORDER BY task_id, list_id;
+`
+
+const getExportRemindersV1Sql = `
+SELECT task_id, remind_at AS remindat, minutes_before_due AS minutesbeforedue
+FROM task_reminder_v1
+ORDER BY id;
+`
+
+const getExportHistoryV1Sql = `
+SELECT task_id, update_type AS updatetype, system_comment AS systemcomment, user_comment AS usercomment, created_at AS createdat
+FROM task_history_v1
+ORDER BY created_at, id;
+`
+
+func (r *StateResolver) GetApiExport(db *sqlx.DB) (generated.ExportDocument, error) {
+	doc := generated.ExportDocument{
+		Version:    ExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Lists:      make([]generated.ExportedList, 0),
+		Tasks:      make([]generated.ExportedTask, 0),
+	}
+	err := db.Select(&doc.Lists, getExportListsV1Sql)
+	if err != nil {
+		return doc, err
+	}
+	for i := range doc.Lists {
+		doc.Lists[i].Position = i
+		if doc.Lists[i].Category == generated.TaskListCategorySmart {
+			filter, err := getTaskListFilter(db, doc.Lists[i].Id)
+			if err != nil {
+				return doc, err
+			}
+			doc.Lists[i].Filter = &filter
+		}
+	}
+
+	err = db.Select(&doc.Tasks, getExportTasksV1Sql)
+	if err != nil {
+		return doc, err
+	}
+	tasks := make(map[int]*generated.ExportedTask, len(doc.Tasks))
+	for i := range doc.Tasks {
+		task := &doc.Tasks[i]
+		task.Memberships = make([]generated.ExportedMembership, 0)
+		task.Reminders = make([]generated.ExportedReminder, 0)
+		task.History = make([]generated.ExportedHistory, 0)
+		tasks[task.Id] = task
+	}
+	// A subtask can be in a list that its parent has since been removed from
+	for i := range doc.Tasks {
+		task := &doc.Tasks[i]
+		if task.ParentTaskId != nil && tasks[*task.ParentTaskId] == nil {
+			task.ParentTaskId = nil
+			task.SubtaskPosition = nil
+		}
+	}
+
+	var memberships []struct {
+		TaskId int `db:"task_id"`
+		generated.ExportedMembership
+	}
+	err = db.Select(&memberships, getExportMembershipsV1Sql)
+	if err != nil {
+		return doc, err
+	}
+	for _, membership := range memberships {
+		if task := tasks[membership.TaskId]; task != nil {
+			task.Memberships = append(task.Memberships, membership.ExportedMembership)
+		}
+	}
+
+	var reminders []struct {
+		TaskId int `db:"task_id"`
+		generated.ExportedReminder
+	}
+	err = db.Select(&reminders, getExportRemindersV1Sql)
+	if err != nil {
+		return doc, err
+	}
+	for _, reminder := range reminders {
+		if task := tasks[reminder.TaskId]; task != nil {
+			task.Reminders = append(task.Reminders, reminder.ExportedReminder)
+		}
+	}
+
+	var history []struct {
+		TaskId int `db:"task_id"`
+		generated.ExportedHistory
+	}
+	err = db.Select(&history, getExportHistoryV1Sql)
+	if err != nil {
+		return doc, err
+	}
+	for _, entry := range history {
+		if task := tasks[entry.TaskId]; task != nil {
+			task.History = append(task.History, entry.ExportedHistory)
+		}
+	}
+	return doc, nil
+}
+
+// Import
+
+// Events don't return the IDs of what they create, but publishing one returns
+// the ID of the event, so tasks and lists remember the Task:Add or TaskList:Add
+// event that created them. That is how /api/import finds them, even while
+// other clients publish events of their own.
+const addCreatedEventIdsV1Sql = `
+ALTER TABLE task_v1 ADD COLUMN created_event_id INTEGER;
+ALTER TABLE task_list_v1 ADD COLUMN created_event_id INTEGER;
+CREATE INDEX IF NOT EXISTS task_created_event_id_v1 ON task_v1 (created_event_id);
+CREATE INDEX IF NOT EXISTS task_list_created_event_id_v1 ON task_list_v1 (created_event_id);
+`
+
+func migrateAddCreatedEventIds(tx *sqlx.Tx) error {
+	_, err := tx.Exec(addCreatedEventIdsV1Sql)
+	return err
+}
+
+const setTaskCreatedEventIdV1Sql = `
+UPDATE task_v1 SET created_event_id = $1 WHERE id = $2;
+`
+
+const setTaskListCreatedEventIdV1Sql = `
+UPDATE task_list_v1 SET created_event_id = $1 WHERE id = $2;
+`
+
+const getTaskByCreatedEventIdV1Sql = `
+SELECT id FROM task_v1 WHERE created_event_id = $1;
+`
+
+const getTaskListByCreatedEventIdV1Sql = `
+SELECT id FROM task_list_v1 WHERE created_event_id = $1;
+`
+
+// recordCreatedBy records the event being applied as the one that created a
+// task or list, with setSql.
+func (h *StateEventHandler) recordCreatedBy(tx *sqlx.Tx, setSql string, id int) error {
+	eventId, err := h.currentEventId(tx)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(setSql, eventId, id)
+	return err
+}
+
+// GetTaskAddedByEvent returns the ID of the task created by the Task:Add event
+// with the given ID.
+func GetTaskAddedByEvent(db *sqlx.DB, eventId int) (int, error) {
+	var taskId int
+	err := db.Get(&taskId, getTaskByCreatedEventIdV1Sql, eventId)
+	if err == sql.ErrNoRows {
+		err = fmt.Errorf("event %d did not add a task", eventId)
+	}
+	return taskId, err
+}
+
+// GetTaskListAddedByEvent returns the ID of the list created by the
+// TaskList:Add event with the given ID.
+func GetTaskListAddedByEvent(db *sqlx.DB, eventId int) (int, error) {
+	var listId int
+	err := db.Get(&listId, getTaskListByCreatedEventIdV1Sql, eventId)
+	if err == sql.ErrNoRows {
+		err = fmt.Errorf("event %d did not add a list", eventId)
+	}
+	return listId, err
+}
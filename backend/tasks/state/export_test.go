@@ -0,0 +1,36 @@
+package state
+
+import (
+	"testing"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestGetTaskAddedByEvent(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	publish(t, db, h, "TaskList:Add", generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+	publish(t, db, h, "TaskList:Add", generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+	// Tasks with the same title in the same list are told apart by their events
+	publish(t, db, h, "Task:Add", generated.TaskAddEvent{Title: "Call the bank", TaskListId: 2})
+	publish(t, db, h, "Task:AddComment", generated.TaskAddCommentEvent{TaskId: 1, UserComment: "Before noon"})
+	publish(t, db, h, "Task:Add", generated.TaskAddEvent{Title: "Call the bank", TaskListId: 2})
+
+	check := func() {
+		t.Helper()
+		if listId, err := GetTaskListAddedByEvent(db, 2); err != nil || listId != 2 {
+			t.Errorf("list added by event 2: %d, %v; want 2", listId, err)
+		}
+		if taskId, err := GetTaskAddedByEvent(db, 5); err != nil || taskId != 2 {
+			t.Errorf("task added by event 5: %d, %v; want 2", taskId, err)
+		}
+		if _, err := GetTaskAddedByEvent(db, 4); err == nil {
+			t.Error("found a task added by a Task:AddComment event")
+		}
+	}
+	check()
+	if _, err := RebuildProjections(db); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
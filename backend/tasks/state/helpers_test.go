@@ -0,0 +1,107 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// A minimal event log, in place of the one owned by the application framework
+const testEventLogSchema = `
+CREATE TABLE IF NOT EXISTS event_v1 (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    event_data BLOB NOT NULL
+);
+`
+
+// openTestDB opens a database with the projection tables, migrated to the
+// current schema, the way the server does when it starts. An empty path gives
+// a new in-memory database.
+func openTestDB(t *testing.T, path string) *sqlx.DB {
+	t.Helper()
+	if path == "" {
+		path = ":memory:"
+	}
+	db, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	tx := db.MustBegin()
+	defer tx.Rollback()
+	tx.MustExec(testEventLogSchema)
+	if err = InitProjections(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// logEvent stores an event in the event log, in the transaction that applies
+// it, as the database does for a published event. Events that are logged
+// without a type are skipped when the log is replayed.
+func logEvent(t *testing.T, tx *sqlx.Tx, eventType string, event interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if eventType != "" {
+		fields["type"] = eventType
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		t.Fatal(err)
+	}
+	tx.MustExec("INSERT INTO event_v1 (event_data) VALUES ($1)", data)
+	return data
+}
+
+// apply applies an event with one of the event handlers in its own
+// transaction, as the database does for a published event. The event is
+// logged without its type, so tests that replay the log use publish instead.
+func apply[T any](t *testing.T, db *sqlx.DB, handler func(*sqlx.Tx, *T) (bool, error), event T) {
+	t.Helper()
+	tx := db.MustBegin()
+	defer tx.Rollback()
+	logEvent(t, tx, "", event)
+	if _, err := handler(tx, &event); err != nil {
+		t.Fatalf("%T: %v", event, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// publish stores an event in the event log and applies it, in one transaction,
+// as the database does for an event published with /api/publish.
+func publish(t *testing.T, db *sqlx.DB, h *StateEventHandler, eventType string, event interface{}) {
+	t.Helper()
+	tx := db.MustBegin()
+	defer tx.Rollback()
+	data := logEvent(t, tx, eventType, event)
+	handled, err := generated.HandleEvent(tx, h, eventType, data)
+	if err != nil {
+		t.Fatalf("%s: %v", eventType, err)
+	}
+	if !handled {
+		t.Fatalf("%s was not handled", eventType)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"github.com/jmoiron/sqlx"
 	"tomyedwab.com/yellowstone-server/tasks/generated"
 )
 
@@ -8,6 +9,14 @@ type StateResolver struct {
 }
 
 type StateEventHandler struct {
+	// Set while the events of an Undo:Apply event are being applied
+	undoing bool
+	// Set while an event that is made up of other events applies them, to
+	// collect their compensating events into a single undo entry
+	combinedUndo *[]undoEvent
+	// Set while the event log is replayed, to the ID of the event being
+	// applied; otherwise it is the newest event in the log
+	replayEventId int
 }
 
 func NewResolver() generated.Resolver {
@@ -17,3 +26,32 @@ func NewResolver() generated.Resolver {
 func NewEventHandler() generated.EventHandler {
 	return &StateEventHandler{}
 }
+
+// projectionInits creates the projection tables, in dependency order. The
+// search indexes are built from the other tables so they come last.
+var projectionInits = []func(tx *sqlx.Tx) error{
+	InitTask,
+	InitTaskList,
+	InitTaskHistory,
+	InitTaskToList,
+	InitTaskRecurrence,
+	InitTaskReminder,
+	InitTaskParent,
+	InitTaskListFilter,
+	InitCalendarFeed,
+	InitCalDAVResource,
+	InitTaskListTrash,
+	InitTaskTrash,
+	InitUndo,
+	InitSearch,
+}
+
+// InitProjections creates all of the projection tables that don't exist yet.
+func InitProjections(tx *sqlx.Tx) error {
+	for _, init := range projectionInits {
+		if err := init(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
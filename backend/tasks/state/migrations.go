@@ -0,0 +1,151 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tables are created with CREATE TABLE IF NOT EXISTS by the Init functions, so
+// changes to existing tables go through migrations instead. Each migration runs
+// once, in its own transaction, in version order, after all the Init functions
+// have run. A migration can change the schema and back-fill data in Up, and/or
+// rebuild a table from scratch by replaying the event log in Replay, typically
+// into a new _v2 table that the queries are then switched over to.
+
+type Migration struct {
+	// Version numbers are never reused; migrations are applied in order
+	Version int
+	Name    string
+	// Up applies schema changes and back-fills existing data
+	Up func(tx *sqlx.Tx) error
+	// Replay, if set, is called after Up for every event in the event log, in
+	// the order the events were applied
+	Replay func(tx *sqlx.Tx, eventId int, eventType string, eventData json.RawMessage) error
+}
+
+// migrations lists all migrations, in version order
+var migrations = []Migration{
+	{Version: 1, Name: "fractional_sort_keys", Up: migrateToSortKeys},
+	{Version: 2, Name: "task_notes", Up: migrateAddTaskNotes},
+	{Version: 3, Name: "task_priority", Up: migrateAddTaskPriority},
+	{Version: 4, Name: "task_start_at", Up: migrateAddTaskStartAt},
+	{Version: 5, Name: "created_event_ids", Up: migrateAddCreatedEventIds},
+}
+
+// Table schema
+const migrationSchema = `
+CREATE TABLE IF NOT EXISTS migration_v1 (
+    version INTEGER PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const getAppliedMigrationsV1Sql = `
+SELECT version, name FROM migration_v1 ORDER BY version;
+`
+
+const insertMigrationV1Sql = `
+INSERT INTO migration_v1 (version, name)
+VALUES ($1, $2);
+`
+
+// The event log is owned by the application framework
+const getEventLogV1Sql = `
+SELECT id, event_data FROM event_v1 ORDER BY id;
+`
+
+// RunMigrations applies all migrations that haven't been applied yet.
+func RunMigrations(db *sqlx.DB) error {
+	return runMigrations(db, migrations)
+}
+
+func runMigrations(db *sqlx.DB, migrations []Migration) error {
+	_, err := db.Exec(migrationSchema)
+	if err != nil {
+		return err
+	}
+	var applied []struct {
+		Version int    `db:"version"`
+		Name    string `db:"name"`
+	}
+	err = db.Select(&applied, getAppliedMigrationsV1Sql)
+	if err != nil {
+		return err
+	}
+	appliedNames := make(map[int]string, len(applied))
+	for _, migration := range applied {
+		appliedNames[migration.Version] = migration.Name
+	}
+
+	lastVersion := 0
+	for _, migration := range migrations {
+		if migration.Version <= lastVersion {
+			return fmt.Errorf("migration %d (%s) is out of order", migration.Version, migration.Name)
+		}
+		lastVersion = migration.Version
+		if name, ok := appliedNames[migration.Version]; ok {
+			if name != migration.Name {
+				return fmt.Errorf("migration %d was applied as %s, not %s", migration.Version, name, migration.Name)
+			}
+			continue
+		}
+		if err = runMigration(db, migration); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+func runMigration(db *sqlx.DB, migration Migration) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = applyMigration(tx, migration); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func applyMigration(tx *sqlx.Tx, migration Migration) error {
+	fmt.Printf("Applying migration %d: %s\n", migration.Version, migration.Name)
+	if migration.Up != nil {
+		if err := migration.Up(tx); err != nil {
+			return err
+		}
+	}
+	if migration.Replay != nil {
+		if err := replayEventLog(tx, migration.Replay); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(insertMigrationV1Sql, migration.Version, migration.Name)
+	return err
+}
+
+func replayEventLog(tx *sqlx.Tx, replay func(tx *sqlx.Tx, eventId int, eventType string, eventData json.RawMessage) error) error {
+	var events []struct {
+		Id        int    `db:"id"`
+		EventData []byte `db:"event_data"`
+	}
+	err := tx.Select(&events, getEventLogV1Sql)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err = json.Unmarshal(event.EventData, &header); err != nil {
+			return fmt.Errorf("event %d: %w", event.Id, err)
+		}
+		if err = replay(tx, event.Id, header.Type, event.EventData); err != nil {
+			return fmt.Errorf("event %d (%s): %w", event.Id, header.Type, err)
+		}
+	}
+	return nil
+}
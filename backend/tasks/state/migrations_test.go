@@ -0,0 +1,102 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// titleMigrations rebuild the task titles into a _v2 table by replaying the
+// Task:Add events in the event log, after the migrations the test database
+// already has.
+func titleMigrations(replay func(tx *sqlx.Tx, eventId int, eventType string, eventData json.RawMessage) error) []Migration {
+	return []Migration{{
+		Version: 100,
+		Name:    "task_titles",
+		Up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("CREATE TABLE task_title_v2 (title TEXT NOT NULL)")
+			return err
+		},
+		Replay: replay,
+	}}
+}
+
+func replayTaskTitles(tx *sqlx.Tx, eventId int, eventType string, eventData json.RawMessage) error {
+	if eventType != "Task:Add" {
+		return nil
+	}
+	var event generated.TaskAddEvent
+	if err := json.Unmarshal(eventData, &event); err != nil {
+		return err
+	}
+	_, err := tx.Exec("INSERT INTO task_title_v2 (title) VALUES ($1)", event.Title)
+	return err
+}
+
+func TestRunMigrationsReplaysEventLog(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	publish(t, db, h, "TaskList:Add", generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+	publish(t, db, h, "Task:Add", generated.TaskAddEvent{Title: "Call the bank", TaskListId: 1})
+	publish(t, db, h, "Task:Add", generated.TaskAddEvent{Title: "Water the plants", TaskListId: 1})
+
+	titles := func() []string {
+		var got []string
+		if err := db.Select(&got, "SELECT title FROM task_title_v2 ORDER BY rowid"); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	// Applied migrations are skipped the next time
+	for i := 0; i < 2; i++ {
+		if err := runMigrations(db, titleMigrations(replayTaskTitles)); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := titles(), []string{"Call the bank", "Water the plants"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: titles %v, want %v", i+1, got, want)
+		}
+	}
+
+	renamed := titleMigrations(replayTaskTitles)
+	renamed[0].Name = "task_titles_v2"
+	if err := runMigrations(db, renamed); err == nil {
+		t.Error("a renamed migration was accepted")
+	}
+}
+
+func TestRunMigrationsRollsBackFailedReplay(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	publish(t, db, h, "TaskList:Add", generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+	publish(t, db, h, "Task:Add", generated.TaskAddEvent{Title: "Call the bank", TaskListId: 1})
+
+	errReplay := errors.New("replay failed")
+	err := runMigrations(db, titleMigrations(func(tx *sqlx.Tx, eventId int, eventType string, eventData json.RawMessage) error {
+		if eventType == "Task:Add" {
+			return errReplay
+		}
+		return nil
+	}))
+	if !errors.Is(err, errReplay) {
+		t.Fatalf("got error %v, want %v", err, errReplay)
+	}
+
+	// Neither the new table nor the migration record is kept
+	var count int
+	if err = db.Get(&count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'task_title_v2'"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("task_title_v2 was created by the failed migration")
+	}
+	if err = db.Get(&count, "SELECT COUNT(*) FROM migration_v1 WHERE name = 'task_titles'"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("the failed migration was recorded as applied")
+	}
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// The projection tables can be rebuilt from scratch by replaying the event log
+// through the event handlers, e.g. to retroactively apply a fix to a handler.
+// Everything happens in a single transaction, so readers see either the old or
+// the new tables, never a partial rebuild.
+
+// projectionTables lists every table built by the event handlers, including
+// those created by migrations
+var projectionTables = []string{
+	"task_v1",
+	"task_list_v1",
+	"task_history_v1",
+	"task_to_list_v1",
+	"task_recurrence_v1",
+	"task_reminder_v1",
+	"task_parent_v1",
+	"task_list_filter_v1",
+	"calendar_feed_v1",
+	"caldav_resource_v1",
+	"task_list_trash_v1",
+	"task_list_trash_task_v1",
+	"task_trash_v1",
+	"task_trash_list_v1",
+	"task_trash_child_v1",
+	"undo_v1",
+	"task_fts_v1",
+	"task_comment_fts_v1",
+	"task_list_fts_v1",
+}
+
+const deleteMigrationsV1Sql = `
+DELETE FROM migration_v1;
+`
+
+func RebuildProjections(db *sqlx.DB) (generated.ProjectionRebuildResponse, error) {
+	fmt.Printf("Rebuilding projections\n")
+	var resp generated.ProjectionRebuildResponse
+	start := time.Now()
+	tx, err := db.Beginx()
+	if err != nil {
+		return resp, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range projectionTables {
+		_, err = tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s;", table))
+		if err != nil {
+			return resp, err
+		}
+	}
+	if err = InitProjections(tx); err != nil {
+		return resp, err
+	}
+
+	// Migrations are applied to the empty tables, so that the handlers see the
+	// current schema
+	_, err = tx.Exec(migrationSchema)
+	if err != nil {
+		return resp, err
+	}
+	_, err = tx.Exec(deleteMigrationsV1Sql)
+	if err != nil {
+		return resp, err
+	}
+	for _, migration := range migrations {
+		if err = applyMigration(tx, migration); err != nil {
+			return resp, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	handler := &StateEventHandler{}
+	err = replayEventLog(tx, func(tx *sqlx.Tx, eventId int, eventType string, eventData json.RawMessage) error {
+		resp.EventCount++
+		handler.replayEventId = eventId
+		// An event that fails, including one that can no longer be decoded,
+		// aborts the rebuild and leaves the old tables in place
+		handled, err := generated.HandleEvent(tx, handler, eventType, eventData)
+		if err != nil {
+			return err
+		}
+		if !handled {
+			resp.IgnoredCount++
+		}
+		return nil
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return resp, err
+	}
+	resp.DurationMs = int(time.Since(start).Milliseconds())
+	fmt.Printf("Rebuilt projections from %d events in %dms\n", resp.EventCount, resp.DurationMs)
+	return resp, nil
+}
@@ -0,0 +1,74 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// dumpTables returns the rows of the given tables, in insertion order.
+func dumpTables(t *testing.T, db *sqlx.DB, tables ...string) map[string][]map[string]interface{} {
+	t.Helper()
+	dump := make(map[string][]map[string]interface{}, len(tables))
+	for _, table := range tables {
+		rows, err := db.Queryx("SELECT * FROM " + table + " ORDER BY rowid")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			row := map[string]interface{}{}
+			if err = rows.MapScan(row); err != nil {
+				t.Fatal(err)
+			}
+			dump[table] = append(dump[table], row)
+		}
+		if err = rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+	return dump
+}
+
+func TestRebuildKeepsUndoEntries(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	publish(t, db, h, "TaskList:Add", generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList, Timestamp: at})
+	publish(t, db, h, "Task:Add", generated.TaskAddEvent{Title: "Call the bank", TaskListId: 1, Timestamp: at.Add(time.Minute)})
+	// Comments can't be undone, so event IDs and undo entries don't line up
+	publish(t, db, h, "Task:AddComment", generated.TaskAddCommentEvent{TaskId: 1, UserComment: "Before noon", Timestamp: at.Add(2 * time.Minute)})
+	publish(t, db, h, "Task:UpdateTitle", generated.TaskUpdateTitleEvent{TaskId: 1, Title: "Call the bank about the card", Timestamp: at.Add(3 * time.Minute)})
+	publish(t, db, h, "Task:Add", generated.TaskAddEvent{Title: "Water the plants", TaskListId: 1, Timestamp: at.Add(4 * time.Minute)})
+
+	undo, err := NewResolver().GetApiUndo(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undo.Entries) != 4 || undo.Entries[1].EventType != "Task:UpdateTitle" || undo.Entries[1].EventId != 4 {
+		t.Fatalf("undo entries %+v, want the title update as event 4", undo.Entries)
+	}
+	publish(t, db, h, "Undo:Apply", generated.UndoApplyEvent{EventId: 4, Timestamp: at.Add(5 * time.Minute)})
+
+	tables := []string{"task_v1", "task_history_v1", "task_to_list_v1", "undo_v1"}
+	before := dumpTables(t, db, tables...)
+	if _, err = RebuildProjections(db); err != nil {
+		t.Fatal(err)
+	}
+	if after := dumpTables(t, db, tables...); !reflect.DeepEqual(after, before) {
+		t.Fatalf("state after rebuild\n%v\nwant\n%v", after, before)
+	}
+
+	// Entries are still found by the IDs they were listed with
+	publish(t, db, h, "Undo:Apply", generated.UndoApplyEvent{EventId: 5, Timestamp: at.Add(6 * time.Minute)})
+	tasks, err := NewResolver().GetApiTaskList(db, nil, nil, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.Tasks) != 1 || tasks.Tasks[0].Title != "Call the bank" {
+		t.Fatalf("tasks %+v, want only the task with its original title", tasks.Tasks)
+	}
+}
@@ -0,0 +1,263 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrenceRule is the subset of RFC 5545 RRULE that tasks support: FREQ
+// (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY (weekly rules only, without
+// ordinal prefixes), BYMONTHDAY (monthly rules only), COUNT and UNTIL.
+//
+// Each occurrence is a separate task with its own copy of the rule, so COUNT is
+// the number of occurrences left including the task's own, and goes down by
+// one with each occurrence.
+type recurrenceRule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var rruleWeekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// parseRecurrence accepts either one of the shorthands "daily", "weekly",
+// "monthly" and "yearly" or an RRULE value, with or without the "RRULE:"
+// prefix.
+func parseRecurrence(value string) (*recurrenceRule, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "daily", "weekly", "monthly", "yearly":
+		return &recurrenceRule{Freq: strings.ToUpper(value), Interval: 1}, nil
+	}
+	if strings.HasPrefix(strings.ToUpper(value), "RRULE:") {
+		value = value[len("RRULE:"):]
+	}
+
+	rule := &recurrenceRule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+			switch rule.Freq {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+			default:
+				return nil, fmt.Errorf("unsupported recurrence frequency %q", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid recurrence interval %q", val)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(val), ",") {
+				weekday, ok := rruleWeekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("unsupported recurrence day %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				monthDay, err := strconv.Atoi(day)
+				if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+					return nil, fmt.Errorf("invalid recurrence month day %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, monthDay)
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid recurrence count %q", val)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseRRuleTime(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("recurrence rule %q is missing FREQ", value)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL can't both be given")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY is only supported for weekly recurrence")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != "MONTHLY" {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported for monthly recurrence")
+	}
+	sort.Slice(rule.ByDay, func(i, j int) bool { return rule.ByDay[i] < rule.ByDay[j] })
+	sort.Ints(rule.ByMonthDay)
+	return rule, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid recurrence UNTIL %q", value)
+}
+
+// String returns the normalized RRULE value that is stored in the database.
+func (r *recurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = rruleWeekdayNames[day]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after the given time, keeping its
+// time of day. The second return value is false once the rule has ended.
+func (r *recurrenceRule) Next(after time.Time) (time.Time, bool) {
+	if r.Count == 1 {
+		return time.Time{}, false
+	}
+	var next time.Time
+	switch r.Freq {
+	case "DAILY":
+		next = after.AddDate(0, 0, r.Interval)
+	case "WEEKLY":
+		next = r.nextWeekly(after)
+	case "MONTHLY":
+		next = r.nextMonthly(after)
+	case "YEARLY":
+		next = addMonthsClamped(after, 12*r.Interval)
+	}
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// NextRule returns the rule for the occurrence after this one.
+func (r *recurrenceRule) NextRule() *recurrenceRule {
+	next := *r
+	if next.Count > 0 {
+		next.Count--
+	}
+	return &next
+}
+
+func (r *recurrenceRule) nextWeekly(after time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return after.AddDate(0, 0, 7*r.Interval)
+	}
+	// Weeks start on Monday (the RFC 5545 default WKST), and only every
+	// Interval-th week counting from the week of "after" is eligible.
+	weekStart := dayNumber(after) - (int(after.Weekday())+6)%7
+	for offset := 1; offset <= 7*(r.Interval+1); offset++ {
+		candidate := after.AddDate(0, 0, offset)
+		if ((dayNumber(candidate)-weekStart)/7)%r.Interval != 0 {
+			continue
+		}
+		for _, day := range r.ByDay {
+			if candidate.Weekday() == day {
+				return candidate
+			}
+		}
+	}
+	return after.AddDate(0, 0, 7*r.Interval)
+}
+
+// dayNumber returns the number of calendar days between the Unix epoch and t's
+// date, ignoring the time of day and DST transitions.
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+func (r *recurrenceRule) nextMonthly(after time.Time) time.Time {
+	if len(r.ByMonthDay) == 0 {
+		return addMonthsClamped(after, r.Interval)
+	}
+	first := time.Date(after.Year(), after.Month(), 1, after.Hour(), after.Minute(), after.Second(), 0, after.Location())
+	// Scan forward month by month; some BYMONTHDAY values (e.g. 31) are skipped
+	// in shorter months, so allow a few years of lookahead.
+	for months := 0; months <= 48*r.Interval; months += r.Interval {
+		month := first.AddDate(0, months, 0)
+		daysInMonth := month.AddDate(0, 1, -1).Day()
+		var days []int
+		for _, day := range r.ByMonthDay {
+			if day < 0 {
+				day = daysInMonth + day + 1
+			}
+			if day >= 1 && day <= daysInMonth {
+				days = append(days, day)
+			}
+		}
+		sort.Ints(days)
+		for _, day := range days {
+			candidate := month.AddDate(0, 0, day-1)
+			if candidate.After(after) {
+				return candidate
+			}
+		}
+	}
+	return addMonthsClamped(after, r.Interval)
+}
+
+// addMonthsClamped adds months to t, clamping the day to the end of the target
+// month instead of overflowing into the next one (Jan 31 + 1 month = Feb 28).
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	target := first.AddDate(0, months, 0)
+	daysInMonth := target.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > daysInMonth {
+		day = daysInMonth
+	}
+	return target.AddDate(0, 0, day-1)
+}
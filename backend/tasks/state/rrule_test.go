@@ -0,0 +1,185 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		want  string
+	}{
+		{"daily", "FREQ=DAILY"},
+		{" Weekly ", "FREQ=WEEKLY"},
+		{"monthly", "FREQ=MONTHLY"},
+		{"yearly", "FREQ=YEARLY"},
+		{"RRULE:FREQ=WEEKLY;BYDAY=FR,MO", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"rrule:freq=weekly;byday=su,sa", "FREQ=WEEKLY;BYDAY=SU,SA"},
+		{"freq=monthly;interval=2;bymonthday=15,-1,1", "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=-1,1,15"},
+		{"FREQ=DAILY;INTERVAL=1", "FREQ=DAILY"},
+		{"FREQ=DAILY;COUNT=5;", "FREQ=DAILY;COUNT=5"},
+		{"FREQ=DAILY;UNTIL=20260110", "FREQ=DAILY;UNTIL=20260110T000000Z"},
+		{"FREQ=YEARLY;UNTIL=20261231T235959Z", "FREQ=YEARLY;UNTIL=20261231T235959Z"},
+	} {
+		rule, err := parseRecurrence(test.value)
+		if err != nil {
+			t.Errorf("parseRecurrence(%q): %v", test.value, err)
+			continue
+		}
+		if got := rule.String(); got != test.want {
+			t.Errorf("parseRecurrence(%q) = %s, want %s", test.value, got, test.want)
+		}
+		// The stored form parses to the same rule
+		again, err := parseRecurrence(rule.String())
+		if err != nil || again.String() != rule.String() {
+			t.Errorf("parseRecurrence(%q) = %v, %v", rule.String(), again, err)
+		}
+	}
+}
+
+func TestParseRecurrenceRejectsUnsupportedRules(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"hourly",
+		"FREQ",
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=x",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;WKST=SU",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+	} {
+		if rule, err := parseRecurrence(value); err == nil {
+			t.Errorf("parseRecurrence(%q) = %s, want an error", value, rule)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+	}
+	local := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 0, 0, 0, newYork)
+	}
+	for _, test := range []struct {
+		rule  string
+		after time.Time
+		// The zero time if the rule has ended
+		want time.Time
+	}{
+		{"daily", date(2026, 1, 30), date(2026, 1, 31)},
+		{"FREQ=DAILY;INTERVAL=3", date(2026, 1, 30), date(2026, 2, 2)},
+		{"weekly", date(2026, 1, 1), date(2026, 1, 8)},
+		{"FREQ=WEEKLY;INTERVAL=2", date(2026, 1, 1), date(2026, 1, 15)},
+		{"yearly", date(2026, 3, 1), date(2027, 3, 1)},
+		{"FREQ=YEARLY;INTERVAL=2", date(2026, 3, 1), date(2028, 3, 1)},
+		{"yearly", date(2028, 2, 29), date(2029, 2, 28)},
+
+		// 2026-01-01 is a Thursday
+		{"FREQ=WEEKLY;BYDAY=MO,WE,FR", date(2026, 1, 1), date(2026, 1, 2)},
+		{"FREQ=WEEKLY;BYDAY=MO,WE,FR", date(2026, 1, 2), date(2026, 1, 5)},
+		{"FREQ=WEEKLY;BYDAY=TH", date(2026, 1, 1), date(2026, 1, 8)},
+		// Only every other week, counting from the week of "after", is eligible
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", date(2026, 1, 2), date(2026, 1, 12)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", date(2026, 1, 12), date(2026, 1, 16)},
+		// Weeks start on Monday, so Sunday is the end of the week
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU", date(2026, 1, 5), date(2026, 1, 11)},
+
+		// Months are clamped to their last day
+		{"monthly", date(2026, 1, 31), date(2026, 2, 28)},
+		{"monthly", date(2028, 1, 31), date(2028, 2, 29)},
+		{"FREQ=MONTHLY;INTERVAL=3", date(2026, 1, 31), date(2026, 4, 30)},
+		{"monthly", date(2026, 2, 28), date(2026, 3, 28)},
+
+		// Explicit month days are skipped in months that don't have them
+		{"FREQ=MONTHLY;BYMONTHDAY=31", date(2026, 1, 31), date(2026, 3, 31)},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", date(2026, 3, 31), date(2026, 5, 31)},
+		{"FREQ=MONTHLY;BYMONTHDAY=29", date(2027, 1, 29), date(2027, 3, 29)},
+		{"FREQ=MONTHLY;BYMONTHDAY=29", date(2028, 1, 29), date(2028, 2, 29)},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", date(2026, 1, 31), date(2026, 2, 28)},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", date(2026, 2, 28), date(2026, 3, 31)},
+		{"FREQ=MONTHLY;BYMONTHDAY=-2", date(2026, 2, 27), date(2026, 3, 30)},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", date(2026, 1, 1), date(2026, 1, 15)},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", date(2026, 1, 15), date(2026, 2, 1)},
+		{"FREQ=MONTHLY;BYMONTHDAY=15,-1", date(2026, 2, 15), date(2026, 2, 28)},
+		{"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=30", date(2026, 1, 30), date(2026, 3, 30)},
+		{"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=30", date(2025, 12, 30), date(2026, 4, 30)},
+
+		// UNTIL is inclusive
+		{"FREQ=DAILY;UNTIL=20260110T090000Z", date(2026, 1, 9), date(2026, 1, 10)},
+		{"FREQ=DAILY;UNTIL=20260110T090000Z", date(2026, 1, 10), time.Time{}},
+		{"FREQ=DAILY;UNTIL=20260110", date(2026, 1, 9), time.Time{}},
+		{"FREQ=MONTHLY;BYMONTHDAY=31;UNTIL=20260430", date(2026, 3, 31), time.Time{}},
+		// COUNT includes the current occurrence
+		{"FREQ=DAILY;COUNT=2", date(2026, 1, 9), date(2026, 1, 10)},
+		{"FREQ=DAILY;COUNT=1", date(2026, 1, 9), time.Time{}},
+
+		// The time of day stays the same across DST transitions, on 2026-03-08
+		// and 2026-11-01 in New York
+		{"daily", local(2026, 3, 7), local(2026, 3, 8)},
+		{"weekly", local(2026, 3, 7), local(2026, 3, 14)},
+		{"FREQ=WEEKLY;BYDAY=MO", local(2026, 3, 2), local(2026, 3, 9)},
+		{"FREQ=WEEKLY;BYDAY=SU", local(2026, 10, 25), local(2026, 11, 1)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", local(2026, 3, 3), local(2026, 3, 17)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU", local(2026, 10, 26), local(2026, 11, 1)},
+		{"monthly", local(2026, 2, 8), local(2026, 3, 8)},
+		{"FREQ=MONTHLY;BYMONTHDAY=1", local(2026, 10, 15), local(2026, 11, 1)},
+	} {
+		rule, err := parseRecurrence(test.rule)
+		if err != nil {
+			t.Errorf("parseRecurrence(%q): %v", test.rule, err)
+			continue
+		}
+		got, ok := rule.Next(test.after)
+		switch {
+		case test.want.IsZero() && ok:
+			t.Errorf("%s after %s: got %s, want the rule to have ended", test.rule, test.after, got)
+		case !test.want.IsZero() && !ok:
+			t.Errorf("%s after %s: ended, want %s", test.rule, test.after, test.want)
+		case !got.Equal(test.want):
+			t.Errorf("%s after %s: got %s, want %s", test.rule, test.after, got, test.want)
+		}
+	}
+}
+
+func TestRecurrenceNextRuleCountsDown(t *testing.T) {
+	rule, err := parseRecurrence("FREQ=WEEKLY;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	var occurrences []time.Time
+	for {
+		next, ok := rule.Next(after)
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, next)
+		after, rule = next, rule.NextRule()
+	}
+	// The first task and the two that follow it
+	if len(occurrences) != 2 || rule.String() != "FREQ=WEEKLY;COUNT=1" {
+		t.Errorf("occurrences %v, last rule %s", occurrences, rule)
+	}
+
+	unbounded, _ := parseRecurrence("weekly")
+	if got := unbounded.NextRule().String(); got != "FREQ=WEEKLY" {
+		t.Errorf("next rule %s, want FREQ=WEEKLY", got)
+	}
+}
@@ -0,0 +1,279 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Table schema
+
+// The full-text indexes are FTS5 virtual tables, so the binary must be built
+// with the sqlite_fts5 tag. They are kept up to date by the event handlers that
+// change task titles, comments and list titles.
+const searchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS task_fts_v1 USING fts5 (
+    task_id UNINDEXED,
+    title
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS task_comment_fts_v1 USING fts5 (
+    task_id UNINDEXED,
+    comment
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS task_list_fts_v1 USING fts5 (
+    list_id UNINDEXED,
+    title
+);
+`
+
+// Matches in search snippets are wrapped in U+E000 and U+E001, from Unicode's
+// private use area. Both are removed from the text when it is indexed, so they
+// only ever mark matches.
+const (
+	snippetMatchStart = "\uE000"
+	snippetMatchEnd   = "\uE001"
+)
+
+// Populates the indexes from existing data the first time they are created
+const backfillSearchV1Sql = `
+INSERT INTO task_fts_v1 (task_id, title)
+SELECT id, replace(replace(title, char(57344), ''), char(57345), '') FROM task_v1
+WHERE NOT EXISTS (SELECT 1 FROM task_fts_v1);
+
+INSERT INTO task_comment_fts_v1 (task_id, comment)
+SELECT task_id, replace(replace(user_comment, char(57344), ''), char(57345), '') FROM task_history_v1
+WHERE user_comment IS NOT NULL AND NOT EXISTS (SELECT 1 FROM task_comment_fts_v1);
+
+INSERT INTO task_list_fts_v1 (list_id, title)
+SELECT id, replace(replace(title, char(57344), ''), char(57345), '') FROM task_list_v1
+WHERE NOT EXISTS (SELECT 1 FROM task_list_fts_v1);
+`
+
+// InitSearch must run after the task, task list and history tables have been
+// initialized.
+func InitSearch(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing Search v1\n")
+	_, err := tx.Exec(searchSchema)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(backfillSearchV1Sql)
+	return err
+}
+
+// Index maintenance
+
+const deleteTaskSearchV1Sql = `
+DELETE FROM task_fts_v1 WHERE task_id = $1;
+`
+
+const insertTaskSearchV1Sql = `
+INSERT INTO task_fts_v1 (task_id, title)
+SELECT id, replace(replace(title, char(57344), ''), char(57345), '') FROM task_v1 WHERE id = $1;
+`
+
+const deleteTaskCommentSearchV1Sql = `
+DELETE FROM task_comment_fts_v1 WHERE task_id = $1;
+`
+
+const insertTaskCommentSearchV1Sql = `
+INSERT INTO task_comment_fts_v1 (task_id, comment)
+VALUES ($1, replace(replace($2, char(57344), ''), char(57345), ''));
+`
+
+const deleteTaskListSearchV1Sql = `
+DELETE FROM task_list_fts_v1 WHERE list_id = $1;
+`
+
+const insertTaskListSearchV1Sql = `
+INSERT INTO task_list_fts_v1 (list_id, title)
+SELECT id, replace(replace(title, char(57344), ''), char(57345), '') FROM task_list_v1 WHERE id = $1;
+`
+
+// indexTask (re)indexes a task's title from task_v1.
+func indexTask(tx *sqlx.Tx, taskId int) error {
+	_, err := tx.Exec(deleteTaskSearchV1Sql, taskId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(insertTaskSearchV1Sql, taskId)
+	return err
+}
+
+func indexTaskComment(tx *sqlx.Tx, taskId int, comment string) error {
+	_, err := tx.Exec(insertTaskCommentSearchV1Sql, taskId, comment)
+	return err
+}
+
+// unindexTask removes a task's title and comments from the index.
+func unindexTask(tx *sqlx.Tx, taskId int) error {
+	_, err := tx.Exec(deleteTaskSearchV1Sql, taskId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(deleteTaskCommentSearchV1Sql, taskId)
+	return err
+}
+
+// indexTaskList (re)indexes a task list's title from task_list_v1.
+func indexTaskList(tx *sqlx.Tx, listId int) error {
+	_, err := tx.Exec(deleteTaskListSearchV1Sql, listId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(insertTaskListSearchV1Sql, listId)
+	return err
+}
+
+// State queries
+
+// bm25() returns lower values for better matches. Each index has its own
+// statistics, so ranks are only comparable within an index; see searchIndex.
+const searchTaskTitlesV1Sql = `
+SELECT task_id AS id, 'title' AS field, snippet(task_fts_v1, 1, char(57344), char(57345), '…', 12) AS snippet, bm25(task_fts_v1) AS rank
+FROM task_fts_v1
+WHERE task_fts_v1 MATCH $1
+ORDER BY rank
+LIMIT 100;
+`
+
+const searchTaskCommentsV1Sql = `
+SELECT task_id AS id, 'comment' AS field, snippet(task_comment_fts_v1, 1, char(57344), char(57345), '…', 12) AS snippet, bm25(task_comment_fts_v1) AS rank
+FROM task_comment_fts_v1
+WHERE task_comment_fts_v1 MATCH $1
+ORDER BY rank
+LIMIT 100;
+`
+
+const searchTaskListsV1Sql = `
+SELECT list_id AS id, 'list_title' AS field, snippet(task_list_fts_v1, 1, char(57344), char(57345), '…', 12) AS snippet, bm25(task_list_fts_v1) AS rank
+FROM task_list_fts_v1
+WHERE task_list_fts_v1 MATCH $1
+ORDER BY rank
+LIMIT 20;
+`
+
+const getListsForTaskV1Sql = `
+SELECT tl.id, tl.title, tl.category, tl.archived
+FROM task_to_list_v1 ttl
+JOIN task_list_v1 tl ON tl.id = ttl.list_id
+WHERE ttl.task_id = $1
+ORDER BY tl.sort_key;
+`
+
+type searchMatch struct {
+	Id      int     `db:"id"`
+	Field   string  `db:"field"`
+	Snippet string  `db:"snippet"`
+	Rank    float64 `db:"rank"`
+	Score   float64
+}
+
+// searchIndex runs a query against one index and scores its matches relative
+// to the best one, from 0 to 1. bm25() values depend on the index's own term
+// and length statistics, so this is what makes matches from different indexes
+// comparable.
+func searchIndex(db *sqlx.DB, indexQuery string, query string) ([]searchMatch, error) {
+	var matches []searchMatch
+	err := db.Select(&matches, indexQuery, query)
+	if err != nil || len(matches) == 0 {
+		return matches, err
+	}
+	best := matches[0].Rank
+	for i := range matches {
+		if best < 0 {
+			matches[i].Score = matches[i].Rank / best
+		} else {
+			matches[i].Score = 1
+		}
+	}
+	return matches, nil
+}
+
+// ftsQuery turns free-form user input into an FTS5 query where every term must
+// match as a word prefix. Terms are quoted so that FTS5 operators and
+// punctuation in the input can't cause syntax errors.
+func ftsQuery(input string) string {
+	var terms []string
+	for _, term := range strings.Fields(input) {
+		terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+func (r *StateResolver) GetApiSearch(db *sqlx.DB, q string) (generated.SearchResponse, error) {
+	results := make([]generated.SearchResult, 0)
+	query := ftsQuery(q)
+	if query == "" {
+		return generated.SearchResponse{Results: results}, nil
+	}
+
+	titleMatches, err := searchIndex(db, searchTaskTitlesV1Sql, query)
+	if err != nil {
+		return generated.SearchResponse{}, err
+	}
+	commentMatches, err := searchIndex(db, searchTaskCommentsV1Sql, query)
+	if err != nil {
+		return generated.SearchResponse{}, err
+	}
+	taskMatches := append(titleMatches, commentMatches...)
+	sort.SliceStable(taskMatches, func(i, j int) bool {
+		return taskMatches[i].Score > taskMatches[j].Score
+	})
+	seen := make(map[int]bool)
+	for _, match := range taskMatches {
+		// A task can match on its title and several comments; only the best
+		// match is returned
+		if seen[match.Id] {
+			continue
+		}
+		seen[match.Id] = true
+
+		task, err := r.GetApiTaskGet(db, match.Id)
+		if err != nil {
+			return generated.SearchResponse{}, err
+		}
+		lists := make([]generated.TaskList, 0)
+		err = db.Select(&lists, getListsForTaskV1Sql, match.Id)
+		if err != nil {
+			return generated.SearchResponse{}, err
+		}
+		results = append(results, generated.SearchResult{
+			Kind:         generated.SearchResultKindTask,
+			MatchedField: match.Field,
+			Snippet:      match.Snippet,
+			Score:        match.Score,
+			Task:         &task,
+			Lists:        lists,
+		})
+	}
+
+	listMatches, err := searchIndex(db, searchTaskListsV1Sql, query)
+	if err != nil {
+		return generated.SearchResponse{}, err
+	}
+	for _, match := range listMatches {
+		list, err := r.GetApiTasklistGet(db, match.Id)
+		if err != nil {
+			return generated.SearchResponse{}, err
+		}
+		results = append(results, generated.SearchResult{
+			Kind:         generated.SearchResultKindList,
+			MatchedField: match.Field,
+			Snippet:      match.Snippet,
+			Score:        match.Score,
+			List:         &list,
+			Lists:        make([]generated.TaskList, 0),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return generated.SearchResponse{Results: results}, nil
+}
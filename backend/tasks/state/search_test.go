@@ -0,0 +1,160 @@
+package state
+
+import (
+	"testing"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestSearch(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	r := NewResolver()
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Groceries", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Buy oat milk", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Renew passport", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddCommentEvent, generated.TaskAddCommentEvent{TaskId: 2, UserComment: "Bring two passport photos"})
+
+	search := func(q string) []generated.SearchResult {
+		t.Helper()
+		response, err := r.GetApiSearch(db, q)
+		if err != nil {
+			t.Fatalf("search for %q: %v", q, err)
+		}
+		return response.Results
+	}
+	for _, test := range []struct {
+		query   string
+		kind    generated.SearchResultKind
+		id      int
+		field   string
+		snippet string
+	}{
+		// Every term matches as a word prefix
+		{"mil", "task", 1, "title", "Buy oat " + snippetMatchStart + "milk" + snippetMatchEnd},
+		{"oat MILK", "task", 1, "title", "Buy " + snippetMatchStart + "oat" + snippetMatchEnd + " " + snippetMatchStart + "milk" + snippetMatchEnd},
+		{"photo", "task", 2, "comment", "Bring two passport " + snippetMatchStart + "photos" + snippetMatchEnd},
+		{"groc", "list", 1, "list_title", snippetMatchStart + "Groceries" + snippetMatchEnd},
+	} {
+		results := search(test.query)
+		if len(results) != 1 {
+			t.Errorf("search for %q: %d results, want 1", test.query, len(results))
+			continue
+		}
+		result := results[0]
+		id := 0
+		if result.Task != nil {
+			id = result.Task.Id
+		} else if result.List != nil {
+			id = result.List.Id
+		}
+		if result.Kind != test.kind || id != test.id || result.MatchedField != test.field || result.Snippet != test.snippet {
+			t.Errorf("search for %q: %s %d matched %s with %q, want %s %d matched %s with %q",
+				test.query, result.Kind, id, result.MatchedField, result.Snippet, test.kind, test.id, test.field, test.snippet)
+		}
+	}
+
+	// A task that matches on its title and a comment is only returned once,
+	// along with its lists
+	results := search("passport")
+	if len(results) != 1 || results[0].Task == nil || results[0].Task.Id != 2 {
+		t.Fatalf("search for passport: %+v, want task 2 once", results)
+	}
+	if len(results[0].Lists) != 1 || results[0].Lists[0].Title != "Groceries" {
+		t.Errorf("search for passport: lists %+v, want Groceries", results[0].Lists)
+	}
+
+	// FTS5 syntax in the query is searched for as text
+	for _, query := range []string{"", "   ", `milk" OR`, "NEAR(", "*", "title:milk"} {
+		search(query)
+	}
+
+	// Changing a title updates the index
+	apply(t, db, h.HandleTaskUpdateTitleEvent, generated.TaskUpdateTitleEvent{TaskId: 1, Title: "Buy rice"})
+	if results := search("milk"); len(results) != 0 {
+		t.Errorf("search for milk after renaming: %+v", results)
+	}
+	if results := search("rice"); len(results) != 1 {
+		t.Errorf("search for rice after renaming: %+v", results)
+	}
+}
+
+func TestSearchSnippetMarkers(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	r := NewResolver()
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Wiki [[pages]]", Category: generated.TaskListCategoryToDoList})
+	// Neither brackets nor the marker characters in the text can be mistaken
+	// for a match
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Link [[wiki]] page " + snippetMatchStart + "x" + snippetMatchEnd, TaskListId: 1})
+
+	for _, test := range []struct {
+		query string
+		kind  generated.SearchResultKind
+		want  string
+	}{
+		{"wiki", "task", "Link [[" + snippetMatchStart + "wiki" + snippetMatchEnd + "]] page x"},
+		{"page", "task", "Link [[wiki]] " + snippetMatchStart + "page" + snippetMatchEnd + " x"},
+		{"pages", "list", "Wiki [[" + snippetMatchStart + "pages" + snippetMatchEnd + "]]"},
+	} {
+		response, err := r.GetApiSearch(db, test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, result := range response.Results {
+			if result.Kind != test.kind {
+				continue
+			}
+			found = true
+			if result.Snippet != test.want {
+				t.Errorf("search for %q: %s snippet %q, want %q", test.query, test.kind, result.Snippet, test.want)
+			}
+		}
+		if !found {
+			t.Errorf("search for %q: no %s result in %+v", test.query, test.kind, response.Results)
+		}
+	}
+}
+
+func TestSearchScoresAreRelativeToEachIndex(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	r := NewResolver()
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Milk run", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Milk", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Buy oat milk, bread, eggs, butter and flour", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Bake a cake", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddCommentEvent, generated.TaskAddCommentEvent{TaskId: 3, UserComment: "Needs milk"})
+
+	response, err := r.GetApiSearch(db, "milk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := make(map[string]float64)
+	for i, result := range response.Results {
+		if result.Score <= 0 || result.Score > 1 {
+			t.Errorf("result %d has score %v, want a score from 0 to 1", i, result.Score)
+		}
+		if i > 0 && result.Score > response.Results[i-1].Score {
+			t.Errorf("result %d scores higher than the one before it", i)
+		}
+		key := string(result.Kind)
+		if result.Task != nil {
+			key += " " + result.Task.Title
+		}
+		scores[key] = result.Score
+	}
+	if len(scores) != 4 {
+		t.Fatalf("results %+v, want three tasks and a list", response.Results)
+	}
+	// The best match in each index scores 1
+	for _, key := range []string{"task Milk", "task Bake a cake", "list"} {
+		if scores[key] != 1 {
+			t.Errorf("%s scores %v, want 1", key, scores[key])
+		}
+	}
+	if score := scores["task Buy oat milk, bread, eggs, butter and flour"]; score >= 1 {
+		t.Errorf("a longer title scores %v, want less than the best title", score)
+	}
+}
@@ -0,0 +1,219 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Smart lists have no rows in task_to_list_v1; their contents are computed
+// from the filter stored here every time they are queried.
+
+// Table schema
+const taskListFilterSchema = `
+CREATE TABLE IF NOT EXISTS task_list_filter_v1 (
+    list_id INTEGER PRIMARY KEY NOT NULL,
+    due_within_days INTEGER,
+    include_completed BOOLEAN NOT NULL,
+    label_ids TEXT NOT NULL,
+    list_ids TEXT NOT NULL,
+    FOREIGN KEY (list_id) REFERENCES task_list_v1(id)
+);
+`
+
+func InitTaskListFilter(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing TaskListFilter v1\n")
+	_, err := tx.Exec(taskListFilterSchema)
+	return err
+}
+
+// Event handler
+const upsertTaskListFilterV1Sql = `
+INSERT INTO task_list_filter_v1 (list_id, due_within_days, include_completed, label_ids, list_ids)
+VALUES (:list_id, :due_within_days, :include_completed, :label_ids, :list_ids)
+ON CONFLICT (list_id) DO UPDATE SET
+    due_within_days = excluded.due_within_days,
+    include_completed = excluded.include_completed,
+    label_ids = excluded.label_ids,
+    list_ids = excluded.list_ids;
+`
+
+type taskListFilterRow struct {
+	ListId           int    `db:"list_id"`
+	DueWithinDays    *int   `db:"due_within_days"`
+	IncludeCompleted bool   `db:"include_completed"`
+	LabelIds         string `db:"label_ids"`
+	ListIds          string `db:"list_ids"`
+}
+
+func (h *StateEventHandler) HandleTaskListUpdateFilterEvent(tx *sqlx.Tx, event *generated.TaskListUpdateFilterEvent) (bool, error) {
+	fmt.Printf("TaskListFilter v1: UpdateFilterEvent %d\n", event.ListId)
+	var category generated.TaskListCategory
+	err := tx.Get(&category, getTaskListCategoryV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	if category != generated.TaskListCategorySmart {
+		return true, fmt.Errorf("list %d is not a smart list", event.ListId)
+	}
+	inverse, err := undoTaskListUpdateFilter(tx, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:UpdateFilter", event.Timestamp, inverse); err != nil {
+		return true, err
+	}
+
+	labelIds, err := json.Marshal(nonNilInts(event.LabelIds))
+	if err != nil {
+		return true, err
+	}
+	listIds, err := json.Marshal(nonNilInts(event.ListIds))
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(upsertTaskListFilterV1Sql, taskListFilterRow{
+		ListId:           event.ListId,
+		DueWithinDays:    event.DueWithinDays,
+		IncludeCompleted: event.IncludeCompleted,
+		LabelIds:         string(labelIds),
+		ListIds:          string(listIds),
+	})
+	return true, err
+}
+
+func nonNilInts(values []int) []int {
+	if values == nil {
+		return []int{}
+	}
+	return values
+}
+
+// State queries
+const getTaskListFilterV1Sql = `
+SELECT list_id, due_within_days, include_completed, label_ids, list_ids
+FROM task_list_filter_v1
+WHERE list_id = $1;
+`
+
+const getSmartTaskListIdsV1Sql = `
+SELECT id FROM task_list_v1 WHERE category = 'smart' AND id NOT IN (SELECT list_id FROM task_list_trash_v1);
+`
+
+const getTasksForFilterV1Sql = `
+SELECT t.id, t.title, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat, t.priority, r.rule AS recurrence,
+    tp.parent_id AS parenttaskid, tp.position AS subtaskposition,
+    (SELECT COUNT(*) FROM task_parent_v1 c WHERE c.parent_id = t.id) AS subtaskcount,
+    (SELECT COUNT(*) FROM task_parent_v1 c JOIN task_v1 ct ON ct.id = c.task_id
+     WHERE c.parent_id = t.id AND ct.completed_at IS NOT NULL) AS subtaskscompleted
+FROM task_v1 t
+LEFT JOIN task_recurrence_v1 r ON r.task_id = t.id
+LEFT JOIN task_parent_v1 tp ON tp.task_id = t.id
+WHERE %s
+ORDER BY t.due_date IS NULL, julianday(t.due_date), t.id;
+`
+
+// getTaskListFilter returns the filter for a smart list. A smart list whose
+// filter hasn't been set yet shows all incomplete tasks.
+func getTaskListFilter(db sqlx.Queryer, listId int) (generated.TaskListFilter, error) {
+	filter := generated.TaskListFilter{
+		ListId:   listId,
+		LabelIds: []int{},
+		ListIds:  []int{},
+	}
+	var row taskListFilterRow
+	err := sqlx.Get(db, &row, getTaskListFilterV1Sql, listId)
+	if err == sql.ErrNoRows {
+		return filter, nil
+	}
+	if err != nil {
+		return filter, err
+	}
+	filter.DueWithinDays = row.DueWithinDays
+	filter.IncludeCompleted = row.IncludeCompleted
+	if err = json.Unmarshal([]byte(row.LabelIds), &filter.LabelIds); err != nil {
+		return filter, err
+	}
+	err = json.Unmarshal([]byte(row.ListIds), &filter.ListIds)
+	return filter, err
+}
+
+// getTasksForFilter evaluates a smart list filter. Tasks are ordered by due
+// date, with tasks without a due date last.
+func getTasksForFilter(db *sqlx.DB, filter generated.TaskListFilter, now time.Time) ([]subtaskRow, error) {
+	// Tasks that were only in lists that have since been deleted are excluded
+	conditions := []string{"EXISTS (SELECT 1 FROM task_to_list_v1 x WHERE x.task_id = t.id)"}
+	var args []interface{}
+	if filter.DueWithinDays != nil {
+		// Due dates are stored with the offset they were set with, so they
+		// have to be compared as times rather than as text
+		conditions = append(conditions, "t.due_date IS NOT NULL AND julianday(t.due_date) < julianday(?)")
+		args = append(args, now.UTC().AddDate(0, 0, *filter.DueWithinDays))
+	}
+	if !filter.IncludeCompleted {
+		conditions = append(conditions, "t.completed_at IS NULL")
+	}
+	if len(filter.LabelIds) > 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM task_to_list_v1 x WHERE x.task_id = t.id AND x.list_id IN (?))")
+		args = append(args, filter.LabelIds)
+	}
+	if len(filter.ListIds) > 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM task_to_list_v1 x WHERE x.task_id = t.id AND x.list_id IN (?))")
+		args = append(args, filter.ListIds)
+	}
+
+	query, args, err := sqlx.In(fmt.Sprintf(getTasksForFilterV1Sql, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]subtaskRow, 0)
+	err = db.Select(&rows, db.Rebind(query), args...)
+	return rows, err
+}
+
+func (r *StateResolver) GetApiTasklistFilter(db *sqlx.DB, listId int) (generated.TaskListFilter, error) {
+	return getTaskListFilter(db, listId)
+}
+
+func (r *StateResolver) GetApiTasklistSmart(db *sqlx.DB) (generated.TaskListResponse, error) {
+	var taskLists []generated.TaskList = make([]generated.TaskList, 0)
+	err := db.Select(&taskLists, getCategoryTaskListsV1Sql, generated.TaskListCategorySmart)
+	return generated.TaskListResponse{TaskLists: taskLists}, err
+}
+
+// getSmartTaskListMetadata computes the total & completed counts for every
+// smart list.
+func getSmartTaskListMetadata(db *sqlx.DB, includeSnoozed bool, now time.Time) ([]generated.TaskListMetadata, error) {
+	var listIds []int
+	err := db.Select(&listIds, getSmartTaskListIdsV1Sql)
+	if err != nil {
+		return nil, err
+	}
+	metadata := make([]generated.TaskListMetadata, 0, len(listIds))
+	for _, listId := range listIds {
+		filter, err := getTaskListFilter(db, listId)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := getTasksForFilter(db, filter, now)
+		if err != nil {
+			return nil, err
+		}
+		if !includeSnoozed {
+			rows = withoutSnoozedTasks(rows, now)
+		}
+		entry := generated.TaskListMetadata{ListId: listId, Total: len(rows)}
+		for _, row := range rows {
+			if row.CompletedAt != nil {
+				entry.Completed++
+			}
+		}
+		metadata = append(metadata, entry)
+	}
+	return metadata, nil
+}
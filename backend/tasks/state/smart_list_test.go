@@ -0,0 +1,51 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestSmartListDueWindowComparesTimes(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	pacific := time.FixedZone("PST", -8*60*60)
+	// 07:30 UTC on the 11th, but earlier as text than the task below
+	lateEvening := time.Date(2026, 3, 10, 23, 30, 0, 0, pacific)
+	earlyMorning := time.Date(2026, 3, 11, 5, 0, 0, 0, time.UTC)
+	overdue := time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Late evening", TaskListId: 1, DueDate: &lateEvening})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Early morning", TaskListId: 1, DueDate: &earlyMorning})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Overdue", TaskListId: 1, DueDate: &overdue})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Someday", TaskListId: 1})
+
+	now := time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		days int
+		want []string
+	}{
+		{1, []string{"Overdue", "Early morning"}},
+		{2, []string{"Overdue", "Early morning", "Late evening"}},
+	} {
+		rows, err := getTasksForFilter(db, generated.TaskListFilter{DueWithinDays: &test.days}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, row := range rows {
+			got = append(got, row.Title)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("due within %d days: %q, want %q", test.days, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("due within %d days: %q, want %q", test.days, got, test.want)
+				break
+			}
+		}
+	}
+}
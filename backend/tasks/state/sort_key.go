@@ -0,0 +1,449 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tasks within a list, and the lists themselves, are ordered by a sort key
+// rather than an integer position. Keys compare as plain strings, and there is
+// always a key between any two others, so moving an item only rewrites that
+// item's key. The keys are unique (enforced by an index), and since a new key
+// is always strictly between its neighbours, two reorders into the same gap
+// end up in a well-defined order instead of with the same position.
+//
+// The format is the one used by https://github.com/rocicorp/fractional-indexing:
+// an "integer" part whose length is given by its first character, followed by
+// a fractional part with no trailing zeros. Appending to the end of a list only
+// increments the integer part, so keys stay short in the common case.
+
+const sortKeyDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// The smallest possible integer part, which can't be decremented any further
+const sortKeyMinInteger = "A00000000000000000000000000"
+
+// firstSortKey is the key given to the only item in an empty list.
+const firstSortKey = "a0"
+
+// sortKeyBetween returns a key that sorts after a and before b. An empty string
+// means there is no bound on that side.
+func sortKeyBetween(a, b string) (string, error) {
+	if a != "" {
+		if err := validateSortKey(a); err != nil {
+			return "", err
+		}
+	}
+	if b != "" {
+		if err := validateSortKey(b); err != nil {
+			return "", err
+		}
+	}
+	if a != "" && b != "" && a >= b {
+		return "", fmt.Errorf("sort key %q is not before %q", a, b)
+	}
+
+	if a == "" {
+		if b == "" {
+			return firstSortKey, nil
+		}
+		ib, _ := sortKeyIntegerPart(b)
+		fb := b[len(ib):]
+		if ib == sortKeyMinInteger {
+			return ib + sortKeyMidpoint("", fb), nil
+		}
+		if ib < b {
+			return ib, nil
+		}
+		key, ok := decrementSortKeyInteger(ib)
+		if !ok {
+			return "", fmt.Errorf("cannot sort before %q", b)
+		}
+		return key, nil
+	}
+
+	ia, _ := sortKeyIntegerPart(a)
+	fa := a[len(ia):]
+	if b == "" {
+		key, ok := incrementSortKeyInteger(ia)
+		if !ok {
+			return ia + sortKeyMidpoint(fa, ""), nil
+		}
+		return key, nil
+	}
+
+	ib, _ := sortKeyIntegerPart(b)
+	fb := b[len(ib):]
+	if ia == ib {
+		return ia + sortKeyMidpoint(fa, fb), nil
+	}
+	key, ok := incrementSortKeyInteger(ia)
+	if !ok {
+		return "", fmt.Errorf("cannot sort after %q", a)
+	}
+	if key < b {
+		return key, nil
+	}
+	return ia + sortKeyMidpoint(fa, ""), nil
+}
+
+// sortKeyMidpoint returns a fractional part between a and b, where an empty b
+// means there is no upper bound.
+func sortKeyMidpoint(a, b string) string {
+	if b != "" {
+		// Skip the common prefix, treating missing digits of a as zeros
+		n := 0
+		for n < len(b) && sortKeyDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + sortKeyMidpoint(sliceFrom(a, n), b[n:])
+		}
+	}
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(sortKeyDigits, a[0])
+	}
+	digitB := len(sortKeyDigits)
+	if b != "" {
+		digitB = strings.IndexByte(sortKeyDigits, b[0])
+	}
+	if digitB-digitA > 1 {
+		return string(sortKeyDigits[(digitA+digitB+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(sortKeyDigits[digitA]) + sortKeyMidpoint(sliceFrom(a, 1), "")
+}
+
+func sortKeyDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return sortKeyDigits[0]
+}
+
+func sliceFrom(s string, i int) string {
+	if i >= len(s) {
+		return ""
+	}
+	return s[i:]
+}
+
+func sortKeyIntegerLength(head byte) (int, error) {
+	switch {
+	case head >= 'a' && head <= 'z':
+		return int(head-'a') + 2, nil
+	case head >= 'A' && head <= 'Z':
+		return int('Z'-head) + 2, nil
+	}
+	return 0, fmt.Errorf("invalid sort key head %q", head)
+}
+
+func sortKeyIntegerPart(key string) (string, error) {
+	length, err := sortKeyIntegerLength(key[0])
+	if err != nil {
+		return "", err
+	}
+	if length > len(key) {
+		return "", fmt.Errorf("invalid sort key %q", key)
+	}
+	return key[:length], nil
+}
+
+func validateSortKey(key string) error {
+	if key == sortKeyMinInteger {
+		return fmt.Errorf("invalid sort key %q", key)
+	}
+	integer, err := sortKeyIntegerPart(key)
+	if err != nil {
+		return err
+	}
+	if len(key) > len(integer) && key[len(key)-1] == sortKeyDigits[0] {
+		return fmt.Errorf("invalid sort key %q", key)
+	}
+	for i := 1; i < len(key); i++ {
+		if strings.IndexByte(sortKeyDigits, key[i]) < 0 {
+			return fmt.Errorf("invalid sort key %q", key)
+		}
+	}
+	return nil
+}
+
+// incrementSortKeyInteger returns the next integer part, or false once the
+// largest integer has been reached.
+func incrementSortKeyInteger(x string) (string, bool) {
+	head, digits := x[0], []byte(x[1:])
+	carry := true
+	for i := len(digits) - 1; carry && i >= 0; i-- {
+		d := strings.IndexByte(sortKeyDigits, digits[i]) + 1
+		if d == len(sortKeyDigits) {
+			digits[i] = sortKeyDigits[0]
+		} else {
+			digits[i] = sortKeyDigits[d]
+			carry = false
+		}
+	}
+	if !carry {
+		return string(head) + string(digits), true
+	}
+	switch head {
+	case 'Z':
+		return "a" + string(sortKeyDigits[0]), true
+	case 'z':
+		return "", false
+	}
+	head++
+	if head > 'a' {
+		digits = append(digits, sortKeyDigits[0])
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
+
+// decrementSortKeyInteger returns the previous integer part, or false once the
+// smallest integer has been reached.
+func decrementSortKeyInteger(x string) (string, bool) {
+	head, digits := x[0], []byte(x[1:])
+	last := sortKeyDigits[len(sortKeyDigits)-1]
+	borrow := true
+	for i := len(digits) - 1; borrow && i >= 0; i-- {
+		d := strings.IndexByte(sortKeyDigits, digits[i]) - 1
+		if d == -1 {
+			digits[i] = last
+		} else {
+			digits[i] = sortKeyDigits[d]
+			borrow = false
+		}
+	}
+	if !borrow {
+		return string(head) + string(digits), true
+	}
+	switch head {
+	case 'a':
+		return "Z" + string(last), true
+	case 'A':
+		return "", false
+	}
+	head--
+	if head < 'Z' {
+		digits = append(digits, last)
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
+
+// Tasks in a list
+const getTaskSortKeyV1Sql = `
+SELECT sort_key FROM task_to_list_v1 WHERE list_id = $1 AND task_id = $2;
+`
+
+const getLastTaskSortKeyV1Sql = `
+SELECT COALESCE(MAX(sort_key), '') FROM task_to_list_v1 WHERE list_id = $1;
+`
+
+// The task being moved is skipped when looking for its new neighbours
+const getNextTaskSortKeyV1Sql = `
+SELECT COALESCE(MIN(sort_key), '') FROM task_to_list_v1
+WHERE list_id = $1 AND sort_key > $2 AND task_id != $3;
+`
+
+const getPreviousTaskSortKeyV1Sql = `
+SELECT COALESCE(MAX(sort_key), '') FROM task_to_list_v1
+WHERE list_id = $1 AND sort_key < $2 AND task_id != $3;
+`
+
+const getTaskSortKeyTakenV1Sql = `
+SELECT COUNT(*) FROM task_to_list_v1 WHERE list_id = $1 AND sort_key = $2;
+`
+
+const insertTaskToListV1Sql = `
+INSERT INTO task_to_list_v1 (task_id, list_id, sort_key)
+VALUES ($1, $2, $3)
+ON CONFLICT (task_id, list_id) DO NOTHING;
+`
+
+const updateTaskSortKeyV1Sql = `
+UPDATE task_to_list_v1
+SET sort_key = $1
+WHERE list_id = $2 AND task_id = $3;
+`
+
+// appendTaskToList adds a task to the end of a list, unless it is already in
+// the list.
+func appendTaskToList(tx *sqlx.Tx, taskId, listId int) error {
+	var last string
+	err := tx.Get(&last, getLastTaskSortKeyV1Sql, listId)
+	if err != nil {
+		return err
+	}
+	key, err := sortKeyBetween(last, "")
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(insertTaskToListV1Sql, taskId, listId, key)
+	return err
+}
+
+const getListIdsForTaskV1Sql = `
+SELECT list_id FROM task_to_list_v1 WHERE task_id = $1 ORDER BY list_id;
+`
+
+// appendTaskToListsOf adds a task to the end of every list that another task
+// is in.
+func appendTaskToListsOf(tx *sqlx.Tx, taskId, sourceTaskId int) error {
+	var listIds []int
+	err := tx.Select(&listIds, getListIdsForTaskV1Sql, sourceTaskId)
+	if err != nil {
+		return err
+	}
+	for _, listId := range listIds {
+		if err = appendTaskToList(tx, taskId, listId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertTaskToListAt adds a task to a list with a key it had before, e.g. when
+// it is restored from the trash. If another task has been given the same key
+// in the meantime, the task goes right after it.
+func insertTaskToListAt(tx *sqlx.Tx, taskId, listId int, key string) error {
+	var taken int
+	err := tx.Get(&taken, getTaskSortKeyTakenV1Sql, listId, key)
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		var next string
+		err = tx.Get(&next, getNextTaskSortKeyV1Sql, listId, key, taskId)
+		if err != nil {
+			return err
+		}
+		key, err = sortKeyBetween(key, next)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(insertTaskToListV1Sql, taskId, listId, key)
+	return err
+}
+
+// getTaskSortKey returns the key of a task in a list.
+func getTaskSortKey(tx *sqlx.Tx, listId, taskId int) (string, error) {
+	var key string
+	err := tx.Get(&key, getTaskSortKeyV1Sql, listId, taskId)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("task %d is not in list %d", taskId, listId)
+	}
+	return key, err
+}
+
+// Task lists
+const getTaskListSortKeyV1Sql = `
+SELECT sort_key FROM task_list_v1 WHERE id = $1;
+`
+
+const getLastTaskListSortKeyV1Sql = `
+SELECT COALESCE(MAX(sort_key), '') FROM task_list_v1;
+`
+
+const getNextTaskListSortKeyV1Sql = `
+SELECT COALESCE(MIN(sort_key), '') FROM task_list_v1
+WHERE sort_key > $1 AND id != $2;
+`
+
+const updateTaskListSortKeyV1Sql = `
+UPDATE task_list_v1
+SET sort_key = $1
+WHERE id = $2;
+`
+
+// nextTaskListSortKey returns the key for a list added after all other lists.
+func nextTaskListSortKey(tx *sqlx.Tx) (string, error) {
+	var last string
+	err := tx.Get(&last, getLastTaskListSortKeyV1Sql)
+	if err != nil {
+		return "", err
+	}
+	return sortKeyBetween(last, "")
+}
+
+// Migration from integer positions to sort keys. Rows are given keys in their
+// current order, so duplicate positions left behind by the old renumbering are
+// resolved at the same time.
+const addSortKeysV1Sql = `
+ALTER TABLE task_to_list_v1 ADD COLUMN sort_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE task_list_v1 ADD COLUMN sort_key TEXT NOT NULL DEFAULT '';
+`
+
+const getTaskToListPositionsV1Sql = `
+SELECT list_id AS groupid, task_id AS id FROM task_to_list_v1 ORDER BY list_id, position, task_id;
+`
+
+const getTaskListPositionsV1Sql = `
+SELECT 0 AS groupid, id FROM task_list_v1 ORDER BY position, id;
+`
+
+const dropPositionsV1Sql = `
+ALTER TABLE task_to_list_v1 DROP COLUMN position;
+ALTER TABLE task_list_v1 DROP COLUMN position;
+
+CREATE UNIQUE INDEX IF NOT EXISTS task_to_list_sort_key_v1 ON task_to_list_v1 (list_id, sort_key);
+CREATE UNIQUE INDEX IF NOT EXISTS task_list_sort_key_v1 ON task_list_v1 (sort_key);
+`
+
+func migrateToSortKeys(tx *sqlx.Tx) error {
+	_, err := tx.Exec(addSortKeysV1Sql)
+	if err != nil {
+		return err
+	}
+	err = backfillSortKeys(tx, getTaskToListPositionsV1Sql, func(key string, listId, taskId int) error {
+		_, err := tx.Exec(updateTaskSortKeyV1Sql, key, listId, taskId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = backfillSortKeys(tx, getTaskListPositionsV1Sql, func(key string, _, listId int) error {
+		_, err := tx.Exec(updateTaskListSortKeyV1Sql, key, listId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(dropPositionsV1Sql)
+	return err
+}
+
+// backfillSortKeys gives the rows of each group ascending keys, in the order
+// returned by selectSql.
+func backfillSortKeys(tx *sqlx.Tx, selectSql string, update func(key string, groupId, id int) error) error {
+	var rows []struct {
+		GroupId int `db:"groupid"`
+		Id      int `db:"id"`
+	}
+	err := tx.Select(&rows, selectSql)
+	if err != nil {
+		return err
+	}
+	key := ""
+	for i, row := range rows {
+		if i > 0 && row.GroupId != rows[i-1].GroupId {
+			key = ""
+		}
+		key, err = sortKeyBetween(key, "")
+		if err != nil {
+			return err
+		}
+		if err = update(key, row.GroupId, row.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
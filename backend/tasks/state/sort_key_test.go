@@ -0,0 +1,232 @@
+package state
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestSortKeyBetween(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want string
+	}{
+		{"", "", "a0"},
+		// Appending and prepending only change the integer part
+		{"a0", "", "a1"},
+		{"a1", "", "a2"},
+		{"", "a0", "Zz"},
+		{"", "a5", "a4"},
+		{"", "a0V", "a0"},
+		{"", "b999", "b99"},
+		// Between neighbours
+		{"a0", "a1", "a0V"},
+		{"a1", "a2", "a1V"},
+		{"a0V", "a1", "a0l"},
+		{"a0", "a0V", "a0G"},
+		{"Zz", "a0", "ZzV"},
+		{"Zz", "a01", "a0"},
+		{"a0", "a2", "a1"},
+		{"a0z", "a1", "a0zV"},
+		{"a0y", "a0z", "a0yV"},
+		{"a0yz", "a0z", "a0yzV"},
+		// The integer part carries into a longer one at the end of a digit
+		// range, and borrows into a shorter one at the start
+		{"a9", "", "aA"},
+		{"az", "", "b00"},
+		{"b0z", "", "b10"},
+		{"bzz", "", "c000"},
+		{"Zz", "", "a0"},
+		{"Yzz", "", "Z0"},
+		{"", "b00", "az"},
+		{"", "a0", "Zz"},
+		{"", "Z0", "Yzz"},
+		// There is no integer after the largest one, so the key gets longer
+		{"z" + strings.Repeat("z", 26), "", "z" + strings.Repeat("z", 26) + "V"},
+		{"", "A" + strings.Repeat("0", 26) + "1", "A" + strings.Repeat("0", 26) + "0V"},
+	} {
+		got, err := sortKeyBetween(test.a, test.b)
+		if err != nil {
+			t.Errorf("sortKeyBetween(%q, %q): %v", test.a, test.b, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("sortKeyBetween(%q, %q) = %q, want %q", test.a, test.b, got, test.want)
+		}
+		if err = validateSortKey(got); err != nil {
+			t.Errorf("sortKeyBetween(%q, %q): %v", test.a, test.b, err)
+		}
+	}
+}
+
+func TestSortKeyBetweenRejectsInvalidBounds(t *testing.T) {
+	for _, test := range []struct{ a, b string }{
+		{"a1", "a0"},
+		{"a0", "a0"},
+		// Trailing zeros in the fractional part
+		{"a00", ""},
+		{"", "a0V0"},
+		// The integer part is longer than the key
+		{"b0", ""},
+		{"", "!0"},
+		{"a!", ""},
+		{"A" + strings.Repeat("0", 26), ""},
+	} {
+		if key, err := sortKeyBetween(test.a, test.b); err == nil {
+			t.Errorf("sortKeyBetween(%q, %q) = %q, want an error", test.a, test.b, key)
+		}
+	}
+}
+
+func TestSortKeyBetweenKeepsOrder(t *testing.T) {
+	// Insert keys at pseudo-random places, and often at the front, where keys
+	// grow fastest
+	var keys []string
+	seed := uint32(7)
+	for i := 0; i < 3000; i++ {
+		seed = seed*1664525 + 1013904223
+		pos := int(seed>>8) % (len(keys) + 1)
+		if i%5 == 0 {
+			pos = 0
+		}
+		var lower, upper string
+		if pos > 0 {
+			lower = keys[pos-1]
+		}
+		if pos < len(keys) {
+			upper = keys[pos]
+		}
+		key, err := sortKeyBetween(lower, upper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = validateSortKey(key); err != nil || (lower != "" && key <= lower) || (upper != "" && key >= upper) {
+			t.Fatalf("sortKeyBetween(%q, %q) = %q: %v", lower, upper, key, err)
+		}
+		keys = append(keys[:pos], append([]string{key}, keys[pos:]...)...)
+	}
+}
+
+// listTaskIds returns the ids of the tasks in a list, in order.
+func listTaskIds(t *testing.T, db *sqlx.DB, listId int) []int {
+	t.Helper()
+	var taskIds []int
+	err := db.Select(&taskIds, "SELECT task_id FROM task_to_list_v1 WHERE list_id = $1 ORDER BY sort_key", listId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return taskIds
+}
+
+func TestReorderTasks(t *testing.T) {
+	ptr := func(id int) *int { return &id }
+	for _, test := range []struct {
+		name   string
+		events []generated.TaskListReorderTasksEvent
+		want   []int
+	}{
+		{"to the front", []generated.TaskListReorderTasksEvent{{OldTaskId: 3}}, []int{3, 1, 2, 4}},
+		{"after a task", []generated.TaskListReorderTasksEvent{{OldTaskId: 1, AfterTaskId: ptr(3)}}, []int{2, 3, 1, 4}},
+		{"after the last task", []generated.TaskListReorderTasksEvent{{OldTaskId: 1, AfterTaskId: ptr(4)}}, []int{2, 3, 4, 1}},
+		{"before a task", []generated.TaskListReorderTasksEvent{{OldTaskId: 1, BeforeTaskId: ptr(4)}}, []int{2, 3, 1, 4}},
+		{"before the first task", []generated.TaskListReorderTasksEvent{{OldTaskId: 4, BeforeTaskId: ptr(1)}}, []int{4, 1, 2, 3}},
+		{"before the last task from the end", []generated.TaskListReorderTasksEvent{{OldTaskId: 4, BeforeTaskId: ptr(3)}}, []int{1, 2, 4, 3}},
+		{"after takes precedence", []generated.TaskListReorderTasksEvent{{OldTaskId: 1, AfterTaskId: ptr(2), BeforeTaskId: ptr(4)}}, []int{2, 1, 3, 4}},
+		{"after itself", []generated.TaskListReorderTasksEvent{{OldTaskId: 2, AfterTaskId: ptr(2)}}, []int{1, 2, 3, 4}},
+		{"before itself", []generated.TaskListReorderTasksEvent{{OldTaskId: 2, BeforeTaskId: ptr(2)}}, []int{1, 2, 3, 4}},
+		// Two clients drop different tasks into the same gap
+		{"into the same gap", []generated.TaskListReorderTasksEvent{
+			{OldTaskId: 4, AfterTaskId: ptr(1)},
+			{OldTaskId: 3, AfterTaskId: ptr(1)},
+		}, []int{1, 3, 4, 2}},
+		{"repeatedly to the front", []generated.TaskListReorderTasksEvent{
+			{OldTaskId: 4}, {OldTaskId: 3}, {OldTaskId: 2}, {OldTaskId: 1}, {OldTaskId: 4},
+		}, []int{4, 1, 2, 3}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			db := openTestDB(t, "")
+			h := &StateEventHandler{}
+			apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+			for _, title := range []string{"one", "two", "three", "four"} {
+				apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: title, TaskListId: 1})
+			}
+			for _, event := range test.events {
+				event.TaskListId = 1
+				apply(t, db, h.HandleTaskListReorderTasksEvent, event)
+			}
+			if got := listTaskIds(t, db, 1); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("tasks %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMigrateToSortKeysRenumbersDuplicatePositions(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	tx := db.MustBegin()
+	tx.MustExec(testEventLogSchema)
+	if err = InitProjections(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Integer positions as the old renumbering could leave them, with
+	// duplicates and gaps
+	db.MustExec(`INSERT INTO task_list_v1 (title, category, archived, position) VALUES ('A', 'toDoList', 0, 2), ('B', 'toDoList', 0, 1), ('C', 'toDoList', 0, 2)`)
+	db.MustExec(`INSERT INTO task_v1 (title) VALUES ('t1'), ('t2'), ('t3'), ('t4')`)
+	db.MustExec(`INSERT INTO task_to_list_v1 (task_id, list_id, position) VALUES (1, 1, 3), (2, 1, 1), (3, 1, 1), (1, 2, 1)`)
+	if err = RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		Id      int    `db:"id"`
+		SortKey string `db:"sort_key"`
+	}
+	for _, test := range []struct {
+		query string
+		want  []row
+	}{
+		// Ties are broken by id
+		{"SELECT task_id AS id, sort_key FROM task_to_list_v1 WHERE list_id = 1 ORDER BY sort_key", []row{{2, "a0"}, {3, "a1"}, {1, "a2"}}},
+		{"SELECT task_id AS id, sort_key FROM task_to_list_v1 WHERE list_id = 2 ORDER BY sort_key", []row{{1, "a0"}}},
+		{"SELECT id, sort_key FROM task_list_v1 ORDER BY sort_key", []row{{2, "a0"}, {1, "a1"}, {3, "a2"}}},
+	} {
+		var got []row
+		if err = db.Select(&got, test.query); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.query, got, test.want)
+		}
+	}
+
+	// The position columns are gone, and keys are unique from now on
+	if _, err = db.Exec("SELECT position FROM task_to_list_v1"); err == nil {
+		t.Error("task_to_list_v1 still has a position column")
+	}
+	if _, err = db.Exec("UPDATE task_to_list_v1 SET sort_key = 'a0' WHERE list_id = 1"); err == nil {
+		t.Error("duplicate sort keys were allowed")
+	}
+
+	// A task deleted since then, whose key has been given to another task
+	db.MustExec(`INSERT INTO task_trash_v1 (task_id) VALUES (4)`)
+	db.MustExec(`INSERT INTO task_trash_list_v1 (task_id, list_id, sort_key) VALUES (4, 1, 'a1')`)
+
+	// The restored task goes right after the one that took its key
+	h := &StateEventHandler{}
+	apply(t, db, h.HandleTaskRestoreEvent, generated.TaskRestoreEvent{TaskId: 4})
+	if got, want := listTaskIds(t, db, 1), []int{2, 3, 4, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks after restoring %v, want %v", got, want)
+	}
+}
@@ -50,49 +50,72 @@ SET due_date = :duedate
 WHERE id = :taskid;
 `
 
-const deleteTaskV1Sql = `
-DELETE FROM task_v1
-WHERE id = :taskid;
+// Deleted tasks are moved to the trash rather than removed from task_v1
+const getTaskTrashedV1Sql = `
+SELECT COUNT(*) FROM task_trash_v1 WHERE task_id = $1;
 `
 
 func (h *StateEventHandler) HandleTaskAddEvent(tx *sqlx.Tx, event *generated.TaskAddEvent) (bool, error) {
+	taskId, err := h.addTask(tx, event)
+	if err != nil {
+		return true, err
+	}
+	return true, h.recordCreatedBy(tx, setTaskCreatedEventIdV1Sql, taskId)
+}
+
+// addTask applies a Task:Add event and returns the ID of the new task.
+func (h *StateEventHandler) addTask(tx *sqlx.Tx, event *generated.TaskAddEvent) (int, error) {
 	fmt.Printf("Task v1: AddTaskEvent %v for list %d\n", event.Title, event.TaskListId)
 	result, err := tx.NamedExec(
 		insertTaskV1Sql,
 		*event,
 	)
 	if err != nil {
-		return true, err
+		return 0, err
 	}
 
 	// Get the ID of the newly inserted task
 	taskId, err := result.LastInsertId()
 	if err != nil {
-		return true, err
+		return 0, err
+	}
+	if err = indexTask(tx, int(taskId)); err != nil {
+		return 0, err
+	}
+	err = h.recordUndo(tx, "Task:Add", event.Timestamp, undoEvent{"Task:Delete", generated.TaskDeleteEvent{TaskId: int(taskId)}})
+	if err != nil {
+		return 0, err
 	}
 
 	// Add the task to the list
-	addToList := generated.TaskListAddTaskEvent{
-		TaskId: int(taskId),
-		ListId: event.TaskListId,
-	}
-	_, err = tx.NamedExec(insertTaskToListV1Sql, addToList)
-	return true, err
+	return int(taskId), appendTaskToList(tx, int(taskId), event.TaskListId)
 }
 
 func (h *StateEventHandler) HandleTaskUpdateTitleEvent(tx *sqlx.Tx, event *generated.TaskUpdateTitleEvent) (bool, error) {
 	fmt.Printf("Task v1: UpdateTaskTitleEvent %d %v\n", event.TaskId, event.Title)
-	_, err := tx.NamedExec(
+	task, err := getTaskForUndo(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "Task:UpdateTitle", event.Timestamp, undoEvent{"Task:UpdateTitle", generated.TaskUpdateTitleEvent{TaskId: task.Id, Title: task.Title}})
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(
 		updateTaskTitleV1Sql,
 		*event,
 	)
 	if err != nil {
 		return true, err
 	}
+	if err = indexTask(tx, event.TaskId); err != nil {
+		return true, err
+	}
 	historyEvent := AddTaskHistoryEvent{
 		TaskId:        event.TaskId,
 		UpdateType:    "update_title",
 		SystemComment: fmt.Sprintf("Title updated to: %s", event.Title),
+		CreatedAt:     event.Timestamp,
 	}
 	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
 	return true, err
@@ -100,7 +123,11 @@ func (h *StateEventHandler) HandleTaskUpdateTitleEvent(tx *sqlx.Tx, event *gener
 
 func (h *StateEventHandler) HandleTaskUpdateCompletedEvent(tx *sqlx.Tx, event *generated.TaskUpdateCompletedEvent) (bool, error) {
 	fmt.Printf("Task v1: UpdateTaskCompletedEvent %d %v\n", event.TaskId, event.CompletedAt)
-	_, err := tx.NamedExec(
+	task, err := getTaskForUndo(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(
 		updateTaskCompletedV1Sql,
 		*event,
 	)
@@ -117,14 +144,40 @@ func (h *StateEventHandler) HandleTaskUpdateCompletedEvent(tx *sqlx.Tx, event *g
 		TaskId:        event.TaskId,
 		UpdateType:    "update_completed",
 		SystemComment: comment,
+		CreatedAt:     event.Timestamp,
 	}
 	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
-	return true, err
+	if err != nil {
+		return true, err
+	}
+	inverse := []undoEvent{{"Task:UpdateCompleted", generated.TaskUpdateCompletedEvent{TaskId: task.Id, CompletedAt: task.CompletedAt}}}
+	if event.CompletedAt != nil {
+		newTaskId, err := createNextOccurrence(tx, event.TaskId, *event.CompletedAt, event.Timestamp)
+		if err != nil {
+			return true, err
+		}
+		if newTaskId != 0 {
+			// The rule moved over to the next occurrence, so it has to be moved back
+			inverse = append([]undoEvent{{"Task:Delete", generated.TaskDeleteEvent{TaskId: newTaskId}}}, inverse...)
+		}
+		if task.Recurrence != nil {
+			inverse = append(inverse, undoEvent{"Task:UpdateRecurrence", generated.TaskUpdateRecurrenceEvent{TaskId: task.Id, Recurrence: task.Recurrence}})
+		}
+	}
+	return true, h.recordUndo(tx, "Task:UpdateCompleted", event.Timestamp, inverse...)
 }
 
 func (h *StateEventHandler) HandleTaskUpdateDueDateEvent(tx *sqlx.Tx, event *generated.TaskUpdateDueDateEvent) (bool, error) {
 	fmt.Printf("Task v1: UpdateTaskDueDateEvent %d %v\n", event.TaskId, event.DueDate)
-	_, err := tx.NamedExec(
+	task, err := getTaskForUndo(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "Task:UpdateDueDate", event.Timestamp, undoEvent{"Task:UpdateDueDate", generated.TaskUpdateDueDateEvent{TaskId: task.Id, DueDate: task.DueDate}})
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(
 		updateTaskDueDateV1Sql,
 		*event,
 	)
@@ -141,6 +194,7 @@ func (h *StateEventHandler) HandleTaskUpdateDueDateEvent(tx *sqlx.Tx, event *gen
 		TaskId:        event.TaskId,
 		UpdateType:    "update_due_date",
 		SystemComment: comment,
+		CreatedAt:     event.Timestamp,
 	}
 	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
 	return true, err
@@ -148,21 +202,41 @@ func (h *StateEventHandler) HandleTaskUpdateDueDateEvent(tx *sqlx.Tx, event *gen
 
 func (h *StateEventHandler) HandleTaskDeleteEvent(tx *sqlx.Tx, event *generated.TaskDeleteEvent) (bool, error) {
 	fmt.Printf("Task v1: DeleteTaskEvent %d\n", event.TaskId)
-	_, err := tx.NamedExec(
-		deleteTaskV1Sql,
-		*event,
-	)
+	var trashed int
+	err := tx.Get(&trashed, getTaskTrashedV1Sql, event.TaskId)
 	if err != nil {
 		return true, err
 	}
+	if trashed > 0 {
+		return true, fmt.Errorf("task %d is already in the trash", event.TaskId)
+	}
+	err = h.recordUndo(tx, "Task:Delete", event.Timestamp, undoEvent{"Task:Restore", generated.TaskRestoreEvent{TaskId: event.TaskId}})
+	if err != nil {
+		return true, err
+	}
+	if err = trashTask(tx, event.TaskId, event.Timestamp); err != nil {
+		return true, err
+	}
 	_, err = tx.NamedExec(deleteTaskToListV1Sql, event)
 	if err != nil {
 		return true, err
 	}
+	_, err = tx.NamedExec(deleteTaskParentV1Sql, event)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(deleteTaskChildrenV1Sql, event)
+	if err != nil {
+		return true, err
+	}
+	if err = unindexTask(tx, event.TaskId); err != nil {
+		return true, err
+	}
 	historyEvent := AddTaskHistoryEvent{
 		TaskId:        event.TaskId,
 		UpdateType:    "delete",
 		SystemComment: "Task deleted",
+		CreatedAt:     event.Timestamp,
 	}
 	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
 	return true, err
@@ -171,8 +245,15 @@ func (h *StateEventHandler) HandleTaskDeleteEvent(tx *sqlx.Tx, event *generated.
 // State queries
 
 const getTaskByIdV1Sql = `
-SELECT id, title, due_date AS duedate, completed_at AS completedat
-FROM task_v1 WHERE id = $1;
+SELECT t.id, t.title, t.notes, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat, t.priority, r.rule AS recurrence,
+    tp.parent_id AS parenttaskid,
+    (SELECT COUNT(*) FROM task_parent_v1 c WHERE c.parent_id = t.id) AS subtaskcount,
+    (SELECT COUNT(*) FROM task_parent_v1 c JOIN task_v1 ct ON ct.id = c.task_id
+     WHERE c.parent_id = t.id AND ct.completed_at IS NOT NULL) AS subtaskscompleted
+FROM task_v1 t
+LEFT JOIN task_recurrence_v1 r ON r.task_id = t.id
+LEFT JOIN task_parent_v1 tp ON tp.task_id = t.id
+WHERE t.id = $1;
 `
 
 const getTaskTitleV1Sql = `
@@ -184,5 +265,9 @@ WHERE id = $1;
 func (r *StateResolver) GetApiTaskGet(db *sqlx.DB, id int) (generated.Task, error) {
 	var task generated.Task
 	err := db.Get(&task, getTaskByIdV1Sql, id)
+	if err != nil {
+		return task, err
+	}
+	err = loadSubtasks(db, &task)
 	return task, err
 }
@@ -2,6 +2,7 @@ package state
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"tomyedwab.com/yellowstone-server/tasks/generated"
@@ -29,16 +30,18 @@ func InitTaskHistory(tx *sqlx.Tx) error {
 // Events
 
 type AddTaskHistoryEvent struct {
-	TaskId        int     `db:"task_id"`
-	UpdateType    string  `db:"update_type"`
-	SystemComment string  `db:"system_comment"`
-	UserComment   *string `db:"user_comment"`
+	TaskId        int                      `db:"task_id"`
+	UpdateType    generated.TaskUpdateType `db:"update_type"`
+	SystemComment string                   `db:"system_comment"`
+	UserComment   *string                  `db:"user_comment"`
+	// The time of the event that made the change, so that a rebuild keeps it
+	CreatedAt time.Time `db:"created_at"`
 }
 
 // Event handler
 const insertTaskHistoryV1Sql = `
-INSERT INTO task_history_v1 (task_id, update_type, system_comment, user_comment)
-VALUES (:task_id, :update_type, :system_comment, :user_comment);
+INSERT INTO task_history_v1 (task_id, update_type, system_comment, user_comment, created_at)
+VALUES (:task_id, :update_type, :system_comment, :user_comment, :created_at);
 `
 
 func (h *StateEventHandler) HandleTaskAddCommentEvent(tx *sqlx.Tx, event *generated.TaskAddCommentEvent) (bool, error) {
@@ -46,9 +49,13 @@ func (h *StateEventHandler) HandleTaskAddCommentEvent(tx *sqlx.Tx, event *genera
 		TaskId:      event.TaskId,
 		UpdateType:  "add_comment",
 		UserComment: &event.UserComment,
+		CreatedAt:   event.Timestamp,
 	}
 	_, err := tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
-	return true, err
+	if err != nil {
+		return true, err
+	}
+	return true, indexTaskComment(tx, event.TaskId, event.UserComment)
 }
 
 // State queries
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/markdown"
+)
+
+// Tasks have long-form notes in Markdown, added to task_v1 by a migration.
+// Notes are only returned by /api/task/get and /api/task/notes, to keep list
+// responses small.
+
+const addTaskNotesV1Sql = `
+ALTER TABLE task_v1 ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+`
+
+func migrateAddTaskNotes(tx *sqlx.Tx) error {
+	_, err := tx.Exec(addTaskNotesV1Sql)
+	return err
+}
+
+// Event handler
+const updateTaskNotesV1Sql = `
+UPDATE task_v1
+SET notes = :notes
+WHERE id = :taskid;
+`
+
+func (h *StateEventHandler) HandleTaskUpdateNotesEvent(tx *sqlx.Tx, event *generated.TaskUpdateNotesEvent) (bool, error) {
+	fmt.Printf("Task v1: UpdateTaskNotesEvent %d\n", event.TaskId)
+	task, err := getTaskForUndo(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	if task.Notes == event.Notes {
+		return true, nil
+	}
+	err = h.recordUndo(tx, "Task:UpdateNotes", event.Timestamp, undoEvent{"Task:UpdateNotes", generated.TaskUpdateNotesEvent{TaskId: task.Id, Notes: task.Notes}})
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(updateTaskNotesV1Sql, *event)
+	if err != nil {
+		return true, err
+	}
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        event.TaskId,
+		UpdateType:    "update_notes",
+		SystemComment: summarizeNotesChange(task.Notes, event.Notes),
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+// summarizeNotesChange describes an edit by the number of lines added and
+// removed, e.g. "Notes updated: 2 lines added, 1 removed".
+func summarizeNotesChange(oldNotes, newNotes string) string {
+	if strings.TrimSpace(oldNotes) == "" {
+		return fmt.Sprintf("Notes added (%s)", pluralize(len(notesLines(newNotes)), "line"))
+	}
+	if strings.TrimSpace(newNotes) == "" {
+		return "Notes cleared"
+	}
+	added, removed := diffLines(notesLines(oldNotes), notesLines(newNotes))
+	switch {
+	case added == 0 && removed == 0:
+		return "Notes updated: whitespace only"
+	case removed == 0:
+		return fmt.Sprintf("Notes updated: %s added", pluralize(added, "line"))
+	case added == 0:
+		return fmt.Sprintf("Notes updated: %s removed", pluralize(removed, "line"))
+	}
+	return fmt.Sprintf("Notes updated: %s added, %d removed", pluralize(added, "line"), removed)
+}
+
+func notesLines(notes string) []string {
+	notes = strings.TrimRight(strings.ReplaceAll(notes, "\r\n", "\n"), "\n")
+	lines := strings.Split(notes, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return lines
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// diffLines returns the number of lines added and removed between two versions,
+// based on their longest common subsequence. A changed line counts as both.
+func diffLines(oldLines, newLines []string) (added, removed int) {
+	// Lines that are unchanged at either end don't need to be compared
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[0] == newLines[0] {
+		oldLines, newLines = oldLines[1:], newLines[1:]
+	}
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[len(oldLines)-1] == newLines[len(newLines)-1] {
+		oldLines, newLines = oldLines[:len(oldLines)-1], newLines[:len(newLines)-1]
+	}
+
+	lcs := make([]int, len(newLines)+1)
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		previous := 0
+		for j := len(newLines) - 1; j >= 0; j-- {
+			current := lcs[j]
+			if oldLines[i] == newLines[j] {
+				lcs[j] = previous + 1
+			} else if lcs[j+1] > lcs[j] {
+				lcs[j] = lcs[j+1]
+			}
+			previous = current
+		}
+	}
+	common := lcs[0]
+	return len(newLines) - common, len(oldLines) - common
+}
+
+// State queries
+const getTaskNotesV1Sql = `
+SELECT notes FROM task_v1 WHERE id = $1;
+`
+
+func (r *StateResolver) GetApiTaskNotes(db *sqlx.DB, id int) (generated.TaskNotesResponse, error) {
+	var notes string
+	err := db.Get(&notes, getTaskNotesV1Sql, id)
+	if err != nil {
+		return generated.TaskNotesResponse{}, err
+	}
+	return generated.TaskNotesResponse{
+		TaskId:   id,
+		Markdown: notes,
+		Html:     markdown.Render(notes),
+	}, nil
+}
+
+func (r *StateResolver) GetApiMarkdownRender(db *sqlx.DB, text string) (generated.RenderedMarkdownResponse, error) {
+	return generated.RenderedMarkdownResponse{Html: markdown.Render(text)}, nil
+}
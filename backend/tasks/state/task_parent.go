@@ -0,0 +1,325 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Table schema
+const taskParentSchema = `
+CREATE TABLE IF NOT EXISTS task_parent_v1 (
+    task_id INTEGER PRIMARY KEY NOT NULL,
+    parent_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    FOREIGN KEY (task_id) REFERENCES task_v1(id),
+    FOREIGN KEY (parent_id) REFERENCES task_v1(id)
+);
+`
+
+func InitTaskParent(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing TaskParent v1\n")
+	_, err := tx.Exec(taskParentSchema)
+	return err
+}
+
+// Event handler
+const deleteTaskParentV1Sql = `
+DELETE FROM task_parent_v1
+WHERE task_id = :taskid;
+`
+
+// Subtasks of a deleted task are promoted to top-level tasks
+const deleteTaskChildrenV1Sql = `
+DELETE FROM task_parent_v1
+WHERE parent_id = :taskid;
+`
+
+const getTaskAncestorsV1Sql = `
+WITH RECURSIVE ancestors(id) AS (
+    SELECT $1
+    UNION
+    SELECT tp.parent_id FROM task_parent_v1 tp JOIN ancestors a ON tp.task_id = a.id
+)
+SELECT id FROM ancestors;
+`
+
+const shiftSubtasksToFrontV1Sql = `
+UPDATE task_parent_v1
+SET position = position + 1
+WHERE parent_id = :parenttaskid;
+`
+
+const insertSubtaskAtFrontV1Sql = `
+INSERT INTO task_parent_v1 (task_id, parent_id, position)
+VALUES (:taskid, :parenttaskid, 1);
+`
+
+const shiftSubtasksAfterV1Sql = `
+UPDATE task_parent_v1
+SET position = position + 1
+WHERE parent_id = :parenttaskid AND position > (
+    SELECT position FROM task_parent_v1 WHERE task_id = :aftertaskid AND parent_id = :parenttaskid
+);
+`
+
+const shiftSubtasksFromV1Sql = `
+UPDATE task_parent_v1
+SET position = position + 1
+WHERE parent_id = $1 AND position >= $2;
+`
+
+// If AfterTaskId is not a subtask of the parent, the task is added at the end
+const insertSubtaskAfterV1Sql = `
+INSERT INTO task_parent_v1 (task_id, parent_id, position)
+SELECT :taskid, :parenttaskid, COALESCE(
+    (SELECT position + 1 FROM task_parent_v1 WHERE task_id = :aftertaskid AND parent_id = :parenttaskid),
+    (SELECT COALESCE(MAX(position), 0) + 1 FROM task_parent_v1 WHERE parent_id = :parenttaskid)
+);
+`
+
+const getTaskDescendantsV1Sql = `
+WITH RECURSIVE descendants(id, depth, position) AS (
+    SELECT task_id, 1, position FROM task_parent_v1 WHERE parent_id = $1
+    UNION ALL
+    SELECT tp.task_id, d.depth + 1, tp.position FROM task_parent_v1 tp JOIN descendants d ON tp.parent_id = d.id
+)
+SELECT id FROM descendants ORDER BY depth, position;
+`
+
+const getTaskParentV1Sql = `
+SELECT parent_id AS parentid, position
+FROM task_parent_v1
+WHERE task_id = $1;
+`
+
+const copyTaskParentV1Sql = `
+INSERT INTO task_parent_v1 (task_id, parent_id, position)
+VALUES ($1, $2, $3);
+`
+
+// Tasks in the trash can't gain or become subtasks, since deleting a task
+// already took it out of its parent and promoted its subtasks
+const getTaskIsTrashedV1Sql = `
+SELECT EXISTS (SELECT 1 FROM task_trash_v1 WHERE task_id = t.id)
+FROM task_v1 t
+WHERE t.id = $1;
+`
+
+func checkTaskNotTrashed(tx *sqlx.Tx, taskId int) error {
+	var trashed bool
+	err := tx.Get(&trashed, getTaskIsTrashedV1Sql, taskId)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("task %d does not exist", taskId)
+	}
+	if err != nil {
+		return err
+	}
+	if trashed {
+		return fmt.Errorf("task %d is in the trash", taskId)
+	}
+	return nil
+}
+
+func (h *StateEventHandler) HandleTaskSetParentEvent(tx *sqlx.Tx, event *generated.TaskSetParentEvent) (bool, error) {
+	fmt.Printf("TaskParent v1: SetParentEvent %d\n", event.TaskId)
+	err := checkTaskNotTrashed(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	if event.ParentTaskId != nil {
+		if err = checkTaskNotTrashed(tx, *event.ParentTaskId); err != nil {
+			return true, err
+		}
+		// Refuse to create a cycle, i.e. make a task a subtask of itself or of
+		// one of its own subtasks
+		var ancestors []int
+		err = tx.Select(&ancestors, getTaskAncestorsV1Sql, *event.ParentTaskId)
+		if err != nil {
+			return true, err
+		}
+		for _, ancestor := range ancestors {
+			if ancestor == event.TaskId {
+				return true, fmt.Errorf("cannot make task %d a subtask of %d: would create a cycle", event.TaskId, *event.ParentTaskId)
+			}
+		}
+	}
+
+	inverse, err := undoTaskSetParent(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "Task:SetParent", event.Timestamp, inverse); err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(deleteTaskParentV1Sql, event)
+	if err != nil {
+		return true, err
+	}
+
+	var comment string
+	if event.ParentTaskId == nil {
+		comment = "Task is no longer a subtask"
+	} else {
+		shiftSql, insertSql := shiftSubtasksToFrontV1Sql, insertSubtaskAtFrontV1Sql
+		if event.AfterTaskId != nil {
+			shiftSql, insertSql = shiftSubtasksAfterV1Sql, insertSubtaskAfterV1Sql
+		}
+		_, err = tx.NamedExec(shiftSql, event)
+		if err != nil {
+			return true, err
+		}
+		_, err = tx.NamedExec(insertSql, event)
+		if err != nil {
+			return true, err
+		}
+		// Subtasks are shown nested under their parent, so they need to be
+		// members of the parent's lists as well
+		if err = appendTaskToListsOf(tx, event.TaskId, *event.ParentTaskId); err != nil {
+			return true, err
+		}
+		comment = fmt.Sprintf("Task made a subtask of #%d", *event.ParentTaskId)
+	}
+
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        event.TaskId,
+		UpdateType:    "update_parent",
+		SystemComment: comment,
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+// withDescendants expands a list of task IDs to also include all of their
+// subtasks, keeping the original order and skipping duplicates. Parents always
+// come before their subtasks.
+func withDescendants(tx *sqlx.Tx, taskIds []int) ([]int, error) {
+	seen := make(map[int]bool)
+	var result []int
+	for _, taskId := range taskIds {
+		if seen[taskId] {
+			continue
+		}
+		var descendants []int
+		err := tx.Select(&descendants, getTaskDescendantsV1Sql, taskId)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range append([]int{taskId}, descendants...) {
+			if !seen[id] {
+				seen[id] = true
+				result = append(result, id)
+			}
+		}
+	}
+	return result, nil
+}
+
+// copyTaskParents recreates the parent/child relationships between duplicated
+// tasks. duplicates maps original task IDs to their copies; copies of subtasks
+// whose parent was not duplicated become top-level tasks.
+func copyTaskParents(tx *sqlx.Tx, duplicates map[int]int) error {
+	for sourceTaskId, newTaskId := range duplicates {
+		var parent struct {
+			ParentId int `db:"parentid"`
+			Position int `db:"position"`
+		}
+		err := tx.Get(&parent, getTaskParentV1Sql, sourceTaskId)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		newParentId, ok := duplicates[parent.ParentId]
+		if !ok {
+			continue
+		}
+		_, err = tx.Exec(copyTaskParentV1Sql, newTaskId, newParentId, parent.Position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// State queries
+
+type subtaskRow struct {
+	generated.Task
+	SubtaskPosition *int `db:"subtaskposition"`
+}
+
+const getSubtasksV1Sql = `
+SELECT t.id, t.title, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat, t.priority, r.rule AS recurrence,
+    tp.parent_id AS parenttaskid, tp.position AS subtaskposition,
+    (SELECT COUNT(*) FROM task_parent_v1 c WHERE c.parent_id = t.id) AS subtaskcount,
+    (SELECT COUNT(*) FROM task_parent_v1 c JOIN task_v1 ct ON ct.id = c.task_id
+     WHERE c.parent_id = t.id AND ct.completed_at IS NOT NULL) AS subtaskscompleted
+FROM task_parent_v1 tp
+JOIN task_v1 t ON t.id = tp.task_id
+LEFT JOIN task_recurrence_v1 r ON r.task_id = t.id
+WHERE tp.parent_id = $1
+ORDER BY tp.position;
+`
+
+// loadSubtasks fills in the Subtasks of a task and all of its descendants.
+func loadSubtasks(db *sqlx.DB, task *generated.Task) error {
+	var rows []subtaskRow
+	err := db.Select(&rows, getSubtasksV1Sql, task.Id)
+	if err != nil {
+		return err
+	}
+	task.Subtasks = make([]generated.Task, len(rows))
+	for i, row := range rows {
+		task.Subtasks[i] = row.Task
+		if err := loadSubtasks(db, &task.Subtasks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildTaskTree nests the tasks of a list under their parents. Tasks whose
+// parent is not in the same list are returned at the top level, in list order;
+// subtasks are ordered by their position within the parent.
+func buildTaskTree(rows []subtaskRow) []generated.Task {
+	inList := make(map[int]bool, len(rows))
+	for _, row := range rows {
+		inList[row.Id] = true
+	}
+
+	children := make(map[int][]subtaskRow)
+	var roots []subtaskRow
+	for _, row := range rows {
+		if row.ParentTaskId != nil && inList[*row.ParentTaskId] {
+			children[*row.ParentTaskId] = append(children[*row.ParentTaskId], row)
+		} else {
+			roots = append(roots, row)
+		}
+	}
+
+	var build func(row subtaskRow) generated.Task
+	build = func(row subtaskRow) generated.Task {
+		task := row.Task
+		subtasks := children[row.Id]
+		sort.SliceStable(subtasks, func(i, j int) bool {
+			return *subtasks[i].SubtaskPosition < *subtasks[j].SubtaskPosition
+		})
+		task.Subtasks = make([]generated.Task, 0, len(subtasks))
+		for _, subtask := range subtasks {
+			task.Subtasks = append(task.Subtasks, build(subtask))
+		}
+		return task
+	}
+
+	tasks := make([]generated.Task, 0, len(roots))
+	for _, row := range roots {
+		tasks = append(tasks, build(row))
+	}
+	return tasks
+}
@@ -0,0 +1,166 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// setParent applies a Task:SetParent event and returns the error it failed
+// with, if any. Nothing is committed when it fails.
+func setParent(t *testing.T, db *sqlx.DB, taskId int, parentTaskId *int) error {
+	t.Helper()
+	tx := db.MustBegin()
+	defer tx.Rollback()
+	h := &StateEventHandler{}
+	event := generated.TaskSetParentEvent{TaskId: taskId, ParentTaskId: parentTaskId}
+	logEvent(t, tx, "", event)
+	if _, err := h.HandleTaskSetParentEvent(tx, &event); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return nil
+}
+
+func subtaskIds(t *testing.T, db *sqlx.DB, parentId int) []int {
+	t.Helper()
+	ids := make([]int, 0)
+	err := db.Select(&ids, "SELECT task_id FROM task_parent_v1 WHERE parent_id = $1 ORDER BY position", parentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestSetParentRefusesCycles(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Projects", Category: generated.TaskListCategoryToDoList})
+	for _, title := range []string{"Renovate", "Kitchen", "Cabinets", "Garden"} {
+		apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: title, TaskListId: 1})
+	}
+	one, two, three := 1, 2, 3
+	if err := setParent(t, db, 2, &one); err != nil {
+		t.Fatal(err)
+	}
+	if err := setParent(t, db, 3, &two); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		taskId int
+		parent *int
+	}{
+		{"itself", 1, &one},
+		{"its subtask", 1, &two},
+		{"a subtask of its subtask", 1, &three},
+		{"its own subtask's subtask, from the middle", 2, &three},
+	} {
+		if err := setParent(t, db, test.taskId, test.parent); err == nil {
+			t.Errorf("making task %d a subtask of %s succeeded", test.taskId, test.name)
+		}
+	}
+	// Nothing changed
+	if got := subtaskIds(t, db, 1); len(got) != 1 || got[0] != 2 {
+		t.Errorf("subtasks of task 1 %v, want [2]", got)
+	}
+	if got := subtaskIds(t, db, 2); len(got) != 1 || got[0] != 3 {
+		t.Errorf("subtasks of task 2 %v, want [3]", got)
+	}
+
+	// Moving a subtask to a sibling branch is fine
+	if err := setParent(t, db, 3, &one); err != nil {
+		t.Errorf("moving task 3 under task 1: %v", err)
+	}
+	if err := setParent(t, db, 1, &three); err == nil {
+		t.Error("making task 1 a subtask of its subtask 3 succeeded")
+	}
+}
+
+func TestSetParentRefusesMissingAndTrashedTasks(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Projects", Category: generated.TaskListCategoryToDoList})
+	for _, title := range []string{"Renovate", "Kitchen", "Deleted"} {
+		apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: title, TaskListId: 1})
+	}
+	apply(t, db, h.HandleTaskDeleteEvent, generated.TaskDeleteEvent{TaskId: 3})
+
+	one, three, missing := 1, 3, 99
+	if err := setParent(t, db, 2, &missing); err == nil {
+		t.Error("making a task a subtask of a missing task succeeded")
+	}
+	if err := setParent(t, db, 2, &three); err == nil {
+		t.Error("making a task a subtask of a trashed task succeeded")
+	}
+	if err := setParent(t, db, 3, &one); err == nil {
+		t.Error("making a trashed task a subtask succeeded")
+	}
+	if err := setParent(t, db, missing, &one); err == nil {
+		t.Error("making a missing task a subtask succeeded")
+	}
+	if got := subtaskIds(t, db, 1); len(got) != 0 {
+		t.Errorf("subtasks of task 1 %v, want none", got)
+	}
+}
+
+func TestRestoringTaskReattachesSubtasks(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Projects", Category: generated.TaskListCategoryToDoList})
+	for _, title := range []string{"Renovate", "Kitchen", "Bathroom", "Hallway", "Garden"} {
+		apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: title, TaskListId: 1})
+	}
+	one, five := 1, 5
+	for _, taskId := range []int{4, 3, 2} {
+		if err := setParent(t, db, taskId, &one); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := subtaskIds(t, db, 1); len(got) != 3 || got[0] != 2 || got[1] != 3 || got[2] != 4 {
+		t.Fatalf("subtasks of task 1 %v, want [2 3 4]", got)
+	}
+
+	// Deleting the parent promotes its subtasks
+	apply(t, db, h.HandleTaskDeleteEvent, generated.TaskDeleteEvent{TaskId: 1})
+	var parents int
+	if err := db.Get(&parents, "SELECT COUNT(*) FROM task_parent_v1"); err != nil {
+		t.Fatal(err)
+	}
+	if parents != 0 {
+		t.Fatalf("%d subtasks left after deleting their parent", parents)
+	}
+
+	// Subtasks that were moved elsewhere or deleted in the meantime stay
+	// where they are
+	if err := setParent(t, db, 3, &five); err != nil {
+		t.Fatal(err)
+	}
+	apply(t, db, h.HandleTaskDeleteEvent, generated.TaskDeleteEvent{TaskId: 4})
+
+	apply(t, db, h.HandleTaskRestoreEvent, generated.TaskRestoreEvent{TaskId: 1})
+	if got := subtaskIds(t, db, 1); len(got) != 1 || got[0] != 2 {
+		t.Errorf("subtasks of task 1 after restoring it %v, want [2]", got)
+	}
+	if got := subtaskIds(t, db, 5); len(got) != 1 || got[0] != 3 {
+		t.Errorf("subtasks of task 5 after restoring task 1 %v, want [3]", got)
+	}
+	var remembered int
+	if err := db.Get(&remembered, "SELECT COUNT(*) FROM task_trash_child_v1 WHERE task_id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if remembered != 0 {
+		t.Errorf("%d subtasks still remembered after restoring task 1", remembered)
+	}
+
+	// Deleting and restoring again round-trips
+	apply(t, db, h.HandleTaskDeleteEvent, generated.TaskDeleteEvent{TaskId: 1})
+	apply(t, db, h.HandleTaskRestoreEvent, generated.TaskRestoreEvent{TaskId: 1})
+	if got := subtaskIds(t, db, 1); len(got) != 1 || got[0] != 2 {
+		t.Errorf("subtasks of task 1 after restoring it again %v, want [2]", got)
+	}
+}
@@ -0,0 +1,177 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Table schema
+const taskRecurrenceSchema = `
+CREATE TABLE IF NOT EXISTS task_recurrence_v1 (
+    task_id INTEGER PRIMARY KEY NOT NULL,
+    rule TEXT NOT NULL,
+    FOREIGN KEY (task_id) REFERENCES task_v1(id)
+);
+`
+
+func InitTaskRecurrence(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing TaskRecurrence v1\n")
+	_, err := tx.Exec(taskRecurrenceSchema)
+	return err
+}
+
+// Event handler
+const upsertTaskRecurrenceV1Sql = `
+INSERT INTO task_recurrence_v1 (task_id, rule)
+VALUES ($1, $2)
+ON CONFLICT (task_id) DO UPDATE SET rule = excluded.rule;
+`
+
+const deleteTaskRecurrenceV1Sql = `
+DELETE FROM task_recurrence_v1
+WHERE task_id = $1;
+`
+
+const getTaskRecurrenceV1Sql = `
+SELECT t.due_date AS duedate, t.start_at AS startat, r.rule
+FROM task_v1 t
+JOIN task_recurrence_v1 r ON r.task_id = t.id
+WHERE t.id = $1;
+`
+
+const insertNextOccurrenceV1Sql = `
+INSERT INTO task_v1 (title, notes, priority, due_date, start_at)
+SELECT title, notes, priority, $1, $2
+FROM task_v1
+WHERE id = $3
+RETURNING id;
+`
+
+func (h *StateEventHandler) HandleTaskUpdateRecurrenceEvent(tx *sqlx.Tx, event *generated.TaskUpdateRecurrenceEvent) (bool, error) {
+	fmt.Printf("TaskRecurrence v1: UpdateTaskRecurrenceEvent %d\n", event.TaskId)
+	task, err := getTaskForUndo(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "Task:UpdateRecurrence", event.Timestamp, undoEvent{"Task:UpdateRecurrence", generated.TaskUpdateRecurrenceEvent{TaskId: task.Id, Recurrence: task.Recurrence}})
+	if err != nil {
+		return true, err
+	}
+	var comment string
+	if event.Recurrence == nil || *event.Recurrence == "" {
+		_, err = tx.Exec(deleteTaskRecurrenceV1Sql, event.TaskId)
+		if err != nil {
+			return true, err
+		}
+		comment = "Recurrence removed"
+	} else {
+		rule, err := parseRecurrence(*event.Recurrence)
+		if err != nil {
+			return true, err
+		}
+		_, err = tx.Exec(upsertTaskRecurrenceV1Sql, event.TaskId, rule.String())
+		if err != nil {
+			return true, err
+		}
+		comment = fmt.Sprintf("Recurrence set to %s", rule.String())
+	}
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        event.TaskId,
+		UpdateType:    "update_recurrence",
+		SystemComment: comment,
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+// createNextOccurrence is called when a task is completed. If the task has a
+// recurrence rule, a copy of the task is created in all of the same lists with
+// the next due date, and the rule moves over to the new task so that toggling
+// completion on the old one doesn't spawn duplicates. Returns the ID of the new
+// task, or 0 if none was created. The history entries have the time of the
+// completing event.
+func createNextOccurrence(tx *sqlx.Tx, taskId int, completedAt, timestamp time.Time) (int, error) {
+	var recurrence struct {
+		DueDate *time.Time `db:"duedate"`
+		StartAt *time.Time `db:"startat"`
+		Rule    string     `db:"rule"`
+	}
+	err := tx.Get(&recurrence, getTaskRecurrenceV1Sql, taskId)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	rule, err := parseRecurrence(recurrence.Rule)
+	if err != nil {
+		return 0, err
+	}
+	after := completedAt
+	if recurrence.DueDate != nil {
+		after = *recurrence.DueDate
+	}
+	nextDueDate, ok := rule.Next(after)
+
+	_, err = tx.Exec(deleteTaskRecurrenceV1Sql, taskId)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		historyEvent := AddTaskHistoryEvent{
+			TaskId:        taskId,
+			UpdateType:    "next_occurrence",
+			SystemComment: "Recurrence ended, no further occurrences",
+			CreatedAt:     timestamp,
+		}
+		_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+		return 0, err
+	}
+
+	var newTaskId int
+	startAt := nextOccurrenceStartAt(recurrence.StartAt, recurrence.DueDate, nextDueDate)
+	err = tx.Get(&newTaskId, insertNextOccurrenceV1Sql, nextDueDate, startAt, taskId)
+	if err != nil {
+		return 0, err
+	}
+	if err = indexTask(tx, newTaskId); err != nil {
+		return 0, err
+	}
+	if err = appendTaskToListsOf(tx, newTaskId, taskId); err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(upsertTaskRecurrenceV1Sql, newTaskId, rule.NextRule().String())
+	if err != nil {
+		return 0, err
+	}
+	if err = copyRelativeReminders(tx, newTaskId, taskId); err != nil {
+		return 0, err
+	}
+
+	for _, historyEvent := range []AddTaskHistoryEvent{
+		{
+			TaskId:        taskId,
+			UpdateType:    "next_occurrence",
+			SystemComment: fmt.Sprintf("Next occurrence created as #%d", newTaskId),
+			CreatedAt:     timestamp,
+		},
+		{
+			TaskId:        newTaskId,
+			UpdateType:    "next_occurrence",
+			SystemComment: fmt.Sprintf("Created as next occurrence of #%d, due %s", taskId, nextDueDate.Format(time.RFC3339)),
+			CreatedAt:     timestamp,
+		},
+	} {
+		_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return newTaskId, nil
+}
@@ -0,0 +1,130 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestCompletingRecurringTask(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	dueDate := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	rule := "FREQ=WEEKLY;UNTIL=20260112T090000Z"
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Chores", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Water the plants", TaskListId: 1, DueDate: &dueDate})
+	apply(t, db, h.HandleTaskUpdateRecurrenceEvent, generated.TaskUpdateRecurrenceEvent{TaskId: 1, Recurrence: &rule})
+
+	type recurrence struct {
+		TaskId int    `db:"task_id"`
+		Rule   string `db:"rule"`
+	}
+	getRecurrences := func() []recurrence {
+		t.Helper()
+		var recurrences []recurrence
+		if err := db.Select(&recurrences, "SELECT task_id, rule FROM task_recurrence_v1 ORDER BY task_id"); err != nil {
+			t.Fatal(err)
+		}
+		return recurrences
+	}
+
+	// The rule moves to the next occurrence, in the same list
+	completedAt := dueDate
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 1, CompletedAt: &completedAt})
+	if got := getRecurrences(); len(got) != 1 || got[0] != (recurrence{2, rule}) {
+		t.Fatalf("recurrences after the first completion %v, want task 2 with the rule", got)
+	}
+	var next struct {
+		Title   string    `db:"title"`
+		DueDate time.Time `db:"due_date"`
+		ListId  int       `db:"list_id"`
+	}
+	err := db.Get(&next, "SELECT t.title, t.due_date, ttl.list_id FROM task_v1 t JOIN task_to_list_v1 ttl ON ttl.task_id = t.id WHERE t.id = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Title != "Water the plants" || !next.DueDate.Equal(dueDate.AddDate(0, 0, 7)) || next.ListId != 1 {
+		t.Errorf("next occurrence %+v, want the same task a week later in list 1", next)
+	}
+
+	// Completing a task again doesn't create another occurrence
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 1, CompletedAt: nil})
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 1, CompletedAt: &completedAt})
+
+	// The occurrence after the last one would be past UNTIL
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 2, CompletedAt: &completedAt})
+	if got := getRecurrences(); len(got) != 0 {
+		t.Errorf("recurrences after the last occurrence %v, want none", got)
+	}
+	var tasks int
+	if err = db.Get(&tasks, "SELECT COUNT(*) FROM task_v1"); err != nil {
+		t.Fatal(err)
+	}
+	if tasks != 2 {
+		t.Errorf("%d tasks, want 2", tasks)
+	}
+	var comment string
+	if err = db.Get(&comment, "SELECT system_comment FROM task_history_v1 WHERE task_id = 2 ORDER BY id DESC LIMIT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if comment != "Recurrence ended, no further occurrences" {
+		t.Errorf("last history entry %q", comment)
+	}
+}
+
+func TestCompletingRecurringTaskWithCount(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	dueDate := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	rule := "FREQ=WEEKLY;COUNT=2"
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Chores", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Water the plants", TaskListId: 1, DueDate: &dueDate})
+	apply(t, db, h.HandleTaskUpdateRecurrenceEvent, generated.TaskUpdateRecurrenceEvent{TaskId: 1, Recurrence: &rule})
+
+	type recurrence struct {
+		TaskId int    `db:"task_id"`
+		Rule   string `db:"rule"`
+	}
+	getRecurrences := func() []recurrence {
+		t.Helper()
+		var recurrences []recurrence
+		if err := db.Select(&recurrences, "SELECT task_id, rule FROM task_recurrence_v1 ORDER BY task_id"); err != nil {
+			t.Fatal(err)
+		}
+		return recurrences
+	}
+
+	completedAt := dueDate
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 1, CompletedAt: &completedAt})
+	if got := getRecurrences(); len(got) != 1 || got[0] != (recurrence{2, "FREQ=WEEKLY;COUNT=1"}) {
+		t.Fatalf("recurrences after the first completion %v, want task 2 with one occurrence left", got)
+	}
+	var nextDueDate time.Time
+	if err := db.Get(&nextDueDate, "SELECT due_date FROM task_v1 WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+	if !nextDueDate.Equal(dueDate.AddDate(0, 0, 7)) {
+		t.Errorf("next occurrence due %s, want a week later", nextDueDate)
+	}
+
+	// The last occurrence doesn't create another
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 2, CompletedAt: &completedAt})
+	if got := getRecurrences(); len(got) != 0 {
+		t.Errorf("recurrences after the last occurrence %v, want none", got)
+	}
+	var tasks int
+	if err := db.Get(&tasks, "SELECT COUNT(*) FROM task_v1"); err != nil {
+		t.Fatal(err)
+	}
+	if tasks != 2 {
+		t.Errorf("%d tasks, want 2", tasks)
+	}
+	var comment string
+	if err := db.Get(&comment, "SELECT system_comment FROM task_history_v1 WHERE task_id = 2 ORDER BY id DESC LIMIT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if comment != "Recurrence ended, no further occurrences" {
+		t.Errorf("last history entry %q", comment)
+	}
+}
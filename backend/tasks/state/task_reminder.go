@@ -0,0 +1,301 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// A reminder is either at a fixed time or a number of minutes before the task's
+// due date, in which case it moves along with the due date. The reminder
+// scheduler polls for reminders whose time has come and records each one it
+// sends in task_reminder_fired_v1.
+//
+// task_reminder_fired_v1 is not a projection: it records what was sent, not
+// what happened in the event log, so it is left alone when the projections are
+// rebuilt. Reminder IDs come out the same when the event log is replayed.
+
+// Table schema
+const taskReminderSchema = `
+CREATE TABLE IF NOT EXISTS task_reminder_v1 (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    task_id INTEGER NOT NULL,
+    remind_at DATETIME,
+    minutes_before_due INTEGER,
+    FOREIGN KEY (task_id) REFERENCES task_v1(id)
+);
+
+CREATE TABLE IF NOT EXISTS task_reminder_fired_v1 (
+    reminder_id INTEGER NOT NULL,
+    fire_at DATETIME NOT NULL,
+    fired_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (reminder_id, fire_at)
+);
+`
+
+func InitTaskReminder(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing TaskReminder v1\n")
+	_, err := tx.Exec(taskReminderSchema)
+	return err
+}
+
+// Event handlers
+const insertTaskReminderV1Sql = `
+INSERT INTO task_reminder_v1 (task_id, remind_at, minutes_before_due)
+VALUES ($1, $2, $3);
+`
+
+const deleteTaskReminderV1Sql = `
+DELETE FROM task_reminder_v1
+WHERE id = $1;
+`
+
+const getTaskReminderV1Sql = `
+SELECT id, task_id AS taskid, remind_at AS remindat, minutes_before_due AS minutesbeforedue
+FROM task_reminder_v1
+WHERE id = $1;
+`
+
+// Relative reminders carry over to copies of a task, which have the same due
+// date (or the next one, for a recurring task)
+const copyRelativeRemindersV1Sql = `
+INSERT INTO task_reminder_v1 (task_id, minutes_before_due)
+SELECT $1, minutes_before_due
+FROM task_reminder_v1
+WHERE task_id = $2 AND minutes_before_due IS NOT NULL
+ORDER BY id;
+`
+
+func (h *StateEventHandler) HandleTaskAddReminderEvent(tx *sqlx.Tx, event *generated.TaskAddReminderEvent) (bool, error) {
+	fmt.Printf("TaskReminder v1: AddReminderEvent %d\n", event.TaskId)
+	if (event.RemindAt == nil) == (event.MinutesBeforeDue == nil) {
+		return true, fmt.Errorf("a reminder needs exactly one of RemindAt and MinutesBeforeDue")
+	}
+	if event.MinutesBeforeDue != nil && *event.MinutesBeforeDue < 0 {
+		return true, fmt.Errorf("invalid reminder offset %d: must not be negative", *event.MinutesBeforeDue)
+	}
+	var title string
+	err := tx.Get(&title, getTaskTitleV1Sql, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+
+	var remindAt *time.Time
+	var comment string
+	if event.RemindAt != nil {
+		// Reminder times are compared when checking whether a reminder has
+		// been sent, so they are stored in UTC
+		utc := event.RemindAt.UTC()
+		remindAt = &utc
+		comment = fmt.Sprintf("Reminder added for %s", utc.Format(time.RFC3339))
+	} else {
+		comment = fmt.Sprintf("Reminder added %s before the due date", pluralize(*event.MinutesBeforeDue, "minute"))
+	}
+	result, err := tx.Exec(insertTaskReminderV1Sql, event.TaskId, remindAt, event.MinutesBeforeDue)
+	if err != nil {
+		return true, err
+	}
+	reminderId, err := result.LastInsertId()
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "Task:AddReminder", event.Timestamp, undoEvent{"Task:RemoveReminder", generated.TaskRemoveReminderEvent{ReminderId: int(reminderId)}})
+	if err != nil {
+		return true, err
+	}
+
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        event.TaskId,
+		UpdateType:    "update_reminders",
+		SystemComment: comment,
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+func (h *StateEventHandler) HandleTaskRemoveReminderEvent(tx *sqlx.Tx, event *generated.TaskRemoveReminderEvent) (bool, error) {
+	fmt.Printf("TaskReminder v1: RemoveReminderEvent %d\n", event.ReminderId)
+	var reminder generated.TaskReminder
+	err := tx.Get(&reminder, getTaskReminderV1Sql, event.ReminderId)
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "Task:RemoveReminder", event.Timestamp, undoEvent{"Task:AddReminder", generated.TaskAddReminderEvent{
+		TaskId:           reminder.TaskId,
+		RemindAt:         reminder.RemindAt,
+		MinutesBeforeDue: reminder.MinutesBeforeDue,
+	}})
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(deleteTaskReminderV1Sql, event.ReminderId)
+	if err != nil {
+		return true, err
+	}
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        reminder.TaskId,
+		UpdateType:    "update_reminders",
+		SystemComment: "Reminder removed",
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+func copyRelativeReminders(tx *sqlx.Tx, newTaskId, sourceTaskId int) error {
+	_, err := tx.Exec(copyRelativeRemindersV1Sql, newTaskId, sourceTaskId)
+	return err
+}
+
+// reminderFireAt returns the time a reminder is sent, or nil if it is
+// relative to the due date and the task has none.
+func reminderFireAt(remindAt *time.Time, minutesBeforeDue *int, dueDate *time.Time) *time.Time {
+	if remindAt != nil {
+		fireAt := remindAt.UTC()
+		return &fireAt
+	}
+	if minutesBeforeDue == nil || dueDate == nil {
+		return nil
+	}
+	fireAt := dueDate.Add(-time.Duration(*minutesBeforeDue) * time.Minute).UTC()
+	return &fireAt
+}
+
+// State queries
+const getTaskRemindersV1Sql = `
+SELECT r.id, r.task_id AS taskid, r.remind_at AS remindat, r.minutes_before_due AS minutesbeforedue,
+    t.due_date AS duedate
+FROM task_reminder_v1 r
+JOIN task_v1 t ON t.id = r.task_id
+WHERE r.task_id = $1
+ORDER BY r.id;
+`
+
+const getTaskReminderFiredV1Sql = `
+SELECT COUNT(*) FROM task_reminder_fired_v1 WHERE reminder_id = $1 AND fire_at = $2;
+`
+
+func (r *StateResolver) GetApiTaskReminders(db *sqlx.DB, taskId int) (generated.TaskReminderResponse, error) {
+	var rows []struct {
+		generated.TaskReminder
+		DueDate *time.Time `db:"duedate"`
+	}
+	err := db.Select(&rows, getTaskRemindersV1Sql, taskId)
+	if err != nil {
+		return generated.TaskReminderResponse{}, err
+	}
+	reminders := make([]generated.TaskReminder, 0, len(rows))
+	for _, row := range rows {
+		reminder := row.TaskReminder
+		reminder.FireAt = reminderFireAt(reminder.RemindAt, reminder.MinutesBeforeDue, row.DueDate)
+		if reminder.FireAt != nil {
+			var fired int
+			err = db.Get(&fired, getTaskReminderFiredV1Sql, reminder.Id, *reminder.FireAt)
+			if err != nil {
+				return generated.TaskReminderResponse{}, err
+			}
+			reminder.Fired = fired > 0
+		}
+		reminders = append(reminders, reminder)
+	}
+	// Reminders without a time go last
+	sort.SliceStable(reminders, func(i, j int) bool {
+		return nilsLast(reminders[i].FireAt == nil, reminders[j].FireAt == nil, func() bool {
+			return reminders[i].FireAt.Before(*reminders[j].FireAt)
+		})
+	})
+	return generated.TaskReminderResponse{Reminders: reminders}, nil
+}
+
+// Scheduler queries
+
+// Reminders are only sent for incomplete tasks that are in a list and not in
+// the trash
+const getPendingRemindersV1Sql = `
+SELECT r.id, r.task_id AS taskid, r.remind_at AS remindat, r.minutes_before_due AS minutesbeforedue,
+    t.title, t.due_date AS duedate
+FROM task_reminder_v1 r
+JOIN task_v1 t ON t.id = r.task_id
+WHERE t.completed_at IS NULL
+    AND r.task_id NOT IN (SELECT task_id FROM task_trash_v1)
+    AND EXISTS (SELECT 1 FROM task_to_list_v1 x WHERE x.task_id = r.task_id)
+ORDER BY r.id;
+`
+
+const claimReminderV1Sql = `
+INSERT INTO task_reminder_fired_v1 (reminder_id, fire_at)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+`
+
+const releaseReminderV1Sql = `
+DELETE FROM task_reminder_fired_v1
+WHERE reminder_id = $1 AND fire_at = $2;
+`
+
+// DueReminder is a reminder whose time has come.
+type DueReminder struct {
+	ReminderId int
+	TaskId     int
+	Title      string
+	DueDate    *time.Time
+	FireAt     time.Time
+}
+
+// GetDueReminders returns the reminders with a time up to now, including the
+// ones that have already been sent; ClaimReminder tells them apart.
+func GetDueReminders(db *sqlx.DB, now time.Time) ([]DueReminder, error) {
+	var rows []struct {
+		Id               int        `db:"id"`
+		TaskId           int        `db:"taskid"`
+		RemindAt         *time.Time `db:"remindat"`
+		MinutesBeforeDue *int       `db:"minutesbeforedue"`
+		Title            string     `db:"title"`
+		DueDate          *time.Time `db:"duedate"`
+	}
+	err := db.Select(&rows, getPendingRemindersV1Sql)
+	if err != nil {
+		return nil, err
+	}
+	var due []DueReminder
+	for _, row := range rows {
+		fireAt := reminderFireAt(row.RemindAt, row.MinutesBeforeDue, row.DueDate)
+		if fireAt == nil || fireAt.After(now) {
+			continue
+		}
+		due = append(due, DueReminder{
+			ReminderId: row.Id,
+			TaskId:     row.TaskId,
+			Title:      row.Title,
+			DueDate:    row.DueDate,
+			FireAt:     *fireAt,
+		})
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].FireAt.Before(due[j].FireAt)
+	})
+	return due, nil
+}
+
+// ClaimReminder marks a reminder as sent for its current time, and returns
+// false if it already was. A reminder is claimed before it is sent, so that it
+// is never sent twice even if the server restarts in between.
+func ClaimReminder(db *sqlx.DB, reminder DueReminder) (bool, error) {
+	result, err := db.Exec(claimReminderV1Sql, reminder.ReminderId, reminder.FireAt)
+	if err != nil {
+		return false, err
+	}
+	claimed, err := result.RowsAffected()
+	return claimed > 0, err
+}
+
+// ReleaseReminder undoes ClaimReminder after a reminder failed to send, so
+// that it is tried again.
+func ReleaseReminder(db *sqlx.DB, reminder DueReminder) error {
+	_, err := db.Exec(releaseReminderV1Sql, reminder.ReminderId, reminder.FireAt)
+	return err
+}
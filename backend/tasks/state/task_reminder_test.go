@@ -0,0 +1,102 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestReminderIsClaimedOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db := openTestDB(t, path)
+	h := &StateEventHandler{}
+	remindAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	addList := generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList}
+	addTask := generated.TaskAddEvent{Title: "Call the bank", TaskListId: 1}
+	addReminder := generated.TaskAddReminderEvent{TaskId: 1, RemindAt: &remindAt}
+	publish(t, db, h, "TaskList:Add", addList)
+	publish(t, db, h, "Task:Add", addTask)
+	publish(t, db, h, "Task:AddReminder", addReminder)
+
+	due, err := GetDueReminders(db, remindAt.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 0 {
+		t.Fatalf("%d reminders due before their time", len(due))
+	}
+	due, err = GetDueReminders(db, remindAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].TaskId != 1 || !due[0].FireAt.Equal(remindAt) {
+		t.Fatalf("due reminders %+v, want the reminder for task 1 at %s", due, remindAt)
+	}
+	reminder := due[0]
+	if claimed, err := ClaimReminder(db, reminder); err != nil || !claimed {
+		t.Fatalf("first claim: %v, %v", claimed, err)
+	}
+	if claimed, err := ClaimReminder(db, reminder); err != nil || claimed {
+		t.Fatalf("second claim: %v, %v", claimed, err)
+	}
+
+	// The claim outlives a restart, and a rebuild of the projections
+	db.Close()
+	db = openTestDB(t, path)
+	if _, err = RebuildProjections(db); err != nil {
+		t.Fatal(err)
+	}
+	due, err = GetDueReminders(db, remindAt.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].ReminderId != reminder.ReminderId {
+		t.Fatalf("due reminders after restart %+v, want reminder %d", due, reminder.ReminderId)
+	}
+	if claimed, err := ClaimReminder(db, due[0]); err != nil || claimed {
+		t.Fatalf("claim after restart: %v, %v", claimed, err)
+	}
+}
+
+func TestReleasedReminderIsClaimedAgain(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	dueDate := time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC)
+	minutes := 30
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Submit report", TaskListId: 1, DueDate: &dueDate})
+	apply(t, db, h.HandleTaskAddReminderEvent, generated.TaskAddReminderEvent{TaskId: 1, MinutesBeforeDue: &minutes})
+
+	due, err := GetDueReminders(db, dueDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || !due[0].FireAt.Equal(dueDate.Add(-30*time.Minute)) {
+		t.Fatalf("due reminders %+v, want one 30 minutes before %s", due, dueDate)
+	}
+	if claimed, err := ClaimReminder(db, due[0]); err != nil || !claimed {
+		t.Fatalf("claim: %v, %v", claimed, err)
+	}
+	if err = ReleaseReminder(db, due[0]); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := ClaimReminder(db, due[0]); err != nil || !claimed {
+		t.Fatalf("claim after release: %v, %v", claimed, err)
+	}
+
+	// Moving the due date moves the reminder, which can be sent again
+	newDueDate := dueDate.Add(24 * time.Hour)
+	apply(t, db, h.HandleTaskUpdateDueDateEvent, generated.TaskUpdateDueDateEvent{TaskId: 1, DueDate: &newDueDate})
+	due, err = GetDueReminders(db, newDueDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 {
+		t.Fatalf("due reminders %+v, want one", due)
+	}
+	if claimed, err := ClaimReminder(db, due[0]); err != nil || !claimed {
+		t.Fatalf("claim after moving the due date: %v, %v", claimed, err)
+	}
+}
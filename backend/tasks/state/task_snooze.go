@@ -0,0 +1,117 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// A snoozed task has a start time, added to task_v1 by a migration, and is
+// left out of list contents and counts until that time arrives. Whether a task
+// is snoozed depends on the time of the query rather than on an event, so that
+// rebuilding the projection from the event log gives the same state.
+
+const addTaskStartAtV1Sql = `
+ALTER TABLE task_v1 ADD COLUMN start_at DATETIME;
+`
+
+func migrateAddTaskStartAt(tx *sqlx.Tx) error {
+	_, err := tx.Exec(addTaskStartAtV1Sql)
+	return err
+}
+
+// Event handlers
+const updateTaskStartAtV1Sql = `
+UPDATE task_v1
+SET start_at = $1
+WHERE id = $2;
+`
+
+// undoTaskSnooze returns the event that puts back a task's previous start time.
+func undoTaskSnooze(task generated.Task) undoEvent {
+	if task.StartAt == nil {
+		return undoEvent{"Task:Unsnooze", generated.TaskUnsnoozeEvent{TaskId: task.Id}}
+	}
+	return undoEvent{"Task:Snooze", generated.TaskSnoozeEvent{TaskId: task.Id, Until: *task.StartAt}}
+}
+
+func (h *StateEventHandler) HandleTaskSnoozeEvent(tx *sqlx.Tx, event *generated.TaskSnoozeEvent) (bool, error) {
+	fmt.Printf("Task v1: SnoozeTaskEvent %d %v\n", event.TaskId, event.Until)
+	task, err := getTaskForUndo(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "Task:Snooze", event.Timestamp, undoTaskSnooze(task)); err != nil {
+		return true, err
+	}
+	// Start times are compared with the current time in SQL, so they are
+	// stored in UTC
+	until := event.Until.UTC()
+	_, err = tx.Exec(updateTaskStartAtV1Sql, until, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        event.TaskId,
+		UpdateType:    "snooze",
+		SystemComment: fmt.Sprintf("Snoozed until %s", until.Format(time.RFC3339)),
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+func (h *StateEventHandler) HandleTaskUnsnoozeEvent(tx *sqlx.Tx, event *generated.TaskUnsnoozeEvent) (bool, error) {
+	fmt.Printf("Task v1: UnsnoozeTaskEvent %d\n", event.TaskId)
+	task, err := getTaskForUndo(tx, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	if task.StartAt == nil {
+		return true, nil
+	}
+	if err = h.recordUndo(tx, "Task:Unsnooze", event.Timestamp, undoTaskSnooze(task)); err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(updateTaskStartAtV1Sql, nil, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        event.TaskId,
+		UpdateType:    "snooze",
+		SystemComment: "Snooze removed",
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+func isSnoozed(task generated.Task, now time.Time) bool {
+	return task.StartAt != nil && task.StartAt.After(now)
+}
+
+// withoutSnoozedTasks removes the tasks that are snoozed at the given time.
+// Subtasks of a snoozed task are not snoozed themselves, and are shown at the
+// top level like the subtasks of any parent that isn't in the list.
+func withoutSnoozedTasks(rows []subtaskRow, now time.Time) []subtaskRow {
+	awake := make([]subtaskRow, 0, len(rows))
+	for _, row := range rows {
+		if !isSnoozed(row.Task, now) {
+			awake = append(awake, row)
+		}
+	}
+	return awake
+}
+
+// nextOccurrenceStartAt returns the start time of a recurring task's next
+// occurrence, which keeps the same offset from the due date.
+func nextOccurrenceStartAt(startAt, dueDate *time.Time, nextDueDate time.Time) *time.Time {
+	if startAt == nil || dueDate == nil {
+		return nil
+	}
+	next := nextDueDate.Add(startAt.Sub(*dueDate)).UTC()
+	return &next
+}
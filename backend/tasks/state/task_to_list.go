@@ -1,7 +1,9 @@
 package state
 
 import (
+	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 
@@ -13,7 +15,7 @@ const taskToListSchema = `
 CREATE TABLE IF NOT EXISTS task_to_list_v1 (
     task_id INTEGER NOT NULL,
     list_id INTEGER NOT NULL,
-    position INTEGER NOT NULL,
+    position INTEGER NOT NULL, -- replaced by sort_key in migration 1
     PRIMARY KEY (task_id, list_id),
     FOREIGN KEY (task_id) REFERENCES task_v1(id),
     FOREIGN KEY (list_id) REFERENCES task_list_v1(id)
@@ -26,14 +28,6 @@ func InitTaskToList(tx *sqlx.Tx) error {
 	return err
 }
 
-// Event handler
-const insertTaskToListV1Sql = `
-INSERT INTO task_to_list_v1 (task_id, list_id, position)
-SELECT :taskid, :listid, COALESCE(MAX(position), 0) + 1
-FROM task_to_list_v1 WHERE list_id = :listid
-ON CONFLICT (task_id, list_id) DO NOTHING;
-`
-
 const deleteTaskToListV1Sql = `
 DELETE FROM task_to_list_v1
 WHERE task_id = :taskid;
@@ -44,78 +38,57 @@ DELETE FROM task_to_list_v1
 WHERE task_id = :taskid AND list_id = :oldlistid;
 `
 
-const moveTaskToListV1Sql = `
-INSERT INTO task_to_list_v1 (task_id, list_id, position)
-SELECT :taskid, :newlistid, COALESCE(MAX(position), 0) + 1
-FROM task_to_list_v1 WHERE list_id = :newlistid
-ON CONFLICT (task_id, list_id) DO NOTHING;
-`
-
-// We can get into situations where multiple tasks have the same position in the
-// list. Repairing just means ordering the tasks by position and then updating
-// the position to be the row number.
-const repairTaskOrderV1Sql = `
-UPDATE task_to_list_v1
-SET position = (
-    SELECT row_num
-    FROM (
-        SELECT task_id, ROW_NUMBER() OVER (ORDER BY position) as row_num
-        FROM task_to_list_v1
-        WHERE list_id = :tasklistid
-    ) numbered
-    WHERE numbered.task_id = task_to_list_v1.task_id
-)
-WHERE list_id = :tasklistid;
-`
-
-const reorderTasksV1Sql = `
-WITH old_task AS (
-  SELECT position FROM task_to_list_v1 WHERE task_id = :oldtaskid AND list_id = :tasklistid
-), new_task AS (
-  SELECT position + 1 AS position FROM task_to_list_v1 WHERE task_id = :aftertaskid AND list_id = :tasklistid
-)
-UPDATE task_to_list_v1
-SET position = CASE
-  WHEN position = (SELECT position FROM old_task) THEN (SELECT position FROM new_task)
-  WHEN position > (SELECT position FROM old_task) AND position < (SELECT position FROM new_task) THEN position - 1
-  WHEN position < (SELECT position FROM old_task) AND position >= (SELECT position FROM new_task) THEN position + 1
-  ELSE position
-END
-WHERE list_id = :tasklistid;
-`
-
-const reorderTaskToFrontV1Sql = `
-UPDATE task_to_list_v1
-SET position = CASE
-  WHEN task_id = :oldtaskid THEN 1
-  ELSE position + 1
-END
-WHERE list_id = :tasklistid;
+const removeTaskFromListV1Sql = `
+DELETE FROM task_to_list_v1
+WHERE task_id = :taskid AND list_id = :listid;
 `
 
 const duplicateTaskV1Sql = `
-INSERT INTO task_v1 (title, due_date, completed_at)
-SELECT title, due_date, NULL
+INSERT INTO task_v1 (title, notes, priority, due_date, start_at, completed_at)
+SELECT title, notes, priority, due_date, start_at, NULL
 FROM task_v1
 WHERE id = $1
 RETURNING id;
 `
 
-const addDuplicatedTaskToListV1Sql = `
-INSERT INTO task_to_list_v1 (task_id, list_id, position)
-SELECT :taskid, :listid, COALESCE(MAX(position), 0) + 1
-FROM task_to_list_v1 WHERE list_id = :listid;
+const duplicateTaskRecurrenceV1Sql = `
+INSERT INTO task_recurrence_v1 (task_id, rule)
+SELECT $1, rule
+FROM task_recurrence_v1
+WHERE task_id = $2;
 `
 
 func (h *StateEventHandler) HandleTaskListAddTaskEvent(tx *sqlx.Tx, event *generated.TaskListAddTaskEvent) (bool, error) {
 	fmt.Printf("TaskToList v1: AddTaskToListEvent %d %d\n", event.TaskId, event.ListId)
-	_, err := tx.NamedExec(insertTaskToListV1Sql, event)
-	return true, err
+	inverse, err := undoAddToList(tx, event.ListId, []int{event.TaskId})
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:AddTask", event.Timestamp, inverse...); err != nil {
+		return true, err
+	}
+	return true, appendTaskToList(tx, event.TaskId, event.ListId)
 }
 
 func (h *StateEventHandler) HandleTaskListMoveTasksEvent(tx *sqlx.Tx, event *generated.TaskListMoveTasksEvent) (bool, error) {
 	fmt.Printf("TaskToList v1: MoveTasksEvent %v from %d to %d\n", event.TaskIds, event.OldListId, event.NewListId)
-	for _, taskId := range event.TaskIds {
+	// Subtasks move along with their parents
+	taskIds, err := withDescendants(tx, event.TaskIds)
+	if err != nil {
+		return true, err
+	}
+	inverse, err := undoAddToList(tx, event.NewListId, taskIds)
+	if err != nil {
+		return true, err
+	}
+	restore, err := undoRemoveFromList(tx, event.OldListId, taskIds)
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:MoveTasks", event.Timestamp, append(inverse, restore...)...); err != nil {
+		return true, err
+	}
+	for _, taskId := range taskIds {
 		// First remove from old list
 		_, err := tx.NamedExec(moveTaskFromListV1Sql, map[string]interface{}{
 			"taskid":    taskId,
@@ -126,11 +99,7 @@ func (h *StateEventHandler) HandleTaskListMoveTasksEvent(tx *sqlx.Tx, event *gen
 		}
 
 		// Then add to new list
-		_, err = tx.NamedExec(moveTaskToListV1Sql, map[string]interface{}{
-			"taskid":    taskId,
-			"newlistid": event.NewListId,
-		})
-		if err != nil {
+		if err = appendTaskToList(tx, taskId, event.NewListId); err != nil {
 			return true, err
 		}
 	}
@@ -139,10 +108,39 @@ func (h *StateEventHandler) HandleTaskListMoveTasksEvent(tx *sqlx.Tx, event *gen
 
 func (h *StateEventHandler) HandleTaskListCopyTasksEvent(tx *sqlx.Tx, event *generated.TaskListCopyTasksEvent) (bool, error) {
 	fmt.Printf("TaskToList v1: CopyTasksEvent %v to %d\n", event.TaskIds, event.NewListId)
+	// Subtasks are copied along with their parents
+	taskIds, err := withDescendants(tx, event.TaskIds)
+	if err != nil {
+		return true, err
+	}
+	inverse, err := undoAddToList(tx, event.NewListId, taskIds)
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:CopyTasks", event.Timestamp, inverse...); err != nil {
+		return true, err
+	}
+	for _, taskId := range taskIds {
+		if err = appendTaskToList(tx, taskId, event.NewListId); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (h *StateEventHandler) HandleTaskListRemoveTasksEvent(tx *sqlx.Tx, event *generated.TaskListRemoveTasksEvent) (bool, error) {
+	fmt.Printf("TaskToList v1: RemoveTasksEvent %v from %d\n", event.TaskIds, event.ListId)
+	inverse, err := undoRemoveFromList(tx, event.ListId, event.TaskIds)
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:RemoveTasks", event.Timestamp, inverse...); err != nil {
+		return true, err
+	}
 	for _, taskId := range event.TaskIds {
-		_, err := tx.NamedExec(insertTaskToListV1Sql, map[string]interface{}{
+		_, err = tx.NamedExec(removeTaskFromListV1Sql, map[string]interface{}{
 			"taskid": taskId,
-			"listid": event.NewListId,
+			"listid": event.ListId,
 		})
 		if err != nil {
 			return true, err
@@ -152,60 +150,133 @@ func (h *StateEventHandler) HandleTaskListCopyTasksEvent(tx *sqlx.Tx, event *gen
 }
 
 func (h *StateEventHandler) HandleTaskListReorderTasksEvent(tx *sqlx.Tx, event *generated.TaskListReorderTasksEvent) (bool, error) {
-	_, err := tx.NamedExec(repairTaskOrderV1Sql, map[string]interface{}{
-		"tasklistid": event.TaskListId,
-	})
+	afterTaskId, err := undoPrecedingTask(tx, event.TaskListId, event.OldTaskId)
 	if err != nil {
 		return true, err
 	}
-
-	if event.AfterTaskId == nil {
-		fmt.Printf("TaskToList v1: ReorderTasksEvent %d %d -> front\n", event.TaskListId, event.OldTaskId)
-		_, err := tx.NamedExec(reorderTaskToFrontV1Sql, event)
+	err = h.recordUndo(tx, "TaskList:ReorderTasks", event.Timestamp, undoEvent{"TaskList:ReorderTasks", generated.TaskListReorderTasksEvent{
+		TaskListId:  event.TaskListId,
+		OldTaskId:   event.OldTaskId,
+		AfterTaskId: afterTaskId,
+	}})
+	if err != nil {
 		return true, err
-	} else {
-		fmt.Printf("TaskToList v1: ReorderTasksEvent %d %d -> %d\n", event.TaskListId, event.OldTaskId, *event.AfterTaskId)
-		_, err := tx.NamedExec(reorderTasksV1Sql, event)
+	}
+
+	key, err := taskReorderSortKey(tx, event)
+	if err != nil || key == "" {
 		return true, err
 	}
+	_, err = tx.Exec(updateTaskSortKeyV1Sql, key, event.TaskListId, event.OldTaskId)
+	return true, err
+}
+
+// taskReorderSortKey returns the new key for the task being reordered, or an
+// empty string if it stays where it is. The key is always between two adjacent
+// tasks, so it can't collide with a key given out by a concurrent reorder.
+// AfterTaskId takes precedence over BeforeTaskId.
+func taskReorderSortKey(tx *sqlx.Tx, event *generated.TaskListReorderTasksEvent) (string, error) {
+	// Make sure the task is in the list
+	if _, err := getTaskSortKey(tx, event.TaskListId, event.OldTaskId); err != nil {
+		return "", err
+	}
+	var lower, upper string
+	var err error
+	switch {
+	case event.AfterTaskId != nil:
+		if *event.AfterTaskId == event.OldTaskId {
+			return "", nil
+		}
+		fmt.Printf("TaskToList v1: ReorderTasksEvent %d %d -> after %d\n", event.TaskListId, event.OldTaskId, *event.AfterTaskId)
+		lower, err = getTaskSortKey(tx, event.TaskListId, *event.AfterTaskId)
+		if err != nil {
+			return "", err
+		}
+		err = tx.Get(&upper, getNextTaskSortKeyV1Sql, event.TaskListId, lower, event.OldTaskId)
+	case event.BeforeTaskId != nil:
+		if *event.BeforeTaskId == event.OldTaskId {
+			return "", nil
+		}
+		fmt.Printf("TaskToList v1: ReorderTasksEvent %d %d -> before %d\n", event.TaskListId, event.OldTaskId, *event.BeforeTaskId)
+		upper, err = getTaskSortKey(tx, event.TaskListId, *event.BeforeTaskId)
+		if err != nil {
+			return "", err
+		}
+		err = tx.Get(&lower, getPreviousTaskSortKeyV1Sql, event.TaskListId, upper, event.OldTaskId)
+	default:
+		fmt.Printf("TaskToList v1: ReorderTasksEvent %d %d -> front\n", event.TaskListId, event.OldTaskId)
+		err = tx.Get(&upper, getNextTaskSortKeyV1Sql, event.TaskListId, "", event.OldTaskId)
+	}
+	if err != nil {
+		return "", err
+	}
+	return sortKeyBetween(lower, upper)
 }
 
 func (h *StateEventHandler) HandleTaskListDuplicateTasksEvent(tx *sqlx.Tx, event *generated.TaskListDuplicateTasksEvent) (bool, error) {
 	fmt.Printf("TaskToList v1: DuplicateTasksEvent %v to %d\n", event.TaskIds, event.NewListId)
-	for _, sourceTaskId := range event.TaskIds {
+	// Subtasks are duplicated along with their parents
+	taskIds, err := withDescendants(tx, event.TaskIds)
+	if err != nil {
+		return true, err
+	}
+	duplicates := make(map[int]int, len(taskIds))
+	var inverse []undoEvent
+	for _, sourceTaskId := range taskIds {
 		// First duplicate the task
 		var newTaskId int
 		err := tx.Get(&newTaskId, duplicateTaskV1Sql, sourceTaskId)
 		if err != nil {
 			return true, err
 		}
+		duplicates[sourceTaskId] = newTaskId
+		inverse = append(inverse, undoEvent{"Task:Delete", generated.TaskDeleteEvent{TaskId: newTaskId}})
+		if err = indexTask(tx, newTaskId); err != nil {
+			return true, err
+		}
 
-		// Then add the new task to the target list
-		_, err = tx.NamedExec(addDuplicatedTaskToListV1Sql, map[string]interface{}{
-			"taskid": newTaskId,
-			"listid": event.NewListId,
-		})
+		// Carry over the recurrence rule, if any
+		_, err = tx.Exec(duplicateTaskRecurrenceV1Sql, newTaskId, sourceTaskId)
 		if err != nil {
 			return true, err
 		}
+		if err = copyRelativeReminders(tx, newTaskId, sourceTaskId); err != nil {
+			return true, err
+		}
+
+		// Then add the new task to the target list
+		if err = appendTaskToList(tx, newTaskId, event.NewListId); err != nil {
+			return true, err
+		}
 	}
-	return true, nil
+	if err = h.recordUndo(tx, "TaskList:DuplicateTasks", event.Timestamp, inverse...); err != nil {
+		return true, err
+	}
+	return true, copyTaskParents(tx, duplicates)
 }
 
 // State queries
 const getTasksForListV1Sql = `
-SELECT t.id, t.title, t.due_date AS duedate, t.completed_at AS completedat
+SELECT t.id, t.title, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat, t.priority, r.rule AS recurrence,
+    tp.parent_id AS parenttaskid, tp.position AS subtaskposition,
+    (SELECT COUNT(*) FROM task_parent_v1 c WHERE c.parent_id = t.id) AS subtaskcount,
+    (SELECT COUNT(*) FROM task_parent_v1 c JOIN task_v1 ct ON ct.id = c.task_id
+     WHERE c.parent_id = t.id AND ct.completed_at IS NOT NULL) AS subtaskscompleted
 FROM task_v1 t
 JOIN task_to_list_v1 ttl ON t.id = ttl.task_id
+LEFT JOIN task_recurrence_v1 r ON r.task_id = t.id
+LEFT JOIN task_parent_v1 tp ON tp.task_id = t.id
 WHERE ttl.list_id = $1
-ORDER BY ttl.position;
+ORDER BY ttl.sort_key;
 `
 
-// Returns the number of total & completed tasks for every list
+// Returns the number of total & completed tasks for every list, leaving out
+// tasks snoozed past $2 unless $1 is set
 const getTaskMetadataV1Sql = `
 SELECT list_id AS listid, COUNT(*) AS total, SUM(CASE WHEN task_v1.completed_at IS NOT NULL THEN 1 ELSE 0 END) AS completed
 FROM task_to_list_v1
 LEFT JOIN task_v1 ON task_to_list_v1.task_id = task_v1.id
+WHERE $1 OR task_v1.start_at IS NULL OR task_v1.start_at <= $2
 GROUP BY list_id;
 `
 
@@ -220,7 +291,7 @@ SELECT ttl.list_id AS listid, ttl.task_id AS taskid, lc.user_comment AS usercomm
 FROM task_to_list_v1 ttl
 LEFT JOIN latest_comments lc ON ttl.task_id = lc.task_id AND lc.rn = 1
 WHERE ttl.list_id = $1 AND lc.user_comment IS NOT NULL
-ORDER BY ttl.position;
+ORDER BY ttl.sort_key;
 `
 
 const getTaskLabelsForListV1Sql = `
@@ -231,16 +302,46 @@ LEFT JOIN task_list_v1 tl ON ttl2.list_id = tl.id
 WHERE ttl1.list_id = $1 AND tl.category = 'label'
 `
 
-func (r *StateResolver) GetApiTaskList(db *sqlx.DB, listId int) (generated.TaskResponse, error) {
-	var tasks []generated.Task = make([]generated.Task, 0)
-	err := db.Select(&tasks, getTasksForListV1Sql, listId)
-	return generated.TaskResponse{Tasks: tasks}, err
+func (r *StateResolver) GetApiTaskList(db *sqlx.DB, hideCompleted *bool, includeSnoozed *bool, listId int, sortMode *generated.TaskSortMode) (generated.TaskResponse, error) {
+	var category generated.TaskListCategory
+	err := db.Get(&category, getTaskListCategoryV1Sql, listId)
+	if err != nil && err != sql.ErrNoRows {
+		return generated.TaskResponse{}, err
+	}
+	now := time.Now()
+	var rows []subtaskRow = make([]subtaskRow, 0)
+	if category == generated.TaskListCategorySmart {
+		filter, err := getTaskListFilter(db, listId)
+		if err != nil {
+			return generated.TaskResponse{}, err
+		}
+		rows, err = getTasksForFilter(db, filter, now)
+		if err != nil {
+			return generated.TaskResponse{}, err
+		}
+	} else {
+		err = db.Select(&rows, getTasksForListV1Sql, listId)
+		if err != nil {
+			return generated.TaskResponse{}, err
+		}
+	}
+	if includeSnoozed == nil || !*includeSnoozed {
+		rows = withoutSnoozedTasks(rows, now)
+	}
+	rows, err = sortTaskRows(rows, sortMode, hideCompleted)
+	return generated.TaskResponse{Tasks: buildTaskTree(rows)}, err
 }
 
-func (r *StateResolver) GetApiTasklistMetadata(db *sqlx.DB) (generated.TaskListMetadataResponse, error) {
+func (r *StateResolver) GetApiTasklistMetadata(db *sqlx.DB, includeSnoozed *bool) (generated.TaskListMetadataResponse, error) {
+	now := time.Now()
+	withSnoozed := includeSnoozed != nil && *includeSnoozed
 	var metadata []generated.TaskListMetadata = make([]generated.TaskListMetadata, 0)
-	err := db.Select(&metadata, getTaskMetadataV1Sql)
-	return generated.TaskListMetadataResponse{Metadata: metadata}, err
+	err := db.Select(&metadata, getTaskMetadataV1Sql, withSnoozed, now.UTC())
+	if err != nil {
+		return generated.TaskListMetadataResponse{}, err
+	}
+	smartMetadata, err := getSmartTaskListMetadata(db, withSnoozed, now)
+	return generated.TaskListMetadataResponse{Metadata: append(metadata, smartMetadata...)}, err
 }
 
 func (r *StateResolver) GetApiTasklistRecent_comments(db *sqlx.DB, listId int) (generated.TaskRecentCommentResponse, error) {
@@ -0,0 +1,320 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Deleted tasks keep their task_v1 row (and history), and remember the lists
+// and sort keys they had there, as well as their parent task and the subtasks
+// that were promoted to top-level tasks, so that they can be restored later.
+// Like deleted task lists, they stay in the trash for the retention window, and
+// the server then purges them with a Task:PurgeTrash event.
+const TaskTrashRetention = 30 * 24 * time.Hour
+
+// Table schema
+const taskTrashSchema = `
+CREATE TABLE IF NOT EXISTS task_trash_v1 (
+    task_id INTEGER PRIMARY KEY NOT NULL,
+    deleted_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    parent_id INTEGER,
+    parent_position INTEGER,
+    FOREIGN KEY (task_id) REFERENCES task_v1(id)
+);
+
+CREATE TABLE IF NOT EXISTS task_trash_list_v1 (
+    task_id INTEGER NOT NULL,
+    list_id INTEGER NOT NULL,
+    sort_key TEXT NOT NULL,
+    PRIMARY KEY (task_id, list_id),
+    FOREIGN KEY (task_id) REFERENCES task_v1(id),
+    FOREIGN KEY (list_id) REFERENCES task_list_v1(id)
+);
+
+CREATE TABLE IF NOT EXISTS task_trash_child_v1 (
+    task_id INTEGER NOT NULL,
+    child_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (task_id, child_id),
+    FOREIGN KEY (task_id) REFERENCES task_v1(id),
+    FOREIGN KEY (child_id) REFERENCES task_v1(id)
+);
+`
+
+func InitTaskTrash(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing TaskTrash v1\n")
+	_, err := tx.Exec(taskTrashSchema)
+	return err
+}
+
+// Event handler
+const insertTaskTrashV1Sql = `
+INSERT INTO task_trash_v1 (task_id, deleted_at, parent_id, parent_position)
+SELECT $1, $2, tp.parent_id, tp.position
+FROM (SELECT $1 AS task_id) t
+LEFT JOIN task_parent_v1 tp ON tp.task_id = t.task_id;
+`
+
+// Memberships of lists that are themselves in the trash are remembered as well,
+// so that the task comes back if either of them is restored
+const trashTaskListsV1Sql = `
+INSERT INTO task_trash_list_v1 (task_id, list_id, sort_key)
+SELECT task_id, list_id, sort_key FROM task_to_list_v1 WHERE task_id = $1
+UNION ALL
+SELECT task_id, list_id, sort_key FROM task_list_trash_task_v1 WHERE task_id = $1;
+`
+
+const trashTaskChildrenV1Sql = `
+INSERT INTO task_trash_child_v1 (task_id, child_id, position)
+SELECT parent_id, task_id, position FROM task_parent_v1 WHERE parent_id = $1;
+`
+
+const deleteTaskFromTrashedListsV1Sql = `
+DELETE FROM task_list_trash_task_v1
+WHERE task_id = $1;
+`
+
+const getTaskTrashV1Sql = `
+SELECT parent_id AS parentid, parent_position AS parentposition
+FROM task_trash_v1
+WHERE task_id = $1;
+`
+
+const getTaskTrashListsV1Sql = `
+SELECT ttl.list_id AS listid, ttl.sort_key AS sortkey, tr.list_id IS NOT NULL AS listtrashed
+FROM task_trash_list_v1 ttl
+LEFT JOIN task_list_trash_v1 tr ON tr.list_id = ttl.list_id
+JOIN task_list_v1 tl ON tl.id = ttl.list_id
+WHERE ttl.task_id = $1;
+`
+
+const restoreTaskToTrashedListV1Sql = `
+INSERT INTO task_list_trash_task_v1 (list_id, task_id, sort_key)
+VALUES ($1, $2, $3)
+ON CONFLICT (list_id, task_id) DO NOTHING;
+`
+
+// The parent is only restored if it hasn't been deleted in the meantime
+const restoreTaskParentV1Sql = `
+INSERT INTO task_parent_v1 (task_id, parent_id, position)
+SELECT $1, $2, $3
+WHERE NOT EXISTS (SELECT 1 FROM task_trash_v1 WHERE task_id = $2);
+`
+
+// Subtasks only go back under the task if they are still top-level tasks that
+// aren't in the trash themselves
+const restoreTaskChildrenV1Sql = `
+INSERT INTO task_parent_v1 (task_id, parent_id, position)
+SELECT c.child_id, c.task_id, c.position
+FROM task_trash_child_v1 c
+WHERE c.task_id = $1
+    AND NOT EXISTS (SELECT 1 FROM task_parent_v1 WHERE task_id = c.child_id)
+    AND NOT EXISTS (SELECT 1 FROM task_trash_v1 WHERE task_id = c.child_id);
+`
+
+const deleteTaskTrashChildrenV1Sql = `
+DELETE FROM task_trash_child_v1
+WHERE task_id = $1;
+`
+
+const deleteTaskTrashV1Sql = `
+DELETE FROM task_trash_v1
+WHERE task_id = $1;
+`
+
+const deleteTaskTrashListsV1Sql = `
+DELETE FROM task_trash_list_v1
+WHERE task_id = $1;
+`
+
+const getExpiredTrashedTasksV1Sql = `
+SELECT task_id FROM task_trash_v1 WHERE deleted_at < $1 ORDER BY task_id;
+`
+
+const indexTaskCommentsV1Sql = `
+INSERT INTO task_comment_fts_v1 (task_id, comment)
+SELECT task_id, replace(replace(user_comment, char(57344), ''), char(57345), '') FROM task_history_v1
+WHERE task_id = $1 AND user_comment IS NOT NULL;
+`
+
+// trashTask records everything needed to restore a task before
+// HandleTaskDeleteEvent removes it from its lists and its parent.
+func trashTask(tx *sqlx.Tx, taskId int, deletedAt time.Time) error {
+	_, err := tx.Exec(insertTaskTrashV1Sql, taskId, deletedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(trashTaskListsV1Sql, taskId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(trashTaskChildrenV1Sql, taskId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(deleteTaskFromTrashedListsV1Sql, taskId)
+	return err
+}
+
+func (h *StateEventHandler) HandleTaskRestoreEvent(tx *sqlx.Tx, event *generated.TaskRestoreEvent) (bool, error) {
+	fmt.Printf("TaskTrash v1: RestoreTaskEvent %d\n", event.TaskId)
+	var trash struct {
+		ParentId       *int `db:"parentid"`
+		ParentPosition *int `db:"parentposition"`
+	}
+	err := tx.Get(&trash, getTaskTrashV1Sql, event.TaskId)
+	if err == sql.ErrNoRows {
+		return true, fmt.Errorf("task %d is not in the trash", event.TaskId)
+	}
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "Task:Restore", event.Timestamp, undoEvent{"Task:Delete", generated.TaskDeleteEvent{TaskId: event.TaskId}})
+	if err != nil {
+		return true, err
+	}
+
+	var lists []struct {
+		ListId      int    `db:"listid"`
+		SortKey     string `db:"sortkey"`
+		ListTrashed bool   `db:"listtrashed"`
+	}
+	err = tx.Select(&lists, getTaskTrashListsV1Sql, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	for _, list := range lists {
+		if list.ListTrashed {
+			_, err = tx.Exec(restoreTaskToTrashedListV1Sql, list.ListId, event.TaskId, list.SortKey)
+			if err != nil {
+				return true, err
+			}
+			continue
+		}
+		if err = insertTaskToListAt(tx, event.TaskId, list.ListId, list.SortKey); err != nil {
+			return true, err
+		}
+	}
+	if trash.ParentId != nil {
+		_, err = tx.Exec(shiftSubtasksFromV1Sql, *trash.ParentId, *trash.ParentPosition)
+		if err != nil {
+			return true, err
+		}
+		_, err = tx.Exec(restoreTaskParentV1Sql, event.TaskId, *trash.ParentId, *trash.ParentPosition)
+		if err != nil {
+			return true, err
+		}
+	}
+	_, err = tx.Exec(restoreTaskChildrenV1Sql, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+
+	_, err = tx.Exec(deleteTaskTrashListsV1Sql, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(deleteTaskTrashChildrenV1Sql, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(deleteTaskTrashV1Sql, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+	if err = indexTask(tx, event.TaskId); err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(indexTaskCommentsV1Sql, event.TaskId)
+	if err != nil {
+		return true, err
+	}
+
+	historyEvent := AddTaskHistoryEvent{
+		TaskId:        event.TaskId,
+		UpdateType:    "restore",
+		SystemComment: "Task restored",
+		CreatedAt:     event.Timestamp,
+	}
+	_, err = tx.NamedExec(insertTaskHistoryV1Sql, historyEvent)
+	return true, err
+}
+
+// HandleTaskPurgeTrashEvent permanently deletes the tasks that were moved to
+// the trash before PurgeBefore. Purged tasks can't be restored, so no undo entry
+// is recorded.
+func (h *StateEventHandler) HandleTaskPurgeTrashEvent(tx *sqlx.Tx, event *generated.TaskPurgeTrashEvent) (bool, error) {
+	fmt.Printf("TaskTrash v1: PurgeTrashEvent %s\n", event.PurgeBefore.Format(time.RFC3339))
+	if !event.Timestamp.IsZero() && event.PurgeBefore.After(event.Timestamp.Add(-TaskTrashRetention)) {
+		return true, fmt.Errorf("tasks deleted after %s are still within the retention window", event.Timestamp.Add(-TaskTrashRetention).Format(time.RFC3339))
+	}
+	var taskIds []int
+	err := tx.Select(&taskIds, getExpiredTrashedTasksV1Sql, event.PurgeBefore)
+	if err != nil {
+		return true, err
+	}
+	for _, taskId := range taskIds {
+		if err = purgeTask(tx, taskId); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// State queries
+const getTrashedTasksV1Sql = `
+SELECT t.id, t.title, t.due_date AS duedate, t.start_at AS startat, t.completed_at AS completedat, t.priority, r.rule AS recurrence,
+    tr.deleted_at AS deletedat
+FROM task_trash_v1 tr
+JOIN task_v1 t ON t.id = tr.task_id
+LEFT JOIN task_recurrence_v1 r ON r.task_id = t.id
+ORDER BY tr.deleted_at DESC, tr.task_id DESC
+LIMIT 100;
+`
+
+const getTrashedTaskListsForTaskV1Sql = `
+SELECT tl.id, tl.title, tl.category, tl.archived
+FROM task_trash_list_v1 ttl
+JOIN task_list_v1 tl ON tl.id = ttl.list_id
+WHERE ttl.task_id = $1
+ORDER BY tl.sort_key;
+`
+
+func (r *StateResolver) GetApiTaskTrash(db *sqlx.DB) (generated.DeletedTaskResponse, error) {
+	var rows []struct {
+		generated.Task
+		DeletedAt time.Time `db:"deletedat"`
+	}
+	err := db.Select(&rows, getTrashedTasksV1Sql)
+	if err != nil {
+		return generated.DeletedTaskResponse{}, err
+	}
+	tasks := make([]generated.DeletedTask, 0, len(rows))
+	for _, row := range rows {
+		lists := make([]generated.TaskList, 0)
+		err = db.Select(&lists, getTrashedTaskListsForTaskV1Sql, row.Id)
+		if err != nil {
+			return generated.DeletedTaskResponse{}, err
+		}
+		row.Task.Subtasks = make([]generated.Task, 0)
+		tasks = append(tasks, generated.DeletedTask{
+			Task:       row.Task,
+			DeletedAt:  row.DeletedAt,
+			PurgeAfter: row.DeletedAt.Add(TaskTrashRetention),
+			Lists:      lists,
+		})
+	}
+	return generated.DeletedTaskResponse{Tasks: tasks}, nil
+}
+
+// GetExpiredTrashedTasks returns the IDs of the tasks that were moved to the
+// trash before a time, for the server to purge.
+func GetExpiredTrashedTasks(db *sqlx.DB, before time.Time) ([]int, error) {
+	taskIds := make([]int, 0)
+	err := db.Select(&taskIds, getExpiredTrashedTasksV1Sql, before)
+	return taskIds, err
+}
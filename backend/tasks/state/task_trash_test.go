@@ -0,0 +1,89 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestPurgeTrashedTasks(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	deletedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	remindAt := time.Date(2025, 12, 31, 9, 0, 0, 0, time.UTC)
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Inbox", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskUpdateFromCalDAVEvent, generated.TaskUpdateFromCalDAVEvent{
+		TaskListId:   1,
+		Title:        "Synced",
+		ResourceName: "from-phone.ics",
+		ResourceUid:  "phone-uid",
+	})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Deleted later", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Kept", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddReminderEvent, generated.TaskAddReminderEvent{TaskId: 1, RemindAt: &remindAt})
+	due, err := GetDueReminders(db, remindAt)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("due reminders %+v, %v", due, err)
+	}
+	if claimed, err := ClaimReminder(db, due[0]); err != nil || !claimed {
+		t.Fatalf("claiming the reminder: %v, %v", claimed, err)
+	}
+	apply(t, db, h.HandleTaskDeleteEvent, generated.TaskDeleteEvent{TaskId: 1, Timestamp: deletedAt})
+	apply(t, db, h.HandleTaskDeleteEvent, generated.TaskDeleteEvent{TaskId: 2, Timestamp: deletedAt.Add(time.Hour)})
+
+	expired, err := GetExpiredTrashedTasks(db, deletedAt.Add(time.Minute))
+	if err != nil || len(expired) != 1 || expired[0] != 1 {
+		t.Fatalf("expired tasks %v, %v, want [1]", expired, err)
+	}
+
+	// Tasks can only be purged once they are past the retention window
+	tx := db.MustBegin()
+	_, err = h.HandleTaskPurgeTrashEvent(tx, &generated.TaskPurgeTrashEvent{
+		PurgeBefore: deletedAt.Add(time.Minute),
+		Timestamp:   deletedAt.Add(TaskTrashRetention - time.Hour),
+	})
+	tx.Rollback()
+	if err == nil {
+		t.Error("purging tasks within the retention window succeeded")
+	}
+
+	var resources int
+	if err = db.Get(&resources, "SELECT COUNT(*) FROM caldav_resource_v1 WHERE task_id = 1"); err != nil || resources != 1 {
+		t.Fatalf("%d CalDAV resources for task 1, %v", resources, err)
+	}
+
+	apply(t, db, h.HandleTaskPurgeTrashEvent, generated.TaskPurgeTrashEvent{
+		PurgeBefore: deletedAt.Add(time.Minute),
+		Timestamp:   deletedAt.Add(TaskTrashRetention + time.Minute),
+	})
+	for _, table := range []struct{ name, query string }{
+		{"task_v1", "SELECT COUNT(*) FROM task_v1 WHERE id = 1"},
+		{"task_trash_v1", "SELECT COUNT(*) FROM task_trash_v1 WHERE task_id = 1"},
+		{"task_trash_list_v1", "SELECT COUNT(*) FROM task_trash_list_v1 WHERE task_id = 1"},
+		{"task_history_v1", "SELECT COUNT(*) FROM task_history_v1 WHERE task_id = 1"},
+		{"task_reminder_v1", "SELECT COUNT(*) FROM task_reminder_v1"},
+		{"task_reminder_fired_v1", "SELECT COUNT(*) FROM task_reminder_fired_v1"},
+		{"caldav_resource_v1", "SELECT COUNT(*) FROM caldav_resource_v1"},
+	} {
+		var count int
+		if err := db.Get(&count, table.query); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%d rows left in %s for the purged task", count, table.name)
+		}
+	}
+
+	// Tasks deleted later are still in the trash
+	trash, err := NewResolver().GetApiTaskTrash(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash.Tasks) != 1 || trash.Tasks[0].Task.Id != 2 {
+		t.Fatalf("trash %+v, want task 2", trash.Tasks)
+	}
+	if want := deletedAt.Add(time.Hour + TaskTrashRetention); !trash.Tasks[0].PurgeAfter.Equal(want) {
+		t.Errorf("task 2 is purged after %s, want %s", trash.Tasks[0].PurgeAfter, want)
+	}
+}
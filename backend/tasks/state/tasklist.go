@@ -15,7 +15,7 @@ CREATE TABLE IF NOT EXISTS task_list_v1 (
     title TEXT NOT NULL,
     category TEXT NOT NULL,
     archived BOOLEAN NOT NULL,
-	position INTEGER NOT NULL
+	position INTEGER NOT NULL -- replaced by sort_key in migration 1
 );
 `
 
@@ -28,8 +28,8 @@ func InitTaskList(tx *sqlx.Tx) error {
 // Event handler
 
 const insertTaskListV1Sql = `
-INSERT INTO task_list_v1 (title, category, archived, position)
-VALUES (:title, :category, :archived, (SELECT IFNULL(MAX(position), 0) + 1 FROM task_list_v1));
+INSERT INTO task_list_v1 (title, category, archived, sort_key)
+VALUES ($1, $2, $3, $4);
 `
 
 const updateTaskListTitleV1Sql = `
@@ -44,50 +44,54 @@ SET archived = :archived
 WHERE id = :listid;
 `
 
-const reorderTaskListV1Sql = `
-WITH old_list AS (
-  SELECT position FROM task_list_v1 WHERE id = :listid
-), new_list AS (
-  SELECT position + 1 AS position FROM task_list_v1 WHERE id = :afterlistid
-)
-UPDATE task_list_v1
-SET position = CASE
-  WHEN position = (SELECT position FROM old_list) THEN (SELECT position FROM new_list)
-  WHEN position > (SELECT position FROM old_list) AND position < (SELECT position FROM new_list) THEN position - 1
-  WHEN position < (SELECT position FROM old_list) AND position >= (SELECT position FROM new_list) THEN position + 1
-  ELSE position
-END
-`
-
-const reorderTaskListToFrontV1Sql = `
-UPDATE task_list_v1
-SET position = CASE
-  WHEN id = :listid THEN 1
-  ELSE position + 1
-END
-`
-
 func (h *StateEventHandler) HandleTaskListAddEvent(tx *sqlx.Tx, event *generated.TaskListAddEvent) (bool, error) {
 	fmt.Printf("TaskList v1: AddTaskListEvent %v %v %v\n", event.Title, event.Category, event.Archived)
-	_, err := tx.NamedExec(
-		insertTaskListV1Sql,
-		*event,
-	)
-	return true, err
+	listId, err := insertTaskList(tx, event.Title, event.Category, event.Archived)
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:Add", event.Timestamp, undoDeleteTaskList(listId, false)); err != nil {
+		return true, err
+	}
+	if err = h.recordCreatedBy(tx, setTaskListCreatedEventIdV1Sql, listId); err != nil {
+		return true, err
+	}
+	return true, indexTaskList(tx, listId)
 }
 
 func (h *StateEventHandler) HandleTaskListUpdateTitleEvent(tx *sqlx.Tx, event *generated.TaskListUpdateTitleEvent) (bool, error) {
 	fmt.Printf("TaskList v1: UpdateTaskListTitleEvent %d %v\n", event.ListId, event.Title)
-	_, err := tx.NamedExec(
+	var taskList generated.TaskList
+	err := tx.Get(&taskList, getTaskListByIdV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "TaskList:UpdateTitle", event.Timestamp, undoEvent{"TaskList:UpdateTitle", generated.TaskListUpdateTitleEvent{ListId: taskList.Id, Title: taskList.Title}})
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(
 		updateTaskListTitleV1Sql,
 		*event,
 	)
-	return true, err
+	if err != nil {
+		return true, err
+	}
+	return true, indexTaskList(tx, event.ListId)
 }
 
 func (h *StateEventHandler) HandleTaskListUpdateArchivedEvent(tx *sqlx.Tx, event *generated.TaskListUpdateArchivedEvent) (bool, error) {
 	fmt.Printf("TaskList v1: UpdateTaskListArchivedEvent %d %v\n", event.ListId, event.Archived)
-	_, err := tx.NamedExec(
+	var taskList generated.TaskList
+	err := tx.Get(&taskList, getTaskListByIdV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "TaskList:UpdateArchived", event.Timestamp, undoEvent{"TaskList:UpdateArchived", generated.TaskListUpdateArchivedEvent{ListId: taskList.Id, Archived: taskList.Archived}})
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.NamedExec(
 		updateTaskListArchivedV1Sql,
 		*event,
 	)
@@ -96,38 +100,74 @@ func (h *StateEventHandler) HandleTaskListUpdateArchivedEvent(tx *sqlx.Tx, event
 
 func (h *StateEventHandler) HandleTaskListReorderEvent(tx *sqlx.Tx, event *generated.TaskListReorderEvent) (bool, error) {
 	fmt.Printf("TaskList v1: ReorderTaskListEvent %d %d\n", event.ListId, event.AfterListId)
-	if event.AfterListId == nil {
-		_, err := tx.NamedExec(
-			reorderTaskListToFrontV1Sql,
-			*event,
-		)
+	inverse, err := undoTaskListReorder(tx, event.ListId)
+	if err != nil {
 		return true, err
 	}
-	_, err := tx.NamedExec(
-		reorderTaskListV1Sql,
-		*event,
-	)
+	if err = h.recordUndo(tx, "TaskList:Reorder", event.Timestamp, inverse); err != nil {
+		return true, err
+	}
+
+	// The list goes between AfterListId (or the front) and the list after it
+	var lower, upper string
+	if event.AfterListId != nil {
+		if *event.AfterListId == event.ListId {
+			return true, nil
+		}
+		err = tx.Get(&lower, getTaskListSortKeyV1Sql, *event.AfterListId)
+		if err != nil {
+			return true, err
+		}
+	}
+	err = tx.Get(&upper, getNextTaskListSortKeyV1Sql, lower, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	key, err := sortKeyBetween(lower, upper)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(updateTaskListSortKeyV1Sql, key, event.ListId)
 	return true, err
 }
 
+// insertTaskList adds a list after all other lists and returns its ID.
+func insertTaskList(tx *sqlx.Tx, title string, category generated.TaskListCategory, archived bool) (int, error) {
+	key, err := nextTaskListSortKey(tx)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(insertTaskListV1Sql, title, category, archived, key)
+	if err != nil {
+		return 0, err
+	}
+	listId, err := result.LastInsertId()
+	return int(listId), err
+}
+
 // State queries
 
+// Lists in the trash are only returned by /api/tasklist/trash
 const getAllTaskListsV1Sql = `
-SELECT id, title, category, archived FROM task_list_v1 ORDER BY position;
+SELECT id, title, category, archived FROM task_list_v1 WHERE id NOT IN (SELECT list_id FROM task_list_trash_v1) ORDER BY sort_key;
 `
 
 const getCategoryTaskListsV1Sql = `
-SELECT id, title, category, archived FROM task_list_v1 WHERE archived = false AND category = $1 ORDER BY position;
+SELECT id, title, category, archived FROM task_list_v1 WHERE archived = false AND category = $1 AND id NOT IN (SELECT list_id FROM task_list_trash_v1) ORDER BY sort_key;
 `
 
 const getArchivedTaskListsV1Sql = `
-SELECT id, title, category, archived FROM task_list_v1 WHERE archived = true ORDER BY position;
+SELECT id, title, category, archived FROM task_list_v1 WHERE archived = true AND id NOT IN (SELECT list_id FROM task_list_trash_v1) ORDER BY sort_key;
 `
 
 const getTaskListByIdV1Sql = `
 SELECT id, title, category, archived FROM task_list_v1 WHERE id = $1;
 `
 
+const getTaskListCategoryV1Sql = `
+SELECT category FROM task_list_v1 WHERE id = $1;
+`
+
 func (r *StateResolver) GetApiTasklistGet(db *sqlx.DB, id int) (generated.TaskList, error) {
 	var taskList generated.TaskList
 	err := db.Get(&taskList, getTaskListByIdV1Sql, id)
@@ -142,13 +182,13 @@ func (r *StateResolver) GetApiTasklistAll(db *sqlx.DB) (generated.TaskListRespon
 
 func (r *StateResolver) GetApiTasklistTodo(db *sqlx.DB) (generated.TaskListResponse, error) {
 	var taskLists []generated.TaskList = make([]generated.TaskList, 0)
-	err := db.Select(&taskLists, getCategoryTaskListsV1Sql, "toDoList")
+	err := db.Select(&taskLists, getCategoryTaskListsV1Sql, generated.TaskListCategoryToDoList)
 	return generated.TaskListResponse{TaskLists: taskLists}, err
 }
 
 func (r *StateResolver) GetApiTasklistTemplate(db *sqlx.DB) (generated.TaskListResponse, error) {
 	var taskLists []generated.TaskList = make([]generated.TaskList, 0)
-	err := db.Select(&taskLists, getCategoryTaskListsV1Sql, "template")
+	err := db.Select(&taskLists, getCategoryTaskListsV1Sql, generated.TaskListCategoryTemplate)
 	return generated.TaskListResponse{TaskLists: taskLists}, err
 }
 
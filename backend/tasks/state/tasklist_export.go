@@ -0,0 +1,182 @@
+package state
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// A list can be exported as CSV or Markdown, for pasting into reports and
+// notes. The export has the list's tasks in the order they are in, snoozed
+// tasks included, with subtasks after their parents. Labels and the latest
+// comment come from the same queries as /api/tasklist/labels and
+// /api/tasklist/recent_comments, so smart lists are exported without them.
+
+// ErrTaskListNotFound is returned when exporting a list that doesn't exist.
+var ErrTaskListNotFound = errors.New("task list not found")
+
+// TaskListExportFormats are the formats RenderTaskListExport accepts.
+var TaskListExportFormats = []string{"csv", "markdown"}
+
+// exportedTask is a task with what the export shows besides its fields.
+type exportedTask struct {
+	generated.Task
+	Depth   int
+	Parent  string
+	Labels  []string
+	Comment string
+}
+
+// RenderTaskListExport renders a list in one of TaskListExportFormats, and
+// returns the list along with the rendered export.
+func RenderTaskListExport(db *sqlx.DB, listId int, format string) (generated.TaskList, []byte, error) {
+	r := NewResolver()
+	list, err := r.GetApiTasklistGet(db, listId)
+	if err == sql.ErrNoRows {
+		return list, nil, ErrTaskListNotFound
+	}
+	if err != nil {
+		return list, nil, err
+	}
+	tasks, err := getTaskListExportTasks(db, listId)
+	if err != nil {
+		return list, nil, err
+	}
+	switch format {
+	case "csv":
+		data, err := renderTaskListCSV(tasks)
+		return list, data, err
+	case "markdown":
+		return list, renderTaskListMarkdown(list, tasks), nil
+	}
+	return list, nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func getTaskListExportTasks(db *sqlx.DB, listId int) ([]exportedTask, error) {
+	r := NewResolver()
+	includeSnoozed := true
+	response, err := r.GetApiTaskList(db, nil, &includeSnoozed, listId, nil)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := r.GetApiTasklistLabels(db, listId)
+	if err != nil {
+		return nil, err
+	}
+	taskLabels := make(map[int][]string)
+	for _, label := range labels.Labels {
+		taskLabels[label.TaskId] = append(taskLabels[label.TaskId], label.Label)
+	}
+	comments, err := r.GetApiTasklistRecent_comments(db, listId)
+	if err != nil {
+		return nil, err
+	}
+	taskComments := make(map[int]string)
+	for _, comment := range comments.Comments {
+		if comment.UserComment != nil {
+			taskComments[comment.TaskId] = *comment.UserComment
+		}
+	}
+
+	var tasks []exportedTask
+	var add func(task generated.Task, depth int, parent string)
+	add = func(task generated.Task, depth int, parent string) {
+		labels := taskLabels[task.Id]
+		sort.Strings(labels)
+		tasks = append(tasks, exportedTask{
+			Task:    task,
+			Depth:   depth,
+			Parent:  parent,
+			Labels:  labels,
+			Comment: taskComments[task.Id],
+		})
+		for _, subtask := range task.Subtasks {
+			add(subtask, depth+1, task.Title)
+		}
+	}
+	for _, task := range response.Tasks {
+		add(task, 0, "")
+	}
+	return tasks, nil
+}
+
+// formatExportDate formats a due date like taskEvent treats it: midnight UTC
+// is a date without a time.
+func formatExportDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	due := t.UTC()
+	if due.Equal(due.Truncate(24 * time.Hour)) {
+		return due.Format("2006-01-02")
+	}
+	return due.Format("2006-01-02 15:04 UTC")
+}
+
+// csvCell keeps spreadsheets from evaluating text as a formula, by prefixing
+// cells that start like one with a quote, as OWASP recommends.
+func csvCell(value string) string {
+	if value != "" && strings.IndexByte("=+-@\t\r", value[0]) >= 0 {
+		return "'" + value
+	}
+	return value
+}
+
+func renderTaskListCSV(tasks []exportedTask) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"Completed", "Title", "Parent", "Due Date", "Labels", "Latest Comment"})
+	for _, task := range tasks {
+		w.Write([]string{
+			strconv.FormatBool(task.CompletedAt != nil),
+			csvCell(task.Title),
+			csvCell(task.Parent),
+			formatExportDate(task.DueDate),
+			csvCell(strings.Join(task.Labels, ", ")),
+			csvCell(task.Comment),
+		})
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+// renderTaskListMarkdown renders the tasks as a task list, with subtasks
+// nested under their parents and the latest comment quoted below each task.
+func renderTaskListMarkdown(list generated.TaskList, tasks []exportedTask) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", list.Title)
+	for _, task := range tasks {
+		indent := strings.Repeat("  ", task.Depth)
+		checkbox := "[ ]"
+		if task.CompletedAt != nil {
+			checkbox = "[x]"
+		}
+		fmt.Fprintf(&b, "%s- %s %s", indent, checkbox, task.Title)
+		var details []string
+		if task.DueDate != nil {
+			details = append(details, "due "+formatExportDate(task.DueDate))
+		}
+		if len(task.Labels) > 0 {
+			details = append(details, strings.Join(task.Labels, ", "))
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(details, "; "))
+		}
+		b.WriteString("\n")
+		if task.Comment != "" {
+			for _, line := range strings.Split(task.Comment, "\n") {
+				fmt.Fprintf(&b, "%s  > %s\n", indent, line)
+			}
+		}
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestRenderTaskListExport(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	dueDate := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	dueTime := time.Date(2026, 1, 6, 17, 30, 0, 0, time.UTC)
+	snoozeUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Move house", Category: generated.TaskListCategoryToDoList})
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "errands", Category: generated.TaskListCategoryLabel})
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "@home", Category: generated.TaskListCategoryLabel})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Book movers", TaskListId: 1, DueDate: &dueDate})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Pack, label boxes", TaskListId: 1, DueDate: &dueTime})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Kitchen", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Cancel internet", TaskListId: 1})
+	parent := 2
+	apply(t, db, h.HandleTaskSetParentEvent, generated.TaskSetParentEvent{TaskId: 3, ParentTaskId: &parent})
+	apply(t, db, h.HandleTaskUpdateCompletedEvent, generated.TaskUpdateCompletedEvent{TaskId: 1, CompletedAt: &dueDate})
+	apply(t, db, h.HandleTaskListCopyTasksEvent, generated.TaskListCopyTasksEvent{NewListId: 2, TaskIds: []int{1}})
+	apply(t, db, h.HandleTaskListCopyTasksEvent, generated.TaskListCopyTasksEvent{NewListId: 3, TaskIds: []int{1}})
+	apply(t, db, h.HandleTaskAddCommentEvent, generated.TaskAddCommentEvent{TaskId: 2, UserComment: "Ask about \"fragile\" stickers\nand tape"})
+	apply(t, db, h.HandleTaskSnoozeEvent, generated.TaskSnoozeEvent{TaskId: 4, Until: snoozeUntil})
+
+	list, data, err := RenderTaskListExport(db, 1, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Title != "Move house" {
+		t.Errorf("exported list %+v", list)
+	}
+	want := "Completed,Title,Parent,Due Date,Labels,Latest Comment\n" +
+		"true,Book movers,,2026-01-05,\"'@home, errands\",\n" +
+		"false,\"Pack, label boxes\",,2026-01-06 17:30 UTC,,\"Ask about \"\"fragile\"\" stickers\nand tape\"\n" +
+		"false,Kitchen,\"Pack, label boxes\",,,\n" +
+		"false,Cancel internet,,,,\n"
+	if string(data) != want {
+		t.Errorf("CSV export\n%q\nwant\n%q", data, want)
+	}
+
+	_, data, err = RenderTaskListExport(db, 1, "markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "# Move house\n\n" +
+		"- [x] Book movers (due 2026-01-05; @home, errands)\n" +
+		"- [ ] Pack, label boxes (due 2026-01-06 17:30 UTC)\n" +
+		"  > Ask about \"fragile\" stickers\n" +
+		"  > and tape\n" +
+		"  - [ ] Kitchen\n" +
+		"- [ ] Cancel internet\n"
+	if string(data) != want {
+		t.Errorf("Markdown export\n%s\nwant\n%s", data, want)
+	}
+
+	if _, _, err = RenderTaskListExport(db, 1, "xlsx"); err == nil {
+		t.Error("exporting as xlsx succeeded")
+	}
+	if _, _, err = RenderTaskListExport(db, 99, "csv"); !errors.Is(err, ErrTaskListNotFound) {
+		t.Errorf("exporting a missing list: %v", err)
+	}
+}
+
+func TestRenderTaskListCSVEscapesFormulas(t *testing.T) {
+	completedAt := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	dueDate := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	tasks := []exportedTask{
+		{
+			Task:    generated.Task{Title: `=HYPERLINK("https://example.com","x")`, DueDate: &dueDate},
+			Labels:  []string{"@home", "errands"},
+			Comment: "+1 from me",
+		},
+		{
+			Task:   generated.Task{Title: "-5 degrees", CompletedAt: &completedAt},
+			Depth:  1,
+			Parent: "=SUM(A1:A2)",
+		},
+		{Task: generated.Task{Title: "\tTabbed"}, Comment: "\rreturn"},
+		// Only the first character counts
+		{Task: generated.Task{Title: "Pay a=b+c"}, Comment: "email me@example.com"},
+	}
+	data, err := renderTaskListCSV(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Completed,Title,Parent,Due Date,Labels,Latest Comment\n" +
+		`false,"'=HYPERLINK(""https://example.com"",""x"")",,2026-01-05,"'@home, errands",'+1 from me` + "\n" +
+		"true,'-5 degrees,'=SUM(A1:A2),,,\n" +
+		"false,'\tTabbed,,,,\"'\rreturn\"\n" +
+		"false,Pay a=b+c,,,,email me@example.com\n"
+	if string(data) != want {
+		t.Errorf("got\n%q\nwant\n%q", data, want)
+	}
+}
@@ -0,0 +1,375 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Deleted task lists stay in the trash, with the task memberships they had, for
+// the retention window. The server checks for lists past the window
+// periodically and purges them with a TaskList:PurgeTrash event.
+const TaskListTrashRetention = 30 * 24 * time.Hour
+
+// Table schema
+const taskListTrashSchema = `
+CREATE TABLE IF NOT EXISTS task_list_trash_v1 (
+    list_id INTEGER PRIMARY KEY NOT NULL,
+    deleted_at DATETIME NOT NULL,
+    delete_orphaned_tasks BOOLEAN NOT NULL,
+    FOREIGN KEY (list_id) REFERENCES task_list_v1(id)
+);
+
+CREATE TABLE IF NOT EXISTS task_list_trash_task_v1 (
+    list_id INTEGER NOT NULL,
+    task_id INTEGER NOT NULL,
+    sort_key TEXT NOT NULL,
+    PRIMARY KEY (list_id, task_id),
+    FOREIGN KEY (list_id) REFERENCES task_list_v1(id),
+    FOREIGN KEY (task_id) REFERENCES task_v1(id)
+);
+`
+
+func InitTaskListTrash(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing TaskListTrash v1\n")
+	_, err := tx.Exec(taskListTrashSchema)
+	return err
+}
+
+// Event handler
+const insertTaskListTrashV1Sql = `
+INSERT INTO task_list_trash_v1 (list_id, deleted_at, delete_orphaned_tasks)
+VALUES ($1, $2, $3);
+`
+
+const trashTaskToListV1Sql = `
+INSERT INTO task_list_trash_task_v1 (list_id, task_id, sort_key)
+SELECT list_id, task_id, sort_key
+FROM task_to_list_v1
+WHERE list_id = $1;
+`
+
+const deleteTaskToListForListV1Sql = `
+DELETE FROM task_to_list_v1
+WHERE list_id = $1;
+`
+
+const getTrashedTaskSortKeysV1Sql = `
+SELECT task_id AS taskid, sort_key AS sortkey
+FROM task_list_trash_task_v1
+WHERE list_id = $1
+ORDER BY sort_key;
+`
+
+const deleteTaskListTrashV1Sql = `
+DELETE FROM task_list_trash_v1
+WHERE list_id = $1;
+`
+
+const deleteTaskListTrashTasksV1Sql = `
+DELETE FROM task_list_trash_task_v1
+WHERE list_id = $1;
+`
+
+const getTaskListTrashedV1Sql = `
+SELECT COUNT(*) FROM task_list_trash_v1 WHERE list_id = $1;
+`
+
+const getTaskListTrashDeleteOrphanedV1Sql = `
+SELECT delete_orphaned_tasks FROM task_list_trash_v1 WHERE list_id = $1;
+`
+
+// A task is orphaned when it is in no list, and no other list in the trash
+// could bring it back
+const getOrphanedTrashedTaskIdsV1Sql = `
+SELECT tt.task_id
+FROM task_list_trash_task_v1 tt
+WHERE tt.list_id = $1
+    AND NOT EXISTS (SELECT 1 FROM task_to_list_v1 ttl WHERE ttl.task_id = tt.task_id)
+    AND NOT EXISTS (SELECT 1 FROM task_list_trash_task_v1 o WHERE o.task_id = tt.task_id AND o.list_id != tt.list_id);
+`
+
+// Purged tasks are deleted outright, along with their subtasks' links to them
+const purgeTaskToListV1Sql = `
+DELETE FROM task_to_list_v1
+WHERE task_id = $1;
+`
+
+const purgeTaskParentV1Sql = `
+DELETE FROM task_parent_v1
+WHERE task_id = $1 OR parent_id = $1;
+`
+
+const purgeTaskTrashChildrenV1Sql = `
+DELETE FROM task_trash_child_v1
+WHERE task_id = $1 OR child_id = $1;
+`
+
+const purgeTaskRemindersFiredV1Sql = `
+DELETE FROM task_reminder_fired_v1
+WHERE reminder_id IN (SELECT id FROM task_reminder_v1 WHERE task_id = $1);
+`
+
+const purgeTaskCalDAVResourceV1Sql = `
+DELETE FROM caldav_resource_v1
+WHERE task_id = $1;
+`
+
+const purgeTaskRemindersV1Sql = `
+DELETE FROM task_reminder_v1
+WHERE task_id = $1;
+`
+
+const purgeTaskHistoryV1Sql = `
+DELETE FROM task_history_v1
+WHERE task_id = $1;
+`
+
+const purgeTaskV1Sql = `
+DELETE FROM task_v1
+WHERE id = $1;
+`
+
+const getExpiredTrashedTaskListsV1Sql = `
+SELECT list_id FROM task_list_trash_v1 WHERE deleted_at < $1 ORDER BY list_id;
+`
+
+const deleteTaskTrashListsForListV1Sql = `
+DELETE FROM task_trash_list_v1
+WHERE list_id = $1;
+`
+
+const deleteTaskListV1Sql = `
+DELETE FROM task_list_v1
+WHERE id = $1;
+`
+
+const deleteTaskListFilterV1Sql = `
+DELETE FROM task_list_filter_v1
+WHERE list_id = $1;
+`
+
+func (h *StateEventHandler) HandleTaskListDeleteEvent(tx *sqlx.Tx, event *generated.TaskListDeleteEvent) (bool, error) {
+	fmt.Printf("TaskListTrash v1: DeleteTaskListEvent %d %v\n", event.ListId, event.DeleteOrphanedTasks)
+	var trashed int
+	err := tx.Get(&trashed, getTaskListTrashedV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	if trashed > 0 {
+		return true, fmt.Errorf("list %d is already in the trash", event.ListId)
+	}
+	// Make sure the list exists
+	var category string
+	err = tx.Get(&category, getTaskListCategoryV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	err = h.recordUndo(tx, "TaskList:Delete", event.Timestamp, undoEvent{"TaskList:Restore", generated.TaskListRestoreEvent{ListId: event.ListId}})
+	if err != nil {
+		return true, err
+	}
+
+	_, err = tx.Exec(insertTaskListTrashV1Sql, event.ListId, event.Timestamp, event.DeleteOrphanedTasks)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(trashTaskToListV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(deleteTaskToListForListV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(deleteTaskListSearchV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	if event.DeleteOrphanedTasks {
+		// Tasks that are going to be deleted no longer show up in search
+		var orphaned []int
+		err = tx.Select(&orphaned, getOrphanedTrashedTaskIdsV1Sql, event.ListId)
+		if err != nil {
+			return true, err
+		}
+		for _, taskId := range orphaned {
+			if err = unindexTask(tx, taskId); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func (h *StateEventHandler) HandleTaskListRestoreEvent(tx *sqlx.Tx, event *generated.TaskListRestoreEvent) (bool, error) {
+	fmt.Printf("TaskListTrash v1: RestoreTaskListEvent %d\n", event.ListId)
+	var deleteOrphaned bool
+	err := tx.Get(&deleteOrphaned, getTaskListTrashDeleteOrphanedV1Sql, event.ListId)
+	if err == sql.ErrNoRows {
+		return true, fmt.Errorf("list %d is not in the trash", event.ListId)
+	}
+	if err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:Restore", event.Timestamp, undoDeleteTaskList(event.ListId, deleteOrphaned)); err != nil {
+		return true, err
+	}
+
+	var tasks []struct {
+		TaskId  int    `db:"taskid"`
+		SortKey string `db:"sortkey"`
+	}
+	err = tx.Select(&tasks, getTrashedTaskSortKeysV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	for _, task := range tasks {
+		if err = insertTaskToListAt(tx, task.TaskId, event.ListId, task.SortKey); err != nil {
+			return true, err
+		}
+	}
+	_, err = tx.Exec(deleteTaskListTrashTasksV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	_, err = tx.Exec(deleteTaskListTrashV1Sql, event.ListId)
+	if err != nil {
+		return true, err
+	}
+	if err = indexTaskList(tx, event.ListId); err != nil {
+		return true, err
+	}
+	if deleteOrphaned {
+		for _, task := range tasks {
+			if err = indexTask(tx, task.TaskId); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// HandleTaskListPurgeTrashEvent permanently deletes the lists that were moved
+// to the trash before PurgeBefore, along with their orphaned tasks if requested.
+// Purged lists can't be restored, so no undo entry is recorded.
+func (h *StateEventHandler) HandleTaskListPurgeTrashEvent(tx *sqlx.Tx, event *generated.TaskListPurgeTrashEvent) (bool, error) {
+	fmt.Printf("TaskListTrash v1: PurgeTrashEvent %s\n", event.PurgeBefore.Format(time.RFC3339))
+	if !event.Timestamp.IsZero() && event.PurgeBefore.After(event.Timestamp.Add(-TaskListTrashRetention)) {
+		return true, fmt.Errorf("lists deleted after %s are still within the retention window", event.Timestamp.Add(-TaskListTrashRetention).Format(time.RFC3339))
+	}
+	var listIds []int
+	err := tx.Select(&listIds, getExpiredTrashedTaskListsV1Sql, event.PurgeBefore)
+	if err != nil {
+		return true, err
+	}
+	for _, listId := range listIds {
+		fmt.Printf("TaskListTrash v1: Purging list %d\n", listId)
+		var deleteOrphaned bool
+		err = tx.Get(&deleteOrphaned, getTaskListTrashDeleteOrphanedV1Sql, listId)
+		if err != nil {
+			return true, err
+		}
+		if deleteOrphaned {
+			var orphaned []int
+			err = tx.Select(&orphaned, getOrphanedTrashedTaskIdsV1Sql, listId)
+			if err != nil {
+				return true, err
+			}
+			for _, taskId := range orphaned {
+				if err = purgeTask(tx, taskId); err != nil {
+					return true, err
+				}
+			}
+		}
+		for _, query := range []string{
+			deleteTaskListTrashTasksV1Sql,
+			deleteTaskListTrashV1Sql,
+			deleteTaskTrashListsForListV1Sql,
+			deleteTaskListFilterV1Sql,
+			deleteTaskListV1Sql,
+		} {
+			_, err = tx.Exec(query, listId)
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// purgeTask permanently deletes a task that is past the trash retention
+// window, or an orphaned task of a purged list, along with everything that
+// refers to it. Unlike Task:Delete, it leaves nothing in the trash or the undo
+// log.
+func purgeTask(tx *sqlx.Tx, taskId int) error {
+	fmt.Printf("TaskListTrash v1: Purging task %d\n", taskId)
+	if err := unindexTask(tx, taskId); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		purgeTaskToListV1Sql,
+		deleteTaskFromTrashedListsV1Sql,
+		deleteTaskTrashListsV1Sql,
+		deleteTaskTrashV1Sql,
+		purgeTaskParentV1Sql,
+		purgeTaskTrashChildrenV1Sql,
+		deleteTaskRecurrenceV1Sql,
+		purgeTaskRemindersFiredV1Sql,
+		purgeTaskRemindersV1Sql,
+		purgeTaskCalDAVResourceV1Sql,
+		purgeTaskHistoryV1Sql,
+		purgeTaskV1Sql,
+	} {
+		if _, err := tx.Exec(query, taskId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// State queries
+const getTrashedTaskListsV1Sql = `
+SELECT tl.id, tl.title, tl.category, tl.archived, tr.deleted_at AS deletedat,
+    tr.delete_orphaned_tasks AS deleteorphanedtasks,
+    (SELECT COUNT(*) FROM task_list_trash_task_v1 tt WHERE tt.list_id = tl.id) AS taskcount
+FROM task_list_trash_v1 tr
+JOIN task_list_v1 tl ON tl.id = tr.list_id
+ORDER BY tr.deleted_at DESC;
+`
+
+// GetExpiredTrashedTaskLists returns the IDs of the task lists that were moved
+// to the trash before a time, for the server to purge.
+func GetExpiredTrashedTaskLists(db *sqlx.DB, before time.Time) ([]int, error) {
+	listIds := make([]int, 0)
+	err := db.Select(&listIds, getExpiredTrashedTaskListsV1Sql, before)
+	return listIds, err
+}
+
+func (r *StateResolver) GetApiTasklistTrash(db *sqlx.DB) (generated.TrashedTaskListResponse, error) {
+	var rows []struct {
+		generated.TaskList
+		DeletedAt           time.Time `db:"deletedat"`
+		DeleteOrphanedTasks bool      `db:"deleteorphanedtasks"`
+		TaskCount           int       `db:"taskcount"`
+	}
+	err := db.Select(&rows, getTrashedTaskListsV1Sql)
+	if err != nil {
+		return generated.TrashedTaskListResponse{}, err
+	}
+	taskLists := make([]generated.TrashedTaskList, 0, len(rows))
+	for _, row := range rows {
+		taskLists = append(taskLists, generated.TrashedTaskList{
+			List:                row.TaskList,
+			DeletedAt:           row.DeletedAt,
+			PurgeAfter:          row.DeletedAt.Add(TaskListTrashRetention),
+			TaskCount:           row.TaskCount,
+			DeleteOrphanedTasks: row.DeleteOrphanedTasks,
+		})
+	}
+	return generated.TrashedTaskListResponse{TaskLists: taskLists}, nil
+}
@@ -0,0 +1,127 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Event handler
+
+const getTemplateTasksV1Sql = `
+SELECT t.id, t.title, t.notes, t.priority, t.due_date AS duedate
+FROM task_v1 t
+JOIN task_to_list_v1 ttl ON t.id = ttl.task_id
+WHERE ttl.list_id = $1
+ORDER BY ttl.sort_key;
+`
+
+const insertTemplateTaskV1Sql = `
+INSERT INTO task_v1 (title, notes, priority, due_date)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+`
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// substitutePlaceholders replaces {{Name}} placeholders with their values.
+// Placeholders without a value are left as-is.
+func substitutePlaceholders(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+func (h *StateEventHandler) HandleTaskListInstantiateEvent(tx *sqlx.Tx, event *generated.TaskListInstantiateEvent) (bool, error) {
+	fmt.Printf("TaskList v1: InstantiateEvent %d %v\n", event.TemplateListId, event.Title)
+	var category generated.TaskListCategory
+	err := tx.Get(&category, getTaskListCategoryV1Sql, event.TemplateListId)
+	if err != nil {
+		return true, err
+	}
+	if category != generated.TaskListCategoryTemplate {
+		return true, fmt.Errorf("list %d is not a template", event.TemplateListId)
+	}
+
+	values := make(map[string]string, len(event.Placeholders))
+	for _, placeholder := range event.Placeholders {
+		values[placeholder.Name] = placeholder.Value
+	}
+
+	// Create the new list
+	listId, err := insertTaskList(tx, substitutePlaceholders(event.Title, values), generated.TaskListCategoryToDoList, false)
+	if err != nil {
+		return true, err
+	}
+	if err = indexTaskList(tx, listId); err != nil {
+		return true, err
+	}
+	if err = h.recordUndo(tx, "TaskList:Instantiate", event.Timestamp, undoDeleteTaskList(listId, true)); err != nil {
+		return true, err
+	}
+
+	var templateTasks []struct {
+		Id       int        `db:"id"`
+		Title    string     `db:"title"`
+		Notes    string     `db:"notes"`
+		Priority *int       `db:"priority"`
+		DueDate  *time.Time `db:"duedate"`
+	}
+	err = tx.Select(&templateTasks, getTemplateTasksV1Sql, event.TemplateListId)
+	if err != nil {
+		return true, err
+	}
+
+	// Due dates keep their offsets from the earliest due date in the template
+	shiftDays := 0
+	if event.StartDate != nil {
+		var earliest *time.Time
+		for _, task := range templateTasks {
+			if task.DueDate != nil && (earliest == nil || task.DueDate.Before(*earliest)) {
+				earliest = task.DueDate
+			}
+		}
+		if earliest != nil {
+			shiftDays = dayNumber(*event.StartDate) - dayNumber(*earliest)
+		}
+	}
+
+	// Tasks are appended in template order, so the order is preserved
+	duplicates := make(map[int]int, len(templateTasks))
+	for _, task := range templateTasks {
+		var dueDate *time.Time
+		if task.DueDate != nil {
+			shifted := task.DueDate.AddDate(0, 0, shiftDays)
+			dueDate = &shifted
+		}
+		var newTaskId int
+		err = tx.Get(&newTaskId, insertTemplateTaskV1Sql, substitutePlaceholders(task.Title, values), substitutePlaceholders(task.Notes, values), task.Priority, dueDate)
+		if err != nil {
+			return true, err
+		}
+		duplicates[task.Id] = newTaskId
+
+		if err = appendTaskToList(tx, newTaskId, listId); err != nil {
+			return true, err
+		}
+		_, err = tx.Exec(duplicateTaskRecurrenceV1Sql, newTaskId, task.Id)
+		if err != nil {
+			return true, err
+		}
+		// Reminders at a fixed time belong to the template itself
+		if err = copyRelativeReminders(tx, newTaskId, task.Id); err != nil {
+			return true, err
+		}
+		if err = indexTask(tx, newTaskId); err != nil {
+			return true, err
+		}
+	}
+	return true, copyTaskParents(tx, duplicates)
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+func TestInstantiateTemplate(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	r := NewResolver()
+	hotelDue := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	packDue := time.Date(2026, 1, 7, 18, 0, 0, 0, time.UTC)
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Trip to {{City}}", Category: generated.TaskListCategoryTemplate})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Book a hotel in {{ City }}", TaskListId: 1, DueDate: &hotelDue})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Pack for {{Weather}} weather", TaskListId: 1, DueDate: &packDue})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Passport", TaskListId: 1})
+	parent := 2
+	apply(t, db, h.HandleTaskSetParentEvent, generated.TaskSetParentEvent{TaskId: 3, ParentTaskId: &parent})
+
+	startDate := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	apply(t, db, h.HandleTaskListInstantiateEvent, generated.TaskListInstantiateEvent{
+		TemplateListId: 1,
+		Title:          "Trip to {{City}}",
+		Placeholders:   []generated.TemplatePlaceholder{{Name: "City", Value: "Lisbon"}},
+		StartDate:      &startDate,
+	})
+
+	lists, err := r.GetApiTasklistTodo(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists.TaskLists) != 1 || lists.TaskLists[0].Id != 2 || lists.TaskLists[0].Title != "Trip to Lisbon" {
+		t.Fatalf("to-do lists %+v, want list 2 titled Trip to Lisbon", lists.TaskLists)
+	}
+
+	// Tasks keep their order and nesting, and placeholders without a value
+	// are left in place
+	response, err := r.GetApiTaskList(db, nil, nil, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := response.Tasks
+	if len(tasks) != 2 || tasks[0].Title != "Book a hotel in Lisbon" || tasks[1].Title != "Pack for {{Weather}} weather" {
+		t.Fatalf("instantiated tasks %+v", tasks)
+	}
+	if len(tasks[1].Subtasks) != 1 || tasks[1].Subtasks[0].Title != "Passport" || tasks[1].Subtasks[0].Id == 3 {
+		t.Errorf("subtasks of the copied task %+v, want a copy of Passport", tasks[1].Subtasks)
+	}
+
+	// The earliest due date moves to the start date and the others keep
+	// their offsets from it
+	for i, want := range []time.Time{
+		time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 3, 18, 0, 0, 0, time.UTC),
+	} {
+		if tasks[i].DueDate == nil || !tasks[i].DueDate.Equal(want) {
+			t.Errorf("task %q due %v, want %v", tasks[i].Title, tasks[i].DueDate, want)
+		}
+	}
+
+	// Only templates can be instantiated
+	tx := db.MustBegin()
+	defer tx.Rollback()
+	_, err = h.HandleTaskListInstantiateEvent(tx, &generated.TaskListInstantiateEvent{TemplateListId: 2, Title: "Copy"})
+	if err == nil {
+		t.Error("instantiating a to-do list succeeded")
+	}
+}
+
+func TestInstantiateCopiesRelativeReminders(t *testing.T) {
+	db := openTestDB(t, "")
+	h := &StateEventHandler{}
+	dueDate := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	remindAt := time.Date(2026, 1, 4, 9, 0, 0, 0, time.UTC)
+	dayBefore, hourBefore := 24*60, 60
+	apply(t, db, h.HandleTaskListAddEvent, generated.TaskListAddEvent{Title: "Trip to {{City}}", Category: generated.TaskListCategoryTemplate})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Book a hotel in {{City}}", TaskListId: 1, DueDate: &dueDate})
+	apply(t, db, h.HandleTaskAddEvent, generated.TaskAddEvent{Title: "Pack", TaskListId: 1})
+	apply(t, db, h.HandleTaskAddReminderEvent, generated.TaskAddReminderEvent{TaskId: 1, MinutesBeforeDue: &dayBefore})
+	apply(t, db, h.HandleTaskAddReminderEvent, generated.TaskAddReminderEvent{TaskId: 1, RemindAt: &remindAt})
+	apply(t, db, h.HandleTaskAddReminderEvent, generated.TaskAddReminderEvent{TaskId: 1, MinutesBeforeDue: &hourBefore})
+
+	startDate := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	apply(t, db, h.HandleTaskListInstantiateEvent, generated.TaskListInstantiateEvent{
+		TemplateListId: 1,
+		Title:          "Trip to {{City}}",
+		Placeholders:   []generated.TemplatePlaceholder{{Name: "City", Value: "Lisbon"}},
+		StartDate:      &startDate,
+	})
+
+	// Tasks 3 and 4 are the instantiated copies of tasks 1 and 2
+	var reminders []struct {
+		TaskId           int `db:"task_id"`
+		MinutesBeforeDue int `db:"minutes_before_due"`
+	}
+	err := db.Select(&reminders, "SELECT task_id, minutes_before_due FROM task_reminder_v1 WHERE task_id > 2 ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		TaskId           int `db:"task_id"`
+		MinutesBeforeDue int `db:"minutes_before_due"`
+	}{{3, dayBefore}, {3, hourBefore}}
+	if !reflect.DeepEqual(reminders, want) {
+		t.Errorf("reminders %+v, want %+v", reminders, want)
+	}
+
+	// The reminders follow the shifted due date
+	due, err := GetDueReminders(db, time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fireTimes []time.Time
+	for _, reminder := range due {
+		if reminder.TaskId == 3 {
+			fireTimes = append(fireTimes, reminder.FireAt)
+		}
+	}
+	if len(fireTimes) != 2 || !fireTimes[0].Equal(time.Date(2026, 5, 31, 9, 0, 0, 0, time.UTC)) || !fireTimes[1].Equal(time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("instantiated reminders fire at %v", fireTimes)
+	}
+}
@@ -0,0 +1,359 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+)
+
+// Every event handler records the compensating events that revert it, computed
+// from the state before the event is applied. An Undo:Apply event then runs
+// them through the same handlers, so an undo is just another set of state
+// changes (and shows up in task history like any other change). Undo entries
+// are identified by the ID of the event in the event log, which stays the same
+// when the projections are rebuilt.
+
+// Table schema
+const undoSchema = `
+CREATE TABLE IF NOT EXISTS undo_v1 (
+    event_id INTEGER PRIMARY KEY NOT NULL,
+    event_type TEXT NOT NULL,
+    inverse_events TEXT NOT NULL,
+    undone BOOLEAN NOT NULL DEFAULT false,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+func InitUndo(tx *sqlx.Tx) error {
+	fmt.Printf("Initializing Undo v1\n")
+	_, err := tx.Exec(undoSchema)
+	return err
+}
+
+type undoEvent struct {
+	Type  string      `json:"type"`
+	Event interface{} `json:"event"`
+}
+
+// Event handler
+
+// The framework stores an event in the event log in the same transaction that
+// it applies the event in, before calling the handlers, so the newest event in
+// the log is the one being applied
+const getLatestEventIdV1Sql = `
+SELECT MAX(id) FROM event_v1;
+`
+
+const insertUndoV1Sql = `
+INSERT INTO undo_v1 (event_id, event_type, inverse_events, created_at)
+VALUES ($1, $2, $3, $4);
+`
+
+const getUndoV1Sql = `
+SELECT inverse_events, undone
+FROM undo_v1
+WHERE event_id = $1;
+`
+
+const markUndoneV1Sql = `
+UPDATE undo_v1
+SET undone = true
+WHERE event_id = $1;
+`
+
+// currentEventId returns the ID of the event being applied.
+func (h *StateEventHandler) currentEventId(tx *sqlx.Tx) (int, error) {
+	if h.replayEventId != 0 {
+		return h.replayEventId, nil
+	}
+	var eventId sql.NullInt64
+	if err := tx.Get(&eventId, getLatestEventIdV1Sql); err != nil {
+		return 0, err
+	}
+	if !eventId.Valid {
+		return 0, fmt.Errorf("the event being applied is not in the event log")
+	}
+	return int(eventId.Int64), nil
+}
+
+// recordUndo stores the compensating events for an event, with the time of the
+// event. Events that are applied as part of an undo are not recorded themselves.
+func (h *StateEventHandler) recordUndo(tx *sqlx.Tx, eventType string, createdAt time.Time, inverse ...undoEvent) error {
+	if h.undoing || len(inverse) == 0 {
+		return nil
+	}
+	if h.combinedUndo != nil {
+		// The events that were applied last are undone first
+		*h.combinedUndo = append(append([]undoEvent{}, inverse...), *h.combinedUndo...)
+		return nil
+	}
+	eventId, err := h.currentEventId(tx)
+	if err != nil {
+		return err
+	}
+	inverseJson, err := json.Marshal(inverse)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(insertUndoV1Sql, eventId, eventType, string(inverseJson), createdAt)
+	return err
+}
+
+func (h *StateEventHandler) HandleUndoApplyEvent(tx *sqlx.Tx, event *generated.UndoApplyEvent) (bool, error) {
+	fmt.Printf("Undo v1: ApplyEvent %d\n", event.EventId)
+	var entry struct {
+		InverseEvents string `db:"inverse_events"`
+		Undone        bool   `db:"undone"`
+	}
+	err := tx.Get(&entry, getUndoV1Sql, event.EventId)
+	if err == sql.ErrNoRows {
+		return true, fmt.Errorf("event %d cannot be undone", event.EventId)
+	}
+	if err != nil {
+		return true, err
+	}
+	if entry.Undone {
+		return true, fmt.Errorf("event %d has already been undone", event.EventId)
+	}
+
+	var inverse []struct {
+		Type  string          `json:"type"`
+		Event json.RawMessage `json:"event"`
+	}
+	if err = json.Unmarshal([]byte(entry.InverseEvents), &inverse); err != nil {
+		return true, err
+	}
+	h.undoing = true
+	defer func() { h.undoing = false }()
+	for _, inverseEvent := range inverse {
+		if err = h.applyUndoEvent(tx, inverseEvent.Type, inverseEvent.Event, event.Timestamp); err != nil {
+			return true, err
+		}
+	}
+	_, err = tx.Exec(markUndoneV1Sql, event.EventId)
+	return true, err
+}
+
+// applyUndoEvent applies a compensating event as if it had been published along
+// with the Undo:Apply event, at the same time.
+func (h *StateEventHandler) applyUndoEvent(tx *sqlx.Tx, eventType string, payload json.RawMessage, timestamp time.Time) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return err
+	}
+	var err error
+	if fields["timestamp"], err = json.Marshal(timestamp); err != nil {
+		return err
+	}
+	if payload, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	handled, err := generated.HandleEvent(tx, h, eventType, payload)
+	if err == nil && !handled {
+		return fmt.Errorf("cannot undo with event type %s", eventType)
+	}
+	return err
+}
+
+// Compensating events, computed from the state before an event
+
+const getPrecedingTaskInListV1Sql = `
+SELECT task_id
+FROM task_to_list_v1
+WHERE list_id = $1 AND sort_key < (SELECT sort_key FROM task_to_list_v1 WHERE task_id = $2 AND list_id = $1)
+ORDER BY sort_key DESC
+LIMIT 1;
+`
+
+const getPrecedingTaskListV1Sql = `
+SELECT id
+FROM task_list_v1
+WHERE sort_key < (SELECT sort_key FROM task_list_v1 WHERE id = $1)
+ORDER BY sort_key DESC
+LIMIT 1;
+`
+
+const getPrecedingSubtaskV1Sql = `
+SELECT tp.parent_id AS parentid, (
+    SELECT s.task_id FROM task_parent_v1 s
+    WHERE s.parent_id = tp.parent_id AND s.position < tp.position
+    ORDER BY s.position DESC
+    LIMIT 1
+) AS aftertaskid
+FROM task_parent_v1 tp
+WHERE tp.task_id = $1;
+`
+
+const getTaskListMembershipV1Sql = `
+SELECT COUNT(*) FROM task_to_list_v1 WHERE task_id = $1 AND list_id = $2;
+`
+
+// Tasks in list order, so that putting them back one at a time after their
+// predecessor restores the original order
+const getTasksInListV1Sql = `
+SELECT task_id FROM task_to_list_v1 WHERE list_id = $1 ORDER BY sort_key;
+`
+
+// getTaskForUndo returns the task as it is before an update event.
+func getTaskForUndo(tx *sqlx.Tx, taskId int) (generated.Task, error) {
+	var task generated.Task
+	err := tx.Get(&task, getTaskByIdV1Sql, taskId)
+	return task, err
+}
+
+// undoPrecedingTask returns the task before taskId in the list, which is
+// where a ReorderTasks event puts it back; nil means the front of the list.
+func undoPrecedingTask(tx *sqlx.Tx, listId, taskId int) (*int, error) {
+	var afterTaskId int
+	err := tx.Get(&afterTaskId, getPrecedingTaskInListV1Sql, listId, taskId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &afterTaskId, nil
+}
+
+func undoTaskSetParent(tx *sqlx.Tx, taskId int) (undoEvent, error) {
+	var parent struct {
+		ParentId    int  `db:"parentid"`
+		AfterTaskId *int `db:"aftertaskid"`
+	}
+	inverse := generated.TaskSetParentEvent{TaskId: taskId}
+	err := tx.Get(&parent, getPrecedingSubtaskV1Sql, taskId)
+	if err == nil {
+		inverse.ParentTaskId = &parent.ParentId
+		inverse.AfterTaskId = parent.AfterTaskId
+	} else if err != sql.ErrNoRows {
+		return undoEvent{}, err
+	}
+	return undoEvent{"Task:SetParent", inverse}, nil
+}
+
+func undoTaskListReorder(tx *sqlx.Tx, listId int) (undoEvent, error) {
+	inverse := generated.TaskListReorderEvent{ListId: listId}
+	var afterListId int
+	err := tx.Get(&afterListId, getPrecedingTaskListV1Sql, listId)
+	if err == nil {
+		inverse.AfterListId = &afterListId
+	} else if err != sql.ErrNoRows {
+		return undoEvent{}, err
+	}
+	return undoEvent{"TaskList:Reorder", inverse}, nil
+}
+
+// undoRemoveFromList returns the events that put tasks back into a list at
+// their current positions, after they have been removed.
+func undoRemoveFromList(tx *sqlx.Tx, listId int, taskIds []int) ([]undoEvent, error) {
+	removed := make(map[int]bool, len(taskIds))
+	for _, taskId := range taskIds {
+		removed[taskId] = true
+	}
+	var listTaskIds []int
+	err := tx.Select(&listTaskIds, getTasksInListV1Sql, listId)
+	if err != nil {
+		return nil, err
+	}
+	var inverse []undoEvent
+	var afterTaskId *int
+	for i, taskId := range listTaskIds {
+		if removed[taskId] {
+			inverse = append(inverse,
+				undoEvent{"TaskList:AddTask", generated.TaskListAddTaskEvent{TaskId: taskId, ListId: listId}},
+				undoEvent{"TaskList:ReorderTasks", generated.TaskListReorderTasksEvent{TaskListId: listId, OldTaskId: taskId, AfterTaskId: afterTaskId}},
+			)
+		}
+		afterTaskId = &listTaskIds[i]
+	}
+	return inverse, nil
+}
+
+// undoAddToList returns the event that removes tasks from a list again, for
+// the tasks that are not in the list yet.
+func undoAddToList(tx *sqlx.Tx, listId int, taskIds []int) ([]undoEvent, error) {
+	var added []int
+	for _, taskId := range taskIds {
+		var count int
+		err := tx.Get(&count, getTaskListMembershipV1Sql, taskId, listId)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			added = append(added, taskId)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return []undoEvent{{"TaskList:RemoveTasks", generated.TaskListRemoveTasksEvent{TaskIds: added, ListId: listId}}}, nil
+}
+
+func undoTaskListUpdateFilter(tx *sqlx.Tx, listId int) (undoEvent, error) {
+	filter, err := getTaskListFilter(tx, listId)
+	if err != nil {
+		return undoEvent{}, err
+	}
+	return undoEvent{"TaskList:UpdateFilter", generated.TaskListUpdateFilterEvent{
+		ListId:           listId,
+		DueWithinDays:    filter.DueWithinDays,
+		IncludeCompleted: filter.IncludeCompleted,
+		LabelIds:         filter.LabelIds,
+		ListIds:          filter.ListIds,
+	}}, nil
+}
+
+// undoDeleteTaskList is used to undo the creation of a list.
+func undoDeleteTaskList(listId int, deleteOrphanedTasks bool) undoEvent {
+	return undoEvent{"TaskList:Delete", generated.TaskListDeleteEvent{
+		ListId:              listId,
+		DeleteOrphanedTasks: deleteOrphanedTasks,
+	}}
+}
+
+// State queries
+const getUndoEntriesV1Sql = `
+SELECT event_id, event_type, inverse_events, created_at
+FROM undo_v1
+WHERE undone = false
+ORDER BY event_id DESC
+LIMIT 20;
+`
+
+func (r *StateResolver) GetApiUndo(db *sqlx.DB) (generated.UndoResponse, error) {
+	var rows []struct {
+		EventId       int       `db:"event_id"`
+		EventType     string    `db:"event_type"`
+		InverseEvents string    `db:"inverse_events"`
+		CreatedAt     time.Time `db:"created_at"`
+	}
+	err := db.Select(&rows, getUndoEntriesV1Sql)
+	if err != nil {
+		return generated.UndoResponse{}, err
+	}
+	entries := make([]generated.UndoEntry, 0, len(rows))
+	for _, row := range rows {
+		var inverse []struct {
+			Type  string          `json:"type"`
+			Event json.RawMessage `json:"event"`
+		}
+		if err = json.Unmarshal([]byte(row.InverseEvents), &inverse); err != nil {
+			return generated.UndoResponse{}, err
+		}
+		events := make([]generated.UndoEvent, 0, len(inverse))
+		for _, inverseEvent := range inverse {
+			events = append(events, generated.UndoEvent{Type: inverseEvent.Type, Payload: string(inverseEvent.Event)})
+		}
+		entries = append(entries, generated.UndoEntry{
+			EventId:   row.EventId,
+			EventType: row.EventType,
+			CreatedAt: row.CreatedAt,
+			Events:    events,
+		})
+	}
+	return generated.UndoResponse{Entries: entries}, nil
+}
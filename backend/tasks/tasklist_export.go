@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tomyedwab/yesterday/applib/database"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+var taskListExportContentTypes = map[string]string{
+	"csv":      "text/csv; charset=utf-8",
+	"markdown": "text/markdown; charset=utf-8",
+}
+
+var taskListExportExtensions = map[string]string{
+	"csv":      ".csv",
+	"markdown": ".md",
+}
+
+// List exports are served as CSV or Markdown rather than JSON, so the route is
+// registered by hand instead of being generated from api.yml.
+func initTaskListExportHandlers(db *database.Database) {
+	http.HandleFunc("/api/tasklist/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		listId, err := strconv.Atoi(r.URL.Query().Get("listId"))
+		if err != nil {
+			http.Error(w, "Invalid listId", http.StatusBadRequest)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if !slices.Contains(state.TaskListExportFormats, format) {
+			http.Error(w, fmt.Sprintf("Invalid format, expected one of: %s", strings.Join(state.TaskListExportFormats, ", ")), http.StatusBadRequest)
+			return
+		}
+		list, data, err := state.RenderTaskListExport(db.GetDB(), listId, format)
+		if errors.Is(err, state.ErrTaskListNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Exporting list %d failed: %v", listId, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", taskListExportContentTypes[format])
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": list.Title + taskListExportExtensions[format]})
+		if disposition != "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+		w.Write(data)
+	})
+}
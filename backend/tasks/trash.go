@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/tomyedwab/yesterday/applib/database"
+	"tomyedwab.com/yellowstone-server/tasks/generated"
+	"tomyedwab.com/yellowstone-server/tasks/state"
+)
+
+// How often the server checks for task lists and tasks that are past the trash
+// retention window
+const trashPurgeInterval = time.Hour
+
+// startTrashPurgeScheduler purges expired task lists and tasks from the trash
+// in the background until the process exits.
+func startTrashPurgeScheduler(db *database.Database) {
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			if err := purgeExpiredTrash(db, time.Now()); err != nil {
+				log.Printf("Purging the trash failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeExpiredTrash publishes a TaskList:PurgeTrash event if any task lists,
+// and a Task:PurgeTrash event if any tasks, have been in the trash for longer
+// than the retention window. The window is counted on the server's clock, so
+// that the event log records when they were purged.
+func purgeExpiredTrash(db *database.Database, now time.Time) error {
+	purgeBefore := now.UTC().Add(-state.TaskListTrashRetention)
+	listIds, err := state.GetExpiredTrashedTaskLists(db.GetDB(), purgeBefore)
+	if err != nil {
+		return err
+	}
+	if len(listIds) > 0 {
+		log.Printf("Purging %d task lists from the trash", len(listIds))
+		_, err = publishEvent(db, "TaskList:PurgeTrash", generated.TaskListPurgeTrashEvent{PurgeBefore: purgeBefore})
+		if err != nil {
+			return err
+		}
+	}
+
+	purgeBefore = now.UTC().Add(-state.TaskTrashRetention)
+	taskIds, err := state.GetExpiredTrashedTasks(db.GetDB(), purgeBefore)
+	if err != nil || len(taskIds) == 0 {
+		return err
+	}
+	log.Printf("Purging %d tasks from the trash", len(taskIds))
+	_, err = publishEvent(db, "Task:PurgeTrash", generated.TaskPurgeTrashEvent{PurgeBefore: purgeBefore})
+	return err
+}
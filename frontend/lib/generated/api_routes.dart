@@ -0,0 +1,188 @@
+// Auto-generated from the tasks schema's api.yml
+// Do not edit this file directly
+
+import 'dart:convert';
+
+import '../yesterday/api.dart';
+import 'types.dart';
+
+class ApiRoutes {
+  final YesterdayApi api;
+
+  ApiRoutes(this.api);
+
+  /// Get all tasks for a specific task list, evaluating the filter for smart lists
+  Future<TaskResponse> getTaskList({
+    required int listId,
+    TaskSortMode? sort,
+    bool? hideCompleted,
+    bool? includeSnoozed,
+  }) {
+    return _get('/task/list', {
+      'listId': '$listId',
+      if (sort != null) 'sort': sort.value,
+      if (hideCompleted != null) 'hideCompleted': '$hideCompleted',
+      if (includeSnoozed != null) 'includeSnoozed': '$includeSnoozed',
+    }, TaskResponse.fromJson);
+  }
+
+  /// Get a specific task by ID
+  Future<Task> getTaskGet({
+    required int id,
+  }) {
+    return _get('/task/get', {
+      'id': '$id',
+    }, Task.fromJson);
+  }
+
+  /// Get the history of changes for a specific task
+  Future<TaskHistoryResponse> getTaskHistory({
+    required int id,
+  }) {
+    return _get('/task/history', {
+      'id': '$id',
+    }, TaskHistoryResponse.fromJson);
+  }
+
+  /// Get the reminders for a specific task
+  Future<TaskReminderResponse> getTaskReminders({
+    required int taskId,
+  }) {
+    return _get('/task/reminders', {
+      'taskId': '$taskId',
+    }, TaskReminderResponse.fromJson);
+  }
+
+  /// Get a task's notes rendered to HTML
+  Future<TaskNotesResponse> getTaskNotes({
+    required int id,
+  }) {
+    return _get('/task/notes', {
+      'id': '$id',
+    }, TaskNotesResponse.fromJson);
+  }
+
+  /// Get recently deleted tasks. Deleted tasks are kept for 30 days, and then permanently deleted
+  Future<DeletedTaskResponse> getTaskTrash() {
+    return _get('/task/trash', {}, DeletedTaskResponse.fromJson);
+  }
+
+  /// Get a specific task list by ID
+  Future<TaskList> getTasklistGet({
+    required int id,
+  }) {
+    return _get('/tasklist/get', {
+      'id': '$id',
+    }, TaskList.fromJson);
+  }
+
+  /// Get all task lists
+  Future<TaskListResponse> getTasklistAll() {
+    return _get('/tasklist/all', {}, TaskListResponse.fromJson);
+  }
+
+  /// Get all task lists in the 'toDoList' category
+  Future<TaskListResponse> getTasklistTodo() {
+    return _get('/tasklist/todo', {}, TaskListResponse.fromJson);
+  }
+
+  /// Get all task lists in the 'template' category
+  Future<TaskListResponse> getTasklistTemplate() {
+    return _get('/tasklist/template', {}, TaskListResponse.fromJson);
+  }
+
+  /// Get all task lists in the 'smart' category
+  Future<TaskListResponse> getTasklistSmart() {
+    return _get('/tasklist/smart', {}, TaskListResponse.fromJson);
+  }
+
+  /// Get the filter that defines the contents of a smart list
+  Future<TaskListFilter> getTasklistFilter({
+    required int listId,
+  }) {
+    return _get('/tasklist/filter', {
+      'listId': '$listId',
+    }, TaskListFilter.fromJson);
+  }
+
+  /// Get all archived task lists
+  Future<TaskListResponse> getTasklistArchived() {
+    return _get('/tasklist/archived', {}, TaskListResponse.fromJson);
+  }
+
+  /// Get all task lists in the trash
+  Future<TrashedTaskListResponse> getTasklistTrash() {
+    return _get('/tasklist/trash', {}, TrashedTaskListResponse.fromJson);
+  }
+
+  /// Get metadata (total and completed task counts) for all task lists, including smart lists
+  Future<TaskListMetadataResponse> getTasklistMetadata({
+    bool? includeSnoozed,
+  }) {
+    return _get('/tasklist/metadata', {
+      if (includeSnoozed != null) 'includeSnoozed': '$includeSnoozed',
+    }, TaskListMetadataResponse.fromJson);
+  }
+
+  /// Get recent comments for tasks in a specific task list
+  Future<TaskRecentCommentResponse> getTasklistRecentComments({
+    required int listId,
+  }) {
+    return _get('/tasklist/recent_comments', {
+      'listId': '$listId',
+    }, TaskRecentCommentResponse.fromJson);
+  }
+
+  /// Get all task labels for tasks in a specific task list
+  Future<TaskLabelsResponse> getTasklistLabels({
+    required int listId,
+  }) {
+    return _get('/tasklist/labels', {
+      'listId': '$listId',
+    }, TaskLabelsResponse.fromJson);
+  }
+
+  /// Render Markdown to HTML the same way task notes are rendered, e.g. to preview an edit
+  Future<RenderedMarkdownResponse> getMarkdownRender({
+    required String text,
+  }) {
+    return _get('/markdown/render', {
+      'text': text,
+    }, RenderedMarkdownResponse.fromJson);
+  }
+
+  /// Full-text search across task titles, comments and task list titles
+  Future<SearchResponse> getSearch({
+    required String q,
+  }) {
+    return _get('/search', {
+      'q': q,
+    }, SearchResponse.fromJson);
+  }
+
+  /// Get all calendar feeds, including their tokens
+  Future<CalendarFeedResponse> getCalendarfeedList() {
+    return _get('/calendarfeed/list', {}, CalendarFeedResponse.fromJson);
+  }
+
+  /// Export every list and task as a versioned JSON document, for backups and for moving to another server
+  Future<ExportDocument> getExport() {
+    return _get('/export', {}, ExportDocument.fromJson);
+  }
+
+  /// Get the most recent events that can be undone with an Undo:Apply event
+  Future<UndoResponse> getUndo() {
+    return _get('/undo', {}, UndoResponse.fromJson);
+  }
+
+  Future<T> _get<T>(String path, Map<String, String> parameters,
+      T Function(Map<String, dynamic>) fromJson) async {
+    final uri = Uri(
+        path: path, queryParameters: parameters.isEmpty ? null : parameters);
+    final response = await api.getCachedResponse(uri.toString());
+    if (response.statusCode != 200) {
+      throw Exception('Failed to load $path');
+    }
+    return fromJson(json.decode(response.body) as Map<String, dynamic>);
+  }
+}
@@ -0,0 +1,876 @@
+// Auto-generated from the tasks schema's events.yml
+// Do not edit this file directly
+
+import 'types.dart';
+
+/// Event to create an iCalendar feed of the tasks with a due date
+class CalendarFeedAddEvent {
+  static const eventType = 'CalendarFeed:Add';
+
+  /// Whether each task is also included as an event, for calendar apps that don't show to-dos
+  final bool includeEvents;
+  /// Only include tasks with this label, null for no label condition
+  final int? labelId;
+  /// Only include tasks in this list (a to-do list or label), null for tasks in any list
+  final int? listId;
+  /// Name of the calendar shown in calendar apps
+  final String title;
+
+  const CalendarFeedAddEvent({
+    required this.includeEvents,
+    this.labelId,
+    this.listId,
+    required this.title,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'IncludeEvents': includeEvents,
+      'LabelId': labelId,
+      'ListId': listId,
+      'Title': title,
+    };
+  }
+}
+
+/// Event to delete a calendar feed, so its URL stops working
+class CalendarFeedDeleteEvent {
+  static const eventType = 'CalendarFeed:Delete';
+
+  /// ID of the feed to delete
+  final int feedId;
+
+  const CalendarFeedDeleteEvent({
+    required this.feedId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'FeedId': feedId,
+    };
+  }
+}
+
+/// Event to replace a calendar feed's token, so the old feed URL stops working
+class CalendarFeedResetTokenEvent {
+  static const eventType = 'CalendarFeed:ResetToken';
+
+  /// ID of the feed to reset the token of
+  final int feedId;
+
+  const CalendarFeedResetTokenEvent({
+    required this.feedId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'FeedId': feedId,
+    };
+  }
+}
+
+/// Event to add a new task
+class TaskAddEvent {
+  static const eventType = 'Task:Add';
+
+  /// Optional due date for the task
+  final DateTime? dueDate;
+  /// ID of the task list to add the task to
+  final int taskListId;
+  /// Title of the new task
+  final String title;
+
+  const TaskAddEvent({
+    this.dueDate,
+    required this.taskListId,
+    required this.title,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'DueDate': dueDate?.toUtc().toIso8601String(),
+      'TaskListId': taskListId,
+      'Title': title,
+    };
+  }
+}
+
+/// Event to add a user comment to a task
+class TaskAddCommentEvent {
+  static const eventType = 'Task:AddComment';
+
+  /// ID of the task to add comment to
+  final int taskId;
+  /// The user comment to add
+  final String userComment;
+
+  const TaskAddCommentEvent({
+    required this.taskId,
+    required this.userComment,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'TaskId': taskId,
+      'UserComment': userComment,
+    };
+  }
+}
+
+/// Event to add a reminder to a task, either at a fixed time or relative to the due date
+class TaskAddReminderEvent {
+  static const eventType = 'Task:AddReminder';
+
+  /// Minutes before the due date to send the reminder, which follows the due date when it changes
+  final int? minutesBeforeDue;
+  /// Fixed time of the reminder; exactly one of RemindAt and MinutesBeforeDue must be set
+  final DateTime? remindAt;
+  /// ID of the task to add a reminder to
+  final int taskId;
+
+  const TaskAddReminderEvent({
+    this.minutesBeforeDue,
+    this.remindAt,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'MinutesBeforeDue': minutesBeforeDue,
+      'RemindAt': remindAt?.toUtc().toIso8601String(),
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to move a task to the trash, making its subtasks top-level tasks
+class TaskDeleteEvent {
+  static const eventType = 'Task:Delete';
+
+  /// ID of the task to delete
+  final int taskId;
+
+  const TaskDeleteEvent({
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to permanently delete the tasks that were moved to the trash before a time, published by the server once they are past the retention window
+class TaskPurgeTrashEvent {
+  static const eventType = 'Task:PurgeTrash';
+
+  /// Tasks deleted before this time are purged; it can be no later than the retention window before the event
+  final DateTime purgeBefore;
+
+  const TaskPurgeTrashEvent({
+    required this.purgeBefore,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'PurgeBefore': purgeBefore.toUtc().toIso8601String(),
+    };
+  }
+}
+
+/// Event to remove a reminder from a task
+class TaskRemoveReminderEvent {
+  static const eventType = 'Task:RemoveReminder';
+
+  /// ID of the reminder to remove
+  final int reminderId;
+
+  const TaskRemoveReminderEvent({
+    required this.reminderId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'ReminderId': reminderId,
+    };
+  }
+}
+
+/// Event to restore a deleted task to the lists and position it was in, along with the subtasks it had
+class TaskRestoreEvent {
+  static const eventType = 'Task:Restore';
+
+  /// ID of the deleted task to restore
+  final int taskId;
+
+  const TaskRestoreEvent({
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to make a task a subtask of another task, or a top-level task again
+class TaskSetParentEvent {
+  static const eventType = 'Task:SetParent';
+
+  /// ID of the sibling subtask to place this task after, null to move to front
+  final int? afterTaskId;
+  /// ID of the new parent task, which can't be in the trash or be this task or one of its subtasks; null to make this a top-level task
+  final int? parentTaskId;
+  /// ID of the task to update
+  final int taskId;
+
+  const TaskSetParentEvent({
+    this.afterTaskId,
+    this.parentTaskId,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'AfterTaskId': afterTaskId,
+      'ParentTaskId': parentTaskId,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to hide a task from its lists until a given time
+class TaskSnoozeEvent {
+  static const eventType = 'Task:Snooze';
+
+  /// ID of the task to snooze
+  final int taskId;
+  /// When the task shows up in its lists again
+  final DateTime until;
+
+  const TaskSnoozeEvent({
+    required this.taskId,
+    required this.until,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'TaskId': taskId,
+      'Until': until.toUtc().toIso8601String(),
+    };
+  }
+}
+
+/// Event to show a snoozed task in its lists again right away
+class TaskUnsnoozeEvent {
+  static const eventType = 'Task:Unsnooze';
+
+  /// ID of the task to unsnooze
+  final int taskId;
+
+  const TaskUnsnoozeEvent({
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to update a task's completion status
+class TaskUpdateCompletedEvent {
+  static const eventType = 'Task:UpdateCompleted';
+
+  /// Completion timestamp, null to mark as not completed
+  final DateTime? completedAt;
+  /// ID of the task to update
+  final int taskId;
+
+  const TaskUpdateCompletedEvent({
+    this.completedAt,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'CompletedAt': completedAt?.toUtc().toIso8601String(),
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to update a task's due date
+class TaskUpdateDueDateEvent {
+  static const eventType = 'Task:UpdateDueDate';
+
+  /// New due date, null to remove due date
+  final DateTime? dueDate;
+  /// ID of the task to update
+  final int taskId;
+
+  const TaskUpdateDueDateEvent({
+    this.dueDate,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'DueDate': dueDate?.toUtc().toIso8601String(),
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to store a to-do that a CalDAV client put, creating the task or changing all of its fields at once
+class TaskUpdateFromCalDAVEvent {
+  static const eventType = 'Task:UpdateFromCalDAV';
+
+  /// Completion timestamp, null if the to-do is not completed
+  final DateTime? completedAt;
+  /// Due date of the task, null for none
+  final DateTime? dueDate;
+  /// Notes of the task
+  final String notes;
+  /// Priority from 1 (P1, highest) to 4 (P4), null for none
+  final int? priority;
+  /// Name of the CalDAV resource, which the client finds the to-do under
+  final String resourceName;
+  /// UID of the to-do
+  final String resourceUid;
+  /// ID of the task to update, null to create a new task
+  final int? taskId;
+  /// ID of the task list the to-do was put in, which a new task is added to
+  final int taskListId;
+  /// Title of the task
+  final String title;
+
+  const TaskUpdateFromCalDAVEvent({
+    this.completedAt,
+    this.dueDate,
+    required this.notes,
+    this.priority,
+    required this.resourceName,
+    required this.resourceUid,
+    this.taskId,
+    required this.taskListId,
+    required this.title,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'CompletedAt': completedAt?.toUtc().toIso8601String(),
+      'DueDate': dueDate?.toUtc().toIso8601String(),
+      'Notes': notes,
+      'Priority': priority,
+      'ResourceName': resourceName,
+      'ResourceUid': resourceUid,
+      'TaskId': taskId,
+      'TaskListId': taskListId,
+      'Title': title,
+    };
+  }
+}
+
+/// Event to update a task's notes
+class TaskUpdateNotesEvent {
+  static const eventType = 'Task:UpdateNotes';
+
+  /// New notes for the task, in Markdown; empty to clear them
+  final String notes;
+  /// ID of the task to update
+  final int taskId;
+
+  const TaskUpdateNotesEvent({
+    required this.notes,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'Notes': notes,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to update a task's priority
+class TaskUpdatePriorityEvent {
+  static const eventType = 'Task:UpdatePriority';
+
+  /// New priority from 1 (P1, highest) to 4 (P4), null to clear it
+  final int? priority;
+  /// ID of the task to update
+  final int taskId;
+
+  const TaskUpdatePriorityEvent({
+    this.priority,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'Priority': priority,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to update a task's recurrence rule
+class TaskUpdateRecurrenceEvent {
+  static const eventType = 'Task:UpdateRecurrence';
+
+  /// Recurrence rule (daily, weekly, monthly, yearly or an RRULE such as FREQ=WEEKLY;BYDAY=MO), null to stop recurring
+  final String? recurrence;
+  /// ID of the task to update
+  final int taskId;
+
+  const TaskUpdateRecurrenceEvent({
+    this.recurrence,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'Recurrence': recurrence,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to update a task's title
+class TaskUpdateTitleEvent {
+  static const eventType = 'Task:UpdateTitle';
+
+  /// ID of the task to update
+  final int taskId;
+  /// New title for the task
+  final String title;
+
+  const TaskUpdateTitleEvent({
+    required this.taskId,
+    required this.title,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'TaskId': taskId,
+      'Title': title,
+    };
+  }
+}
+
+/// Event to add a new task list
+class TaskListAddEvent {
+  static const eventType = 'TaskList:Add';
+
+  /// Whether the task list should be archived
+  final bool archived;
+  /// Category of the task list
+  final TaskListCategory category;
+  /// Title of the new task list
+  final String title;
+
+  const TaskListAddEvent({
+    required this.archived,
+    required this.category,
+    required this.title,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'Archived': archived,
+      'Category': category.toJson(),
+      'Title': title,
+    };
+  }
+}
+
+/// Event to add a task to a task list
+class TaskListAddTaskEvent {
+  static const eventType = 'TaskList:AddTask';
+
+  /// ID of the list to add the task to
+  final int listId;
+  /// ID of the task to add
+  final int taskId;
+
+  const TaskListAddTaskEvent({
+    required this.listId,
+    required this.taskId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'ListId': listId,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Event to copy tasks to another list
+class TaskListCopyTasksEvent {
+  static const eventType = 'TaskList:CopyTasks';
+
+  /// ID of the destination list
+  final int newListId;
+  /// Array of task IDs to copy
+  final List<int> taskIds;
+
+  const TaskListCopyTasksEvent({
+    required this.newListId,
+    required this.taskIds,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'NewListId': newListId,
+      'TaskIds': taskIds,
+    };
+  }
+}
+
+/// Event to move a task list to the trash, removing all of its tasks from it
+class TaskListDeleteEvent {
+  static const eventType = 'TaskList:Delete';
+
+  /// Whether tasks that are in no other list are deleted when the list is purged from the trash
+  final bool deleteOrphanedTasks;
+  /// ID of the task list to delete
+  final int listId;
+
+  const TaskListDeleteEvent({
+    required this.deleteOrphanedTasks,
+    required this.listId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'DeleteOrphanedTasks': deleteOrphanedTasks,
+      'ListId': listId,
+    };
+  }
+}
+
+/// Event to duplicate tasks to another list
+class TaskListDuplicateTasksEvent {
+  static const eventType = 'TaskList:DuplicateTasks';
+
+  /// ID of the destination list
+  final int newListId;
+  /// Array of task IDs to duplicate
+  final List<int> taskIds;
+
+  const TaskListDuplicateTasksEvent({
+    required this.newListId,
+    required this.taskIds,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'NewListId': newListId,
+      'TaskIds': taskIds,
+    };
+  }
+}
+
+/// Event to create a new to-do list from a template list, duplicating all of its tasks in order
+class TaskListInstantiateEvent {
+  static const eventType = 'TaskList:Instantiate';
+
+  /// Values to substitute for {{Name}} placeholders in the list and task titles and notes
+  final List<TemplatePlaceholder> placeholders;
+  /// If set, due dates are shifted so that the earliest due date in the template falls on this date
+  final DateTime? startDate;
+  /// ID of the template list to instantiate
+  final int templateListId;
+  /// Title of the new to-do list, which may also contain placeholders
+  final String title;
+
+  const TaskListInstantiateEvent({
+    required this.placeholders,
+    this.startDate,
+    required this.templateListId,
+    required this.title,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'Placeholders': placeholders.map((e) => e.toJson()).toList(),
+      'StartDate': startDate?.toUtc().toIso8601String(),
+      'TemplateListId': templateListId,
+      'Title': title,
+    };
+  }
+}
+
+/// Event to move tasks from one list to another
+class TaskListMoveTasksEvent {
+  static const eventType = 'TaskList:MoveTasks';
+
+  /// ID of the destination list
+  final int newListId;
+  /// ID of the source list
+  final int oldListId;
+  /// Array of task IDs to move
+  final List<int> taskIds;
+
+  const TaskListMoveTasksEvent({
+    required this.newListId,
+    required this.oldListId,
+    required this.taskIds,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'NewListId': newListId,
+      'OldListId': oldListId,
+      'TaskIds': taskIds,
+    };
+  }
+}
+
+/// Event to permanently delete the task lists that were moved to the trash before a time, published by the server once they are past the retention window
+class TaskListPurgeTrashEvent {
+  static const eventType = 'TaskList:PurgeTrash';
+
+  /// Lists deleted before this time are purged; it can be no later than the retention window before the event
+  final DateTime purgeBefore;
+
+  const TaskListPurgeTrashEvent({
+    required this.purgeBefore,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'PurgeBefore': purgeBefore.toUtc().toIso8601String(),
+    };
+  }
+}
+
+/// Event to remove tasks from a list, without removing their subtasks
+class TaskListRemoveTasksEvent {
+  static const eventType = 'TaskList:RemoveTasks';
+
+  /// ID of the list to remove the tasks from
+  final int listId;
+  /// Array of task IDs to remove
+  final List<int> taskIds;
+
+  const TaskListRemoveTasksEvent({
+    required this.listId,
+    required this.taskIds,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'ListId': listId,
+      'TaskIds': taskIds,
+    };
+  }
+}
+
+/// Event to reorder a task list
+class TaskListReorderEvent {
+  static const eventType = 'TaskList:Reorder';
+
+  /// ID of the list to place this list after, null to move to front
+  final int? afterListId;
+  /// ID of the task list to reorder
+  final int listId;
+
+  const TaskListReorderEvent({
+    this.afterListId,
+    required this.listId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'AfterListId': afterListId,
+      'ListId': listId,
+    };
+  }
+}
+
+/// Event to reorder tasks within a list
+class TaskListReorderTasksEvent {
+  static const eventType = 'TaskList:ReorderTasks';
+
+  /// ID of the task to place this task after; takes precedence over BeforeTaskId
+  final int? afterTaskId;
+  /// ID of the task to place this task before, if AfterTaskId is null; both null moves it to the front
+  final int? beforeTaskId;
+  /// ID of the task to reorder
+  final int oldTaskId;
+  /// ID of the task list containing the tasks
+  final int taskListId;
+
+  const TaskListReorderTasksEvent({
+    this.afterTaskId,
+    this.beforeTaskId,
+    required this.oldTaskId,
+    required this.taskListId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'AfterTaskId': afterTaskId,
+      'BeforeTaskId': beforeTaskId,
+      'OldTaskId': oldTaskId,
+      'TaskListId': taskListId,
+    };
+  }
+}
+
+/// Event to restore a task list from the trash along with its tasks
+class TaskListRestoreEvent {
+  static const eventType = 'TaskList:Restore';
+
+  /// ID of the task list to restore
+  final int listId;
+
+  const TaskListRestoreEvent({
+    required this.listId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'ListId': listId,
+    };
+  }
+}
+
+/// Event to update a task list's archived status
+class TaskListUpdateArchivedEvent {
+  static const eventType = 'TaskList:UpdateArchived';
+
+  /// New archived status for the task list
+  final bool archived;
+  /// ID of the task list to update
+  final int listId;
+
+  const TaskListUpdateArchivedEvent({
+    required this.archived,
+    required this.listId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'Archived': archived,
+      'ListId': listId,
+    };
+  }
+}
+
+/// Event to update the filter that defines the contents of a smart list
+class TaskListUpdateFilterEvent {
+  static const eventType = 'TaskList:UpdateFilter';
+
+  /// Only include tasks due within this many days from now (including overdue tasks), null for no due date condition
+  final int? dueWithinDays;
+  /// Whether completed tasks are included
+  final bool includeCompleted;
+  /// Only include tasks that have any of these labels, empty for no label condition
+  final List<int> labelIds;
+  /// ID of the smart list to update
+  final int listId;
+  /// Only include tasks that are in any of these lists, empty for no list condition
+  final List<int> listIds;
+
+  const TaskListUpdateFilterEvent({
+    this.dueWithinDays,
+    required this.includeCompleted,
+    required this.labelIds,
+    required this.listId,
+    required this.listIds,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'DueWithinDays': dueWithinDays,
+      'IncludeCompleted': includeCompleted,
+      'LabelIds': labelIds,
+      'ListId': listId,
+      'ListIds': listIds,
+    };
+  }
+}
+
+/// Event to update a task list's title
+class TaskListUpdateTitleEvent {
+  static const eventType = 'TaskList:UpdateTitle';
+
+  /// ID of the task list to update
+  final int listId;
+  /// New title for the task list
+  final String title;
+
+  const TaskListUpdateTitleEvent({
+    required this.listId,
+    required this.title,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'ListId': listId,
+      'Title': title,
+    };
+  }
+}
+
+/// Event to undo an earlier event by applying its compensating events
+class UndoApplyEvent {
+  static const eventType = 'Undo:Apply';
+
+  /// ID of the event to undo, from /api/undo
+  final int eventId;
+
+  const UndoApplyEvent({
+    required this.eventId,
+  });
+
+  Map<String, Object?> toJson() {
+    return {
+      'type': eventType,
+      'EventId': eventId,
+    };
+  }
+}
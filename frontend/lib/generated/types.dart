@@ -0,0 +1,1396 @@
+// Auto-generated from the tasks schema's types.yml
+// Do not edit this file directly
+
+/// An iCalendar feed of the tasks with a due date, which calendar apps can subscribe to
+class CalendarFeed {
+  /// Unique identifier for the feed
+  final int id;
+  /// Whether each task is also included as an event, for calendar apps that don't show to-dos
+  final bool includeEvents;
+  /// Only include tasks with this label, null for tasks with any label or none
+  final int? labelId;
+  /// Only include tasks in this list, null for tasks in any list
+  final int? listId;
+  /// Name of the calendar shown in calendar apps
+  final String title;
+  /// Secret token that grants access to the feed at /api/ical?feedId=<Id>&token=<Token>
+  final String token;
+
+  const CalendarFeed({
+    required this.id,
+    required this.includeEvents,
+    this.labelId,
+    this.listId,
+    required this.title,
+    required this.token,
+  });
+
+  factory CalendarFeed.fromJson(Map<String, dynamic> json) {
+    return CalendarFeed(
+      id: json['Id'] as int,
+      includeEvents: json['IncludeEvents'] as bool,
+      labelId: json['LabelId'] as int?,
+      listId: json['ListId'] as int?,
+      title: json['Title'] as String,
+      token: json['Token'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Id': id,
+      'IncludeEvents': includeEvents,
+      'LabelId': labelId,
+      'ListId': listId,
+      'Title': title,
+      'Token': token,
+    };
+  }
+}
+
+/// Response containing all calendar feeds
+class CalendarFeedResponse {
+  /// Array of calendar feeds
+  final List<CalendarFeed> feeds;
+
+  const CalendarFeedResponse({
+    required this.feeds,
+  });
+
+  factory CalendarFeedResponse.fromJson(Map<String, dynamic> json) {
+    return CalendarFeedResponse(
+      feeds: (json['Feeds'] as List<dynamic>? ?? const [])
+          .map((e) => CalendarFeed.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Feeds': feeds.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// A task in the trash
+class DeletedTask {
+  /// When the task was deleted
+  final DateTime deletedAt;
+  /// Lists the task will be restored to
+  final List<TaskList> lists;
+  /// When the task will be permanently deleted and can no longer be restored
+  final DateTime purgeAfter;
+  /// The deleted task
+  final Task task;
+
+  const DeletedTask({
+    required this.deletedAt,
+    required this.lists,
+    required this.purgeAfter,
+    required this.task,
+  });
+
+  factory DeletedTask.fromJson(Map<String, dynamic> json) {
+    return DeletedTask(
+      deletedAt: DateTime.parse(json['DeletedAt'] as String),
+      lists: (json['Lists'] as List<dynamic>? ?? const [])
+          .map((e) => TaskList.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      purgeAfter: DateTime.parse(json['PurgeAfter'] as String),
+      task: Task.fromJson(json['Task'] as Map<String, dynamic>),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'DeletedAt': deletedAt.toUtc().toIso8601String(),
+      'Lists': lists.map((e) => e.toJson()).toList(),
+      'PurgeAfter': purgeAfter.toUtc().toIso8601String(),
+      'Task': task.toJson(),
+    };
+  }
+}
+
+/// Response containing recently deleted tasks
+class DeletedTaskResponse {
+  /// Array of deleted tasks, most recently deleted first
+  final List<DeletedTask> tasks;
+
+  const DeletedTaskResponse({
+    required this.tasks,
+  });
+
+  factory DeletedTaskResponse.fromJson(Map<String, dynamic> json) {
+    return DeletedTaskResponse(
+      tasks: (json['Tasks'] as List<dynamic>? ?? const [])
+          .map((e) => DeletedTask.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Tasks': tasks.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// A portable backup of every list and task, produced by /api/export and read back by /api/import
+class ExportDocument {
+  /// When the document was exported
+  final DateTime exportedAt;
+  /// Every list that is not in the trash, in display order
+  final List<ExportedList> lists;
+  /// Every task that is in at least one of the lists
+  final List<ExportedTask> tasks;
+  /// Version of the document format, currently 1
+  final int version;
+
+  const ExportDocument({
+    required this.exportedAt,
+    required this.lists,
+    required this.tasks,
+    required this.version,
+  });
+
+  factory ExportDocument.fromJson(Map<String, dynamic> json) {
+    return ExportDocument(
+      exportedAt: DateTime.parse(json['ExportedAt'] as String),
+      lists: (json['Lists'] as List<dynamic>? ?? const [])
+          .map((e) => ExportedList.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      tasks: (json['Tasks'] as List<dynamic>? ?? const [])
+          .map((e) => ExportedTask.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      version: json['Version'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'ExportedAt': exportedAt.toUtc().toIso8601String(),
+      'Lists': lists.map((e) => e.toJson()).toList(),
+      'Tasks': tasks.map((e) => e.toJson()).toList(),
+      'Version': version,
+    };
+  }
+}
+
+/// A history entry in an export document. Only comments are imported; the rest of the history is recreated by the import itself
+class ExportedHistory {
+  /// When the history entry was created
+  final DateTime createdAt;
+  /// System-generated comment describing the change
+  final String systemComment;
+  /// Type of update, add_comment for comments
+  final TaskUpdateType updateType;
+  /// The user's comment, for comments
+  final String? userComment;
+
+  const ExportedHistory({
+    required this.createdAt,
+    required this.systemComment,
+    required this.updateType,
+    this.userComment,
+  });
+
+  factory ExportedHistory.fromJson(Map<String, dynamic> json) {
+    return ExportedHistory(
+      createdAt: DateTime.parse(json['CreatedAt'] as String),
+      systemComment: json['SystemComment'] as String,
+      updateType: TaskUpdateType.fromJson(json['UpdateType'] as String),
+      userComment: json['UserComment'] as String?,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'CreatedAt': createdAt.toUtc().toIso8601String(),
+      'SystemComment': systemComment,
+      'UpdateType': updateType.toJson(),
+      'UserComment': userComment,
+    };
+  }
+}
+
+/// A list in an export document
+class ExportedList {
+  /// Whether the list is archived
+  final bool archived;
+  /// Category of the list
+  final TaskListCategory category;
+  /// The filter of a smart list, null for other lists
+  final TaskListFilter? filter;
+  /// ID of the list on the exporting server, which tasks refer to; lists get new IDs when imported
+  final int id;
+  /// Position of the list in display order, from 0
+  final int position;
+  /// Title/name of the list
+  final String title;
+
+  const ExportedList({
+    required this.archived,
+    required this.category,
+    this.filter,
+    required this.id,
+    required this.position,
+    required this.title,
+  });
+
+  factory ExportedList.fromJson(Map<String, dynamic> json) {
+    return ExportedList(
+      archived: json['Archived'] as bool,
+      category: TaskListCategory.fromJson(json['Category'] as String),
+      filter: json['Filter'] == null ? null : TaskListFilter.fromJson(json['Filter'] as Map<String, dynamic>),
+      id: json['Id'] as int,
+      position: json['Position'] as int,
+      title: json['Title'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Archived': archived,
+      'Category': category.toJson(),
+      'Filter': filter?.toJson(),
+      'Id': id,
+      'Position': position,
+      'Title': title,
+    };
+  }
+}
+
+/// A task's place in a list
+class ExportedMembership {
+  /// ID of the list in the export document
+  final int listId;
+  /// Position of the task in the list, from 0
+  final int position;
+
+  const ExportedMembership({
+    required this.listId,
+    required this.position,
+  });
+
+  factory ExportedMembership.fromJson(Map<String, dynamic> json) {
+    return ExportedMembership(
+      listId: json['ListId'] as int,
+      position: json['Position'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'ListId': listId,
+      'Position': position,
+    };
+  }
+}
+
+/// A reminder in an export document
+class ExportedReminder {
+  /// Minutes before the due date to send the reminder, null for reminders at a fixed time
+  final int? minutesBeforeDue;
+  /// Fixed time of the reminder, null for reminders relative to the due date
+  final DateTime? remindAt;
+
+  const ExportedReminder({
+    this.minutesBeforeDue,
+    this.remindAt,
+  });
+
+  factory ExportedReminder.fromJson(Map<String, dynamic> json) {
+    return ExportedReminder(
+      minutesBeforeDue: json['MinutesBeforeDue'] as int?,
+      remindAt: json['RemindAt'] == null ? null : DateTime.parse(json['RemindAt'] as String),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'MinutesBeforeDue': minutesBeforeDue,
+      'RemindAt': remindAt?.toUtc().toIso8601String(),
+    };
+  }
+}
+
+/// A task in an export document
+class ExportedTask {
+  /// Timestamp when the task was completed, null if not completed
+  final DateTime? completedAt;
+  /// Due date, null if the task has none
+  final DateTime? dueDate;
+  /// The task's history and comments, oldest first
+  final List<ExportedHistory> history;
+  /// ID of the task on the exporting server, which other tasks refer to; tasks get new IDs when imported
+  final int id;
+  /// The lists the task is in
+  final List<ExportedMembership> memberships;
+  /// Notes in Markdown, empty if the task has none
+  final String notes;
+  /// ID of the parent task, null for top-level tasks
+  final int? parentTaskId;
+  /// Priority from 1 (P1, highest) to 4 (P4), null if the task has no priority
+  final int? priority;
+  /// Normalized recurrence rule, null if the task does not recur
+  final String? recurrence;
+  /// The task's reminders
+  final List<ExportedReminder> reminders;
+  /// Time the task is snoozed until, null if it has never been snoozed
+  final DateTime? startAt;
+  /// Position of the task among its parent's subtasks, from 0; null for top-level tasks
+  final int? subtaskPosition;
+  /// Title/name of the task
+  final String title;
+
+  const ExportedTask({
+    this.completedAt,
+    this.dueDate,
+    required this.history,
+    required this.id,
+    required this.memberships,
+    required this.notes,
+    this.parentTaskId,
+    this.priority,
+    this.recurrence,
+    required this.reminders,
+    this.startAt,
+    this.subtaskPosition,
+    required this.title,
+  });
+
+  factory ExportedTask.fromJson(Map<String, dynamic> json) {
+    return ExportedTask(
+      completedAt: json['CompletedAt'] == null ? null : DateTime.parse(json['CompletedAt'] as String),
+      dueDate: json['DueDate'] == null ? null : DateTime.parse(json['DueDate'] as String),
+      history: (json['History'] as List<dynamic>? ?? const [])
+          .map((e) => ExportedHistory.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      id: json['Id'] as int,
+      memberships: (json['Memberships'] as List<dynamic>? ?? const [])
+          .map((e) => ExportedMembership.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      notes: json['Notes'] as String,
+      parentTaskId: json['ParentTaskId'] as int?,
+      priority: json['Priority'] as int?,
+      recurrence: json['Recurrence'] as String?,
+      reminders: (json['Reminders'] as List<dynamic>? ?? const [])
+          .map((e) => ExportedReminder.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      startAt: json['StartAt'] == null ? null : DateTime.parse(json['StartAt'] as String),
+      subtaskPosition: json['SubtaskPosition'] as int?,
+      title: json['Title'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'CompletedAt': completedAt?.toUtc().toIso8601String(),
+      'DueDate': dueDate?.toUtc().toIso8601String(),
+      'History': history.map((e) => e.toJson()).toList(),
+      'Id': id,
+      'Memberships': memberships.map((e) => e.toJson()).toList(),
+      'Notes': notes,
+      'ParentTaskId': parentTaskId,
+      'Priority': priority,
+      'Recurrence': recurrence,
+      'Reminders': reminders.map((e) => e.toJson()).toList(),
+      'StartAt': startAt?.toUtc().toIso8601String(),
+      'SubtaskPosition': subtaskPosition,
+      'Title': title,
+    };
+  }
+}
+
+/// Result of importing an export document
+class ImportResponse {
+  /// Number of comments added
+  final int commentCount;
+  /// Number of lists created
+  final int listCount;
+  /// Number of tasks created
+  final int taskCount;
+
+  const ImportResponse({
+    required this.commentCount,
+    required this.listCount,
+    required this.taskCount,
+  });
+
+  factory ImportResponse.fromJson(Map<String, dynamic> json) {
+    return ImportResponse(
+      commentCount: json['CommentCount'] as int,
+      listCount: json['ListCount'] as int,
+      taskCount: json['TaskCount'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'CommentCount': commentCount,
+      'ListCount': listCount,
+      'TaskCount': taskCount,
+    };
+  }
+}
+
+/// Result of rebuilding the projection tables from the event log
+class ProjectionRebuildResponse {
+  /// How long the rebuild took, in milliseconds
+  final int durationMs;
+  /// Number of events replayed
+  final int eventCount;
+  /// Number of events that no handler applies to
+  final int ignoredCount;
+
+  const ProjectionRebuildResponse({
+    required this.durationMs,
+    required this.eventCount,
+    required this.ignoredCount,
+  });
+
+  factory ProjectionRebuildResponse.fromJson(Map<String, dynamic> json) {
+    return ProjectionRebuildResponse(
+      durationMs: json['DurationMs'] as int,
+      eventCount: json['EventCount'] as int,
+      ignoredCount: json['IgnoredCount'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'DurationMs': durationMs,
+      'EventCount': eventCount,
+      'IgnoredCount': ignoredCount,
+    };
+  }
+}
+
+/// Markdown rendered to HTML
+class RenderedMarkdownResponse {
+  /// The rendered HTML, with any raw HTML in the Markdown escaped
+  final String html;
+
+  const RenderedMarkdownResponse({
+    required this.html,
+  });
+
+  factory RenderedMarkdownResponse.fromJson(Map<String, dynamic> json) {
+    return RenderedMarkdownResponse(
+      html: json['Html'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Html': html,
+    };
+  }
+}
+
+/// Response containing full-text search results ordered by relevance
+class SearchResponse {
+  /// Array of search results
+  final List<SearchResult> results;
+
+  const SearchResponse({
+    required this.results,
+  });
+
+  factory SearchResponse.fromJson(Map<String, dynamic> json) {
+    return SearchResponse(
+      results: (json['Results'] as List<dynamic>? ?? const [])
+          .map((e) => SearchResult.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Results': results.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// A single full-text search match, either a task or a task list
+class SearchResult {
+  /// What matched: task (title or comment) or list (list title)
+  final SearchResultKind kind;
+  /// The matching task list, null for task matches
+  final TaskList? list;
+  /// Task lists the matching task belongs to, empty for list matches
+  final List<TaskList> lists;
+  /// Which field matched (title, comment, list_title)
+  final String matchedField;
+  /// Relevance from 0 to 1, higher is better. Each of the task title, comment and list title indexes is scored separately, relative to its own best match, so that scores are comparable across them
+  final double score;
+  /// Excerpt of the matching text, with each match between U+E000 and U+E001 from the private use area. These characters are removed from text when it is indexed, so they only ever mark matches
+  final String snippet;
+  /// The matching task, null for list matches
+  final Task? task;
+
+  const SearchResult({
+    required this.kind,
+    this.list,
+    required this.lists,
+    required this.matchedField,
+    required this.score,
+    required this.snippet,
+    this.task,
+  });
+
+  factory SearchResult.fromJson(Map<String, dynamic> json) {
+    return SearchResult(
+      kind: SearchResultKind.fromJson(json['Kind'] as String),
+      list: json['List'] == null ? null : TaskList.fromJson(json['List'] as Map<String, dynamic>),
+      lists: (json['Lists'] as List<dynamic>? ?? const [])
+          .map((e) => TaskList.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      matchedField: json['MatchedField'] as String,
+      score: (json['Score'] as num).toDouble(),
+      snippet: json['Snippet'] as String,
+      task: json['Task'] == null ? null : Task.fromJson(json['Task'] as Map<String, dynamic>),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Kind': kind.toJson(),
+      'List': list?.toJson(),
+      'Lists': lists.map((e) => e.toJson()).toList(),
+      'MatchedField': matchedField,
+      'Score': score,
+      'Snippet': snippet,
+      'Task': task?.toJson(),
+    };
+  }
+}
+
+/// Represents a single task in the system
+class Task {
+  /// Timestamp when the task was completed, null if not completed
+  final DateTime? completedAt;
+  /// Optional due date for the task
+  final DateTime? dueDate;
+  /// Unique identifier for the task
+  final int id;
+  /// Long-form notes in Markdown, empty if the task has none
+  final String notes;
+  /// ID of the parent task, null for top-level tasks
+  final int? parentTaskId;
+  /// Priority from 1 (P1, highest) to 4 (P4), null if the task has no priority
+  final int? priority;
+  /// Normalized recurrence rule (e.g. FREQ=WEEKLY;BYDAY=MO), null if the task does not recur
+  final String? recurrence;
+  /// The task is snoozed, and left out of lists, until this time; null if it has never been snoozed
+  final DateTime? startAt;
+  /// Number of direct subtasks
+  final int subtaskCount;
+  /// Ordered subtasks of this task
+  final List<Task> subtasks;
+  /// Number of direct subtasks that are completed
+  final int subtasksCompleted;
+  /// Title/name of the task
+  final String title;
+
+  const Task({
+    this.completedAt,
+    this.dueDate,
+    required this.id,
+    required this.notes,
+    this.parentTaskId,
+    this.priority,
+    this.recurrence,
+    this.startAt,
+    required this.subtaskCount,
+    required this.subtasks,
+    required this.subtasksCompleted,
+    required this.title,
+  });
+
+  factory Task.fromJson(Map<String, dynamic> json) {
+    return Task(
+      completedAt: json['CompletedAt'] == null ? null : DateTime.parse(json['CompletedAt'] as String),
+      dueDate: json['DueDate'] == null ? null : DateTime.parse(json['DueDate'] as String),
+      id: json['Id'] as int,
+      notes: json['Notes'] as String,
+      parentTaskId: json['ParentTaskId'] as int?,
+      priority: json['Priority'] as int?,
+      recurrence: json['Recurrence'] as String?,
+      startAt: json['StartAt'] == null ? null : DateTime.parse(json['StartAt'] as String),
+      subtaskCount: json['SubtaskCount'] as int,
+      subtasks: (json['Subtasks'] as List<dynamic>? ?? const [])
+          .map((e) => Task.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      subtasksCompleted: json['SubtasksCompleted'] as int,
+      title: json['Title'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'CompletedAt': completedAt?.toUtc().toIso8601String(),
+      'DueDate': dueDate?.toUtc().toIso8601String(),
+      'Id': id,
+      'Notes': notes,
+      'ParentTaskId': parentTaskId,
+      'Priority': priority,
+      'Recurrence': recurrence,
+      'StartAt': startAt?.toUtc().toIso8601String(),
+      'SubtaskCount': subtaskCount,
+      'Subtasks': subtasks.map((e) => e.toJson()).toList(),
+      'SubtasksCompleted': subtasksCompleted,
+      'Title': title,
+    };
+  }
+}
+
+/// Represents a single history entry for a task
+class TaskHistory {
+  /// When this history entry was created
+  final DateTime createdAt;
+  /// Unique identifier for the history entry
+  final int id;
+  /// System-generated comment describing the change
+  final String systemComment;
+  /// ID of the task this history entry belongs to
+  final int taskId;
+  /// Type of update
+  final TaskUpdateType updateType;
+  /// Optional user-provided comment
+  final String? userComment;
+
+  const TaskHistory({
+    required this.createdAt,
+    required this.id,
+    required this.systemComment,
+    required this.taskId,
+    required this.updateType,
+    this.userComment,
+  });
+
+  factory TaskHistory.fromJson(Map<String, dynamic> json) {
+    return TaskHistory(
+      createdAt: DateTime.parse(json['CreatedAt'] as String),
+      id: json['Id'] as int,
+      systemComment: json['SystemComment'] as String,
+      taskId: json['TaskId'] as int,
+      updateType: TaskUpdateType.fromJson(json['UpdateType'] as String),
+      userComment: json['UserComment'] as String?,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'CreatedAt': createdAt.toUtc().toIso8601String(),
+      'Id': id,
+      'SystemComment': systemComment,
+      'TaskId': taskId,
+      'UpdateType': updateType.toJson(),
+      'UserComment': userComment,
+    };
+  }
+}
+
+/// Response containing task history and title
+class TaskHistoryResponse {
+  /// Array of history entries for the task
+  final List<TaskHistory> history;
+  /// Current title of the task
+  final String title;
+
+  const TaskHistoryResponse({
+    required this.history,
+    required this.title,
+  });
+
+  factory TaskHistoryResponse.fromJson(Map<String, dynamic> json) {
+    return TaskHistoryResponse(
+      history: (json['History'] as List<dynamic>? ?? const [])
+          .map((e) => TaskHistory.fromJson(e as Map<String, dynamic>))
+          .toList(),
+      title: json['Title'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'History': history.map((e) => e.toJson()).toList(),
+      'Title': title,
+    };
+  }
+}
+
+/// Label information for a task
+class TaskLabels {
+  /// The label text
+  final String label;
+  /// ID of the list representing this label
+  final int listId;
+  /// ID of the task with the label
+  final int taskId;
+
+  const TaskLabels({
+    required this.label,
+    required this.listId,
+    required this.taskId,
+  });
+
+  factory TaskLabels.fromJson(Map<String, dynamic> json) {
+    return TaskLabels(
+      label: json['Label'] as String,
+      listId: json['ListId'] as int,
+      taskId: json['TaskId'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Label': label,
+      'ListId': listId,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Response containing task labels for tasks in a list
+class TaskLabelsResponse {
+  /// Array of task label entries
+  final List<TaskLabels> labels;
+
+  const TaskLabelsResponse({
+    required this.labels,
+  });
+
+  factory TaskLabelsResponse.fromJson(Map<String, dynamic> json) {
+    return TaskLabelsResponse(
+      labels: (json['Labels'] as List<dynamic>? ?? const [])
+          .map((e) => TaskLabels.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Labels': labels.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// Represents a task list in the system
+class TaskList {
+  /// Whether the task list is archived
+  final bool archived;
+  /// Category of the list
+  final TaskListCategory category;
+  /// Unique identifier for the task list
+  final int id;
+  /// Title/name of the task list
+  final String title;
+
+  const TaskList({
+    required this.archived,
+    required this.category,
+    required this.id,
+    required this.title,
+  });
+
+  factory TaskList.fromJson(Map<String, dynamic> json) {
+    return TaskList(
+      archived: json['Archived'] as bool,
+      category: TaskListCategory.fromJson(json['Category'] as String),
+      id: json['Id'] as int,
+      title: json['Title'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Archived': archived,
+      'Category': category.toJson(),
+      'Id': id,
+      'Title': title,
+    };
+  }
+}
+
+/// The filter that defines the contents of a smart list
+class TaskListFilter {
+  /// Only include tasks due within this many days from now (including overdue tasks), null for no due date condition
+  final int? dueWithinDays;
+  /// Whether completed tasks are included
+  final bool includeCompleted;
+  /// Only include tasks that have any of these labels, empty for no label condition
+  final List<int> labelIds;
+  /// ID of the smart list
+  final int listId;
+  /// Only include tasks that are in any of these lists, empty for no list condition
+  final List<int> listIds;
+
+  const TaskListFilter({
+    this.dueWithinDays,
+    required this.includeCompleted,
+    required this.labelIds,
+    required this.listId,
+    required this.listIds,
+  });
+
+  factory TaskListFilter.fromJson(Map<String, dynamic> json) {
+    return TaskListFilter(
+      dueWithinDays: json['DueWithinDays'] as int?,
+      includeCompleted: json['IncludeCompleted'] as bool,
+      labelIds: (json['LabelIds'] as List<dynamic>? ?? const [])
+          .map((e) => e as int)
+          .toList(),
+      listId: json['ListId'] as int,
+      listIds: (json['ListIds'] as List<dynamic>? ?? const [])
+          .map((e) => e as int)
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'DueWithinDays': dueWithinDays,
+      'IncludeCompleted': includeCompleted,
+      'LabelIds': labelIds,
+      'ListId': listId,
+      'ListIds': listIds,
+    };
+  }
+}
+
+/// Metadata information for a task list
+class TaskListMetadata {
+  /// Number of completed tasks in the list
+  final int completed;
+  /// ID of the task list
+  final int listId;
+  /// Total number of tasks in the list
+  final int total;
+
+  const TaskListMetadata({
+    required this.completed,
+    required this.listId,
+    required this.total,
+  });
+
+  factory TaskListMetadata.fromJson(Map<String, dynamic> json) {
+    return TaskListMetadata(
+      completed: json['Completed'] as int,
+      listId: json['ListId'] as int,
+      total: json['Total'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Completed': completed,
+      'ListId': listId,
+      'Total': total,
+    };
+  }
+}
+
+/// Response containing task list metadata
+class TaskListMetadataResponse {
+  /// Array of metadata entries for task lists
+  final List<TaskListMetadata> metadata;
+
+  const TaskListMetadataResponse({
+    required this.metadata,
+  });
+
+  factory TaskListMetadataResponse.fromJson(Map<String, dynamic> json) {
+    return TaskListMetadataResponse(
+      metadata: (json['Metadata'] as List<dynamic>? ?? const [])
+          .map((e) => TaskListMetadata.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Metadata': metadata.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// Response containing a list of task lists
+class TaskListResponse {
+  /// Array of task lists
+  final List<TaskList> taskLists;
+
+  const TaskListResponse({
+    required this.taskLists,
+  });
+
+  factory TaskListResponse.fromJson(Map<String, dynamic> json) {
+    return TaskListResponse(
+      taskLists: (json['TaskLists'] as List<dynamic>? ?? const [])
+          .map((e) => TaskList.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'TaskLists': taskLists.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// A task's notes, along with the HTML they render to
+class TaskNotesResponse {
+  /// The notes rendered to HTML, with any raw HTML in the Markdown escaped
+  final String html;
+  /// The notes as entered, in Markdown
+  final String markdown;
+  /// ID of the task
+  final int taskId;
+
+  const TaskNotesResponse({
+    required this.html,
+    required this.markdown,
+    required this.taskId,
+  });
+
+  factory TaskNotesResponse.fromJson(Map<String, dynamic> json) {
+    return TaskNotesResponse(
+      html: json['Html'] as String,
+      markdown: json['Markdown'] as String,
+      taskId: json['TaskId'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Html': html,
+      'Markdown': markdown,
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Recent comment information for a task
+class TaskRecentComment {
+  /// When the comment was created, null if no comment
+  final DateTime? createdAt;
+  /// ID of the task list containing the task
+  final int listId;
+  /// ID of the task with the comment
+  final int taskId;
+  /// The user comment, null if no comment
+  final String? userComment;
+
+  const TaskRecentComment({
+    this.createdAt,
+    required this.listId,
+    required this.taskId,
+    this.userComment,
+  });
+
+  factory TaskRecentComment.fromJson(Map<String, dynamic> json) {
+    return TaskRecentComment(
+      createdAt: json['CreatedAt'] == null ? null : DateTime.parse(json['CreatedAt'] as String),
+      listId: json['ListId'] as int,
+      taskId: json['TaskId'] as int,
+      userComment: json['UserComment'] as String?,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'CreatedAt': createdAt?.toUtc().toIso8601String(),
+      'ListId': listId,
+      'TaskId': taskId,
+      'UserComment': userComment,
+    };
+  }
+}
+
+/// Response containing recent comments for tasks in a list
+class TaskRecentCommentResponse {
+  /// Array of recent comment entries
+  final List<TaskRecentComment> comments;
+
+  const TaskRecentCommentResponse({
+    required this.comments,
+  });
+
+  factory TaskRecentCommentResponse.fromJson(Map<String, dynamic> json) {
+    return TaskRecentCommentResponse(
+      comments: (json['Comments'] as List<dynamic>? ?? const [])
+          .map((e) => TaskRecentComment.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Comments': comments.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// A reminder for a task, at a fixed time or a number of minutes before its due date
+class TaskReminder {
+  /// When the reminder is sent, null if it is relative to the due date and the task has none
+  final DateTime? fireAt;
+  /// Whether the reminder has been sent for its current time
+  final bool fired;
+  /// Unique identifier for the reminder
+  final int id;
+  /// Minutes before the due date to send the reminder, null for reminders at a fixed time
+  final int? minutesBeforeDue;
+  /// Fixed time of the reminder, null for reminders relative to the due date
+  final DateTime? remindAt;
+  /// ID of the task the reminder is for
+  final int taskId;
+
+  const TaskReminder({
+    this.fireAt,
+    required this.fired,
+    required this.id,
+    this.minutesBeforeDue,
+    this.remindAt,
+    required this.taskId,
+  });
+
+  factory TaskReminder.fromJson(Map<String, dynamic> json) {
+    return TaskReminder(
+      fireAt: json['FireAt'] == null ? null : DateTime.parse(json['FireAt'] as String),
+      fired: json['Fired'] as bool,
+      id: json['Id'] as int,
+      minutesBeforeDue: json['MinutesBeforeDue'] as int?,
+      remindAt: json['RemindAt'] == null ? null : DateTime.parse(json['RemindAt'] as String),
+      taskId: json['TaskId'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'FireAt': fireAt?.toUtc().toIso8601String(),
+      'Fired': fired,
+      'Id': id,
+      'MinutesBeforeDue': minutesBeforeDue,
+      'RemindAt': remindAt?.toUtc().toIso8601String(),
+      'TaskId': taskId,
+    };
+  }
+}
+
+/// Response containing the reminders for a task
+class TaskReminderResponse {
+  /// Array of reminders, in the order they are sent
+  final List<TaskReminder> reminders;
+
+  const TaskReminderResponse({
+    required this.reminders,
+  });
+
+  factory TaskReminderResponse.fromJson(Map<String, dynamic> json) {
+    return TaskReminderResponse(
+      reminders: (json['Reminders'] as List<dynamic>? ?? const [])
+          .map((e) => TaskReminder.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Reminders': reminders.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// Response containing a list of tasks
+class TaskResponse {
+  /// Array of top-level tasks, with subtasks nested under their parents
+  final List<Task> tasks;
+
+  const TaskResponse({
+    required this.tasks,
+  });
+
+  factory TaskResponse.fromJson(Map<String, dynamic> json) {
+    return TaskResponse(
+      tasks: (json['Tasks'] as List<dynamic>? ?? const [])
+          .map((e) => Task.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Tasks': tasks.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// A value to substitute for a {{Name}} placeholder when instantiating a template
+class TemplatePlaceholder {
+  /// Name of the placeholder, without the surrounding braces
+  final String nameValue;
+  /// Text to substitute for the placeholder
+  final String valueValue;
+
+  const TemplatePlaceholder({
+    required this.nameValue,
+    required this.valueValue,
+  });
+
+  factory TemplatePlaceholder.fromJson(Map<String, dynamic> json) {
+    return TemplatePlaceholder(
+      nameValue: json['Name'] as String,
+      valueValue: json['Value'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Name': nameValue,
+      'Value': valueValue,
+    };
+  }
+}
+
+/// A task list in the trash
+class TrashedTaskList {
+  /// Whether tasks that are in no other list will be deleted along with the list
+  final bool deleteOrphanedTasks;
+  /// When the task list was deleted
+  final DateTime deletedAt;
+  /// The deleted task list
+  final TaskList list;
+  /// When the task list will be permanently deleted and can no longer be restored
+  final DateTime purgeAfter;
+  /// Number of tasks that were in the list when it was deleted
+  final int taskCount;
+
+  const TrashedTaskList({
+    required this.deleteOrphanedTasks,
+    required this.deletedAt,
+    required this.list,
+    required this.purgeAfter,
+    required this.taskCount,
+  });
+
+  factory TrashedTaskList.fromJson(Map<String, dynamic> json) {
+    return TrashedTaskList(
+      deleteOrphanedTasks: json['DeleteOrphanedTasks'] as bool,
+      deletedAt: DateTime.parse(json['DeletedAt'] as String),
+      list: TaskList.fromJson(json['List'] as Map<String, dynamic>),
+      purgeAfter: DateTime.parse(json['PurgeAfter'] as String),
+      taskCount: json['TaskCount'] as int,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'DeleteOrphanedTasks': deleteOrphanedTasks,
+      'DeletedAt': deletedAt.toUtc().toIso8601String(),
+      'List': list.toJson(),
+      'PurgeAfter': purgeAfter.toUtc().toIso8601String(),
+      'TaskCount': taskCount,
+    };
+  }
+}
+
+/// Response containing the task lists in the trash
+class TrashedTaskListResponse {
+  /// Array of deleted task lists, most recently deleted first
+  final List<TrashedTaskList> taskLists;
+
+  const TrashedTaskListResponse({
+    required this.taskLists,
+  });
+
+  factory TrashedTaskListResponse.fromJson(Map<String, dynamic> json) {
+    return TrashedTaskListResponse(
+      taskLists: (json['TaskLists'] as List<dynamic>? ?? const [])
+          .map((e) => TrashedTaskList.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'TaskLists': taskLists.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// An event that can be undone
+class UndoEntry {
+  /// When the event was applied
+  final DateTime createdAt;
+  /// ID of the event in the event log, which identifies the undo entry
+  final int eventId;
+  /// Type of the event that would be undone
+  final String eventType;
+  /// Compensating events, computed from the state before the event, in the order they are applied
+  final List<UndoEvent> events;
+
+  const UndoEntry({
+    required this.createdAt,
+    required this.eventId,
+    required this.eventType,
+    required this.events,
+  });
+
+  factory UndoEntry.fromJson(Map<String, dynamic> json) {
+    return UndoEntry(
+      createdAt: DateTime.parse(json['CreatedAt'] as String),
+      eventId: json['EventId'] as int,
+      eventType: json['EventType'] as String,
+      events: (json['Events'] as List<dynamic>? ?? const [])
+          .map((e) => UndoEvent.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'CreatedAt': createdAt.toUtc().toIso8601String(),
+      'EventId': eventId,
+      'EventType': eventType,
+      'Events': events.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// A compensating event that reverts part of an earlier event
+class UndoEvent {
+  /// JSON-encoded event payload
+  final String payload;
+  /// Event type, e.g. Task:UpdateTitle
+  final String type;
+
+  const UndoEvent({
+    required this.payload,
+    required this.type,
+  });
+
+  factory UndoEvent.fromJson(Map<String, dynamic> json) {
+    return UndoEvent(
+      payload: json['Payload'] as String,
+      type: json['Type'] as String,
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Payload': payload,
+      'Type': type,
+    };
+  }
+}
+
+/// Response containing the events that can be undone
+class UndoResponse {
+  /// Array of undo entries, most recent first
+  final List<UndoEntry> entries;
+
+  const UndoResponse({
+    required this.entries,
+  });
+
+  factory UndoResponse.fromJson(Map<String, dynamic> json) {
+    return UndoResponse(
+      entries: (json['Entries'] as List<dynamic>? ?? const [])
+          .map((e) => UndoEntry.fromJson(e as Map<String, dynamic>))
+          .toList(),
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'Entries': entries.map((e) => e.toJson()).toList(),
+    };
+  }
+}
+
+/// What a search result is
+enum SearchResultKind {
+  task('task'),
+  list('list');
+
+  const SearchResultKind(this.value);
+
+  final String value;
+
+  static SearchResultKind fromJson(String value) {
+    return values.firstWhere((e) => e.value == value,
+        orElse: () => throw ArgumentError.value(value, 'SearchResultKind'));
+  }
+
+  String toJson() => value;
+}
+
+/// Category of a task list
+enum TaskListCategory {
+  toDoList('toDoList'),
+  template('template'),
+  label('label'),
+  smart('smart');
+
+  const TaskListCategory(this.value);
+
+  final String value;
+
+  static TaskListCategory fromJson(String value) {
+    return values.firstWhere((e) => e.value == value,
+        orElse: () => throw ArgumentError.value(value, 'TaskListCategory'));
+  }
+
+  String toJson() => value;
+}
+
+/// Order of the top-level tasks of a task list: manual, due (earliest first), priority (P1 first), created (newest first) or completed (incomplete tasks first, then most recently completed)
+enum TaskSortMode {
+  manual('manual'),
+  due('due'),
+  priority('priority'),
+  created('created'),
+  completed('completed');
+
+  const TaskSortMode(this.value);
+
+  final String value;
+
+  static TaskSortMode fromJson(String value) {
+    return values.firstWhere((e) => e.value == value,
+        orElse: () => throw ArgumentError.value(value, 'TaskSortMode'));
+  }
+
+  String toJson() => value;
+}
+
+/// Type of change recorded in a task's history
+enum TaskUpdateType {
+  updateTitle('update_title'),
+  updateNotes('update_notes'),
+  updatePriority('update_priority'),
+  updateCompleted('update_completed'),
+  updateDueDate('update_due_date'),
+  snooze('snooze'),
+  updateReminders('update_reminders'),
+  updateRecurrence('update_recurrence'),
+  nextOccurrence('next_occurrence'),
+  updateParent('update_parent'),
+  delete('delete'),
+  restore('restore'),
+  addComment('add_comment');
+
+  const TaskUpdateType(this.value);
+
+  final String value;
+
+  static TaskUpdateType fromJson(String value) {
+    return values.firstWhere((e) => e.value == value,
+        orElse: () => throw ArgumentError.value(value, 'TaskUpdateType'));
+  }
+
+  String toJson() => value;
+}
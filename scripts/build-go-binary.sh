@@ -47,7 +47,7 @@ mkdir -p "$BACKEND_DIR/tasks/generated"
     ../backend/tasks/schema/api.yml \
     generated ../backend/tasks/generated/types.go)
 (cd $BACKEND_DIR &&
-  "$GO_BIN" build -o "../build/pkg/bin/app" ./tasks/main.go &&
+  "$GO_BIN" build -tags sqlite_fts5 -o "../build/pkg/bin/app" ./tasks/main.go &&
   cp -p tasks/manifest.json ../build/pkg/
 )
 
@@ -0,0 +1,587 @@
+//go:build ignore
+
+// Run with "go run generate-dart-models.go", like generate-kotlin-models.go.
+// The build tag keeps it out of the package, which already has a main.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"yellowstone-generator/schema"
+)
+
+// Dart keywords and enum members that can't be used as identifiers
+var dartReservedWords = map[string]bool{
+	"assert": true, "break": true, "case": true, "catch": true, "class": true,
+	"const": true, "continue": true, "default": true, "do": true, "else": true,
+	"enum": true, "extends": true, "false": true, "final": true, "finally": true,
+	"for": true, "if": true, "in": true, "is": true, "new": true, "null": true,
+	"rethrow": true, "return": true, "super": true, "switch": true, "this": true,
+	"throw": true, "true": true, "try": true, "var": true, "void": true,
+	"while": true, "with": true, "index": true, "name": true, "value": true,
+	"values": true,
+}
+
+var (
+	dartPackage   = flag.String("package", "", "Dart package whose lib/yesterday/api.dart the routes use (default: import it relative to lib/generated)")
+	componentName = flag.String("component", "", "component the schema belongs to, named in the generated files' headers (default: the name of the directory containing the schema directory)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-package name] [-component name] <input_yaml> <output_dart>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	inputFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
+
+	// Ensure output directory exists
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The whole schema is loaded, so that references to types in the other
+	// files are checked, and the input file picks what is generated from it
+	schemaDir, err := filepath.Abs(filepath.Dir(inputFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding schema directory: %v\n", err)
+		os.Exit(1)
+	}
+	s, err := schema.LoadDir(schemaDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading schema: %v\n", err)
+		os.Exit(1)
+	}
+	if *componentName == "" {
+		// e.g. tasks for backend/tasks/schema
+		*componentName = filepath.Base(filepath.Dir(schemaDir))
+	}
+
+	// Generate Dart file
+	var dartCode string
+	inputName := filepath.Base(inputFile)
+	switch inputName {
+	case schema.EventsFileName:
+		dartCode = generateDartEventsFile(s.Events)
+	case schema.APIFileName:
+		dartCode = generateDartRoutesFile(s)
+	case schema.TypesFileName:
+		dartCode = generateDartFile(s.Types, s.Enums)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown schema file %s, expected %s, %s or %s\n", inputName, schema.TypesFileName, schema.EventsFileName, schema.APIFileName)
+		os.Exit(1)
+	}
+
+	// Write to file
+	if err := os.WriteFile(outputFile, []byte(dartCode), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing Dart file: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch inputName {
+	case schema.EventsFileName:
+		fmt.Printf("Generated Dart events: %s\n", outputFile)
+	case schema.APIFileName:
+		fmt.Printf("Generated Dart routes: %s\n", outputFile)
+	default:
+		fmt.Printf("Generated Dart models: %s\n", outputFile)
+	}
+}
+
+// generatedHeader is the comment at the top of each generated file
+func generatedHeader(fileName string) string {
+	return fmt.Sprintf("// Auto-generated from the %s schema's %s\n// Do not edit this file directly\n", *componentName, fileName)
+}
+
+func generateDartFile(types map[string]schema.Type, enums map[string]schema.Enum) string {
+	var builder strings.Builder
+
+	// File header
+	builder.WriteString(generatedHeader(schema.TypesFileName))
+
+	// Generate classes for each type (sorted for deterministic order)
+	var typeNames []string
+	for typeName := range types {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	for _, typeName := range typeNames {
+		builder.WriteString("\n")
+		builder.WriteString(generateDartClass(typeName, types[typeName], enums))
+	}
+
+	var enumNames []string
+	for enumName := range enums {
+		enumNames = append(enumNames, enumName)
+	}
+	sort.Strings(enumNames)
+
+	for _, enumName := range enumNames {
+		builder.WriteString("\n")
+		builder.WriteString(generateDartEnum(enumName, enums[enumName]))
+	}
+
+	return builder.String()
+}
+
+// generateDartEnum generates an enum whose values are serialized as the
+// schema's values.
+func generateDartEnum(enumName string, enumDef schema.Enum) string {
+	var builder strings.Builder
+
+	writeDartDocComment(&builder, "", enumDef.Description)
+	builder.WriteString(fmt.Sprintf("enum %s {\n", enumName))
+	var valueLines []string
+	for _, value := range enumDef.Values {
+		valueLines = append(valueLines, fmt.Sprintf("  %s('%s')", dartEnumValueName(value), value))
+	}
+	builder.WriteString(strings.Join(valueLines, ",\n"))
+	builder.WriteString(";\n\n")
+	builder.WriteString(fmt.Sprintf("  const %s(this.value);\n\n", enumName))
+	builder.WriteString("  final String value;\n\n")
+	builder.WriteString(fmt.Sprintf("  static %s fromJson(String value) {\n", enumName))
+	builder.WriteString("    return values.firstWhere((e) => e.value == value,\n")
+	builder.WriteString(fmt.Sprintf("        orElse: () => throw ArgumentError.value(value, '%s'));\n", enumName))
+	builder.WriteString("  }\n\n")
+	builder.WriteString("  String toJson() => value;\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// dartEnumValueName converts an enum value to a Dart enum value name, e.g.
+// "toDoList" stays toDoList and "add_comment" becomes addComment
+func dartEnumValueName(value string) string {
+	var builder strings.Builder
+	upper := false
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			builder.WriteRune(r)
+			upper = false
+		default:
+			upper = builder.Len() > 0
+		}
+	}
+	return dartIdentifier(toCamelCase(builder.String()))
+}
+
+// dartIdentifier avoids names that Dart reserves
+func dartIdentifier(name string) string {
+	if dartReservedWords[name] {
+		return name + "Value"
+	}
+	return name
+}
+
+func generateDartClass(className string, classDef schema.Type, enums map[string]schema.Enum) string {
+	var builder strings.Builder
+
+	propNames := sortedPropertyNames(classDef.Properties)
+
+	writeDartDocComment(&builder, "", classDef.Description)
+	builder.WriteString(fmt.Sprintf("class %s {\n", className))
+	builder.WriteString(generateDartFields(className, classDef.Properties, propNames))
+
+	// fromJson
+	builder.WriteString(fmt.Sprintf("\n  factory %s.fromJson(Map<String, dynamic> json) {\n", className))
+	builder.WriteString(fmt.Sprintf("    return %s(\n", className))
+	for _, propName := range propNames {
+		propDef := classDef.Properties[propName]
+		value := dartFromJson(fmt.Sprintf("json['%s']", propName), propDef.Type, propDef.ItemType, propDef.Nullable, enums)
+		builder.WriteString(fmt.Sprintf("      %s: %s,\n", dartIdentifier(toCamelCase(propName)), value))
+	}
+	builder.WriteString("    );\n")
+	builder.WriteString("  }\n")
+
+	// toJson
+	builder.WriteString("\n  Map<String, dynamic> toJson() {\n")
+	builder.WriteString("    return {\n")
+	builder.WriteString(generateDartJsonEntries(classDef.Properties, propNames))
+	builder.WriteString("    };\n")
+	builder.WriteString("  }\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// generateDartFields generates the final fields of a class and its const
+// constructor. Nullable fields are optional in the constructor.
+func generateDartFields(className string, properties map[string]schema.Property, propNames []string) string {
+	var builder strings.Builder
+
+	for _, propName := range propNames {
+		propDef := properties[propName]
+		writeDartDocComment(&builder, "  ", propDef.Description)
+		dartType := dartTypeFromSchema(propDef.Type, propDef.ItemType, propDef.Nullable)
+		builder.WriteString(fmt.Sprintf("  final %s %s;\n", dartType, dartIdentifier(toCamelCase(propName))))
+	}
+	if len(propNames) > 0 {
+		builder.WriteString("\n")
+	}
+
+	if len(propNames) == 0 {
+		builder.WriteString(fmt.Sprintf("  const %s();\n", className))
+		return builder.String()
+	}
+	builder.WriteString(fmt.Sprintf("  const %s({\n", className))
+	for _, propName := range propNames {
+		propDef := properties[propName]
+		name := dartIdentifier(toCamelCase(propName))
+		if propDef.Nullable {
+			builder.WriteString(fmt.Sprintf("    this.%s,\n", name))
+		} else {
+			builder.WriteString(fmt.Sprintf("    required this.%s,\n", name))
+		}
+	}
+	builder.WriteString("  });\n")
+
+	return builder.String()
+}
+
+// generateDartJsonEntries generates the map entries of toJson, keyed by the
+// schema's property names
+func generateDartJsonEntries(properties map[string]schema.Property, propNames []string) string {
+	var builder strings.Builder
+	for _, propName := range propNames {
+		propDef := properties[propName]
+		name := dartIdentifier(toCamelCase(propName))
+		builder.WriteString(fmt.Sprintf("      '%s': %s,\n", propName, dartToJson(name, propDef.Type, propDef.ItemType, propDef.Nullable)))
+	}
+	return builder.String()
+}
+
+func sortedPropertyNames(properties map[string]schema.Property) []string {
+	var propNames []string
+	for propName := range properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+	return propNames
+}
+
+func writeDartDocComment(builder *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	builder.WriteString(fmt.Sprintf("%s/// %s\n", indent, description))
+}
+
+func dartTypeFromSchema(typeName, itemType string, nullable bool) string {
+	var baseType string
+
+	switch typeName {
+	case "integer":
+		baseType = "int"
+	case "number":
+		baseType = "double"
+	case "string":
+		baseType = "String"
+	case "boolean":
+		baseType = "bool"
+	case "timestamp":
+		baseType = "DateTime"
+	case "array":
+		if itemType != "" {
+			baseType = fmt.Sprintf("List<%s>", dartTypeFromSchema(itemType, "", false))
+		} else {
+			baseType = "List<dynamic>"
+		}
+	default:
+		baseType = typeName
+	}
+
+	if nullable {
+		return baseType + "?"
+	}
+	return baseType
+}
+
+// dartFromJson returns the expression that converts a decoded JSON value to
+// its Dart type. Go encodes nil slices as null, so arrays that aren't nullable
+// are read as empty instead.
+func dartFromJson(expr, typeName, itemType string, nullable bool, enums map[string]schema.Enum) string {
+	switch typeName {
+	case "integer", "string", "boolean":
+		return fmt.Sprintf("%s as %s", expr, dartTypeFromSchema(typeName, "", nullable))
+	case "number":
+		if nullable {
+			return fmt.Sprintf("(%s as num?)?.toDouble()", expr)
+		}
+		return fmt.Sprintf("(%s as num).toDouble()", expr)
+	case "array":
+		if itemType == "" {
+			if nullable {
+				return fmt.Sprintf("%s as List<dynamic>?", expr)
+			}
+			return fmt.Sprintf("%s as List<dynamic>? ?? const []", expr)
+		}
+		item := dartFromJson("e", itemType, "", false, enums)
+		if nullable {
+			return fmt.Sprintf("(%s as List<dynamic>?)\n          ?.map((e) => %s)\n          .toList()", expr, item)
+		}
+		return fmt.Sprintf("(%s as List<dynamic>? ?? const [])\n          .map((e) => %s)\n          .toList()", expr, item)
+	}
+
+	var value string
+	switch {
+	case typeName == "timestamp":
+		value = fmt.Sprintf("DateTime.parse(%s as String)", expr)
+	case enums[typeName].Values != nil:
+		value = fmt.Sprintf("%s.fromJson(%s as String)", typeName, expr)
+	default:
+		value = fmt.Sprintf("%s.fromJson(%s as Map<String, dynamic>)", typeName, expr)
+	}
+	if nullable {
+		return fmt.Sprintf("%s == null ? null : %s", expr, value)
+	}
+	return value
+}
+
+// dartToJson returns the expression that converts a Dart value back to JSON.
+// Enums and classes have a toJson method, and timestamps are sent in UTC.
+func dartToJson(name, typeName, itemType string, nullable bool) string {
+	access := "."
+	if nullable {
+		access = "?."
+	}
+	switch typeName {
+	case "integer", "number", "string", "boolean":
+		return name
+	case "timestamp":
+		return name + access + "toUtc().toIso8601String()"
+	case "array":
+		if itemType == "" || isDartPrimitive(itemType) {
+			return name
+		}
+		return fmt.Sprintf("%s%smap((e) => %s).toList()", name, access, dartToJson("e", itemType, "", false))
+	}
+	return name + access + "toJson()"
+}
+
+func isDartPrimitive(typeName string) bool {
+	switch typeName {
+	case "integer", "number", "string", "boolean":
+		return true
+	}
+	return false
+}
+
+func toCamelCase(str string) string {
+	if len(str) <= 1 {
+		return strings.ToLower(str)
+	}
+	return strings.ToLower(str[:1]) + str[1:]
+}
+
+func generateDartEventsFile(events map[string]schema.Type) string {
+	var builder strings.Builder
+
+	// File header
+	builder.WriteString(generatedHeader(schema.EventsFileName))
+	builder.WriteString("\n")
+	builder.WriteString("import 'types.dart';\n")
+
+	// Generate a class for each event (sorted for deterministic order), which
+	// is published with YesterdayApi.doPublishRequest(event.toJson())
+	var eventNames []string
+	for eventName := range events {
+		eventNames = append(eventNames, eventName)
+	}
+	sort.Strings(eventNames)
+
+	for _, eventName := range eventNames {
+		builder.WriteString("\n")
+		builder.WriteString(generateDartEventClass(eventName, events[eventName]))
+	}
+
+	return builder.String()
+}
+
+func generateDartEventClass(eventName string, eventDef schema.Type) string {
+	var builder strings.Builder
+
+	// Generate class name from event name (e.g., "Task:Add" -> "TaskAddEvent")
+	className := strings.Title(eventNameToFunctionName(eventName)) + "Event"
+	propNames := sortedPropertyNames(eventDef.Properties)
+
+	writeDartDocComment(&builder, "", eventDef.Description)
+	builder.WriteString(fmt.Sprintf("class %s {\n", className))
+	builder.WriteString(fmt.Sprintf("  static const eventType = '%s';\n\n", eventName))
+	builder.WriteString(generateDartFields(className, eventDef.Properties, propNames))
+
+	builder.WriteString("\n  Map<String, Object?> toJson() {\n")
+	builder.WriteString("    return {\n")
+	builder.WriteString("      'type': eventType,\n")
+	builder.WriteString(generateDartJsonEntries(eventDef.Properties, propNames))
+	builder.WriteString("    };\n")
+	builder.WriteString("  }\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+func eventNameToFunctionName(eventName string) string {
+	// Convert "Task:Add" to "taskAdd", "TaskList:UpdateTitle" to "taskListUpdateTitle"
+	parts := strings.Split(eventName, ":")
+	if len(parts) != 2 {
+		return toCamelCase(eventName)
+	}
+
+	prefix := toCamelCase(parts[0])
+	suffix := parts[1]
+	return prefix + suffix
+}
+
+func generateDartRoutesFile(s *schema.Schema) string {
+	var builder strings.Builder
+
+	// File header
+	builder.WriteString(generatedHeader(schema.APIFileName))
+	builder.WriteString("\n")
+	builder.WriteString("import 'dart:convert';\n\n")
+	builder.WriteString(fmt.Sprintf("import '%s';\n", yesterdayAPIImport()))
+	builder.WriteString("import 'types.dart';\n\n")
+
+	// Generate routes class
+	builder.WriteString("class ApiRoutes {\n")
+	builder.WriteString("  final YesterdayApi api;\n\n")
+	builder.WriteString("  ApiRoutes(this.api);\n")
+
+	// Generate function for each route. Responses are fetched through the
+	// API's cache, which only makes GET requests, so routes with a request
+	// body are left out.
+	for _, route := range s.Routes {
+		if route.Body != "" {
+			continue
+		}
+		builder.WriteString("\n")
+		builder.WriteString(generateDartRouteFunction(s, route))
+	}
+
+	builder.WriteString("\n")
+	builder.WriteString("  Future<T> _get<T>(String path, Map<String, String> parameters,\n")
+	builder.WriteString("      T Function(Map<String, dynamic>) fromJson) async {\n")
+	builder.WriteString("    final uri = Uri(\n")
+	builder.WriteString("        path: path, queryParameters: parameters.isEmpty ? null : parameters);\n")
+	builder.WriteString("    final response = await api.getCachedResponse(uri.toString());\n")
+	builder.WriteString("    if (response.statusCode != 200) {\n")
+	builder.WriteString("      throw Exception('Failed to load $path');\n")
+	builder.WriteString("    }\n")
+	builder.WriteString("    return fromJson(json.decode(response.body) as Map<String, dynamic>);\n")
+	builder.WriteString("  }\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// yesterdayAPIImport returns the URI the routes import YesterdayApi from.
+func yesterdayAPIImport() string {
+	if *dartPackage == "" {
+		return "../yesterday/api.dart"
+	}
+	return fmt.Sprintf("package:%s/yesterday/api.dart", *dartPackage)
+}
+
+func generateDartRouteFunction(s *schema.Schema, route schema.Route) string {
+	var builder strings.Builder
+
+	writeDartDocComment(&builder, "  ", route.Description)
+
+	// Generate function name from route path (e.g., "/api/task/list" -> "getTaskList")
+	functionName := routeToFunctionName(route.Route, route.Method)
+
+	// Parameters are named, and optional ones are left out of the request
+	// when null
+	if len(route.Parameters) > 0 {
+		builder.WriteString(fmt.Sprintf("  Future<%s> %s({\n", route.Returns, functionName))
+		for _, param := range route.Parameters {
+			dartType := dartTypeFromSchema(param.Type, param.ItemType, !param.Required)
+			name := dartIdentifier(param.Name)
+			if param.Required {
+				builder.WriteString(fmt.Sprintf("    required %s %s,\n", dartType, name))
+			} else {
+				builder.WriteString(fmt.Sprintf("    %s %s,\n", dartType, name))
+			}
+		}
+		builder.WriteString("  }) {\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("  Future<%s> %s() {\n", route.Returns, functionName))
+	}
+
+	// YesterdayApi prefixes paths with /api
+	path := strings.TrimPrefix(route.Route, "/api")
+	if len(route.Parameters) > 0 {
+		builder.WriteString(fmt.Sprintf("    return _get('%s', {\n", path))
+		for _, param := range route.Parameters {
+			name := dartIdentifier(param.Name)
+			if param.Required {
+				builder.WriteString(fmt.Sprintf("      '%s': %s,\n", param.Name, dartParamValue(s, param, name)))
+			} else {
+				builder.WriteString(fmt.Sprintf("      if (%s != null) '%s': %s,\n", name, param.Name, dartParamValue(s, param, name)))
+			}
+		}
+		builder.WriteString(fmt.Sprintf("    }, %s.fromJson);\n", route.Returns))
+	} else {
+		builder.WriteString(fmt.Sprintf("    return _get('%s', {}, %s.fromJson);\n", path, route.Returns))
+	}
+	builder.WriteString("  }\n")
+
+	return builder.String()
+}
+
+// dartParamValue returns the expression for a parameter's value in the query
+// string. Arrays are sent as comma-separated lists, and enums as their values.
+func dartParamValue(s *schema.Schema, param schema.Parameter, name string) string {
+	if s.IsEnum(param.Type) {
+		return name + ".value"
+	}
+	switch param.Type {
+	case "string":
+		return name
+	case "timestamp":
+		return name + ".toUtc().toIso8601String()"
+	case "array":
+		return name + ".join(',')"
+	}
+	return fmt.Sprintf("'$%s'", name)
+}
+
+func routeToFunctionName(routePath string, method string) string {
+	// Convert "/api/task/list" to "getTaskList", "/api/tasklist/todo" to "getTasklistTodo"
+	trimmed := strings.TrimPrefix(routePath, "/")
+	parts := strings.Split(trimmed, "/")
+
+	// Skip "api" prefix if present
+	if len(parts) > 0 && parts[0] == "api" {
+		parts = parts[1:]
+	}
+
+	// Join parts with title case, handling underscores
+	var functionParts []string
+	for _, part := range parts {
+		for _, subPart := range strings.Split(part, "_") {
+			functionParts = append(functionParts, strings.Title(subPart))
+		}
+	}
+
+	return strings.ToLower(method) + strings.Join(functionParts, "")
+}
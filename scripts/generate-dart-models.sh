@@ -0,0 +1,37 @@
+#!/bin/bash
+
+# Script to generate Dart models from YAML schema
+
+set -e
+
+# Check for required arguments
+if [ "$#" -lt 2 ]; then
+    echo "Usage: $0 [-package name] [-component name] <input_yaml> <output_dart>"
+    exit 1
+fi
+
+echo "Generating Dart models from schema..."
+
+# Try to find Go binary in common locations
+GO_BIN=""
+if command -v go >/dev/null 2>&1; then
+    GO_BIN="go"
+elif [ -f "/usr/local/go/bin/go" ]; then
+    GO_BIN="/usr/local/go/bin/go"
+elif [ -f "$HOME/go/bin/go" ]; then
+    GO_BIN="$HOME/go/bin/go"
+elif [ -f "/usr/bin/go" ]; then
+    GO_BIN="/usr/bin/go"
+else
+    echo "Error: Go binary not found. Please ensure Go is installed."
+    exit 1
+fi
+
+echo "Using Go binary: $GO_BIN"
+
+# Get the directory where this script is located
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+
+# Change to scripts directory and run the Go generator
+cd "$SCRIPT_DIR"
+"$GO_BIN" run generate-dart-models.go "$@"
\ No newline at end of file
@@ -1,61 +1,35 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"unicode"
 
-	"gopkg.in/yaml.v3"
+	"yellowstone-generator/schema"
 )
 
-// TypeDefinition represents a type definition from the YAML schema
-type TypeDefinition struct {
-	Description string                        `yaml:"description"`
-	Properties  map[string]PropertyDefinition `yaml:"properties"`
-}
-
-// PropertyDefinition represents a property within a type
-type PropertyDefinition struct {
-	Type        string `yaml:"type"`
-	ItemType    string `yaml:"itemType"`
-	Nullable    bool   `yaml:"nullable"`
-	Description string `yaml:"description"`
-}
-
-// RouteParameter represents a parameter for a route
-type RouteParameter struct {
-	Name        string `yaml:"name"`
-	Type        string `yaml:"type"`
-	Required    bool   `yaml:"required"`
-	Description string `yaml:"description"`
-}
-
-// RouteDefinition represents an API route definition
-type RouteDefinition struct {
-	Route       string           `yaml:"route"`
-	Description string           `yaml:"description"`
-	Method      string           `yaml:"method"`
-	Parameters  []RouteParameter `yaml:"parameters"`
-	Returns     string           `yaml:"returns"`
-}
-
-// Schema represents the root schema structure
-type Schema struct {
-	Types  map[string]TypeDefinition `yaml:"types"`
-	Events map[string]TypeDefinition `yaml:"events"`
-	Routes []RouteDefinition         `yaml:"routes"`
-}
+var (
+	kotlinPackage = flag.String("package", "com.tomyedwab.yellowstone.generated", "Kotlin package of the generated code")
+	componentName = flag.String("component", "", "component whose data views the routes load (default: the name of the directory containing the schema directory)")
+)
 
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <input_yaml> <output_kotlin>\n", os.Args[0])
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-package name] [-component name] <input_yaml> <output_kotlin>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	inputFile := os.Args[1]
-	outputFile := os.Args[2]
+	inputFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
@@ -63,27 +37,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Load YAML schema
-	yamlData, err := os.ReadFile(inputFile)
+	// The whole schema is loaded, so that references to types in the other
+	// files are checked, and the input file picks what is generated from it
+	schemaDir, err := filepath.Abs(filepath.Dir(inputFile))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading YAML file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error finding schema directory: %v\n", err)
 		os.Exit(1)
 	}
-
-	var schema Schema
-	if err := yaml.Unmarshal(yamlData, &schema); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing YAML: %v\n", err)
+	s, err := schema.LoadDir(schemaDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading schema: %v\n", err)
 		os.Exit(1)
 	}
+	if *componentName == "" {
+		// e.g. tasks for backend/tasks/schema
+		*componentName = filepath.Base(filepath.Dir(schemaDir))
+	}
 
 	// Generate Kotlin file
 	var kotlinCode string
-	if len(schema.Events) > 0 {
-		kotlinCode = generateKotlinEventsFile(schema.Events)
-	} else if len(schema.Routes) > 0 {
-		kotlinCode = generateKotlinRoutesFile(schema.Routes)
-	} else {
-		kotlinCode = generateKotlinFile(schema.Types)
+	inputName := filepath.Base(inputFile)
+	switch inputName {
+	case schema.EventsFileName:
+		kotlinCode = generateKotlinEventsFile(s.Events)
+	case schema.APIFileName:
+		kotlinCode = generateKotlinRoutesFile(s)
+	case schema.TypesFileName:
+		kotlinCode = generateKotlinFile(s.Types, s.Enums)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown schema file %s, expected %s, %s or %s\n", inputName, schema.TypesFileName, schema.EventsFileName, schema.APIFileName)
+		os.Exit(1)
 	}
 
 	// Write to file
@@ -92,24 +75,28 @@ func main() {
 		os.Exit(1)
 	}
 
-	if len(schema.Events) > 0 {
+	switch inputName {
+	case schema.EventsFileName:
 		fmt.Printf("Generated Kotlin events: %s\n", outputFile)
-	} else if len(schema.Routes) > 0 {
+	case schema.APIFileName:
 		fmt.Printf("Generated Kotlin routes: %s\n", outputFile)
-	} else {
+	default:
 		fmt.Printf("Generated Kotlin models: %s\n", outputFile)
 	}
 }
 
-func generateKotlinFile(types map[string]TypeDefinition) string {
+// generatedHeader is the comment at the top of each generated file
+func generatedHeader(fileName string) string {
+	return fmt.Sprintf("// Auto-generated from the %s schema's %s\n// Do not edit this file directly\n\n", *componentName, fileName)
+}
+
+func generateKotlinFile(types map[string]schema.Type, enums map[string]schema.Enum) string {
 	var builder strings.Builder
 
 	// File header
-	builder.WriteString("package com.tomyedwab.yellowstone.generated\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", *kotlinPackage))
 	builder.WriteString("import com.google.gson.annotations.SerializedName\n\n")
-	// TODO(tom) STOPSHIP this should not be hard-coded
-	builder.WriteString("// Auto-generated from backend/tasks/schema/types.yml\n")
-	builder.WriteString("// Do not edit this file directly\n\n")
+	builder.WriteString(generatedHeader(schema.TypesFileName))
 
 	// Generate data classes for each type (sorted for deterministic order)
 	var typeNames []string
@@ -124,10 +111,64 @@ func generateKotlinFile(types map[string]TypeDefinition) string {
 		builder.WriteString("\n")
 	}
 
+	// Enums from events.yml are generated here too, for use by Events.kt
+	builder.WriteString(generateKotlinEnumClasses(enums))
+
 	return builder.String()
 }
 
-func generateKotlinDataClass(className string, classDef TypeDefinition) string {
+// generateKotlinEnumClasses generates an enum class for each enum (sorted for
+// deterministic order), serialized as the enum's values. The value is also
+// kept in a property, for route parameters.
+func generateKotlinEnumClasses(enums map[string]schema.Enum) string {
+	var builder strings.Builder
+
+	var enumNames []string
+	for enumName := range enums {
+		enumNames = append(enumNames, enumName)
+	}
+	sort.Strings(enumNames)
+
+	for _, enumName := range enumNames {
+		enumDef := enums[enumName]
+		if enumDef.Description != "" {
+			builder.WriteString("/**\n")
+			builder.WriteString(fmt.Sprintf(" * %s\n", enumDef.Description))
+			builder.WriteString(" */\n")
+		}
+		builder.WriteString(fmt.Sprintf("enum class %s(val value: String) {\n", enumName))
+		var valueLines []string
+		for _, value := range enumDef.Values {
+			valueLines = append(valueLines, fmt.Sprintf("    @SerializedName(\"%s\") %s(\"%s\")", value, enumConstantName(value), value))
+		}
+		builder.WriteString(strings.Join(valueLines, ",\n"))
+		builder.WriteString("\n}\n")
+	}
+
+	return builder.String()
+}
+
+// enumConstantName converts an enum value to a Kotlin constant name, e.g.
+// "toDoList" to TO_DO_LIST and "add_comment" to ADD_COMMENT
+func enumConstantName(value string) string {
+	var builder strings.Builder
+	for i, r := range value {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 {
+				builder.WriteRune('_')
+			}
+			builder.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(unicode.ToUpper(r))
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	return builder.String()
+}
+
+func generateKotlinDataClass(className string, classDef schema.Type) string {
 	var builder strings.Builder
 
 	// Add description as comment if present
@@ -171,6 +212,8 @@ func kotlinTypeFromSchema(typeName, itemType string, nullable bool) string {
 	switch typeName {
 	case "integer":
 		baseType = "Int"
+	case "number":
+		baseType = "Double"
 	case "string":
 		baseType = "String"
 	case "boolean":
@@ -202,20 +245,18 @@ func toCamelCase(str string) string {
 	return strings.ToLower(str[:1]) + str[1:]
 }
 
-func generateKotlinEventsFile(events map[string]TypeDefinition) string {
+func generateKotlinEventsFile(events map[string]schema.Type) string {
 	var builder strings.Builder
 
 	// File header
-	builder.WriteString("package com.tomyedwab.yellowstone.generated\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", *kotlinPackage))
 	builder.WriteString("import com.tomyedwab.yellowstone.provider.connection.ConnectionAction\n")
 	builder.WriteString("import com.tomyedwab.yellowstone.provider.connection.ConnectionStateProvider\n")
 	builder.WriteString("import com.tomyedwab.yellowstone.provider.connection.PendingEvent\n")
 	builder.WriteString("import java.text.SimpleDateFormat\n")
 	builder.WriteString("import java.util.*\n")
 	builder.WriteString("import java.util.UUID\n\n")
-	// TODO(tom) STOPSHIP this should not be hard-coded
-	builder.WriteString("// Auto-generated from backend/tasks/schema/events.yml\n")
-	builder.WriteString("// Do not edit this file directly\n\n")
+	builder.WriteString(generatedHeader(schema.EventsFileName))
 
 	// Generate events class
 	builder.WriteString("class Events(\n")
@@ -240,7 +281,7 @@ func generateKotlinEventsFile(events map[string]TypeDefinition) string {
 	return builder.String()
 }
 
-func generateEventFunction(eventName string, eventDef TypeDefinition) string {
+func generateEventFunction(eventName string, eventDef schema.Type) string {
 	var builder strings.Builder
 
 	// Add description as comment if present
@@ -309,19 +350,17 @@ func eventNameToFunctionName(eventName string) string {
 	return prefix + suffix
 }
 
-func generateKotlinRoutesFile(routes []RouteDefinition) string {
+func generateKotlinRoutesFile(s *schema.Schema) string {
 	var builder strings.Builder
 
 	// File header
-	builder.WriteString("package com.tomyedwab.yellowstone.generated\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", *kotlinPackage))
 	builder.WriteString("import androidx.lifecycle.LiveData\n")
 	builder.WriteString("import com.google.gson.reflect.TypeToken\n")
 	builder.WriteString("import com.tomyedwab.yellowstone.services.connection.DataViewResult\n")
 	builder.WriteString("import com.tomyedwab.yellowstone.services.connection.DataViewService\n")
 	builder.WriteString("import com.tomyedwab.yellowstone.provider.connection.HubConnectionState\n\n")
-	// TODO(tom) STOPSHIP this should not be hard-coded
-	builder.WriteString("// Auto-generated from backend/tasks/schema/api.yml\n")
-	builder.WriteString("// Do not edit this file directly\n\n")
+	builder.WriteString(generatedHeader(schema.APIFileName))
 
 	// Generate routes class
 	builder.WriteString("class ApiRoutes(\n")
@@ -329,9 +368,13 @@ func generateKotlinRoutesFile(routes []RouteDefinition) string {
 	builder.WriteString("    private val connectionState: LiveData<HubConnectionState>\n")
 	builder.WriteString(") {\n\n")
 
-	// Generate function for each route
-	for _, route := range routes {
-		builder.WriteString(generateRouteFunction(route))
+	// Generate function for each route. Data views only make GET requests, so
+	// routes with a request body are left out.
+	for _, route := range s.Routes {
+		if route.Body != "" {
+			continue
+		}
+		builder.WriteString(generateRouteFunction(s, route))
 		builder.WriteString("\n")
 	}
 
@@ -340,7 +383,7 @@ func generateKotlinRoutesFile(routes []RouteDefinition) string {
 	return builder.String()
 }
 
-func generateRouteFunction(route RouteDefinition) string {
+func generateRouteFunction(s *schema.Schema, route schema.Route) string {
 	var builder strings.Builder
 
 	// Add description as comment if present
@@ -358,10 +401,18 @@ func generateRouteFunction(route RouteDefinition) string {
 
 	// Generate parameters
 	var paramLines []string
+	hasOptionalParams := false
 	for _, param := range route.Parameters {
-		kotlinType := kotlinTypeFromSchema(param.Type, "", !param.Required)
+		kotlinType := kotlinTypeFromSchema(param.Type, param.ItemType, !param.Required)
 		kotlinParamName := toCamelCase(param.Name)
-		paramLines = append(paramLines, fmt.Sprintf("%s: %s", kotlinParamName, kotlinType))
+		if param.Required {
+			paramLines = append(paramLines, fmt.Sprintf("%s: %s", kotlinParamName, kotlinType))
+		} else {
+			// Optional parameters default to null, so that adding one doesn't
+			// break existing callers
+			paramLines = append(paramLines, fmt.Sprintf("%s: %s = null", kotlinParamName, kotlinType))
+			hasOptionalParams = true
+		}
 	}
 
 	builder.WriteString(strings.Join(paramLines, ", "))
@@ -370,17 +421,31 @@ func generateRouteFunction(route RouteDefinition) string {
 	// Generate function body
 	builder.WriteString("        return dataViewService.createDataView(\n")
 	builder.WriteString("            connectionState = connectionState,\n")
-	builder.WriteString("            componentName = \"tasks\",\n") // TODO(tom) STOPSHIP this should not be hard-coded
+	builder.WriteString(fmt.Sprintf("            componentName = \"%s\",\n", *componentName))
 	builder.WriteString(fmt.Sprintf("            apiPath = \"%s\",\n", strings.TrimPrefix(route.Route, "/")))
 
 	// Generate apiParams map
-	if len(route.Parameters) > 0 {
+	if hasOptionalParams {
+		// Null parameters are left out of the request
+		builder.WriteString("            apiParams = listOfNotNull(\n")
+		var paramMapLines []string
+		for _, param := range route.Parameters {
+			kotlinParamName := toCamelCase(param.Name)
+			if param.Required {
+				paramMapLines = append(paramMapLines, fmt.Sprintf("                \"%s\" to %s", param.Name, kotlinParamValue(s, param, kotlinParamName)))
+			} else {
+				paramMapLines = append(paramMapLines, fmt.Sprintf("                %s?.let { \"%s\" to %s }", kotlinParamName, param.Name, kotlinParamValue(s, param, "it")))
+			}
+		}
+		builder.WriteString(strings.Join(paramMapLines, ",\n"))
+		builder.WriteString("\n            ).toMap(),\n")
+	} else if len(route.Parameters) > 0 {
 		builder.WriteString("            apiParams = mapOf(\n")
 		var paramMapLines []string
 		for _, param := range route.Parameters {
 			kotlinParamName := toCamelCase(param.Name)
 			// Convert all parameter values to strings
-			paramMapLines = append(paramMapLines, fmt.Sprintf("                \"%s\" to %s.toString()", param.Name, kotlinParamName))
+			paramMapLines = append(paramMapLines, fmt.Sprintf("                \"%s\" to %s", param.Name, kotlinParamValue(s, param, kotlinParamName)))
 		}
 		builder.WriteString(strings.Join(paramMapLines, ",\n"))
 		builder.WriteString("\n            ),\n")
@@ -395,6 +460,18 @@ func generateRouteFunction(route RouteDefinition) string {
 	return builder.String()
 }
 
+// kotlinParamValue returns the expression for a parameter's value in the query
+// string. Arrays are sent as comma-separated lists, and enums as their values.
+func kotlinParamValue(s *schema.Schema, param schema.Parameter, name string) string {
+	if param.Type == "array" {
+		return name + ".joinToString(\",\")"
+	}
+	if s.IsEnum(param.Type) {
+		return name + ".value"
+	}
+	return name + ".toString()"
+}
+
 func routeToFunctionName(routePath string, method string) string {
 	// Convert "/api/task/list" to "getTaskList", "/api/tasklist/todo" to "getTasklistTodo"
 	// Remove leading slash and split by "/"
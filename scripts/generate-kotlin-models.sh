@@ -5,14 +5,11 @@
 set -e
 
 # Check for required arguments
-if [ "$#" -ne 2 ]; then
-    echo "Usage: $0 <input_yaml> <output_kotlin>"
+if [ "$#" -lt 2 ]; then
+    echo "Usage: $0 [-package name] [-component name] <input_yaml> <output_kotlin>"
     exit 1
 fi
 
-INPUT_YAML="$1"
-OUTPUT_KOTLIN="$2"
-
 echo "Generating Kotlin models from schema..."
 
 # Try to find Go binary in common locations
@@ -37,4 +34,4 @@ SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 
 # Change to scripts directory and run the Go generator
 cd "$SCRIPT_DIR"
-"$GO_BIN" run generate-kotlin-models.go "$INPUT_YAML" "$OUTPUT_KOTLIN"
\ No newline at end of file
+"$GO_BIN" run generate-kotlin-models.go "$@"
\ No newline at end of file
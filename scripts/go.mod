@@ -1,5 +1,9 @@
 module generate-kotlin-models
 
-go 1.21
+go 1.24.4
 
-require gopkg.in/yaml.v3 v3.0.1
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+require yellowstone-generator v0.0.0
+
+replace yellowstone-generator => ../util
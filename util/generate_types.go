@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"go/format"
 	"log"
@@ -9,55 +11,11 @@ import (
 	"sort"
 	"strings"
 	"text/template"
+	"unicode"
 
-	"gopkg.in/yaml.v3"
+	"yellowstone-generator/schema"
 )
 
-type Property struct {
-	Type        string `yaml:"type"`
-	ItemType    string `yaml:"itemType"`
-	Nullable    bool   `yaml:"nullable"`
-	Required    bool   `yaml:"required"`
-	Description string `yaml:"description"`
-}
-
-type TypeDef struct {
-	Description string              `yaml:"description"`
-	Properties  map[string]Property `yaml:"properties"`
-}
-
-type EventDef struct {
-	Description string              `yaml:"description"`
-	Properties  map[string]Property `yaml:"properties"`
-}
-
-type Parameter struct {
-	Name        string `yaml:"name"`
-	Type        string `yaml:"type"`
-	Required    bool   `yaml:"required"`
-	Description string `yaml:"description"`
-}
-
-type Route struct {
-	Route       string      `yaml:"route"`
-	Description string      `yaml:"description"`
-	Method      string      `yaml:"method"`
-	Parameters  []Parameter `yaml:"parameters"`
-	Returns     string      `yaml:"returns"`
-}
-
-type TypesSchema struct {
-	Types map[string]TypeDef `yaml:"types"`
-}
-
-type EventsSchema struct {
-	Events map[string]EventDef `yaml:"events"`
-}
-
-type APISchema struct {
-	Routes []Route `yaml:"routes"`
-}
-
 type Config struct {
 	TypesFile  string
 	EventsFile string
@@ -94,10 +52,8 @@ func main() {
 }
 
 type Generator struct {
-	config       Config
-	typesSchema  TypesSchema
-	eventsSchema EventsSchema
-	apiSchema    APISchema
+	config Config
+	schema *schema.Schema
 }
 
 func (g *Generator) Generate() error {
@@ -123,38 +79,107 @@ func (g *Generator) Generate() error {
 	}
 
 	fmt.Printf("Generated and formatted Go types and resolver interface in %s\n", g.config.OutputFile)
+
+	// The OpenAPI document goes next to the generated code, which embeds it
+	document, err := g.generateOpenAPI()
+	if err != nil {
+		return fmt.Errorf("generating OpenAPI document: %w", err)
+	}
+	openAPIFile := filepath.Join(filepath.Dir(g.config.OutputFile), openAPIFileName)
+	if err := os.WriteFile(openAPIFile, document, 0644); err != nil {
+		return fmt.Errorf("writing OpenAPI document: %w", err)
+	}
+
+	fmt.Printf("Generated OpenAPI document in %s\n", openAPIFile)
 	return nil
 }
 
 func (g *Generator) loadSchemas() error {
-	// Load types schema
-	typesData, err := os.ReadFile(g.config.TypesFile)
+	var err error
+	g.schema, err = schema.Load(g.config.TypesFile, g.config.EventsFile, g.config.APIFile)
 	if err != nil {
-		return fmt.Errorf("reading types file: %w", err)
-	}
-	if err := yaml.Unmarshal(typesData, &g.typesSchema); err != nil {
-		return fmt.Errorf("parsing types schema: %w", err)
+		return err
 	}
+	return g.checkNames()
+}
 
-	// Load events schema
-	eventsData, err := os.ReadFile(g.config.EventsFile)
-	if err != nil {
-		return fmt.Errorf("reading events file: %w", err)
+// checkNames rejects schemas whose names would clash in the generated code:
+// enum values with the same constant name, and event types named like types.
+func (g *Generator) checkNames() error {
+	constNames := make(map[string]string)
+	for name, enum := range g.schema.Enums {
+		for _, value := range enum.Values {
+			constName := enumConstName(name, value)
+			if other, exists := constNames[constName]; exists {
+				return fmt.Errorf("enum %s: value %q has the same constant name as %q", name, value, other)
+			}
+			constNames[constName] = value
+		}
 	}
-	if err := yaml.Unmarshal(eventsData, &g.eventsSchema); err != nil {
-		return fmt.Errorf("parsing events schema: %w", err)
+	for name := range g.schema.Events {
+		if typeName := eventTypeName(name) + "Event"; g.schema.IsType(typeName) || g.schema.IsEnum(typeName) {
+			return fmt.Errorf("event %s: a type has the same name as its Go type, %s", name, typeName)
+		}
 	}
+	return nil
+}
 
-	// Load API schema
-	apiData, err := os.ReadFile(g.config.APIFile)
-	if err != nil {
-		return fmt.Errorf("reading API file: %w", err)
+// eventTypeName returns the name of an event without the colon, e.g.
+// TaskListAdd for TaskList:Add.
+func eventTypeName(name string) string {
+	return strings.ReplaceAll(name, ":", "")
+}
+
+// enumConstName returns the name of the constant for an enum value, e.g.
+// TaskUpdateTypeAddComment for add_comment.
+func enumConstName(enumName, value string) string {
+	var name strings.Builder
+	name.WriteString(enumName)
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		name.WriteString(strings.ToUpper(part[:1]) + part[1:])
 	}
-	if err := yaml.Unmarshal(apiData, &g.apiSchema); err != nil {
-		return fmt.Errorf("parsing API schema: %w", err)
+	return name.String()
+}
+
+// bodyTypes returns the types used in request bodies, along with the types
+// of their properties, which all need validation functions.
+func (g *Generator) bodyTypes() map[string]schema.Type {
+	types := make(map[string]schema.Type)
+	var add func(name string)
+	add = func(name string) {
+		typeDef, exists := g.schema.Types[name]
+		if _, added := types[name]; added || !exists {
+			return
+		}
+		types[name] = typeDef
+		for _, prop := range typeDef.Properties {
+			add(prop.Type)
+			add(prop.ItemType)
+		}
 	}
+	for _, route := range g.schema.Routes {
+		add(route.Body)
+	}
+	return types
+}
 
-	return nil
+// checksProperties reports whether validating a type looks at any of its
+// properties: the required ones, and the ones with types from types.yml.
+func (g *Generator) checksProperties(typeDef schema.Type) bool {
+	for _, prop := range typeDef.Properties {
+		if !prop.Nullable || g.isSchemaType(prop.Type) || g.isSchemaType(prop.ItemType) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSchemaType reports whether a type name refers to a type in types.yml.
+func (g *Generator) isSchemaType(typeName string) bool {
+	_, exists := g.schema.Types[typeName]
+	return exists
 }
 
 func (g *Generator) generateCode() (string, error) {
@@ -163,14 +188,55 @@ func (g *Generator) generateCode() (string, error) {
 package {{.Package}}
 
 import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 	"github.com/jmoiron/sqlx"
 	"github.com/tomyedwab/yesterday/applib/database"
 	"github.com/tomyedwab/yesterday/applib/httputils"
 )
 
+// Generated Enums from {{.TypesFile}} and {{.EventsFile}}
+
+{{range $name, $enum := .Enums}}
+// {{$enum.Description}}
+type {{$name}} string
+
+const (
+{{- range $enum.Values}}
+	{{EnumConstName $name .}} {{$name}} = "{{.}}"
+{{- end}}
+)
+
+// Valid reports whether the value is one of the values of {{$name}}.
+func (v {{$name}}) Valid() bool {
+	switch v {
+	case {{range $i, $value := $enum.Values}}{{if $i}}, {{end}}{{EnumConstName $name $value}}{{end}}:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON rejects values that are not values of {{$name}}.
+func (v *{{$name}}) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if !{{$name}}(value).Valid() {
+		return fmt.Errorf("invalid {{$name}} %q", value)
+	}
+	*v = {{$name}}(value)
+	return nil
+}
+{{end}}
+
 // Generated Types from {{.TypesFile}}
 
 {{range $name, $type := .Types}}
@@ -190,6 +256,8 @@ type {{EventTypeName $name}}Event struct {
 {{- range $propName, $prop := $event.Properties}}
 	{{$propName}} {{GoType $prop}} ` + "`json:\"{{$propName}}\"`" + `{{if $prop.Description}} // {{$prop.Description}}{{end}}
 {{- end}}
+
+	Timestamp time.Time ` + "`json:\"timestamp\"`" + ` // When the event was published, from the stored event
 }
 {{end}}
 
@@ -197,7 +265,7 @@ type {{EventTypeName $name}}Event struct {
 
 type Resolver interface {
 {{- range .Routes}}
-	{{ResolverMethodName .}} (db *sqlx.DB, {{ResolverParams .}}) ({{.Returns}}, error)
+	{{ResolverMethodName .}} (db *sqlx.DB{{ResolverParams .}}) ({{.Returns}}, error)
 {{- end}}
 }
 
@@ -209,6 +277,23 @@ type EventHandler interface {
 {{- end}}
 }
 
+// Generated event dispatch from {{.EventsFile}}, for applying stored events
+// outside of the database's own event handling. Unknown event types are ignored.
+
+func HandleEvent(tx *sqlx.Tx, eventHandler EventHandler, eventType string, eventData []byte) (bool, error) {
+	switch eventType {
+{{- range $name, $event := .Events}}
+	case "{{$name}}":
+		var event {{EventTypeName $name}}Event
+		if err := json.Unmarshal(eventData, &event); err != nil {
+			return false, err
+		}
+		return eventHandler.{{EventHandlerMethodName $name}}(tx, &event)
+{{- end}}
+	}
+	return false, nil
+}
+
 // Generated initialization function
 
 func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHandler) error {
@@ -222,7 +307,26 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 	// Register HTTP routes
 {{- range .Routes}}
 	http.HandleFunc("{{.Route}}", func(w http.ResponseWriter, r *http.Request) {
+{{- if .Body}}
+		if r.Method != "{{ToUpper .Method}}" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+{{- end}}
 {{- range .Parameters}}
+{{- if eq .Type "array"}}
+		{{.Name}}, err := parseIntegerArray(r.URL.Query()["{{.Name}}"])
+		if err != nil {
+			http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+			return
+		}
+{{- if .Required}}
+		if len({{.Name}}) == 0 {
+			http.Error(w, "Missing {{.Name}} parameter", http.StatusBadRequest)
+			return
+		}
+{{- end}}
+{{- else}}
 		{{.Name}}Str := r.URL.Query().Get("{{.Name}}")
 {{- if .Required}}
 		if {{.Name}}Str == "" {
@@ -230,13 +334,91 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 			return
 		}
 {{- end}}
+{{- if .Required}}
 {{- if eq .Type "integer"}}
 		{{.Name}}, err := strconv.Atoi({{.Name}}Str)
 		if err != nil {
 			http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
 			return
 		}
+{{- else if eq .Type "boolean"}}
+		{{.Name}}, err := strconv.ParseBool({{.Name}}Str)
+		if err != nil {
+			http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+			return
+		}
+{{- else if eq .Type "timestamp"}}
+		{{.Name}}, err := time.Parse(time.RFC3339, {{.Name}}Str)
+		if err != nil {
+			http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+			return
+		}
+{{- else if eq .Type "string"}}
+		{{.Name}} := {{.Name}}Str
+{{- else if IsEnum .Type}}
+		{{.Name}} := {{.Type}}({{.Name}}Str)
+		if !{{.Name}}.Valid() {
+			http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+			return
+		}
+{{- end}}
+{{- else}}
+		// Optional parameters are nil when they are missing
+{{- if eq .Type "integer"}}
+		var {{.Name}} *int
+		if {{.Name}}Str != "" {
+			value, err := strconv.Atoi({{.Name}}Str)
+			if err != nil {
+				http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+				return
+			}
+			{{.Name}} = &value
+		}
+{{- else if eq .Type "boolean"}}
+		var {{.Name}} *bool
+		if {{.Name}}Str != "" {
+			value, err := strconv.ParseBool({{.Name}}Str)
+			if err != nil {
+				http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+				return
+			}
+			{{.Name}} = &value
+		}
+{{- else if eq .Type "timestamp"}}
+		var {{.Name}} *time.Time
+		if {{.Name}}Str != "" {
+			value, err := time.Parse(time.RFC3339, {{.Name}}Str)
+			if err != nil {
+				http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+				return
+			}
+			{{.Name}} = &value
+		}
+{{- else if eq .Type "string"}}
+		var {{.Name}} *string
+		if {{.Name}}Str != "" {
+			{{.Name}} = &{{.Name}}Str
+		}
+{{- else if IsEnum .Type}}
+		var {{.Name}} *{{.Type}}
+		if {{.Name}}Str != "" {
+			value := {{.Type}}({{.Name}}Str)
+			if !value.Valid() {
+				http.Error(w, "Invalid {{.Name}} parameter", http.StatusBadRequest)
+				return
+			}
+			{{.Name}} = &value
+		}
 {{- end}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Body}}
+		var body {{.Body}}
+		if err := decodeRequestBody(w, r, &body, validate{{.Body}}); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
 {{- end}}
 
 		resp, err := resolver.{{ResolverMethodName .}}({{ResolverCallParams .}})
@@ -244,19 +426,130 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 	})
 {{- end}}
 
+	// Serve the OpenAPI document describing the routes and events above
+	http.HandleFunc("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument)
+	})
+
+	return nil
+}
+
+// Generated OpenAPI document from {{.APIFile}}, {{.TypesFile}} and {{.EventsFile}}
+
+//go:embed ` + openAPIFileName + `
+var openAPIDocument []byte
+
+// Generated request body handling
+
+// Request bodies are limited to this size
+const maxRequestBodySize = 1 << 20
+
+// decodeRequestBody decodes a JSON request body after checking it with a
+// validation function. Properties that the body type doesn't have are
+// rejected, so that misspelled properties don't go unnoticed.
+func decodeRequestBody(w http.ResponseWriter, r *http.Request, value interface{}, validate func(json.RawMessage) error) error {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		return err
+	}
+	if err = validate(data); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+// parseIntegerArray parses the values of an array parameter, each of which can
+// be a comma-separated list.
+func parseIntegerArray(values []string) ([]int, error) {
+	result := make([]int, 0, len(values))
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item == "" {
+				continue
+			}
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+func decodeObject(data json.RawMessage, typeName string) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("expected %s object", typeName)
+	}
+	return fields, nil
+}
+
+func isMissing(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+// Generated validation of the request body types from {{.APIFile}}. Properties
+// are required unless they are nullable.
+
+{{range $name, $type := .BodyTypes}}
+func validate{{$name}}(data json.RawMessage) error {
+{{- if ChecksProperties $type}}
+	fields, err := decodeObject(data, "{{$name}}")
+	if err != nil {
+		return err
+	}
+{{- else}}
+	if _, err := decodeObject(data, "{{$name}}"); err != nil {
+		return err
+	}
+{{- end}}
+{{- range $propName, $prop := $type.Properties}}
+{{- if not $prop.Nullable}}
+	if isMissing(fields["{{$propName}}"]) {
+		return fmt.Errorf("{{$name}}: missing {{$propName}}")
+	}
+{{- end}}
+{{- if IsSchemaType $prop.Type}}
+	if !isMissing(fields["{{$propName}}"]) {
+		if err := validate{{$prop.Type}}(fields["{{$propName}}"]); err != nil {
+			return fmt.Errorf("{{$name}}.{{$propName}}: %w", err)
+		}
+	}
+{{- else if and (eq $prop.Type "array") (IsSchemaType $prop.ItemType)}}
+	if !isMissing(fields["{{$propName}}"]) {
+		var items []json.RawMessage
+		if err := json.Unmarshal(fields["{{$propName}}"], &items); err != nil {
+			return fmt.Errorf("{{$name}}.{{$propName}}: expected array")
+		}
+		for i, item := range items {
+			if err := validate{{$prop.ItemType}}(item); err != nil {
+				return fmt.Errorf("{{$name}}.{{$propName}}[%d]: %w", i, err)
+			}
+		}
+	}
+{{- end}}
+{{- end}}
 	return nil
 }
+{{end}}
 `
 
 	funcMap := template.FuncMap{
-		"GoType": g.goType,
-		"EventTypeName": func(name string) string {
-			return strings.ReplaceAll(name, ":", "")
-		},
+		"GoType":                 g.goType,
+		"EventTypeName":          eventTypeName,
 		"ResolverMethodName":     g.resolverMethodName,
 		"ResolverParams":         g.resolverParams,
 		"EventHandlerMethodName": g.eventHandlerMethodName,
 		"ResolverCallParams":     g.resolverCallParams,
+		"IsSchemaType":           g.isSchemaType,
+		"IsEnum":                 g.schema.IsEnum,
+		"ChecksProperties":       g.checksProperties,
+		"ToUpper":                strings.ToUpper,
+		"EnumConstName":          enumConstName,
 	}
 
 	t, err := template.New("code").Funcs(funcMap).Parse(tmpl)
@@ -269,17 +562,21 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 		TypesFile  string
 		EventsFile string
 		APIFile    string
-		Types      map[string]TypeDef
-		Events     map[string]EventDef
-		Routes     []Route
+		Types      map[string]schema.Type
+		Events     map[string]schema.Type
+		Routes     []schema.Route
+		BodyTypes  map[string]schema.Type
+		Enums      map[string]schema.Enum
 	}{
 		Package:    g.config.Package,
 		TypesFile:  filepath.Base(g.config.TypesFile),
 		EventsFile: filepath.Base(g.config.EventsFile),
 		APIFile:    filepath.Base(g.config.APIFile),
-		Types:      g.typesSchema.Types,
-		Events:     g.eventsSchema.Events,
-		Routes:     g.apiSchema.Routes,
+		Types:      g.schema.Types,
+		Events:     g.schema.Events,
+		Routes:     g.schema.Routes,
+		BodyTypes:  g.bodyTypes(),
+		Enums:      g.schema.Enums,
 	}
 
 	var buf strings.Builder
@@ -290,7 +587,7 @@ func InitHandlers(db *database.Database, resolver Resolver, eventHandler EventHa
 	return buf.String(), nil
 }
 
-func (g *Generator) goType(prop Property) string {
+func (g *Generator) goType(prop schema.Property) string {
 	var baseType string
 
 	switch prop.Type {
@@ -298,6 +595,8 @@ func (g *Generator) goType(prop Property) string {
 		baseType = "string"
 	case "integer":
 		baseType = "int"
+	case "number":
+		baseType = "float64"
 	case "boolean":
 		baseType = "bool"
 	case "timestamp":
@@ -323,6 +622,8 @@ func (g *Generator) resolveType(typeName string) string {
 	switch typeName {
 	case "integer":
 		return "int"
+	case "number":
+		return "float64"
 	case "string":
 		return "string"
 	case "boolean":
@@ -332,7 +633,7 @@ func (g *Generator) resolveType(typeName string) string {
 	}
 
 	// Check if it's a custom type from our schema
-	if _, exists := g.typesSchema.Types[typeName]; exists {
+	if _, exists := g.schema.Types[typeName]; exists {
 		return typeName
 	}
 
@@ -340,7 +641,7 @@ func (g *Generator) resolveType(typeName string) string {
 	return typeName
 }
 
-func (g *Generator) resolverMethodName(route Route) string {
+func (g *Generator) resolverMethodName(route schema.Route) string {
 	// Convert route path to method name, mapping closely to the actual route
 	// e.g., "/api/task/list" -> "GetApiTaskList"
 	// e.g., "/api/task/get" -> "GetApiTaskGet"
@@ -392,17 +693,13 @@ func (g *Generator) eventHandlerMethodName(eventName string) string {
 	return strings.Join(methodParts, "")
 }
 
-func (g *Generator) resolverCallParams(route Route) string {
+func (g *Generator) resolverCallParams(route schema.Route) string {
 	var params []string
 	// First parameter is always the database
 	params = append(params, "db.GetDB()")
 
-	if len(route.Parameters) == 0 {
-		return strings.Join(params, ", ")
-	}
-
 	// Sort parameters to ensure consistent ordering
-	sortedParams := make([]Parameter, len(route.Parameters))
+	sortedParams := make([]schema.Parameter, len(route.Parameters))
 	copy(sortedParams, route.Parameters)
 	sort.Slice(sortedParams, func(i, j int) bool {
 		return sortedParams[i].Name < sortedParams[j].Name
@@ -412,30 +709,230 @@ func (g *Generator) resolverCallParams(route Route) string {
 		params = append(params, param.Name)
 	}
 
+	// The request body comes last
+	if route.Body != "" {
+		params = append(params, "body")
+	}
+
 	return strings.Join(params, ", ")
 }
 
-func (g *Generator) resolverParams(route Route) string {
-	if len(route.Parameters) == 0 {
-		return ""
-	}
-
+func (g *Generator) resolverParams(route schema.Route) string {
 	var params []string
 
 	// Sort parameters to ensure consistent ordering
-	sortedParams := make([]Parameter, len(route.Parameters))
+	sortedParams := make([]schema.Parameter, len(route.Parameters))
 	copy(sortedParams, route.Parameters)
 	sort.Slice(sortedParams, func(i, j int) bool {
 		return sortedParams[i].Name < sortedParams[j].Name
 	})
 
 	for _, param := range sortedParams {
-		paramType := g.goType(Property{
+		// Missing optional parameters are nil, except for arrays, which are
+		// empty
+		paramType := g.goType(schema.Property{
 			Type:     param.Type,
-			Nullable: !param.Required,
+			ItemType: param.ItemType,
+			Nullable: !param.Required && param.Type != "array",
 		})
-		params = append(params, fmt.Sprintf("%s %s", param.Name, paramType))
+		params = append(params, fmt.Sprintf(", %s %s", param.Name, paramType))
 	}
 
-	return strings.Join(params, ", ")
+	if route.Body != "" {
+		params = append(params, fmt.Sprintf(", body %s", route.Body))
+	}
+
+	return strings.Join(params, "")
+}
+
+// The OpenAPI document is written next to the generated code under this name
+const openAPIFileName = "openapi.json"
+
+// generateOpenAPI returns an OpenAPI 3.1 document with the routes from
+// api.yml. The types and enums from types.yml are its component schemas, along
+// with a JSON Schema for each event in events.yml, named like the generated
+// event types (e.g. TaskAddEvent for Task:Add).
+func (g *Generator) generateOpenAPI() ([]byte, error) {
+	schemas := make(map[string]interface{})
+	for name, enum := range g.schema.Enums {
+		schemas[name] = map[string]interface{}{
+			"description": enum.Description,
+			"type":        "string",
+			"enum":        enum.Values,
+		}
+	}
+	for name, typeDef := range g.schema.Types {
+		// Responses and request bodies have exactly the properties of their
+		// type, as request bodies with other properties are rejected
+		typeSchema := g.objectSchema(typeDef.Description, typeDef.Properties)
+		typeSchema["additionalProperties"] = false
+		schemas[name] = typeSchema
+	}
+	for name, event := range g.schema.Events {
+		typeSchema := g.objectSchema(event.Description, event.Properties)
+		typeSchema["title"] = name
+		schemas[eventTypeName(name)+"Event"] = typeSchema
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, route := range g.schema.Routes {
+		method := strings.ToLower(route.Method)
+		if paths[route.Route] == nil {
+			paths[route.Route] = make(map[string]interface{})
+		}
+		paths[route.Route][method] = g.openAPIOperation(route)
+	}
+
+	title := g.schema.Info.Title
+	if title == "" {
+		title = g.config.Package
+	}
+	version := g.schema.Info.Version
+	if version == "" {
+		version = "1.0.0"
+	}
+	info := map[string]interface{}{
+		"title":   title,
+		"version": version,
+	}
+	if g.schema.Info.Description != "" {
+		info["description"] = g.schema.Info.Description
+	}
+
+	document := map[string]interface{}{
+		"openapi": "3.1.0",
+		"info":    info,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+		},
+	}
+
+	// Descriptions are kept readable rather than escaped for HTML
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// openAPIOperation describes a route. Parameters are in the query string, with
+// arrays as comma-separated lists.
+func (g *Generator) openAPIOperation(route schema.Route) map[string]interface{} {
+	operation := map[string]interface{}{
+		"operationId": g.resolverMethodName(route),
+		"description": route.Description,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "OK",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": g.jsonSchema(schema.Property{Type: route.Returns}),
+					},
+				},
+			},
+		},
+	}
+	if len(route.Parameters) > 0 {
+		var parameters []interface{}
+		for _, param := range route.Parameters {
+			parameter := map[string]interface{}{
+				"name":        param.Name,
+				"in":          "query",
+				"required":    param.Required,
+				"description": param.Description,
+				"schema":      g.jsonSchema(schema.Property{Type: param.Type, ItemType: param.ItemType}),
+			}
+			if param.Type == "array" {
+				parameter["style"] = "form"
+				parameter["explode"] = false
+			}
+			parameters = append(parameters, parameter)
+		}
+		operation["parameters"] = parameters
+	}
+	if route.Body != "" {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": g.jsonSchema(schema.Property{Type: route.Body}),
+				},
+			},
+		}
+	}
+	if len(route.Parameters) > 0 || route.Body != "" {
+		operation["responses"].(map[string]interface{})["400"] = map[string]interface{}{
+			"description": "Missing or invalid parameters or request body",
+		}
+	}
+	return operation
+}
+
+// objectSchema returns the JSON Schema of a type or event. Properties that
+// aren't nullable are required, as they are in request bodies.
+func (g *Generator) objectSchema(description string, properties map[string]schema.Property) map[string]interface{} {
+	schemaProperties := make(map[string]interface{})
+	required := []string{}
+	for name, prop := range properties {
+		propSchema := g.jsonSchema(prop)
+		if prop.Description != "" {
+			propSchema["description"] = prop.Description
+		}
+		schemaProperties[name] = propSchema
+		if !prop.Nullable {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	typeSchema := map[string]interface{}{
+		"type":       "object",
+		"properties": schemaProperties,
+		"required":   required,
+	}
+	if description != "" {
+		typeSchema["description"] = description
+	}
+	return typeSchema
+}
+
+// jsonSchema returns the JSON Schema of a property. Types and enums from the
+// schema are referenced by name.
+func (g *Generator) jsonSchema(prop schema.Property) map[string]interface{} {
+	var propSchema map[string]interface{}
+	switch prop.Type {
+	case "integer", "number", "string", "boolean":
+		propSchema = map[string]interface{}{"type": prop.Type}
+	case "timestamp":
+		propSchema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case "array":
+		propSchema = map[string]interface{}{"type": "array"}
+		if prop.ItemType != "" {
+			propSchema["items"] = g.jsonSchema(schema.Property{Type: prop.ItemType})
+		}
+	default:
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + prop.Type}
+		if prop.Nullable {
+			return map[string]interface{}{
+				"anyOf": []interface{}{ref, map[string]interface{}{"type": "null"}},
+			}
+		}
+		return ref
+	}
+	if prop.Nullable {
+		propSchema["type"] = []interface{}{propSchema["type"], "null"}
+	}
+	return propSchema
 }
@@ -2,4 +2,4 @@ module yellowstone-generator
 
 go 1.24.4
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,185 @@
+// Package schema loads the YAML files that describe a component's types,
+// events and API routes, for the code generators to share. Load checks the
+// schema as a whole, so the generators can rely on every type they see being
+// defined.
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The files of a schema directory
+const (
+	TypesFileName  = "types.yml"
+	EventsFileName = "events.yml"
+	APIFileName    = "api.yml"
+)
+
+// Property is a property of a type or an event. Type is one of the
+// PrimitiveTypes, "array" with the type of its items in ItemType, or the name
+// of a type or enum.
+type Property struct {
+	Type        string `yaml:"type"`
+	ItemType    string `yaml:"itemType"`
+	Nullable    bool   `yaml:"nullable"`
+	Description string `yaml:"description"`
+}
+
+// Type is a type from types.yml, or an event from events.yml.
+type Type struct {
+	Description string              `yaml:"description"`
+	Properties  map[string]Property `yaml:"properties"`
+}
+
+// An Enum is a string type that only takes the listed values.
+type Enum struct {
+	Description string   `yaml:"description"`
+	Values      []string `yaml:"values"`
+}
+
+// Parameters are read from the query string. Array parameters (only arrays of
+// integers are supported) can be repeated or given as a comma-separated list.
+type Parameter struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	ItemType    string `yaml:"itemType"`
+	Required    bool   `yaml:"required"`
+	Description string `yaml:"description"`
+}
+
+// A Route with a Body takes a JSON request body of a type from types.yml.
+type Route struct {
+	Route       string      `yaml:"route"`
+	Description string      `yaml:"description"`
+	Method      string      `yaml:"method"`
+	Parameters  []Parameter `yaml:"parameters"`
+	Body        string      `yaml:"body"`
+	Returns     string      `yaml:"returns"`
+}
+
+// Info names the API in api.yml.
+type Info struct {
+	Title       string `yaml:"title"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+// file is what any of the schema files can contain
+type file struct {
+	Info   *Info           `yaml:"info"`
+	Types  map[string]Type `yaml:"types"`
+	Events map[string]Type `yaml:"events"`
+	Enums  map[string]Enum `yaml:"enums"`
+	Routes []Route         `yaml:"routes"`
+}
+
+// Schema is the combined contents of types.yml, events.yml and api.yml.
+type Schema struct {
+	// The files the schema was loaded from
+	TypesFile  string
+	EventsFile string
+	APIFile    string
+
+	Info   Info
+	Types  map[string]Type
+	Events map[string]Type
+	// Enums from both types.yml and events.yml
+	Enums  map[string]Enum
+	Routes []Route
+}
+
+// LoadDir loads the schema files in a directory.
+func LoadDir(dir string) (*Schema, error) {
+	return Load(
+		filepath.Join(dir, TypesFileName),
+		filepath.Join(dir, EventsFileName),
+		filepath.Join(dir, APIFileName),
+	)
+}
+
+// Load loads and validates a schema. Properties that the files aren't
+// expected to have are rejected, so that misspelled ones don't go unnoticed.
+func Load(typesFile, eventsFile, apiFile string) (*Schema, error) {
+	s := &Schema{
+		TypesFile:  typesFile,
+		EventsFile: eventsFile,
+		APIFile:    apiFile,
+		Enums:      make(map[string]Enum),
+	}
+
+	types, err := loadFile(typesFile)
+	if err != nil {
+		return nil, err
+	}
+	events, err := loadFile(eventsFile)
+	if err != nil {
+		return nil, err
+	}
+	api, err := loadFile(apiFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each file only has its own sections, so that nothing is silently ignored
+	if len(types.Events) > 0 || len(types.Routes) > 0 || types.Info != nil {
+		return nil, fmt.Errorf("%s: only types and enums are allowed", typesFile)
+	}
+	if len(events.Types) > 0 || len(events.Routes) > 0 || events.Info != nil {
+		return nil, fmt.Errorf("%s: only events and enums are allowed", eventsFile)
+	}
+	if len(api.Types) > 0 || len(api.Events) > 0 || len(api.Enums) > 0 {
+		return nil, fmt.Errorf("%s: only info and routes are allowed", apiFile)
+	}
+
+	s.Types = types.Types
+	s.Events = events.Events
+	s.Routes = api.Routes
+	if api.Info != nil {
+		s.Info = *api.Info
+	}
+	for _, f := range []*file{types, events} {
+		for name, enum := range f.Enums {
+			if _, exists := s.Enums[name]; exists {
+				return nil, fmt.Errorf("enum %s: defined in both %s and %s", name, typesFile, eventsFile)
+			}
+			s.Enums[name] = enum
+		}
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func loadFile(path string) (*file, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var f file
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// IsType reports whether a name refers to a type in types.yml.
+func (s *Schema) IsType(name string) bool {
+	_, exists := s.Types[name]
+	return exists
+}
+
+// IsEnum reports whether a name refers to an enum.
+func (s *Schema) IsEnum(name string) bool {
+	_, exists := s.Enums[name]
+	return exists
+}
@@ -0,0 +1,202 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PrimitiveTypes are the types of properties that aren't defined in the
+// schema. Timestamps are RFC 3339 strings.
+var PrimitiveTypes = []string{"integer", "number", "string", "boolean", "timestamp"}
+
+// ParameterTypes are the types that route parameters can have, besides the
+// enums in the schema. Arrays can only have integer items.
+var ParameterTypes = []string{"integer", "string", "boolean", "timestamp", "array"}
+
+// EnvelopeFields are stored alongside the properties of every event, so events
+// can't have properties with these names. JSON decoding in Go ignores case, so
+// neither can they differ only in case.
+var EnvelopeFields = []string{"type", "timestamp"}
+
+// Methods are the HTTP methods that routes can have.
+var Methods = []string{"GET", "POST", "PUT", "DELETE"}
+
+var (
+	// Type, enum and property names become identifiers in generated code
+	identifierPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	// Event names are a type and an action, e.g. TaskList:UpdateTitle
+	eventNamePattern = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*:[A-Z][A-Za-z0-9]*$`)
+)
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate checks that every type the schema refers to is defined and that
+// the names in it can be used in generated code. It returns all the problems
+// it finds, not just the first.
+func (s *Schema) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for _, name := range sortedKeys(s.Enums) {
+		enum := s.Enums[name]
+		if !identifierPattern.MatchString(name) {
+			fail("enum %s: invalid name", name)
+		}
+		if s.IsType(name) || contains(PrimitiveTypes, name) {
+			fail("enum %s: a type has the same name", name)
+		}
+		if len(enum.Values) == 0 {
+			fail("enum %s: no values", name)
+		}
+		seen := make(map[string]bool)
+		for _, value := range enum.Values {
+			if value == "" {
+				fail("enum %s: empty value", name)
+			}
+			if seen[value] {
+				fail("enum %s: duplicate value %q", name, value)
+			}
+			seen[value] = true
+		}
+	}
+
+	for _, name := range sortedKeys(s.Types) {
+		if !identifierPattern.MatchString(name) || contains(PrimitiveTypes, name) || name == "array" {
+			fail("type %s: invalid name", name)
+		}
+		for _, err := range s.validateProperties(s.Types[name].Properties) {
+			fail("type %s: %w", name, err)
+		}
+	}
+
+	for _, name := range sortedKeys(s.Events) {
+		if !eventNamePattern.MatchString(name) {
+			fail("event %s: invalid name, expected Type:Action", name)
+		}
+		for _, err := range s.validateProperties(s.Events[name].Properties) {
+			fail("event %s: %w", name, err)
+		}
+		for _, propName := range sortedKeys(s.Events[name].Properties) {
+			if contains(EnvelopeFields, strings.ToLower(propName)) {
+				fail("event %s: property %s: reserved for the event envelope", name, propName)
+			}
+		}
+	}
+
+	routes := make(map[string]bool)
+	for _, route := range s.Routes {
+		for _, err := range s.validateRoute(route) {
+			fail("route %s: %w", route.Route, err)
+		}
+		// Routes are registered by path, whatever their method
+		if routes[route.Route] {
+			fail("route %s: defined more than once", route.Route)
+		}
+		routes[route.Route] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Schema) validateProperties(properties map[string]Property) []error {
+	var errs []error
+	for _, name := range sortedKeys(properties) {
+		prop := properties[name]
+		if !identifierPattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("property %s: invalid name", name))
+		}
+		if err := s.validatePropertyType(prop.Type, prop.ItemType); err != nil {
+			errs = append(errs, fmt.Errorf("property %s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validatePropertyType(typeName, itemType string) error {
+	if typeName == "array" {
+		if itemType == "" {
+			return fmt.Errorf("array without an itemType")
+		}
+		if itemType == "array" {
+			return fmt.Errorf("arrays of arrays are not supported")
+		}
+		if !s.isPropertyType(itemType) {
+			return fmt.Errorf("unknown itemType %q", itemType)
+		}
+		return nil
+	}
+	if itemType != "" {
+		return fmt.Errorf("itemType %q on a property of type %s", itemType, typeName)
+	}
+	if !s.isPropertyType(typeName) {
+		return fmt.Errorf("unknown type %q", typeName)
+	}
+	return nil
+}
+
+func (s *Schema) isPropertyType(typeName string) bool {
+	return contains(PrimitiveTypes, typeName) || s.IsType(typeName) || s.IsEnum(typeName)
+}
+
+func (s *Schema) validateRoute(route Route) []error {
+	var errs []error
+	if !strings.HasPrefix(route.Route, "/") {
+		errs = append(errs, fmt.Errorf("path must start with /"))
+	}
+	method := strings.ToUpper(route.Method)
+	if !contains(Methods, method) {
+		errs = append(errs, fmt.Errorf("unsupported method %q", route.Method))
+	}
+	if !s.IsType(route.Returns) {
+		errs = append(errs, fmt.Errorf("unknown return type %q", route.Returns))
+	}
+	if route.Body != "" {
+		if !s.IsType(route.Body) {
+			errs = append(errs, fmt.Errorf("unknown body type %q", route.Body))
+		}
+		if method != "POST" && method != "PUT" {
+			errs = append(errs, fmt.Errorf("only POST and PUT routes can have a body"))
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, param := range route.Parameters {
+		if !identifierPattern.MatchString(param.Name) || param.Name == "body" {
+			errs = append(errs, fmt.Errorf("parameter %s: invalid name", param.Name))
+		}
+		if names[param.Name] {
+			errs = append(errs, fmt.Errorf("parameter %s: defined more than once", param.Name))
+		}
+		names[param.Name] = true
+		switch {
+		case !contains(ParameterTypes, param.Type) && !s.IsEnum(param.Type):
+			errs = append(errs, fmt.Errorf("parameter %s: unsupported type %q", param.Name, param.Type))
+		case param.Type == "array" && param.ItemType != "integer":
+			errs = append(errs, fmt.Errorf("parameter %s: unsupported array item type %q", param.Name, param.ItemType))
+		case param.Type != "array" && param.ItemType != "":
+			errs = append(errs, fmt.Errorf("parameter %s: itemType %q on a parameter of type %s", param.Name, param.ItemType, param.Type))
+		}
+	}
+	return errs
+}